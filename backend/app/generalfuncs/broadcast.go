@@ -0,0 +1,29 @@
+package generalfuncs
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"social-network/app/handlers/websocket"
+)
+
+// SendToPostViewers sends the message to the online users who can see the post, the store is
+// asked once for all of them
+func SendToPostViewers(ctx context.Context, postID int, msg websocket.WebSocketMessage) {
+	online := []int{}
+	for _, id := range websocket.Hub.GetOnlineUsers() {
+		if userID, err := strconv.Atoi(id); err == nil {
+			online = append(online, userID)
+		}
+	}
+	if len(online) == 0 {
+		return
+	}
+	viewers, err := stores.Posts.Viewers(ctx, postID, online)
+	if err != nil {
+		log.Println("Error querying post viewers:", err)
+		return
+	}
+	websocket.SendToUsers(ctx, viewers, msg)
+}
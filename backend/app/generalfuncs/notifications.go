@@ -1,34 +1,31 @@
 package generalfuncs
 
 import (
+	"context"
 	"log"
 	"strconv"
-	"time"
 
 	"social-network/app/handlers/websocket"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/store"
 )
 
-func CreateNotification(n models.Notification) error {
+var stores *store.Stores
 
-	//horizontal expansion WORK BETTER
-	_, err := db.Database.Exec(`INSERT INTO notifications 
-	(user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, post_id, group_id, group_name, event_id, is_read, created_at) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		n.UserID,
-		n.FollowRequestID,
-		n.Type,
-		n.SenderID,
-		n.SenderName,
-		n.SenderAvatar,
-		n.PostID,
-		n.GroupID,
-		n.GroupName,
-		n.EventID,
-		false,
-		time.Now(),
-	)
+// SetStores is called by server.SetupRoutes before any notification can be sent
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// CreateNotification stores the notification and signals the user over the websocket.
+// Reactions are aggregated: they update the unread reaction notification of the same target
+func CreateNotification(ctx context.Context, n models.Notification) error {
+	var err error
+	if n.Type == "reaction" {
+		err = stores.Notifications.Aggregate(ctx, &n)
+	} else {
+		err = stores.Notifications.Create(ctx, &n)
+	}
 
 	//check if user is online and send websocket notification
 	log.Printf("Creating notification for user %d of type %s", n.UserID, n.Type)
@@ -39,6 +36,7 @@ func CreateNotification(n models.Notification) error {
 	if websocket.IsUserOnline(strconv.Itoa(n.UserID)) {
 		// for notification just a signal is enough
 		websocket.SendToUser(
+			ctx,
 			strconv.Itoa(n.UserID),
 			websocket.WebSocketMessage{
 				Type: "notification",
@@ -55,22 +53,11 @@ func CreateNotification(n models.Notification) error {
 // exclude the user who triggered the notification
 // to avoid sending notification to self
 // but i have to add the ws message for user that sent the message
-func GetGroupMemberIDs(groupID int, excludeUserID int) ([]int, error) {
-	var memberIDs []int
-	rows, err := db.Database.Query(`SELECT user_id FROM group_members WHERE group_id = ? AND user_id != ?`, groupID, excludeUserID)
+func GetGroupMemberIDs(ctx context.Context, groupID int, excludeUserID int) ([]int, error) {
+	memberIDs, err := stores.Groups.MemberIDs(ctx, groupID, excludeUserID)
 	if err != nil {
 		log.Println("Error querying group members:", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var userID int
-		if err := rows.Scan(&userID); err != nil {
-			log.Println("Error scanning group member ID:", err)
-			continue
-		}
-		memberIDs = append(memberIDs, userID)
-	}
 	return memberIDs, nil
 }
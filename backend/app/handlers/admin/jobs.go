@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+var stores *store.Stores
+
+// SetStores sets the job store read by the admin pages
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// ListJobs is GET /admin/jobs?status=failed&limit=50, the queue as the workers see it
+// plus how many jobs are in every status
+func ListJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobDone, models.JobFailed:
+	default:
+		response.Invalid(w, "Unknown job status", response.FieldError{Field: "status", Message: "must be pending, running, done or failed"})
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	jobs, err := stores.Jobs.List(r.Context(), status, limit)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to list jobs")
+		return
+	}
+	counts, err := stores.Jobs.Counts(r.Context())
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to count jobs")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]any{
+		"counts": counts,
+		"jobs":   jobs,
+	})
+}
@@ -0,0 +1,265 @@
+// Package audience handles the users' audience lists ("Close friends", "Family"), named groups of
+// people an almost_private post can be shared with in one go. A post shared with a list is seen by
+// whoever is in the list and follows the owner when it is read, see store.PostStore. Anyone can be
+// added, the others see nothing until they follow. Lists are private to their owner
+package audience
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the audience list handlers use
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+const (
+	maxNameLength = 50
+	// maxMembers is how many users one request can add
+	maxMembers = 100
+)
+
+// ListAudiences returns the user's lists by name, with their member counts
+func ListAudiences(w http.ResponseWriter, r *http.Request) {
+	lists, err := stores.Audiences.List(r.Context(), middleware.CurrentUserID(r))
+	if err != nil {
+		log.Println("Error querying audience lists:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch audience lists")
+		return
+	}
+	response.List(w, r, "", lists, nil)
+}
+
+type createRequest struct {
+	Name      string `json:"name"`
+	MemberIDs []int  `json:"member_ids"`
+}
+
+// CreateAudience adds a named list, the body is {"name": "Family", "member_ids": [4, 9]}
+func CreateAudience(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name, ok := validName(w, req.Name)
+	if !ok {
+		return
+	}
+	userID := middleware.CurrentUserID(r)
+	if !validMembers(w, userID, req.MemberIDs) {
+		return
+	}
+
+	l := models.AudienceList{UserID: userID, Name: name}
+	err := stores.Audiences.Create(r.Context(), &l)
+	if errors.Is(err, store.ErrConflict) {
+		response.Error(w, http.StatusConflict, "You already have an audience list with this name")
+		return
+	}
+	if err != nil {
+		log.Println("Error creating audience list:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to create audience list")
+		return
+	}
+	if len(req.MemberIDs) > 0 && !addMembers(w, r, l.ID, req.MemberIDs) {
+		stores.Audiences.Delete(r.Context(), l.ID)
+		return
+	}
+	if l, ok = reload(w, r, l.ID); ok {
+		response.JSON(w, http.StatusCreated, map[string]interface{}{"audience_list": l})
+	}
+}
+
+// GetAudience returns the list with its members
+func GetAudience(w http.ResponseWriter, r *http.Request) {
+	if l, ok := ownList(w, r); ok {
+		response.JSON(w, http.StatusOK, map[string]interface{}{"audience_list": l})
+	}
+}
+
+type renameRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameAudience changes the name of a list, the body is {"name": "Close friends"}
+func RenameAudience(w http.ResponseWriter, r *http.Request) {
+	l, ok := ownList(w, r)
+	if !ok {
+		return
+	}
+	var req renameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name, ok := validName(w, req.Name)
+	if !ok {
+		return
+	}
+	err := stores.Audiences.Rename(r.Context(), l.ID, name)
+	if errors.Is(err, store.ErrConflict) {
+		response.Error(w, http.StatusConflict, "You already have an audience list with this name")
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Audience list not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error renaming audience list:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to rename audience list")
+		return
+	}
+	l.Name = name
+	response.JSON(w, http.StatusOK, map[string]interface{}{"audience_list": l})
+}
+
+// DeleteAudience removes a list. The posts shared with it stay visible to the rest of their
+// audience, its members lose access unless they were picked one by one
+func DeleteAudience(w http.ResponseWriter, r *http.Request) {
+	l, ok := ownList(w, r)
+	if !ok {
+		return
+	}
+	if err := stores.Audiences.Delete(r.Context(), l.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Println("Error deleting audience list:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to delete audience list")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Audience list deleted"})
+}
+
+type membersRequest struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+// AddMembers puts users on the list, the body is {"user_ids": [4, 9]}. Users already on it are
+// left alone. The answer is the list with its members
+func AddMembers(w http.ResponseWriter, r *http.Request) {
+	l, ok := ownList(w, r)
+	if !ok {
+		return
+	}
+	var req membersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.UserIDs) == 0 {
+		response.Invalid(w, "No users to add", response.FieldError{Field: "user_ids", Message: "is required"})
+		return
+	}
+	if !validMembers(w, l.UserID, req.UserIDs) || !addMembers(w, r, l.ID, req.UserIDs) {
+		return
+	}
+	if l, ok = reload(w, r, l.ID); ok {
+		response.JSON(w, http.StatusOK, map[string]interface{}{"audience_list": l})
+	}
+}
+
+// RemoveMember takes a user off the list, the posts shared with it are hidden from them right away
+func RemoveMember(w http.ResponseWriter, r *http.Request) {
+	l, ok := ownList(w, r)
+	if !ok {
+		return
+	}
+	userID := params.PathID(r, "userID")
+	if userID <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	removed, err := stores.Audiences.RemoveMember(r.Context(), l.ID, userID)
+	if err != nil {
+		log.Println("Error removing audience list member:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to remove member")
+		return
+	}
+	if !removed {
+		response.Error(w, http.StatusNotFound, "User is not on this list")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Member removed"})
+}
+
+func validName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		response.Invalid(w, "Invalid audience list name", response.FieldError{Field: "name", Message: "is required, max 50 characters"})
+		return "", false
+	}
+	return name, true
+}
+
+// validMembers keeps the owner off their own lists, they see their posts anyway
+func validMembers(w http.ResponseWriter, ownerID int, userIDs []int) bool {
+	if len(userIDs) > maxMembers {
+		response.Invalid(w, "Too many users", response.FieldError{Field: "user_ids", Message: "at most 100 per request"})
+		return false
+	}
+	for _, id := range userIDs {
+		if id <= 0 || id == ownerID {
+			response.Invalid(w, "Invalid list member", response.FieldError{Field: "user_ids", Message: "must be other existing users"})
+			return false
+		}
+	}
+	return true
+}
+
+func addMembers(w http.ResponseWriter, r *http.Request, listID int, userIDs []int) bool {
+	err := stores.Audiences.AddMembers(r.Context(), listID, userIDs)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Invalid(w, "Invalid list member", response.FieldError{Field: "user_ids", Message: "must be other existing users"})
+		return false
+	}
+	if err != nil {
+		log.Println("Error adding audience list members:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to add members")
+		return false
+	}
+	return true
+}
+
+// ownList reads the {id} list, someone else's list is a 404 like a missing one
+func ownList(w http.ResponseWriter, r *http.Request) (models.AudienceList, bool) {
+	id := params.PathID(r, "id")
+	if id <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid audience list ID")
+		return models.AudienceList{}, false
+	}
+	l, err := stores.Audiences.Get(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && l.UserID != middleware.CurrentUserID(r)) {
+		response.Error(w, http.StatusNotFound, "Audience list not found")
+		return models.AudienceList{}, false
+	}
+	if err != nil {
+		log.Println("Error loading audience list:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch audience list")
+		return models.AudienceList{}, false
+	}
+	return l, true
+}
+
+// reload reads a list back with its members after a change
+func reload(w http.ResponseWriter, r *http.Request, id int) (models.AudienceList, bool) {
+	l, err := stores.Audiences.Get(r.Context(), id)
+	if err != nil {
+		log.Println("Error loading audience list:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch audience list")
+		return l, false
+	}
+	return l, true
+}
@@ -0,0 +1,127 @@
+package audience
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
+	"social-network/app/models"
+	"social-network/app/store/memstore"
+)
+
+func setup() {
+	s := memstore.New()
+	SetStores(s)
+	post.SetStores(s)
+	preview.SetStores(s)
+}
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// call runs the handler as RequireAuth would with the path values set
+func call(handler http.HandlerFunc, method, body string, userID int, path ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/audience-lists", strings.NewReader(body))
+	for i := 0; i+1 < len(path); i += 2 {
+		r.SetPathValue(path[i], path[i+1])
+	}
+	r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestAudienceLists(t *testing.T) {
+	setup()
+	ctx := context.Background()
+	owner := newUser(t, "owner")
+	friend := newUser(t, "friend")
+	other := newUser(t, "other")
+	for _, id := range []int{friend, other} {
+		if err := stores.Follows.Follow(ctx, id, owner); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := call(CreateAudience, http.MethodPost, fmt.Sprintf(`{"name": " Family ", "member_ids": [%d]}`, friend), owner)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		List models.AudienceList `json:"audience_list"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.List.Name != "Family" || created.List.MemberCount != 1 {
+		t.Errorf("created list = %+v", created.List)
+	}
+	id := strconv.Itoa(created.List.ID)
+
+	if w := call(CreateAudience, http.MethodPost, `{"name": "Family"}`, owner); w.Code != http.StatusConflict {
+		t.Errorf("same name: status %d, want 409", w.Code)
+	}
+	if w := call(AddMembers, http.MethodPut, fmt.Sprintf(`{"user_ids": [%d]}`, owner), owner, "id", id); w.Code != http.StatusBadRequest {
+		t.Errorf("owner on their own list: status %d, want 400", w.Code)
+	}
+	if w := call(AddMembers, http.MethodPut, `{"user_ids": [9999]}`, owner, "id", id); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown user: status %d, want 400", w.Code)
+	}
+	// someone else's list looks missing
+	if w := call(GetAudience, http.MethodGet, "", other, "id", id); w.Code != http.StatusNotFound {
+		t.Errorf("other user reading the list: status %d, want 404", w.Code)
+	}
+
+	// a post shared with the list, by someone else it's refused
+	body := `{"content": "family only", "privacy": "almost_private", "audience_lists": [` + id + `]}`
+	if w := call(post.CreatePost, http.MethodPost, body, other); w.Code != http.StatusBadRequest {
+		t.Errorf("post with someone else's list: status %d, want 400", w.Code)
+	}
+	w = call(post.CreatePost, http.MethodPost, body, owner)
+	var res struct {
+		Post models.Post `json:"post"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil || res.Post.ID == 0 {
+		t.Fatalf("create post: status %d, %v", w.Code, err)
+	}
+	canView := func(userID int) bool {
+		ok, err := stores.Posts.CanView(ctx, res.Post.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !canView(friend) || canView(other) {
+		t.Error("only the list member should see the post")
+	}
+
+	if w := call(RemoveMember, http.MethodDelete, "", owner, "id", id, "userID", strconv.Itoa(friend)); w.Code != http.StatusOK {
+		t.Errorf("remove member: status %d", w.Code)
+	}
+	if w := call(AddMembers, http.MethodPut, fmt.Sprintf(`{"user_ids": [%d]}`, other), owner, "id", id); w.Code != http.StatusOK {
+		t.Errorf("add member: status %d: %s", w.Code, w.Body)
+	}
+	if canView(friend) || !canView(other) {
+		t.Error("the post should follow the list's members")
+	}
+
+	if w := call(DeleteAudience, http.MethodDelete, "", owner, "id", id); w.Code != http.StatusOK {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if canView(other) {
+		t.Error("the post is still shared with the deleted list")
+	}
+}
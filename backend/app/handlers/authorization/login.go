@@ -2,7 +2,6 @@ package authorization
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
@@ -10,11 +9,19 @@ import (
 
 	"social-network/app/middleware"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/response"
+	"social-network/app/store"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+var stores *store.Stores
+
+// SetStores wires the user store used by login, logout and register
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
 //=================TO DO==================
 // add input validation and sanitization
 // implement rate limiting to prevent brute-force attacks
@@ -24,34 +31,29 @@ import (
 //========================================
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var loginReq models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&loginReq); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid JSON data")
 		return
 	}
 
 	if loginReq.Password == "" || loginReq.Email == "" {
-		http.Error(w, "Missing credentials", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Missing credentials")
 		return
 	}
 
 	userID, err := AuthenticateUser(r.Context(), loginReq)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
-			http.Error(w, "Wrong email or password", http.StatusUnauthorized)
+			response.Error(w, http.StatusUnauthorized, "Wrong email or password")
 			return
 		}
 		log.Printf("auth error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	middleware.CreateSession(userID, w)
+	middleware.CreateSession(userID, w, r)
 
 	// always return JSON for vue frontend
 	w.Header().Set("Content-Type", "application/json")
@@ -66,12 +68,9 @@ var ErrInvalidCredentials = errors.New("invalid credentials")
 
 func AuthenticateUser(ctx context.Context, loginReq models.LoginRequest) (int, error) {
 	// Query password and id in one go
-	var hashedPassword string
-	var userID int
-	query := "SELECT id, password FROM users WHERE email = ? LIMIT 1"
-	err := db.Database.QueryRowContext(ctx, query, loginReq.Email).Scan(&userID, &hashedPassword)
+	userID, hashedPassword, err := stores.Users.Credentials(ctx, loginReq.Email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			return 0, ErrInvalidCredentials
 		}
 		return 0, err
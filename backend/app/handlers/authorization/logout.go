@@ -4,40 +4,28 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"social-network/db"
+	"social-network/app/middleware"
+	"social-network/app/response"
 )
 
 // logout handler
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Get the session cookie
 	cookie, err := r.Cookie("session_token")
 	if err != nil {
-		http.Error(w, "No active session", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "No active session")
 		return
 	}
 
 	// Delete the session from the database
-	_, err = db.Database.Exec("DELETE FROM sessions WHERE id = ?", cookie.Value)
+	err = stores.Users.DeleteSession(r.Context(), cookie.Value)
 	if err != nil {
-		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
 
 	// Invalidate the cookie
-	expiredCookie := http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   -1,
-	}
-	http.SetCookie(w, &expiredCookie)
+	middleware.ClearSessionCookie(w, r)
 
 	// Return success response as JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -6,7 +6,7 @@ import (
 	"net/http"
 
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/response"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -20,83 +20,79 @@ import (
 //========================================
 
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	defer r.Body.Close()
 
 	var registerData models.RegisterStruct
 	if err := json.NewDecoder(r.Body).Decode(&registerData); err != nil {
 		log.Printf("Register: Failed to decode body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	log.Printf("Register: Received data - email: %s, username: %s, avatar: %s",
 		registerData.Email, registerData.Username, registerData.Avatar)
 
-	if registerData.Email == "" || registerData.Password == "" || registerData.FirstName == "" || registerData.LastName == "" || registerData.DateOfBirth == "" {
+	// every missing field is reported at once so the form can mark them all
+	var missing []response.FieldError
+	for _, field := range []struct{ name, value string }{
+		{"email", registerData.Email},
+		{"password", registerData.Password},
+		{"first_name", registerData.FirstName},
+		{"last_name", registerData.LastName},
+		{"date_of_birth", registerData.DateOfBirth},
+	} {
+		if field.value == "" {
+			missing = append(missing, response.FieldError{Field: field.name, Message: "is required"})
+		}
+	}
+	if len(missing) > 0 {
 		log.Printf("Register: Missing required fields")
-		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		response.Invalid(w, "Missing required fields", missing...)
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerData.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Register: Failed to hash password: %v", err)
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to hash password")
 		return
 	}
 
 	// Check if email already exists
-	var existingEmail string
-	err = db.Database.QueryRow("SELECT email FROM users WHERE email = ?", registerData.Email).Scan(&existingEmail)
-	if err == nil {
+	emailTaken, err := stores.Users.EmailTaken(r.Context(), registerData.Email)
+	if err != nil {
+		log.Printf("Register: Failed to check email: %v", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to register user")
+		return
+	}
+	if emailTaken {
 		log.Printf("Register: Email already exists: %s", registerData.Email)
-		http.Error(w, "Email already registered", http.StatusConflict)
+		response.ErrorCode(w, http.StatusConflict, "email_taken", "Email already registered")
 		return
 	}
 
 	// Check if username already exists (only if username is provided)
 	if registerData.Username != "" {
-		var existingUsername string
-		err = db.Database.QueryRow("SELECT username FROM users WHERE username = ?", registerData.Username).Scan(&existingUsername)
-		if err == nil {
+		usernameTaken, err := stores.Users.UsernameTaken(r.Context(), registerData.Username)
+		if err != nil {
+			log.Printf("Register: Failed to check username: %v", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to register user")
+			return
+		}
+		if usernameTaken {
 			log.Printf("Register: Username already exists: %s", registerData.Username)
-			http.Error(w, "Username already taken", http.StatusConflict)
+			response.ErrorCode(w, http.StatusConflict, "username_taken", "Username already taken")
 			return
 		}
 	}
 
-	query := `
-		INSERT INTO users (
-			email, password, first_name, last_name,
-			date_of_birth, avatar, username, about_me,
-			is_private, created_at
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-	`
-
-	result, err := db.Database.Exec(
-		query,
-		registerData.Email,
-		string(hashedPassword),
-		registerData.FirstName,
-		registerData.LastName,
-		registerData.DateOfBirth,
-		registerData.Avatar,
-		registerData.Username,
-		registerData.AboutMe,
-		registerData.IsPrivate,
-	)
+	userID, err := stores.Users.Create(r.Context(), registerData, string(hashedPassword))
 	if err != nil {
 		log.Printf("Register: Failed to insert user: %v", err)
-		http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to register user")
 		return
 	}
 
-	userID, _ := result.LastInsertId()
 	log.Printf("Register: User created successfully with ID: %d", userID)
 
 	w.Header().Set("Content-Type", "application/json")
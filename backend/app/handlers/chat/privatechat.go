@@ -4,41 +4,32 @@ package chat
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
-	"time"
 
+	"social-network/app/handlers/preview"
 	"social-network/app/handlers/profile"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
-// save message to database func
-func SavePrivateMessage(senderID int, receiverID int, content string, timestamp int64) {
-	message, err := db.Database.Prepare("INSERT INTO messages (sender_id, receiver_id, content, created_at) VALUES (?, ?, ?, ?)")
-	if err != nil {
-		return
-	}
-	defer message.Close()
+var stores *store.Stores
 
-	_, err = message.Exec(senderID, receiverID, content, time.Unix(timestamp, 0))
-	if err != nil {
-		return
-	}
+// SetStores sets the chat, group and follow stores used by the chat handlers
+func SetStores(s *store.Stores) {
+	stores = s
 }
 
 func GetConversations(w http.ResponseWriter, r *http.Request) {
 	// get users followers and user that are following him
 	// who are online and offline to appear at the conversations list
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID := r.Context().Value("ctxUserID").(int)
 	// fill the list with followers or followed users from db
-	followers := profile.GetUserFollowers(userID)
-	following := profile.GetUserFollowing(userID)
-	groups := GetUserGroups(userID)
+	followers := profile.GetUserFollowers(r.Context(), userID)
+	following := profile.GetUserFollowing(r.Context(), userID)
+	groups := GetUserGroups(r.Context(), userID)
 
 	// Send the list of followers as a JSON response
 	w.Header().Set("Content-Type", "application/json")
@@ -49,66 +40,57 @@ func GetConversations(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// messagesPerPage is the default page size of both chats, next_cursor goes to older messages
+const messagesPerPage = 50
+
 func GetMessages(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	chatId := params.Get(r, "id", "user_id")
+	if chatId == "" {
+		response.Error(w, http.StatusBadRequest, "Missing chatId parameter")
 		return
 	}
-
-	chatId := r.URL.Query().Get("user_id")
-	if chatId == "" {
-		http.Error(w, "Missing chatId parameter", http.StatusBadRequest)
+	chatID, err := strconv.Atoi(chatId)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid chatId parameter")
 		return
 	}
-	userIDint := r.Context().Value("ctxUserID").(int)
-	userID := strconv.Itoa(userIDint)
-	// Query messages between current user and chatId/receiverID user
-	rows, err := db.Database.Query(`
-        SELECT sender_id, receiver_id, content, created_at 
-        FROM messages 
-        WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)
-        ORDER BY created_at ASC
-    `, userID, chatId, chatId, userID)
+	userID := r.Context().Value("ctxUserID").(int)
+
+	page, err := params.Page(r, messagesPerPage)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.BadPage(w, err)
 		return
 	}
-	defer rows.Close()
 
-	var messages []map[string]interface{}
-	for rows.Next() {
-		var senderID, receiverID int
-		var content string
-		var createdAt time.Time
+	// Query messages between current user and chatId/receiverID user, the latest page first
+	conversation, next, err := stores.Chat.Conversation(r.Context(), userID, chatID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Database error")
+		return
+	}
 
-		if err := rows.Scan(&senderID, &receiverID, &content, &createdAt); err != nil {
-			continue
+	preview.Messages(r.Context(), conversation)
+	messages := []map[string]interface{}{}
+	for _, msg := range conversation {
+		m := map[string]interface{}{
+			"sender_id":   msg.SenderID,
+			"receiver_id": msg.ReceiverID,
+			"content":     msg.Content,
+			"created_at":  msg.CreatedAt,
 		}
-
-		messages = append(messages, map[string]interface{}{
-			"sender_id":   senderID,
-			"receiver_id": receiverID,
-			"content":     content,
-			"created_at":  createdAt.Unix(),
-		})
+		if msg.ContentHTML != nil {
+			m["format"], m["content_html"] = msg.Format, msg.ContentHTML
+		}
+		if msg.Preview != nil {
+			m["preview"] = msg.Preview
+		}
+		messages = append(messages, m)
 	}
 
-	if messages == nil {
-		messages = []map[string]interface{}{}
-	}
-	chatID, _ := strconv.Atoi(chatId)
 	// mark messages as read
-	MarkMessagesAsRead(chatID, userID)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(messages)
-}
+	if err := stores.Chat.MarkRead(r.Context(), chatID, userID); err != nil {
+		log.Printf("[CHAT] Failed to mark messages as read: %v", err)
+	}
 
-// mark messages as read
-func MarkMessagesAsRead(senderID int, receiverID string) error {
-	_, err := db.Database.Exec(
-		"UPDATE messages SET is_read = 1 WHERE sender_id = ? AND receiver_id = ? AND is_read = 0",
-		senderID, receiverID,
-	)
-	return err
+	response.List(w, r, "", messages, next)
 }
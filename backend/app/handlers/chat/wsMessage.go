@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -8,9 +9,13 @@ import (
 	"time"
 
 	"social-network/app/generalfuncs"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/preview"
 	"social-network/app/handlers/websocket"
+	"social-network/app/markdown"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
 )
 
 // general function to send message to receiver with appropriate json information
@@ -19,7 +24,7 @@ import (
 func SendMessage(w http.ResponseWriter, r *http.Request) {
 	senderID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -27,37 +32,40 @@ func SendMessage(w http.ResponseWriter, r *http.Request) {
 
 	var req models.SendMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /users/{id}/messages
+	if id := params.PathID(r, "id"); id > 0 {
+		req.ReceiverID = id
+	}
 
 	if req.Content == "" || req.ReceiverID == 0 {
-		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Missing required fields")
 		return
 	}
-
-	createdAt := time.Now().Unix()
-
-	result, err := db.Database.Exec(
-		"INSERT INTO messages (sender_id, receiver_id, content, created_at, is_read) VALUES (?, ?, ?, ?, 0)",
-		senderID, req.ReceiverID, req.Content, createdAt,
-	)
-	if err != nil {
-		log.Printf("[CHAT] DB error: %v", err)
-		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+	html, ok := markdown.Valid(w, &req.Format, req.Content, 0)
+	if !ok {
 		return
 	}
 
-	msgID, _ := result.LastInsertId()
-
 	msg := models.Message{
-		ID:         int(msgID),
-		SenderID:   senderID,
-		ReceiverID: req.ReceiverID,
-		Content:    req.Content,
-		CreatedAt:  createdAt,
-		IsRead:     false,
+		SenderID:    senderID,
+		ReceiverID:  req.ReceiverID,
+		Content:     req.Content,
+		Format:      req.Format,
+		ContentHTML: html,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	if err := stores.Chat.SaveMessage(r.Context(), &msg); err != nil {
+		log.Printf("[CHAT] DB error: %v", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to send message")
+		return
 	}
+	sent := []models.Message{msg} // a link shared before has its preview right away
+	preview.Messages(r.Context(), sent)
+	msg = sent[0]
 
 	wsMsg := websocket.WebSocketMessage{
 		Type: "private_message",
@@ -66,10 +74,10 @@ func SendMessage(w http.ResponseWriter, r *http.Request) {
 
 	receiverIDStr := strconv.Itoa(req.ReceiverID)
 	log.Printf("[CHAT] Sending message to receiver %s", receiverIDStr)
-	websocket.SendToUser(receiverIDStr, wsMsg)
-	SendMessageNotification(receiverIDStr, senderID)
+	websocket.SendToUser(r.Context(), receiverIDStr, wsMsg)
+	SendMessageNotification(r.Context(), receiverIDStr, senderID)
 	log.Printf("[CHAT] Sending message to sender %s", userID)
-	websocket.SendToUser(userID, wsMsg)
+	websocket.SendToUser(r.Context(), userID, wsMsg)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(msg)
@@ -79,80 +87,79 @@ func SendMessage(w http.ResponseWriter, r *http.Request) {
 func SendGroupMessage(w http.ResponseWriter, r *http.Request) {
 	senderID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req models.SendGroupMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /groups/{id}/messages
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
 
 	if req.Content == "" || req.GroupID == 0 {
-		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Missing required fields")
+		return
+	}
+	html, ok := markdown.Valid(w, &req.Format, req.Content, 0)
+	if !ok {
 		return
 	}
 
-	createdAt := time.Now().Unix()
+	groupMsg := models.GroupMessage{
+		GroupID:     req.GroupID,
+		SenderID:    senderID,
+		Content:     req.Content,
+		Format:      req.Format,
+		ContentHTML: html,
+		CreatedAt:   time.Now().Unix(),
+	}
 
-	// Save to database - use createdAt as Unix timestamp
-	result, err := db.Database.Exec(
-		"INSERT INTO group_messages (group_id, sender_id, content, created_at) VALUES (?, ?, ?, ?)",
-		req.GroupID, senderID, req.Content, createdAt,
-	)
-	if err != nil {
+	// Save to database - the store also fills in the sender's username for display
+	if err := stores.Chat.SaveGroupMessage(r.Context(), &groupMsg); err != nil {
 		log.Printf("[CHAT] DB error saving group message: %v", err)
-		http.Error(w, "Failed to save group message", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to save group message")
 		return
 	}
-
-	msgID, _ := result.LastInsertId()
+	sent := []models.GroupMessage{groupMsg}
+	preview.GroupMessages(r.Context(), sent)
+	groupMsg = sent[0]
 
 	// Get group members to broadcast to
-	memberIDs, err := generalfuncs.GetGroupMemberIDs(req.GroupID, senderID)
+	memberIDs, err := generalfuncs.GetGroupMemberIDs(r.Context(), req.GroupID, senderID)
 	if err != nil {
 		log.Printf("[CHAT] Error fetching group members: %v", err)
-		http.Error(w, "Failed to fetch group members", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch group members")
 		return
 	}
 
-	// Get sender's username for display
-	var senderName string
-	db.Database.QueryRow("SELECT username FROM users WHERE id = ?", senderID).Scan(&senderName)
-
-	// Build the message
-	groupMsg := models.GroupMessage{
-		ID:         int(msgID),
-		GroupID:    req.GroupID,
-		SenderID:   senderID,
-		SenderName: senderName,
-		Content:    req.Content,
-		CreatedAt:  createdAt,
-	}
-
 	wsMsg := websocket.WebSocketMessage{
 		Type: "group_message",
 		Data: groupMsg,
 	}
 
 	// Broadcast to all group members (including sender)
-	websocket.SendToUsers(memberIDs, wsMsg)
-	websocket.SendToUser(strconv.Itoa(senderID), wsMsg)
+	websocket.SendToUsers(r.Context(), memberIDs, wsMsg)
+	websocket.SendToUser(r.Context(), strconv.Itoa(senderID), wsMsg)
 
 	log.Printf("[CHAT] Group message sent to group %d by user %d", req.GroupID, senderID)
+	mention.GroupMessage(r.Context(), groupMsg)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(groupMsg)
 }
 
 // Send notification for new message
-func SendMessageNotification(receiverID string, senderID int) {
+func SendMessageNotification(ctx context.Context, receiverID string, senderID int) {
 	if websocket.IsUserOnline(receiverID) {
 		wsMsg := websocket.WebSocketMessage{
 			Type: "new_message_notification",
 			Data: senderID,
 		}
-		websocket.SendToUser(receiverID, wsMsg)
+		websocket.SendToUser(ctx, receiverID, wsMsg)
 	}
 }
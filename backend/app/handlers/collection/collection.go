@@ -0,0 +1,269 @@
+// Package collection handles the private collections of saved posts and group posts (bookmarks).
+// Every user has a default "Saved" collection next to the ones they create. A collection only
+// ever shows what its owner can still see, see store.CollectionStore
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the collection handlers use
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+const maxNameLength = 50
+
+// ListCollections returns the user's collections, "Saved" first
+func ListCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := stores.Collections.List(r.Context(), middleware.CurrentUserID(r))
+	if err != nil {
+		log.Println("Error querying collections:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch collections")
+		return
+	}
+	response.List(w, r, "", collections, nil)
+}
+
+// CreateCollection adds a named collection, the body is {"name": "Recipes"}
+func CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var c models.Collection
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > maxNameLength {
+		response.Invalid(w, "Invalid collection name", response.FieldError{Field: "name", Message: "is required, max 50 characters"})
+		return
+	}
+	c.UserID = middleware.CurrentUserID(r)
+
+	err := stores.Collections.Create(r.Context(), &c)
+	if errors.Is(err, store.ErrConflict) {
+		response.Error(w, http.StatusConflict, "You already have a collection with this name")
+		return
+	}
+	if err != nil {
+		log.Println("Error creating collection:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to create collection")
+		return
+	}
+	response.JSON(w, http.StatusCreated, map[string]interface{}{"collection": c})
+}
+
+// DeleteCollection removes a collection and its items, the posts stay where they are.
+// The default collection can only be emptied
+func DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	c, ok := ownCollection(w, r)
+	if !ok {
+		return
+	}
+	if c.IsDefault {
+		response.Error(w, http.StatusConflict, "The default collection can't be deleted")
+		return
+	}
+	if err := stores.Collections.Delete(r.Context(), c.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Println("Error deleting collection:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to delete collection")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Collection deleted"})
+}
+
+// ListItems returns the items of a collection in their order, with the posts as the feed shows
+// them. A post the user can't see anymore is left out, it comes back if it becomes visible again
+func ListItems(w http.ResponseWriter, r *http.Request) {
+	c, ok := ownCollection(w, r)
+	if !ok {
+		return
+	}
+	writeItems(w, r, c.ID)
+}
+
+type addRequest struct {
+	PostID      *int `json:"post_id"`
+	GroupPostID *int `json:"group_post_id"`
+}
+
+// AddItem saves a post or a group post the user can see at the end of the collection, the body
+// is {"post_id": 12} or {"group_post_id": 7}. Saving it again answers the existing item with a 200
+func AddItem(w http.ResponseWriter, r *http.Request) {
+	c, ok := ownCollection(w, r)
+	if !ok {
+		return
+	}
+	var req addRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if (req.PostID == nil) == (req.GroupPostID == nil) {
+		response.Invalid(w, "Either post_id or group_post_id is required",
+			response.FieldError{Field: "post_id", Message: "set exactly one of post_id and group_post_id"})
+		return
+	}
+
+	item := models.CollectionItem{CollectionID: c.ID, PostID: req.PostID, GroupPostID: req.GroupPostID}
+	added, err := stores.Collections.Add(r.Context(), &item, middleware.CurrentUserID(r))
+	if errors.Is(err, store.ErrNotFound) { // missing or not visible, the same answer as GetPostHandler
+		response.Error(w, http.StatusNotFound, "Post not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error saving post to collection:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to save post")
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	response.JSON(w, status, map[string]interface{}{"item": item})
+}
+
+// RemoveItem takes an item out of the collection
+func RemoveItem(w http.ResponseWriter, r *http.Request) {
+	c, ok := ownCollection(w, r)
+	if !ok {
+		return
+	}
+	itemID := params.PathID(r, "itemID")
+	if itemID <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+	err := stores.Collections.Remove(r.Context(), c.ID, itemID)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Item not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error removing collection item:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to remove item")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Item removed"})
+}
+
+type orderRequest struct {
+	ItemIDs []int `json:"item_ids"`
+}
+
+// ReorderItems moves items to the front of the collection in the given order, the body is
+// {"item_ids": [5, 2]}. Items left out keep their order behind them, so the hidden ones the user
+// doesn't know about stay put. The answer is the reordered list
+func ReorderItems(w http.ResponseWriter, r *http.Request) {
+	c, ok := ownCollection(w, r)
+	if !ok {
+		return
+	}
+	var req orderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err := stores.Collections.Reorder(r.Context(), c.ID, req.ItemIDs)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Invalid(w, "Invalid item order",
+			response.FieldError{Field: "item_ids", Message: "must be items of the collection, each once"})
+		return
+	}
+	if err != nil {
+		log.Println("Error reordering collection:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to reorder items")
+		return
+	}
+	writeItems(w, r, c.ID)
+}
+
+// ownCollection reads the {id} collection, someone else's collection is a 404 like a missing one
+func ownCollection(w http.ResponseWriter, r *http.Request) (models.Collection, bool) {
+	id := params.PathID(r, "id")
+	if id <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid collection ID")
+		return models.Collection{}, false
+	}
+	c, err := stores.Collections.Get(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && c.UserID != middleware.CurrentUserID(r)) {
+		response.Error(w, http.StatusNotFound, "Collection not found")
+		return models.Collection{}, false
+	}
+	if err != nil {
+		log.Println("Error loading collection:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch collection")
+		return models.Collection{}, false
+	}
+	return c, true
+}
+
+func writeItems(w http.ResponseWriter, r *http.Request, collectionID int) {
+	userID := middleware.CurrentUserID(r)
+	items, err := stores.Collections.Items(r.Context(), collectionID, userID)
+	if err == nil {
+		err = fill(r.Context(), items, userID)
+	}
+	if err != nil {
+		log.Println("Error querying collection items:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch collection")
+		return
+	}
+	response.List(w, r, "", items, nil)
+}
+
+// fill adds what the feed and the group pages show to the saved posts: reactions, polls, link
+// previews and the shared post of reposts
+func fill(ctx context.Context, items []models.CollectionItem, viewerID int) error {
+	posts, groupPosts := []models.Post{}, []models.GroupPost{}
+	for _, item := range items {
+		if item.Post != nil {
+			posts = append(posts, *item.Post)
+		} else {
+			groupPosts = append(groupPosts, *item.GroupPost)
+		}
+	}
+	if err := reaction.Posts(ctx, posts, viewerID); err != nil {
+		return err
+	}
+	if err := post.WithOriginals(ctx, posts, viewerID); err != nil {
+		return err
+	}
+	if err := poll.Posts(ctx, posts, viewerID); err != nil {
+		return err
+	}
+	if err := reaction.GroupPosts(ctx, groupPosts, viewerID); err != nil {
+		return err
+	}
+	if err := poll.GroupPosts(ctx, groupPosts, viewerID); err != nil {
+		return err
+	}
+	preview.Posts(ctx, posts)
+
+	for i := range items {
+		if items[i].Post != nil {
+			items[i].Post, posts = &posts[0], posts[1:]
+		} else {
+			items[i].GroupPost, groupPosts = &groupPosts[0], groupPosts[1:]
+		}
+	}
+	return nil
+}
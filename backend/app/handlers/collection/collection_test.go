@@ -0,0 +1,94 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/models"
+	"social-network/app/store/memstore"
+)
+
+func setup() {
+	s := memstore.New()
+	SetStores(s)
+	post.SetStores(s)
+	poll.SetStores(s)
+	preview.SetStores(s)
+	reaction.SetStores(s)
+}
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// call runs the handler as RequireAuth would with the {id} path value set
+func call(handler http.HandlerFunc, method string, collectionID int, body string, userID int) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/collections/"+strconv.Itoa(collectionID), strings.NewReader(body))
+	r.SetPathValue("id", strconv.Itoa(collectionID))
+	r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestSavedCollection(t *testing.T) {
+	setup()
+	ctx := context.Background()
+	owner := newUser(t, "owner")
+	other := newUser(t, "other")
+	p := models.Post{UserID: other, Content: "worth keeping", Privacy: models.PrivacyPublic}
+	if err := stores.Posts.Create(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	collections, err := stores.Collections.List(ctx, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := collections[0].ID
+
+	body := `{"post_id": ` + strconv.Itoa(p.ID) + `}`
+	if w := call(AddItem, http.MethodPost, saved, body, owner); w.Code != http.StatusCreated {
+		t.Errorf("save: status %d, want 201: %s", w.Code, w.Body)
+	}
+	if w := call(AddItem, http.MethodPost, saved, body, owner); w.Code != http.StatusOK {
+		t.Errorf("save again: status %d, want 200", w.Code)
+	}
+	if w := call(AddItem, http.MethodPost, saved, `{}`, owner); w.Code != http.StatusBadRequest {
+		t.Errorf("save without a post: status %d, want 400", w.Code)
+	}
+
+	// someone else's collection looks missing
+	if w := call(ListItems, http.MethodGet, saved, "", other); w.Code != http.StatusNotFound {
+		t.Errorf("other user listing the collection: status %d, want 404", w.Code)
+	}
+	if w := call(DeleteCollection, http.MethodDelete, saved, "", owner); w.Code != http.StatusConflict {
+		t.Errorf("deleting the default collection: status %d, want 409", w.Code)
+	}
+
+	w := call(ListItems, http.MethodGet, saved, "", owner)
+	var page struct {
+		Items []models.CollectionItem `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Post == nil || page.Items[0].Post.Content != p.Content {
+		t.Errorf("items = %+v, want the saved post", page.Items)
+	}
+}
@@ -7,114 +7,92 @@ import (
 	"strings"
 
 	"social-network/app/generalfuncs"
+	"social-network/app/handlers/images"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/reaction"
+	"social-network/app/markdown"
+	"social-network/app/middleware"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
-func CommentsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		GetComments(w, r)
-	case http.MethodPost:
-		CreateComment(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+var stores *store.Stores
+
+// SetStores wires the post store the comment handlers read and write
+func SetStores(s *store.Stores) {
+	stores = s
 }
 
-func CreateComment(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// maxContentLength is the visible length of a comment, like the posts'
+const maxContentLength = 400
 
+func CreateComment(w http.ResponseWriter, r *http.Request) {
 	var comment models.Comment
 	err := json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 	comment.UserID = r.Context().Value("ctxUserID").(int)
+	// POST /posts/{id}/comments, the body only has the content
+	if id := params.PathID(r, "id"); id > 0 {
+		comment.PostID = id
+	}
 
 	// Input validation ---------------------------------------------------------------------------
-	if comment.Image == nil && strings.TrimSpace(comment.Content) == "" {
-		http.Error(w, "Image or text is required", http.StatusBadRequest)
+	if comment.Image == nil && len(comment.Attachments) == 0 && strings.TrimSpace(comment.Content) == "" {
+		response.Error(w, http.StatusBadRequest, "Image or text is required")
 		return
 	}
-	if len(comment.Content) > 400 {
-		http.Error(w, "Content is too long (max 400 characters)", http.StatusBadRequest)
+	html, ok := markdown.Valid(w, &comment.Format, comment.Content, maxContentLength)
+	if !ok {
 		return
 	}
-	if comment.Image != nil && *comment.Image != "" && !strings.HasPrefix(*comment.Image, "/images") {
-		http.Error(w, "Invalid image path", http.StatusBadRequest)
-		return
+	comment.ContentHTML = html
+	var image string
+	if comment.Image != nil {
+		image = *comment.Image
 	}
-
-	// Insert comment into database
-	query := `INSERT INTO comments (content, image, post_id, user_id, created_at) 
-VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`
-
-	result, err := db.Database.Exec(query,
-		comment.Content,
-		comment.Image,
-		comment.PostID,
-		comment.UserID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+	image, ok = images.Attach(w, &comment.Attachments, image)
+	if !ok {
 		return
 	}
-	// insert the notification of the comment for the post owner
-	//
-	var postOwnerID int
-	err = db.Database.QueryRow(
-		"SELECT user_id FROM posts WHERE id = ?",
-		comment.PostID,
-	).Scan(&postOwnerID)
-	if err != nil {
-		http.Error(w, "Failed to get post owner", http.StatusInternalServerError)
-		return
+	comment.Image = nil
+	if image != "" {
+		comment.Image = &image
 	}
-	// avoid notifying oneself
-	//
-	// Get the ID of the newly created comment
-	commentID, err := result.LastInsertId()
+
+	// the post owner receives the notification of the comment
+	post, err := stores.Posts.Get(r.Context(), comment.PostID)
 	if err != nil {
-		http.Error(w, "Failed to get comment ID", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to get post owner")
 		return
 	}
-	comment.ID = int(commentID)
-
-	// Fetch the comment including username, avatar, and created_at to match expected structure
-	row := db.Database.QueryRow(`
-		SELECT c.id, c.content, c.image, c.post_id, c.user_id, c.created_at, 
-		       u.username, u.first_name, u.last_name, u.avatar
-		FROM comments c
-		JOIN users u ON c.user_id = u.id
-		WHERE c.id = ?`, comment.ID)
-
-	var username, first_name, last_name, avatar string
-	if err := row.Scan(&comment.ID, &comment.Content, &comment.Image, &comment.PostID,
-		&comment.UserID, &comment.CreatedAt, &username, &first_name, &last_name, &avatar); err != nil {
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+	postOwnerID := post.UserID
+
+	// Insert comment into database, the store fills in username, avatar and created_at
+	// to match the expected structure
+	if err := stores.Posts.CreateComment(r.Context(), &comment); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to create comment")
 		return
 	}
-	comment.Username = username
-	comment.FirstName = first_name
-	comment.LastName = last_name
-	comment.Avatar = &avatar
 
+	mention.Comment(r.Context(), comment)
+
+	// avoid notifying oneself
 	if postOwnerID != comment.UserID {
 		// insert notification into database
-		err = generalfuncs.CreateNotification(models.Notification{
+		err = generalfuncs.CreateNotification(r.Context(), models.Notification{
 			UserID:     postOwnerID,     // who receives it
 			Type:       "new_comment",   // notification type
 			SenderID:   &comment.UserID, // who triggered it
 			PostID:     &comment.PostID, // related post
-			SenderName: &first_name,
+			SenderName: &comment.FirstName,
 		})
 		if err != nil {
-			http.Error(w, "Failed to create notification", http.StatusInternalServerError)
+			response.Error(w, http.StatusInternalServerError, "Failed to create notification")
 			return
 		}
 	}
@@ -125,49 +103,37 @@ VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`
 	json.NewEncoder(w).Encode(comment)
 }
 
-// GET /comments?post_id=123
+// commentsPerPage is the default page size of GetComments, oldest comments first
+const commentsPerPage = 50
+
+// GET /posts/123/comments (or the old GET /comments?post_id=123)
 func GetComments(w http.ResponseWriter, r *http.Request) {
-	postIDStr := r.URL.Query().Get("post_id")
+	postIDStr := params.Get(r, "id", "post_id")
 	if postIDStr == "" {
-		http.Error(w, "post_id is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "post_id is required")
 		return
 	}
 
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "invalid post_id", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "invalid post_id")
 		return
 	}
 
-	query := `
-        SELECT c.id, c.content, c.image, c.post_id, c.user_id, c.created_at, u.username, u.first_name, u.last_name, u.avatar
-        FROM comments c
-        JOIN users u ON c.user_id = u.id
-        WHERE c.post_id = ?
-        ORDER BY c.created_at ASC
-    `
-	rows, err := db.Database.Query(query, postID)
+	page, err := params.Page(r, commentsPerPage)
 	if err != nil {
-		http.Error(w, "failed to fetch comments", http.StatusInternalServerError)
+		response.BadPage(w, err)
 		return
 	}
-	defer rows.Close()
-
-	comments := []models.Comment{}
-	for rows.Next() {
-		var c models.Comment
-		var username, firstName, lastName, avatar string
-		if err := rows.Scan(&c.ID, &c.Content, &c.Image, &c.PostID, &c.UserID, &c.CreatedAt, &username, &firstName, &lastName, &avatar); err != nil {
-			http.Error(w, "failed to parse comment", http.StatusInternalServerError)
-			return
-		}
-		c.Username = username
-		c.FirstName = firstName
-		c.LastName = lastName
-		c.Avatar = &avatar
-		comments = append(comments, c)
+	comments, next, err := stores.Posts.Comments(r.Context(), postID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to fetch comments")
+		return
+	}
+	if err := reaction.Comments(r.Context(), comments, middleware.CurrentUserID(r)); err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to fetch reactions")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
+	response.List(w, r, "", comments, next)
 }
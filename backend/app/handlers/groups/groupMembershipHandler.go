@@ -1,8 +1,10 @@
 package groups
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -10,7 +12,10 @@ import (
 
 	"social-network/app/generalfuncs"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+	"social-network/app/telemetry"
 )
 
 type InviteRequest struct {
@@ -25,120 +30,95 @@ type RespondToInviteRequest struct {
 func InviteUserToGroup(w http.ResponseWriter, r *http.Request) {
 	inviterID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req InviteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /groups/{id}/invites
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
 
 	if req.GroupID <= 0 || req.InviteeID <= 0 {
-		http.Error(w, "Invalid group or user ID", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid group or user ID")
 		return
 	}
 
-	var isMember bool
-	if err := db.Database.QueryRow(
-		`SELECT EXISTS(
-			SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?
-		)`,
-		req.GroupID, inviterID,
-	).Scan(&isMember); err != nil {
+	isMember, err := stores.Groups.IsMember(r.Context(), req.GroupID, inviterID)
+	if err != nil {
 		log.Println("[Invite] membership check failed:", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
 	if !isMember {
-		http.Error(w, "You must be a member to invite users", http.StatusForbidden)
+		response.Error(w, http.StatusForbidden, "You must be a member to invite users")
 		return
 	}
 
 	// check if invitee is already a member
-	var alreadyMember bool
-	if err := db.Database.QueryRow(
-		`SELECT EXISTS(
-			SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?
-		)`,
-		req.GroupID, req.InviteeID,
-	).Scan(&alreadyMember); err != nil {
+	alreadyMember, err := stores.Groups.IsMember(r.Context(), req.GroupID, req.InviteeID)
+	if err != nil {
 		log.Println("[Invite] member check failed:", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
 	if alreadyMember {
-		http.Error(w, "User is already a member", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "User is already a member")
 		return
 	}
 
 	// check if invitation already exists
-	var existingInvite bool
-	if err := db.Database.QueryRow(
-		`SELECT EXISTS(
-			SELECT 1 FROM group_invitations
-			WHERE group_id = ? AND user_id = ? AND status = 'pending'
-		)`,
-		req.GroupID, req.InviteeID,
-	).Scan(&existingInvite); err != nil {
+	existingInvite, err := stores.Groups.HasPendingInvite(r.Context(), req.GroupID, req.InviteeID)
+	if err != nil {
 		log.Println("[Invite] invite check failed:", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
 	if existingInvite {
-		http.Error(w, "Invitation already sent", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invitation already sent")
 		return
 	}
 
-	now := time.Now().Unix()
-
 	// create invitation
-	if _, err := db.Database.Exec(
-		`INSERT INTO group_invitations
-		 (group_id, inviter_id, user_id, status, created_at)
-		 VALUES (?, ?, ?, 'pending', ?)`,
-		req.GroupID, inviterID, req.InviteeID, now,
-	); err != nil {
+	if err := stores.Groups.CreateInvite(r.Context(), req.GroupID, inviterID, req.InviteeID); err != nil {
 		log.Println("[Invite] insert invitation failed:", err)
-		http.Error(w, "Failed to send invitation", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to send invitation")
 		return
 	}
 
 	// query groupname
-	var groupName string
-	if err := db.Database.QueryRow(
-		"SELECT group_name FROM groups WHERE id = ?",
-		req.GroupID,
-	).Scan(&groupName); err != nil {
+	group, err := stores.Groups.Get(r.Context(), req.GroupID, inviterID)
+	if err != nil {
 		log.Println("[Invite] group lookup failed:", err)
-		http.Error(w, "Group not found", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Group not found")
 		return
 	}
 
 	// query inviter name
-	var inviterName string
-	if err := db.Database.QueryRow(
-		"SELECT username FROM users WHERE id = ?",
-		inviterID,
-	).Scan(&inviterName); err != nil {
+	inviterName, err := displayName(r.Context(), inviterID)
+	if err != nil {
 		log.Println("[Invite] inviter lookup failed:", err)
-		http.Error(w, "User not found", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "User not found")
 		return
 	}
 
 	// Create notification
 	// send ws notification
-	err := generalfuncs.CreateNotification(models.Notification{
+	err = generalfuncs.CreateNotification(r.Context(), models.Notification{
 		UserID:     req.InviteeID,
 		Type:       "group_invitation",
 		SenderID:   &inviterID,
 		SenderName: &inviterName,
 		GroupID:    &req.GroupID,
-		GroupName:  &groupName,
+		GroupName:  &group.Groupname,
 		CreatedAt:  time.Now(),
 	})
 	if err != nil {
@@ -154,72 +134,40 @@ func InviteUserToGroup(w http.ResponseWriter, r *http.Request) {
 func AcceptGroupInvite(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	// FIXED: Decode into the struct, not into a field
 	var req RespondToInviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeBody(r, &req); err != nil {
 		log.Println("[AcceptInvite] decode error:", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
 
 	log.Printf("[AcceptInvite] userID=%d, groupID=%d", userID, req.GroupID)
 
 	if req.GroupID <= 0 {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
-	// FIXED: Use user_id column (not invitee_id) to match your INSERT
-	var inviteID int
-	err := db.Database.QueryRow(
-		"SELECT id FROM group_invitations WHERE group_id = ? AND user_id = ? AND status = 'pending'",
-		req.GroupID, userID,
-	).Scan(&inviteID)
-
-	if err != nil {
-		log.Println("[AcceptInvite] invitation not found:", err)
-		http.Error(w, "Invitation not found", http.StatusNotFound)
-		return
-	}
-
-	now := time.Now().Unix()
-
-	// Update invitation status
-	_, err = db.Database.Exec(
-		"UPDATE group_invitations SET status = 'accepted' WHERE id = ?",
-		inviteID,
-	)
-	if err != nil {
-		log.Println("[AcceptInvite] update failed:", err)
-	}
-
-	// Add user as member
-	_, err = db.Database.Exec(
-		"INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'member', ?)",
-		req.GroupID, userID, now,
-	)
-
-	if err != nil {
+	// joins the group and deletes the invitation plus its notification
+	if err := stores.Groups.AcceptInvite(r.Context(), req.GroupID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			log.Println("[AcceptInvite] invitation not found:", err)
+			response.Error(w, http.StatusNotFound, "Invitation not found")
+			return
+		}
 		log.Println("[AcceptInvite] insert member failed:", err)
-		http.Error(w, "Failed to join group", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to join group")
 		return
 	}
 
-	// delete notification and invitation
-	db.Database.Exec(
-		"DELETE FROM notifications WHERE user_id = ? AND type = 'group_invitation' AND group_id = ?",
-		userID, req.GroupID,
-	)
-
-	db.Database.Exec(
-		"DELETE FROM group_invitations WHERE user_id = ? AND group_id = ?",
-		userID, req.GroupID,
-	)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Joined group successfully"})
 }
@@ -227,55 +175,39 @@ func AcceptGroupInvite(w http.ResponseWriter, r *http.Request) {
 func DeclineGroupInvite(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	// FIXED: Decode into struct, not into int directly
 	var req RespondToInviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeBody(r, &req); err != nil {
 		log.Println("[DeclineInvite] decode error:", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
 
 	log.Printf("[DeclineInvite] userID=%d, groupID=%d", userID, req.GroupID)
 
 	if req.GroupID <= 0 {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
-	// FIXED: Use user_id column (not invitee_id) to match your INSERT
-	result, err := db.Database.Exec(
-		"UPDATE group_invitations SET status = 'declined' WHERE group_id = ? AND user_id = ? AND status = 'pending'",
-		req.GroupID, userID,
-	)
-
-	if err != nil {
+	// declines and deletes the invitation plus its notification
+	if err := stores.Groups.DeclineInvite(r.Context(), req.GroupID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			response.Error(w, http.StatusNotFound, "Invitation not found")
+			return
+		}
 		log.Println("[DeclineInvite] update failed:", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Invitation not found", http.StatusNotFound)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
-	// Delete related notifications
-	//and delete the invitation itself
-	db.Database.Exec(
-		"DELETE FROM notifications WHERE user_id = ? AND type = 'group_invitation' AND group_id = ?",
-		userID, req.GroupID,
-	)
-
-	db.Database.Exec(
-		"DELETE FROM group_invitations WHERE user_id = ? AND group_id = ?",
-		userID, req.GroupID,
-	)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation declined"})
 }
@@ -283,90 +215,69 @@ func DeclineGroupInvite(w http.ResponseWriter, r *http.Request) {
 func RequestJoinGroup(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	groupID := r.FormValue("group_id")
+	// POST /groups/{id}/requests, or the old form value
+	groupID := r.PathValue("id")
+	if groupID == "" {
+		groupID = r.FormValue("group_id")
+	}
 	log.Printf("[JoinRequest] userID=%d, groupID=%s", userID, groupID)
 
 	if groupID == "" {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
-	groupIDInt, _ := strconv.Atoi(groupID)
+	groupIDInt, err := strconv.Atoi(groupID)
+	if err != nil || groupIDInt <= 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
+		return
+	}
 
 	// Check if already a member
-	var isMember bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
-		groupID, userID,
-	).Scan(&isMember)
-
+	isMember, _ := stores.Groups.IsMember(r.Context(), groupIDInt, userID)
 	if isMember {
-		http.Error(w, "Already a member", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Already a member")
 		return
 	}
 
 	// Check if request already exists
-	var existingRequest bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_join_requests WHERE group_id = ? AND user_id = ? AND status = 'pending')",
-		groupID, userID,
-	).Scan(&existingRequest)
-
+	existingRequest, _ := stores.Groups.HasPendingJoinRequest(r.Context(), groupIDInt, userID)
 	if existingRequest {
-		http.Error(w, "Request already pending", http.StatusBadRequest)
-		return
-	}
-
-	now := time.Now().Unix()
-	// CREATE TABLE IF NOT EXISTS group_join_requests (
-	//     id INTEGER PRIMARY KEY AUTOINCREMENT,
-	//     group_id INTEGER NOT NULL,
-	//     user_id INTEGER NOT NULL,
-	//     status TEXT DEFAULT 'pending',
-	//     created_at INTEGER NOT NULL,
-	//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
-	//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-	//     UNIQUE(group_id, user_id)
-	// );
-	_, err := db.Database.Exec(
-		"INSERT INTO group_join_requests (group_id, user_id, status, created_at) VALUES (?, ?, 'pending', ?)",
-		groupIDInt, userID, now,
-	)
+		response.Error(w, http.StatusBadRequest, "Request already pending")
+		return
+	}
 
-	if err != nil {
-		http.Error(w, "Failed to send request", http.StatusInternalServerError)
+	if err := stores.Groups.CreateJoinRequest(r.Context(), groupIDInt, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to send request")
 		return
 	}
 
-	// Get group creator and title
-	var creatorID int
-	var groupName string
-	err = db.Database.QueryRow("SELECT COALESCE(group_name, title), creator_id FROM groups WHERE id = ?", groupID).Scan(&groupName, &creatorID)
+	// Get group creator and name
+	group, err := stores.Groups.Get(r.Context(), groupIDInt, userID)
 	if err != nil {
-		http.Error(w, "Failed to get group info", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to get group info")
 		return
 	}
 
 	//get sender name
-	var senderName string
-	err = db.Database.QueryRow("SELECT COALESCE(username, first_name) FROM users WHERE id = ?", userID).Scan(&senderName)
+	senderName, err := displayName(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to get user info")
 		return
 	}
 
 	// notification and ws
-	err = generalfuncs.CreateNotification(models.Notification{
-		UserID:     creatorID,
+	err = generalfuncs.CreateNotification(r.Context(), models.Notification{
+		UserID:     group.CreatorID,
 		Type:       "group_join_request",
 		SenderID:   &userID,
 		SenderName: &senderName,
 		GroupID:    &groupIDInt,
-		GroupName:  &groupName,
+		GroupName:  &group.Groupname,
 		CreatedAt:  time.Now(),
 	})
 	if err != nil {
@@ -380,60 +291,27 @@ func RequestJoinGroup(w http.ResponseWriter, r *http.Request) {
 func GetJoinRequests(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	groupID := r.URL.Query().Get("id")
-	if groupID == "" {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+	groupID := params.ID(r, "id", "id")
+	if groupID == 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
 	// Check if user is the creator
-	var creatorID int
-	db.Database.QueryRow("SELECT creator_id FROM groups WHERE id = ?", groupID).Scan(&creatorID)
-
-	if creatorID != userID {
-		http.Error(w, "Only the creator can view join requests", http.StatusForbidden)
+	if !isCreator(r.Context(), groupID, userID) {
+		response.Error(w, http.StatusForbidden, "Only the creator can view join requests")
 		return
 	}
 
-	rows, err := db.Database.Query(`
-        SELECT gjr.id, gjr.user_id, gjr.created_at, u.username, u.avatar
-        FROM group_join_requests gjr
-        JOIN users u ON gjr.user_id = u.id
-        WHERE gjr.group_id = ? AND gjr.status = 'pending'
-        ORDER BY gjr.created_at DESC
-    `, groupID)
-
+	requests, err := stores.Groups.JoinRequests(r.Context(), groupID)
 	if err != nil {
-		http.Error(w, "Failed to fetch requests", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch requests")
 		return
 	}
-	defer rows.Close()
-
-	type JoinRequest struct {
-		ID        int    `json:"id"`
-		UserID    int    `json:"user_id"`
-		Username  string `json:"username"`
-		Avatar    string `json:"avatar,omitempty"`
-		CreatedAt int64  `json:"created_at"`
-	}
-
-	requests := []JoinRequest{}
-	for rows.Next() {
-		var req JoinRequest
-		var avatar *string
-		err := rows.Scan(&req.ID, &req.UserID, &req.CreatedAt, &req.Username, &avatar)
-		if err != nil {
-			continue
-		}
-		if avatar != nil {
-			req.Avatar = *avatar
-		}
-		requests = append(requests, req)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(requests)
@@ -447,153 +325,81 @@ type ApproveRequestPayload struct {
 func ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
 	approverID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req ApproveRequestPayload
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeBody(r, &req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /groups/{id}/requests/{userID}/approve|reject
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
+	if id := params.PathID(r, "userID"); id > 0 {
+		req.RequesterID = id
+	}
 
 	if req.GroupID <= 0 || req.RequesterID <= 0 {
-		http.Error(w, "Group ID and User ID are required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID and User ID are required")
 		return
 	}
 
 	// Check creator
-	var creatorID int
-	err := db.Database.QueryRow(
-		"SELECT creator_id FROM groups WHERE id = ?",
-		req.GroupID,
-	).Scan(&creatorID)
-
-	if err == sql.ErrNoRows {
-		http.Error(w, "Group not found", http.StatusNotFound)
+	group, err := stores.Groups.Get(r.Context(), req.GroupID, approverID)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Group not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
-	if creatorID != approverID {
-		http.Error(w, "Only the creator can approve requests", http.StatusForbidden)
+	if !group.IsCreator {
+		response.Error(w, http.StatusForbidden, "Only the creator can approve requests")
 		return
 	}
 
 	// Check join request exists
-	var requestID int
-	var status string
-	err = db.Database.QueryRow(
-		"SELECT id, status FROM group_join_requests WHERE group_id = ? AND user_id = ?",
-		req.GroupID,
-		req.RequesterID,
-	).Scan(&requestID, &status)
-
-	if err == sql.ErrNoRows {
-		http.Error(w, "Join request not found", http.StatusNotFound)
+	status, err := stores.Groups.JoinRequestStatus(r.Context(), req.GroupID, req.RequesterID)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Join request not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
 	if status != "pending" {
-		http.Error(w, "Join request is not pending", http.StatusBadRequest)
-		return
-	}
-
-	// transaction explanation: begin, rollback on error, commit at end
-	//very useful if we want to ensure all db operations succeed or none at all
-	//if one fails we rollback to the initial state
-	//else we commit the changes
-	tx, err := db.Database.Begin()
-	if err != nil {
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
-
-	now := time.Now().Unix()
-
-	// Approve request using the actual request ID
-	if _, err := tx.Exec(
-		"UPDATE group_join_requests SET status = 'approved' WHERE id = ?",
-		requestID,
-	); err != nil {
-		http.Error(w, "Failed to approve request", http.StatusInternalServerError)
-		return
-	}
-
-	// Add member
-	if _, err := tx.Exec(
-		`INSERT INTO group_members (group_id, user_id, role, joined_at)
-         VALUES (?, ?, 'member', ?)`,
-		req.GroupID,
-		req.RequesterID,
-		now,
-	); err != nil {
-		http.Error(w, "Failed to add group member", http.StatusInternalServerError)
+		response.Error(w, http.StatusBadRequest, "Join request is not pending")
 		return
 	}
 
-	// Commit transaction FIRST before sending notifications
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
-		return
-	}
-
-	// Get group name for notification (after commit)
-	//get group name if null get the title instead
-	//coalesce returns the first non-null value
-	//useful i may say
-	var groupName string
-	err = db.Database.QueryRow(
-		"SELECT COALESCE(group_name, title) FROM groups WHERE id = ?",
-		req.GroupID,
-	).Scan(&groupName)
-	if err != nil {
-		http.Error(w, "Failed to get group name", http.StatusInternalServerError)
-		log.Println("[ApproveJoinRequest] group name fetch error:", err)
+	// approving adds the member and always deletes the join request notification and request,
+	// all in one transaction so a failure can't leave a member with a dangling request
+	//fooled me once shame on you
+	//fooled me twice shame on me
+	if err := stores.Groups.ApproveJoinRequest(r.Context(), req.GroupID, req.RequesterID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to approve request")
 		return
 	}
 
 	// Send notification to the requester that their request was approved
-	// have to test if using a go routine here is safe, more efficient or in general a good idea
-	go func() {
-		generalfuncs.CreateNotification(models.Notification{
-			UserID: req.RequesterID,
-			Type:   "group_join_request_approved", // have to add this type
-			//cause i forgot it before
+	// runs after the request returns, telemetry.Go keeps it in the request's trace without its cancellation
+	telemetry.Go(r.Context(), "notify join request approved", func(ctx context.Context) {
+		generalfuncs.CreateNotification(ctx, models.Notification{
+			UserID:    req.RequesterID,
+			Type:      "group_join_request_approved",
 			SenderID:  &approverID,
 			GroupID:   &req.GroupID,
-			GroupName: &groupName,
+			GroupName: &group.Groupname,
 			CreatedAt: time.Now(),
 		})
-	}()
-
-	// always delete the join request notification and  request after approval
-	//fooled me once shame on you
-	//fooled me twice shame on me
-	_, err = db.Database.Exec(
-		"DELETE FROM notifications WHERE group_id = ? AND sender_id = ? AND type = 'group_join_request'",
-		req.GroupID, req.RequesterID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
-		return
-	}
-	_, err = db.Database.Exec(
-		"DELETE FROM group_join_requests WHERE group_id = ? AND user_id = ?",
-		req.GroupID, req.RequesterID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to delete group join request", http.StatusInternalServerError)
-		return
-	}
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -604,44 +410,36 @@ func ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
 func RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req ApproveRequestPayload
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeBody(r, &req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /groups/{id}/requests/{userID}/approve|reject
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
+	if id := params.PathID(r, "userID"); id > 0 {
+		req.RequesterID = id
+	}
 
 	if req.GroupID <= 0 || req.RequesterID <= 0 {
-		http.Error(w, "Group ID and Requester ID are required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID and Requester ID are required")
 		return
 	}
 
 	// Check if user is the creator
-	var creatorID int
-	db.Database.QueryRow("SELECT creator_id FROM groups WHERE id = ?", req.GroupID).Scan(&creatorID)
-
-	if creatorID != userID {
-		http.Error(w, "Only the creator can reject requests", http.StatusForbidden)
+	if !isCreator(r.Context(), req.GroupID, userID) {
+		response.Error(w, http.StatusForbidden, "Only the creator can reject requests")
 		return
 	}
 
-	_, err := db.Database.Exec(
-		"DELETE FROM notifications WHERE group_id = ? AND sender_id = ? AND type = 'group_join_request'",
-		req.GroupID, req.RequesterID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
-		return
-	}
-	_, err = db.Database.Exec(
-		"DELETE FROM group_join_requests WHERE group_id = ? AND user_id = ?",
-		req.GroupID, req.RequesterID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to delete group join request", http.StatusInternalServerError)
+	if err := stores.Groups.DeleteJoinRequest(r.Context(), req.GroupID, req.RequesterID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to delete group join request")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -651,7 +449,7 @@ func RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
 func LeaveGroup(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -659,32 +457,97 @@ func LeaveGroup(w http.ResponseWriter, r *http.Request) {
 		GroupID int `json:"group_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.GroupID <= 0 {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
-	// Remove member
-	result, err := db.Database.Exec(
-		"DELETE FROM group_members WHERE group_id = ? AND user_id = ?",
-		req.GroupID, userID,
-	)
+	removeMember(w, r, req.GroupID, userID)
+}
+
+// RemoveGroupMember is DELETE /groups/{id}/members/{userID}, members can remove themselves (leave)
+// and the creator can remove anybody else
+func RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("ctxUserID").(int)
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	groupID := params.PathID(r, "id")
+	memberID := params.PathID(r, "userID")
+	if groupID == 0 || memberID == 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID and User ID are required")
+		return
+	}
+
+	if memberID != userID {
+		group, err := stores.Groups.Get(r.Context(), groupID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			response.Error(w, http.StatusNotFound, "Group not found")
+			return
+		}
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !group.IsCreator {
+			response.Error(w, http.StatusForbidden, "Only the creator can remove members")
+			return
+		}
+	}
+
+	removeMember(w, r, groupID, memberID)
+}
 
+func removeMember(w http.ResponseWriter, r *http.Request, groupID, memberID int) {
+	removed, err := stores.Groups.RemoveMember(r.Context(), groupID, memberID)
 	if err != nil {
-		http.Error(w, "Failed to leave group", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to remove member")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "You are not a member of this group", http.StatusBadRequest)
+	if !removed {
+		response.Error(w, http.StatusBadRequest, "Not a member of this group")
 		return
 	}
 
+	message := "Left group successfully"
+	if userID, _ := r.Context().Value("ctxUserID").(int); userID != memberID {
+		message = "Member removed"
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Left group successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// decodeBody is json Decode that accepts an empty body,
+// the REST routes have the ids in the path and nothing else to send
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// isCreator reports whether the user created the group, unknown groups count as false
+func isCreator(ctx context.Context, groupID, userID int) bool {
+	group, err := stores.Groups.Get(ctx, groupID, userID)
+	return err == nil && group.IsCreator
+}
+
+// displayName is the username, or the first name for users without one
+func displayName(ctx context.Context, userID int) (string, error) {
+	user, err := stores.Users.Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if name := deref(user.Username); name != "" {
+		return name, nil
+	}
+	return user.FirstName, nil
 }
@@ -3,105 +3,62 @@ package groups
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
-	"time"
 
-	"social-network/db"
+	"social-network/app/handlers/reaction"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
 )
 
-type GroupComment struct {
-	ID        int    `json:"id"`
-	PostID    int    `json:"post_id"`
-	UserID    int    `json:"user_id"`
-	Content   string `json:"content"`
-	Image     string `json:"image,omitempty"`
-	CreatedAt int64  `json:"created_at"`
-	Author    *User  `json:"author,omitempty"`
-}
-
-type CreateCommentRequest struct {
-	GroupID int    `json:"group_id"`
-	PostID  int    `json:"post_id"`
-	Content string `json:"content"`
-	Image   string `json:"image,omitempty"`
-}
-
 func CreateGroupComment(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	var req CreateCommentRequest
+	var req models.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// POST /groups/{id}/posts/{postID}/comments
+	if id := params.PathID(r, "id"); id > 0 {
+		req.GroupID = id
+	}
+	if id := params.PathID(r, "postID"); id > 0 {
+		req.PostID = id
+	}
 
 	// Check if user is a member
-	var isMember bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
-		req.GroupID, userID,
-	).Scan(&isMember)
-
+	isMember, _ := stores.Groups.IsMember(r.Context(), req.GroupID, userID)
 	if !isMember {
-		http.Error(w, "Must be a member to comment", http.StatusForbidden)
+		response.Error(w, http.StatusForbidden, "Must be a member to comment")
 		return
 	}
 
 	// Check if post exists and belongs to this group
-	var postExists bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_posts WHERE id = ? AND group_id = ?)",
-		req.PostID, req.GroupID,
-	).Scan(&postExists)
-
+	postExists, _ := stores.Groups.PostInGroup(r.Context(), req.PostID, req.GroupID)
 	if !postExists {
-		http.Error(w, "Post not found", http.StatusNotFound)
+		response.Error(w, http.StatusNotFound, "Post not found")
 		return
 	}
 
 	if req.Content == "" && req.Image == "" {
-		http.Error(w, "Content or Image is required", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Content or Image is required")
 		return
 	}
 
-	now := time.Now().Unix()
-
-	result, err := db.Database.Exec(
-		"INSERT INTO group_post_comments (post_id, user_id, content, image, created_at) VALUES (?, ?, ?, ?, ?)",
-		req.PostID, userID, req.Content, req.Image, now,
-	)
-
-	if err != nil {
-		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
-		return
-	}
-
-	commentID, _ := result.LastInsertId()
-
-	// Get author info
-	var author User
-	var avatar *string
-	db.Database.QueryRow(
-		"SELECT id, username, avatar FROM users WHERE id = ?",
-		userID,
-	).Scan(&author.ID, &author.Username, &avatar)
-
-	if avatar != nil {
-		author.Avatar = *avatar
+	// the store fills in the id, date and author info
+	comment := models.GroupComment{
+		PostID:  req.PostID,
+		UserID:  userID,
+		Content: req.Content,
+		Image:   req.Image,
 	}
-
-	comment := GroupComment{
-		ID:        int(commentID),
-		PostID:    req.PostID,
-		UserID:    userID,
-		Content:   req.Content,
-		Image:     req.Image,
-		CreatedAt: now,
-		Author:    &author,
+	if err := stores.Groups.CreateComment(r.Context(), &comment); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to create comment")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -109,38 +66,52 @@ func CreateGroupComment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(comment)
 }
 
+// groupCommentsPerPage is the default page size of ListGroupComments, oldest first
+const groupCommentsPerPage = 50
+
 func ListGroupComments(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	groupID := r.URL.Query().Get("group_id")
-	postID := r.URL.Query().Get("post_id")
+	groupID := params.ID(r, "id", "group_id")
+	postID := params.ID(r, "postID", "post_id")
 
-	if groupID == "" || postID == "" {
-		http.Error(w, "Group ID and Post ID are required", http.StatusBadRequest)
+	if groupID == 0 || postID == 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID and Post ID are required")
 		return
 	}
 
 	// Check if user is a member
-	var isMember bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
-		groupID, userID,
-	).Scan(&isMember)
-
+	isMember, _ := stores.Groups.IsMember(r.Context(), groupID, userID)
 	if !isMember {
-		http.Error(w, "Must be a member to view comments", http.StatusForbidden)
+		response.Error(w, http.StatusForbidden, "Must be a member to view comments")
 		return
 	}
 
-	comments := fetchCommentsForPost(func() int {
-		id, _ := strconv.Atoi(postID)
-		return id
-	}())
+	// otherwise a member of one group could read the comments of any group
+	postExists, _ := stores.Groups.PostInGroup(r.Context(), postID, groupID)
+	if !postExists {
+		response.Error(w, http.StatusNotFound, "Post not found")
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
+	page, err := params.Page(r, groupCommentsPerPage)
+	if err != nil {
+		response.BadPage(w, err)
+		return
+	}
+	comments, next, err := stores.Groups.Comments(r.Context(), postID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch comments")
+		return
+	}
+	if err := reaction.GroupComments(r.Context(), comments, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		return
+	}
+
+	response.List(w, r, "", comments, next)
 }
@@ -1,53 +1,53 @@
 package groups
 
 import (
+	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
-	"time"
 
-	"social-network/db"
+	"social-network/app/handlers/images"
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/reaction"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
-type GroupPost struct {
-	ID        int            `json:"id"`
-	GroupID   int            `json:"group_id"`
-	UserID    int            `json:"user_id"`
-	Content   string         `json:"content"`
-	Image     string         `json:"image,omitempty"`
-	CreatedAt int64          `json:"created_at"`
-	Author    *User          `json:"author,omitempty"`
-	Comments  []GroupComment `json:"comments,omitempty"`
-}
-
-type CreatePostRequest struct {
-	GroupID string `json:"group_id"`
-	Content string `json:"content"`
-	Image   string `json:"image,omitempty"`
-}
-
 func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	var req CreatePostRequest
+	var req models.CreatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	if req.Content == "" && req.Image == "" {
-		http.Error(w, "Content or Image is required", http.StatusBadRequest)
+	if req.Content == "" && req.Image == "" && len(req.Attachments) == 0 {
+		response.Error(w, http.StatusBadRequest, "Content or Image is required")
+		return
+	}
+	image, ok := images.Attach(w, &req.Attachments, req.Image)
+	if !ok {
+		return
+	}
+	if !poll.Valid(w, req.Poll) {
 		return
 	}
 
-	now := time.Now().Unix()
-	groupIDint, err := strconv.Atoi(req.GroupID)
-	if err != nil || groupIDint == 0 {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+	// POST /groups/{id}/posts has the id in the path, the old route sends it in the body as a string
+	groupIDint := params.PathID(r, "id")
+	if groupIDint == 0 {
+		groupIDint, _ = strconv.Atoi(req.GroupID)
+	}
+	if groupIDint <= 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 	// }
@@ -55,50 +55,25 @@ func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 	// groupIDInt, _ := strconv.Atoi(groupID)
 
 	// Check if user is a member
-	var isMember bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
-		groupIDint, userID,
-	).Scan(&isMember)
-
+	isMember, _ := stores.Groups.IsMember(r.Context(), groupIDint, userID)
 	if !isMember {
-		http.Error(w, "Must be a member to post", http.StatusForbidden)
+		response.Error(w, http.StatusForbidden, "Must be a member to post")
 		return
 	}
 
-	result, err := db.Database.Exec(
-		"INSERT INTO group_posts (group_id, user_id, content, image, created_at) VALUES (?, ?, ?, ?, ?)",
-		groupIDint, userID, req.Content, req.Image, now,
-	)
-
-	if err != nil {
-		http.Error(w, "Failed to create post", http.StatusInternalServerError)
-		return
-	}
-
-	postID, _ := result.LastInsertId()
-
-	// Get author info
-	var author User
-	var avatar *string
-	db.Database.QueryRow(
-		"SELECT id, username, avatar FROM users WHERE id = ?",
-		userID,
-	).Scan(&author.ID, &author.Username, &avatar)
-
-	if avatar != nil {
-		author.Avatar = *avatar
+	// the store fills in the id, date and author info
+	post := models.GroupPost{
+		GroupID:     groupIDint,
+		UserID:      userID,
+		Content:     req.Content,
+		Image:       image,
+		Attachments: req.Attachments,
+		Comments:    []models.GroupComment{},
+		Poll:        req.Poll,
 	}
-
-	post := GroupPost{
-		ID:        int(postID),
-		GroupID:   groupIDint,
-		UserID:    userID,
-		Content:   req.Content,
-		Image:     req.Image,
-		CreatedAt: now,
-		Author:    &author,
-		Comments:  []GroupComment{},
+	if err := stores.Groups.CreatePost(r.Context(), &post); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to create post")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -106,116 +81,62 @@ func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(post)
 }
 
+// groupPostsPerPage is the default page size of ListGroupPosts, each post comes with all its comments
+const groupPostsPerPage = 20
+
 func ListGroupPosts(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("ctxUserID").(int)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	groupID := r.URL.Query().Get("id")
-
-	if groupID == "" {
-		http.Error(w, "Group ID is required", http.StatusBadRequest)
+	groupID := params.ID(r, "id", "id")
+	if groupID == 0 {
+		response.Error(w, http.StatusBadRequest, "Group ID is required")
 		return
 	}
 
 	// Check if user is a member
-	var isMember bool
-	db.Database.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
-		groupID, userID,
-	).Scan(&isMember)
-
+	isMember, _ := stores.Groups.IsMember(r.Context(), groupID, userID)
 	if !isMember {
-		http.Error(w, "Must be a member to view posts", http.StatusForbidden)
+		response.Error(w, http.StatusForbidden, "Must be a member to view posts")
 		return
 	}
 
-	rows, err := db.Database.Query(`
-        SELECT gp.id, gp.group_id, gp.user_id, gp.content, gp.image, gp.created_at,
-               u.username, u.avatar
-        FROM group_posts gp
-        JOIN users u ON gp.user_id = u.id
-        WHERE gp.group_id = ?
-        ORDER BY gp.created_at DESC
-    `, groupID)
-
+	page, err := params.Page(r, groupPostsPerPage)
 	if err != nil {
-		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		response.BadPage(w, err)
+		return
+	}
+	posts, next, err := stores.Groups.Posts(r.Context(), groupID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch posts")
 		return
 	}
-	defer rows.Close()
-
-	posts := []GroupPost{}
-	for rows.Next() {
-		var p GroupPost
-		var author User
-		var image, avatar *string
-
-		err := rows.Scan(
-			&p.ID, &p.GroupID, &p.UserID, &p.Content, &image, &p.CreatedAt,
-			&author.Username, &avatar,
-		)
-		if err != nil {
-			continue
-		}
-
-		author.ID = p.UserID
-		if avatar != nil {
-			author.Avatar = *avatar
-		}
-		if image != nil {
-			p.Image = *image
-		}
-		p.Author = &author
-
-		// Fetch comments for this post
-		p.Comments = fetchCommentsForPost(p.ID)
 
-		posts = append(posts, p)
+	// Fetch comments for every post
+	for i := range posts {
+		posts[i].Comments = fetchCommentsForPost(r.Context(), posts[i].ID)
+	}
+	if err := reaction.GroupPosts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		return
+	}
+	if err := poll.GroupPosts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch polls")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	response.List(w, r, "", posts, next)
 }
 
-func fetchCommentsForPost(postID int) []GroupComment {
-	rows, err := db.Database.Query(`
-        SELECT gc.id, gc.post_id, gc.user_id, gc.content, gc.image, gc.created_at,
-               u.username, u.avatar
-        FROM group_post_comments gc
-        JOIN users u ON gc.user_id = u.id
-        WHERE gc.post_id = ?
-        ORDER BY gc.created_at ASC
-    `, postID)
-
+// fetchCommentsForPost returns all the comments of a post, for the posts list
+func fetchCommentsForPost(ctx context.Context, postID int) []models.GroupComment {
+	comments, _, err := stores.Groups.Comments(ctx, postID, store.Page{})
 	if err != nil {
-		return []GroupComment{}
+		log.Printf("Failed to fetch comments for post %d: %v", postID, err)
+		return []models.GroupComment{}
 	}
-	defer rows.Close()
-
-	comments := []GroupComment{}
-	for rows.Next() {
-		var c GroupComment
-		var author User
-		var avatar *string
-
-		err := rows.Scan(
-			&c.ID, &c.PostID, &c.UserID, &c.Content, &c.Image, &c.CreatedAt,
-			&author.Username, &avatar,
-		)
-		if err != nil {
-			continue
-		}
-
-		author.ID = c.UserID
-		if avatar != nil {
-			author.Avatar = *avatar
-		}
-		c.Author = &author
-		comments = append(comments, c)
-	}
-
 	return comments
 }
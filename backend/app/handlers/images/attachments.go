@@ -0,0 +1,57 @@
+package images
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"social-network/app/models"
+	"social-network/app/response"
+)
+
+const maxAltText = 1000
+
+// Attach checks the attachments of a new or edited post or comment and answers the validation
+// error when they are wrong. A client that only knows the single image gets it as the one
+// attachment, a missing content type is guessed from the extension. The answer is the path the
+// image field keeps: the first attachment's, "" without attachments
+func Attach(w http.ResponseWriter, attachments *[]models.Attachment, image string) (string, bool) {
+	list := *attachments
+	if len(list) == 0 && image != "" {
+		list = []models.Attachment{{Path: image}}
+	}
+	if len(list) > models.MaxAttachments {
+		response.Invalid(w, "Too many images (max 4)", response.FieldError{Field: "attachments", Message: "max 4 images"})
+		return "", false
+	}
+	for i := range list {
+		a := &list[i]
+		a.ID, a.AltText = 0, strings.TrimSpace(a.AltText)
+		if !strings.HasPrefix(a.Path, "/images/") {
+			response.Invalid(w, "Invalid image path", response.FieldError{Field: "attachments.path", Message: "must be an uploaded /images path"})
+			return "", false
+		}
+		if a.ContentType == "" {
+			a.ContentType = mime.TypeByExtension(strings.ToLower(path.Ext(a.Path)))
+		}
+		if !strings.HasPrefix(a.ContentType, "image/") {
+			response.Invalid(w, "Invalid image type", response.FieldError{Field: "attachments.content_type", Message: "must be an image type"})
+			return "", false
+		}
+		if utf8.RuneCountInString(a.AltText) > maxAltText {
+			response.Invalid(w, "Alt text is too long", response.FieldError{Field: "attachments.alt_text", Message: "max 1000 characters"})
+			return "", false
+		}
+		if a.Width < 0 || a.Height < 0 {
+			response.Invalid(w, "Invalid image size", response.FieldError{Field: "attachments.width", Message: "width and height can't be negative"})
+			return "", false
+		}
+	}
+	*attachments = list
+	if len(list) == 0 {
+		return "", true
+	}
+	return list[0].Path, true
+}
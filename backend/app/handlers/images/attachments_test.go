@@ -0,0 +1,40 @@
+package images
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-network/app/models"
+)
+
+func TestAttach(t *testing.T) {
+	// the old single image becomes the one attachment
+	var attachments []models.Attachment
+	image, ok := Attach(httptest.NewRecorder(), &attachments, "/images/posts/old.JPG")
+	if !ok || image != "/images/posts/old.JPG" || len(attachments) != 1 || attachments[0].ContentType != "image/jpeg" {
+		t.Errorf("legacy image: %q, %+v", image, attachments)
+	}
+
+	attachments = []models.Attachment{{ID: 7, Path: "/images/posts/a.png", AltText: "  a dog "}, {Path: "/images/posts/b.gif"}}
+	image, ok = Attach(httptest.NewRecorder(), &attachments, "/images/posts/ignored.png")
+	if !ok || image != "/images/posts/a.png" || attachments[0].ID != 0 || attachments[0].AltText != "a dog" {
+		t.Errorf("attachments: %q, %+v", image, attachments)
+	}
+	if image, ok := Attach(httptest.NewRecorder(), &[]models.Attachment{}, ""); !ok || image != "" {
+		t.Errorf("no images: %q, %v", image, ok)
+	}
+
+	for name, list := range map[string][]models.Attachment{
+		"outside link":  {{Path: "https://example.com/a.png"}},
+		"not an image":  {{Path: "/images/posts/a.txt"}},
+		"unknown type":  {{Path: "/images/posts/a"}},
+		"negative size": {{Path: "/images/posts/a.png", Width: -1}},
+		"too many":      make([]models.Attachment, models.MaxAttachments+1),
+	} {
+		w := httptest.NewRecorder()
+		if _, ok := Attach(w, &list, ""); ok || w.Code != http.StatusBadRequest {
+			t.Errorf("%s: accepted, status %d", name, w.Code)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+
+	"social-network/app/response"
 )
 
 // i have to add messages folder too
@@ -30,15 +32,10 @@ const (
 
 // ImageUploadHandler handles image uploads for posts, avatars, messages, and comments
 func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Parse the multipart form (max 10MB)
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		http.Error(w, "Could not parse multipart form", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Could not parse multipart form")
 		return
 	}
 
@@ -55,20 +52,20 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 	case "comment":
 		imageType = CommentsImage
 	default:
-		http.Error(w, "Invalid image type", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid image type")
 		return
 	}
 
 	file, handler, err := r.FormFile("image")
 	if err != nil {
-		http.Error(w, "Could not retrieve file", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Could not retrieve file")
 		return
 	}
 	defer file.Close()
 
 	// Basic validation
 	if !strings.HasPrefix(handler.Header.Get("Content-Type"), "image/") {
-		http.Error(w, "Invalid file type", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid file type")
 		return
 	}
 
@@ -83,7 +80,7 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 	uploadDir := path.Join(baseUploadDir, folderMap[imageType])
 	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(uploadDir, 0o755); err != nil {
-			http.Error(w, "Could not create directory", http.StatusInternalServerError)
+			response.Error(w, http.StatusInternalServerError, "Could not create directory")
 			return
 		}
 	}
@@ -94,7 +91,7 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 
 	dst, err := os.Create(filepath)
 	if err != nil {
-		http.Error(w, "Could not save file", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Could not save file")
 		return
 	}
 	defer dst.Close()
@@ -102,7 +99,7 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 	// Copy the uploaded file to destination
 	_, err = io.Copy(dst, file)
 	if err != nil {
-		http.Error(w, "Could not save file", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Could not save file")
 		return
 	}
 
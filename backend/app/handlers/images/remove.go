@@ -0,0 +1,22 @@
+package images
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+)
+
+// Remove deletes the file behind an /images/... URL as handed out by ImageUploadHandler.
+// Anything else (an outside link, a path with ..) is left alone, and so is a file that is already gone
+func Remove(imageURL string) error {
+	rel, ok := strings.CutPrefix(imageURL, "/images/")
+	if !ok || rel == "" || path.Clean("/"+rel) != "/"+rel {
+		return nil
+	}
+	err := os.Remove(path.Join(baseUploadDir, rel))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
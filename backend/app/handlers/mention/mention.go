@@ -0,0 +1,150 @@
+// Package mention sends the "mention" notifications of posts, comments and group messages and
+// serves the username autocomplete of the @ popup. Finding and saving the mentions is the
+// stores' job (see app/mention), the handlers get them back in the Mentions field
+package mention
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the notifications and the autocomplete read
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+const (
+	autocompleteSize = 8
+	autocompleteMax  = 20
+)
+
+// suggestion is a user of the autocomplete, what the popup shows
+type suggestion struct {
+	ID        int     `json:"id"`
+	Username  string  `json:"username"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Avatar    *string `json:"avatar,omitempty"`
+}
+
+// Autocomplete is GET /users/autocomplete?q=al, the users whose username starts with q (a
+// leading '@' is fine), case insensitive. ?limit= takes up to 20 users, 8 by default
+func Autocomplete(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@")
+	if prefix == "" {
+		response.Invalid(w, "q is required", response.FieldError{Field: "q", Message: "is required"})
+		return
+	}
+	limit := autocompleteSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > autocompleteMax {
+			response.Invalid(w, "Invalid limit", response.FieldError{Field: "limit", Message: "must be between 1 and 20"})
+			return
+		}
+		limit = n
+	}
+
+	users, err := stores.Users.Autocomplete(r.Context(), prefix, limit)
+	if err != nil {
+		log.Println("Error querying usernames:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	suggestions := make([]suggestion, len(users))
+	for i, u := range users {
+		suggestions[i] = suggestion{ID: u.ID, Username: *u.Username, FirstName: u.FirstName, LastName: u.LastName, Avatar: u.Avatar}
+	}
+	response.JSON(w, http.StatusOK, map[string]interface{}{"users": suggestions})
+}
+
+// Post notifies the users mentioned in the post who can see it. previous are the mentions of
+// the version an edit replaced, those users were handled when it was saved
+func Post(ctx context.Context, post models.Post, previous []models.Mention) {
+	notify(ctx, post.UserID, post.Mentions, previous, func(userID int) (bool, error) {
+		return stores.Posts.CanView(ctx, post.ID, userID)
+	}, models.Notification{
+		PostID:     &post.ID,
+		TargetType: ptr(models.TargetPost),
+		TargetID:   &post.ID,
+	})
+}
+
+// Comment notifies the users mentioned in the comment who can see its post
+func Comment(ctx context.Context, comment models.Comment) {
+	notify(ctx, comment.UserID, comment.Mentions, nil, func(userID int) (bool, error) {
+		return stores.Posts.CanView(ctx, comment.PostID, userID)
+	}, models.Notification{
+		PostID:     &comment.PostID,
+		TargetType: ptr(models.TargetComment),
+		TargetID:   &comment.ID,
+	})
+}
+
+// GroupMessage notifies the members of the group mentioned in the message, the others can't
+// read the chat
+func GroupMessage(ctx context.Context, msg models.GroupMessage) {
+	notify(ctx, msg.SenderID, msg.Mentions, nil, func(userID int) (bool, error) {
+		return stores.Groups.IsMember(ctx, msg.GroupID, userID)
+	}, models.Notification{
+		GroupID:    &msg.GroupID,
+		TargetType: ptr(models.TargetGroupMessage),
+		TargetID:   &msg.ID,
+	})
+}
+
+// notify sends n, as a "mention" from the author, once to every mentioned user that canSee the
+// content. Mentioning yourself or someone who can't see the content does nothing: the mention
+// stays in the text but must not tell them the content exists. It is best effort like the other
+// notifications, the content is saved either way
+func notify(ctx context.Context, authorID int, mentions, previous []models.Mention, canSee func(userID int) (bool, error), n models.Notification) {
+	done := map[int]bool{authorID: true}
+	for _, m := range previous {
+		done[m.UserID] = true
+	}
+
+	var author models.User
+	for _, m := range mentions {
+		if done[m.UserID] {
+			continue
+		}
+		done[m.UserID] = true
+		visible, err := canSee(m.UserID)
+		if err != nil {
+			log.Println("Error checking who can see a mention:", err)
+			continue
+		}
+		if !visible {
+			continue
+		}
+
+		if author.ID == 0 {
+			if author, err = stores.Users.Get(ctx, authorID); err != nil {
+				log.Println("Error loading mentioning user:", err)
+				return
+			}
+		}
+		name := author.FirstName
+		if author.Username != nil && *author.Username != "" {
+			name = *author.Username
+		}
+		n.UserID = m.UserID
+		n.Type = "mention"
+		n.SenderID, n.SenderName, n.SenderAvatar = &authorID, &name, author.Avatar
+		if err := generalfuncs.CreateNotification(ctx, n); err != nil {
+			log.Println("Error creating mention notification:", err)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,108 @@
+package mention
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/store/memstore"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	s := memstore.New()
+	SetStores(s)
+	generalfuncs.SetStores(s)
+}
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// mentions returns the mention notifications of the user
+func mentions(t *testing.T, userID int) []models.Notification {
+	t.Helper()
+	all, _, err := stores.Notifications.List(context.Background(), userID, store.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := []models.Notification{}
+	for _, n := range all {
+		if n.Type == "mention" {
+			found = append(found, n)
+		}
+	}
+	return found
+}
+
+func TestOnlyViewersAreNotified(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	author := newUser(t, "author")
+	follower := newUser(t, "follower")
+	stranger := newUser(t, "stranger")
+	late := newUser(t, "late")
+	stores.Follows.Follow(ctx, follower, author)
+	stores.Follows.Follow(ctx, late, author)
+
+	post := models.Post{UserID: author, Content: "@follower @stranger @author @follower", Privacy: models.PrivacyPrivate}
+	if err := stores.Posts.Create(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+	Post(ctx, post, nil)
+
+	if got := mentions(t, follower); len(got) != 1 || *got[0].PostID != post.ID || *got[0].SenderID != author {
+		t.Errorf("follower got %+v, want one mention of the post", got)
+	}
+	if got := mentions(t, stranger); len(got) != 0 {
+		t.Errorf("stranger can't see the post but got %+v", got)
+	}
+	if got := mentions(t, author); len(got) != 0 {
+		t.Errorf("author mentioned themselves and got %+v", got)
+	}
+
+	// the edit only notifies the newly mentioned
+	previous := post.Mentions
+	post.Content = "@follower @late"
+	if _, err := stores.Posts.Update(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+	Post(ctx, post, previous)
+	if len(mentions(t, follower)) != 1 || len(mentions(t, late)) != 1 {
+		t.Errorf("after the edit: follower %d, late %d mentions, want 1 each", len(mentions(t, follower)), len(mentions(t, late)))
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	setup(t)
+	newUser(t, "alice")
+	newUser(t, "Alfred")
+	newUser(t, "bob")
+
+	r := httptest.NewRequest(http.MethodGet, "/users/autocomplete?q=@al", nil)
+	w := httptest.NewRecorder()
+	Autocomplete(w, r)
+	var resp struct{ Users []suggestion }
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || len(resp.Users) != 2 || resp.Users[0].Username != "Alfred" {
+		t.Errorf("autocomplete @al: status %d, %+v", w.Code, resp.Users)
+	}
+
+	w = httptest.NewRecorder()
+	Autocomplete(w, httptest.NewRequest(http.MethodGet, "/users/autocomplete?q=", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty q: status %d", w.Code)
+	}
+}
@@ -1,121 +1,56 @@
 package notifications
 
 import (
-	"database/sql"
-	"encoding/json"
 	"net/http"
-
-	"social-network/app/models"
+	"strconv"
 
 	"social-network/app/middleware"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
+var stores *store.Stores
+
+// SetStores sets the notification store used by the handlers below
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// notificationsPerPage is the default page size, newest first
+const notificationsPerPage = 20
+
 // is vertical better than horizontal?
 func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	userID := middleware.GetUserId(middleware.GetCookieValue(r))
+	userID := middleware.CurrentUserID(r)
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	rows, err := db.Database.Query(`
-		SELECT
-		  id,
-		  user_id,
-		  type,
-		  sender_id,
-		  follow_request_id,
-		  sender_name,
-		  sender_avatar,
-		  post_id,
-		  group_id,
-		  group_name,
-		  event_id,
-		  is_read,
-		  created_at
-		FROM notifications
-		WHERE user_id = ?
-		ORDER BY created_at DESC
-	`, userID)
+	page, err := params.Page(r, notificationsPerPage)
 	if err != nil {
-		http.Error(w, "failed to get notifications", http.StatusInternalServerError)
+		response.BadPage(w, err)
 		return
 	}
-	defer rows.Close()
-
-	var notifications []models.Notification
-
-	for rows.Next() {
-		var n models.Notification
-		var senderID, postID, groupID, eventID sql.NullInt64
-
-		if err := rows.Scan(
-			&n.ID,
-			&n.UserID,
-			&n.Type,
-			&senderID,
-			&n.FollowRequestID,
-			&n.SenderName,
-			&n.SenderAvatar,
-			&postID,
-			&groupID,
-			&n.GroupName,
-			&eventID,
-			&n.IsRead,
-			&n.CreatedAt,
-		); err != nil {
-			http.Error(w, "failed to scan notification", http.StatusInternalServerError)
-			return
-		}
-
-		if senderID.Valid {
-			senderIDInt := int(senderID.Int64)
-			n.SenderID = &senderIDInt
-		}
-		if postID.Valid {
-			postIDInt := int(postID.Int64)
-			n.PostID = &postIDInt
-		}
-		if groupID.Valid {
-			groupIDInt := int(groupID.Int64)
-			n.GroupID = &groupIDInt
-		}
-		if eventID.Valid {
-			eventIDInt := int(eventID.Int64)
-			n.EventID = &eventIDInt
-		}
-
-		notifications = append(notifications, n)
+	notifications, next, err := stores.Notifications.List(r.Context(), userID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to get notifications")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(notifications)
+	response.List(w, r, "", notifications, next)
 }
 
 func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	userID := middleware.GetUserId(middleware.GetCookieValue(r))
+	userID := middleware.CurrentUserID(r)
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	_, err := db.Database.Exec(
-		`UPDATE notifications SET is_read = 1 WHERE user_id = ?`,
-		userID,
-	)
-	if err != nil {
-		http.Error(w, "failed to update notifications", http.StatusInternalServerError)
+	if err := stores.Notifications.MarkAllRead(r.Context(), userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to update notifications")
 		return
 	}
 
@@ -123,35 +58,26 @@ func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
 }
 
 func RemoveNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID := r.Context().Value("ctxUserID").(int)
 
-	notificationID := r.URL.Query().Get("id")
+	notificationID := params.Get(r, "id", "id")
 	if notificationID == "" {
-		http.Error(w, "missing notification id", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "missing notification id")
 		return
 	}
-	result, err := db.Database.Exec(
-		`DELETE FROM notifications WHERE id = ? AND user_id = ?`,
-		notificationID,
-		userID,
-	)
+	id, err := strconv.Atoi(notificationID)
 	if err != nil {
-		http.Error(w, "failed to delete notification", http.StatusInternalServerError)
+		response.Error(w, http.StatusBadRequest, "invalid notification id")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	removed, err := stores.Notifications.Delete(r.Context(), id, userID)
 	if err != nil {
-		http.Error(w, "failed to get affected rows", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "failed to delete notification")
 		return
 	}
-	if rowsAffected <= 0 {
-		http.Error(w, "notification not found", http.StatusNotFound)
+	if !removed {
+		response.Error(w, http.StatusNotFound, "notification not found")
 		return
 	}
 
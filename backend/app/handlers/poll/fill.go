@@ -0,0 +1,41 @@
+package poll
+
+import (
+	"context"
+
+	"social-network/app/models"
+)
+
+// fill attaches their polls to the items as the viewer may see them, with one lookup for all of
+// them. at returns the id of an item and where its poll goes
+func fill[T any](ctx context.Context, targetType string, items []T, viewerID int, at func(*T) (int, **models.Poll)) error {
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i], _ = at(&items[i])
+	}
+	polls, err := stores.Polls.Polls(ctx, targetType, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		id, poll := at(&items[i])
+		*poll = nil
+		if p, ok := polls[id]; ok {
+			p = view(p, viewerID)
+			*poll = &p
+		}
+	}
+	return nil
+}
+
+func Posts(ctx context.Context, posts []models.Post, viewerID int) error {
+	return fill(ctx, models.TargetPost, posts, viewerID, func(p *models.Post) (int, **models.Poll) {
+		return p.ID, &p.Poll
+	})
+}
+
+func GroupPosts(ctx context.Context, posts []models.GroupPost, viewerID int) error {
+	return fill(ctx, models.TargetGroupPost, posts, viewerID, func(p *models.GroupPost) (int, **models.Poll) {
+		return p.ID, &p.Poll
+	})
+}
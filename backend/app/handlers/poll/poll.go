@@ -0,0 +1,243 @@
+// Package poll handles the polls attached to posts and group posts: reading one, voting and
+// taking the vote back. Whoever can see the post can vote, see reachable
+package poll
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/handlers/websocket"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+	"social-network/app/telemetry"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the poll handlers and the fill helpers use
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+const maxOptionLength = 100
+
+// Valid checks the poll of a new post and answers the validation error when it's wrong. It clears
+// what only the server sets, a post without a poll is fine
+func Valid(w http.ResponseWriter, poll *models.Poll) bool {
+	if poll == nil {
+		return true
+	}
+	if len(poll.Options) < models.PollMinOptions || len(poll.Options) > models.PollMaxOptions {
+		response.Invalid(w, "A poll needs 2 to 10 options", response.FieldError{Field: "poll.options", Message: "must have between 2 and 10 options"})
+		return false
+	}
+	for i, option := range poll.Options {
+		text := strings.TrimSpace(option.Text)
+		if text == "" || utf8.RuneCountInString(text) > maxOptionLength {
+			response.Invalid(w, "Invalid poll option", response.FieldError{Field: "poll.options", Message: "each option needs a text, max 100 characters"})
+			return false
+		}
+		poll.Options[i] = models.PollOption{Text: text}
+	}
+	if poll.ClosesAt != nil {
+		at := poll.ClosesAt.Truncate(time.Second)
+		if !at.After(time.Now()) {
+			response.Invalid(w, "closes_at must be in the future", response.FieldError{Field: "poll.closes_at", Message: "must be in the future"})
+			return false
+		}
+		poll.ClosesAt = &at
+	}
+	poll.ID, poll.PostID, poll.GroupPostID, poll.GroupID = 0, nil, nil, nil
+	poll.Closed, poll.ResultsHidden, poll.Voters, poll.MyVotes = false, false, 0, nil
+	return true
+}
+
+// GetPoll returns the {id} poll with the results the user may see
+func GetPoll(w http.ResponseWriter, r *http.Request) {
+	poll, ok := reachable(w, r)
+	if !ok {
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]interface{}{"poll": view(poll, middleware.CurrentUserID(r))})
+}
+
+type voteRequest struct {
+	OptionIDs []int `json:"option_ids"`
+}
+
+// Vote sets the user's choice, the body is {"option_ids": [3]}. Voting again replaces the
+// earlier choice, until the poll closes. The answer is the poll with its results
+func Vote(w http.ResponseWriter, r *http.Request) {
+	poll, ok := open(w, r)
+	if !ok {
+		return
+	}
+	var req voteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.OptionIDs) == 0 || (!poll.Multiple && len(req.OptionIDs) > 1) {
+		message := "choose one option"
+		if poll.Multiple {
+			message = "choose at least one option"
+		}
+		response.Invalid(w, "Invalid vote", response.FieldError{Field: "option_ids", Message: message})
+		return
+	}
+	for i, id := range req.OptionIDs {
+		known := slices.ContainsFunc(poll.Options, func(o models.PollOption) bool { return o.ID == id })
+		if !known || slices.Contains(req.OptionIDs[:i], id) {
+			response.Invalid(w, "Invalid vote", response.FieldError{Field: "option_ids", Message: "must be options of the poll, each once"})
+			return
+		}
+	}
+
+	if err := stores.Polls.Vote(r.Context(), poll.ID, middleware.CurrentUserID(r), req.OptionIDs); err != nil {
+		log.Println("Error saving vote:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to save vote")
+		return
+	}
+	answer(w, r, poll.ID)
+}
+
+// Unvote takes the user's vote back while the poll is open, removing a vote that isn't there is fine
+func Unvote(w http.ResponseWriter, r *http.Request) {
+	poll, ok := open(w, r)
+	if !ok {
+		return
+	}
+	if _, err := stores.Polls.Unvote(r.Context(), poll.ID, middleware.CurrentUserID(r)); err != nil {
+		log.Println("Error removing vote:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to remove vote")
+		return
+	}
+	answer(w, r, poll.ID)
+}
+
+// answer writes the poll as the user sees it after the vote and pushes the new counts
+func answer(w http.ResponseWriter, r *http.Request, pollID int) {
+	poll, err := stores.Polls.Get(r.Context(), pollID, middleware.CurrentUserID(r))
+	if err != nil { // ErrNotFound too, the post was deleted in the meantime
+		log.Println("Error loading poll:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch poll")
+		return
+	}
+	broadcast(r.Context(), poll)
+	response.JSON(w, http.StatusOK, map[string]interface{}{"poll": view(poll, middleware.CurrentUserID(r))})
+}
+
+// reachable reads the {id} poll when the user may see its post: the post's privacy for regular
+// posts, a membership for group posts. Otherwise it's a 404, like for the post itself
+func reachable(w http.ResponseWriter, r *http.Request) (models.Poll, bool) {
+	id := params.PathID(r, "id")
+	if id <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid poll ID")
+		return models.Poll{}, false
+	}
+	userID := middleware.CurrentUserID(r)
+	poll, err := stores.Polls.Get(r.Context(), id, userID)
+	visible := err == nil
+	if err == nil {
+		if poll.GroupID != nil {
+			visible, err = stores.Groups.IsMember(r.Context(), *poll.GroupID, userID)
+		} else {
+			visible, err = stores.Posts.CanView(r.Context(), *poll.PostID, userID)
+		}
+	}
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !visible) {
+		response.Error(w, http.StatusNotFound, "Poll not found")
+		return models.Poll{}, false
+	}
+	if err != nil {
+		log.Println("Error loading poll:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch poll")
+		return models.Poll{}, false
+	}
+	return poll, true
+}
+
+// open is reachable for the votes, a closed poll is a 409
+func open(w http.ResponseWriter, r *http.Request) (models.Poll, bool) {
+	poll, ok := reachable(w, r)
+	if ok && closed(poll) {
+		response.Error(w, http.StatusConflict, "The poll is closed")
+		return models.Poll{}, false
+	}
+	return poll, ok
+}
+
+func closed(poll models.Poll) bool {
+	return poll.ClosesAt != nil && !poll.ClosesAt.After(time.Now())
+}
+
+// view is the poll as the viewer may see it: on a hide_results poll the counts stay hidden from
+// the users who haven't voted until it closes. The author always sees them
+func view(poll models.Poll, viewerID int) models.Poll {
+	poll.Closed = closed(poll)
+	if poll.HideResults && !poll.Closed && len(poll.MyVotes) == 0 && poll.AuthorID != viewerID {
+		poll.ResultsHidden, poll.Voters = true, 0
+		for i := range poll.Options {
+			poll.Options[i].Votes = 0
+		}
+	}
+	return poll
+}
+
+// broadcast sends the new counts as a "poll_update" to the online users who can see the post.
+// While the results are hidden only the voters and the author get them. It runs after the response
+func broadcast(ctx context.Context, poll models.Poll) {
+	counts := make([]map[string]int, len(poll.Options))
+	for i, o := range poll.Options {
+		counts[i] = map[string]int{"id": o.ID, "votes": o.Votes}
+	}
+	msg := websocket.WebSocketMessage{
+		Type: "poll_update",
+		Data: map[string]interface{}{
+			"poll_id":       poll.ID,
+			"post_id":       poll.PostID,
+			"group_post_id": poll.GroupPostID,
+			"group_id":      poll.GroupID,
+			"options":       counts,
+			"voters":        poll.Voters,
+		},
+	}
+
+	telemetry.Go(ctx, "broadcast poll update", func(ctx context.Context) {
+		if poll.HideResults && !closed(poll) {
+			voters, err := stores.Polls.Voters(ctx, poll.ID)
+			if err != nil {
+				log.Println("Error querying poll voters:", err)
+				return
+			}
+			if !slices.Contains(voters, poll.AuthorID) {
+				voters = append(voters, poll.AuthorID)
+			}
+			websocket.SendToUsers(ctx, voters, msg)
+			return
+		}
+
+		if poll.GroupID != nil {
+			memberIDs, err := stores.Groups.MemberIDs(ctx, *poll.GroupID, 0)
+			if err != nil {
+				log.Println("Error querying group members:", err)
+				return
+			}
+			websocket.SendToUsers(ctx, memberIDs, msg)
+			return
+		}
+
+		generalfuncs.SendToPostViewers(ctx, *poll.PostID, msg)
+	})
+}
@@ -0,0 +1,128 @@
+package poll
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store/memstore"
+)
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// call runs the handler on /polls/{id} as the user and decodes the poll of the answer
+func call(t *testing.T, handler http.HandlerFunc, method string, pollID int, body string, userID int) (int, models.Poll) {
+	t.Helper()
+	r := httptest.NewRequest(method, "/polls/"+strconv.Itoa(pollID), strings.NewReader(body))
+	r.SetPathValue("id", strconv.Itoa(pollID))
+	r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var answer struct {
+		Poll models.Poll `json:"poll"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, answer.Poll
+}
+
+func TestVoting(t *testing.T) {
+	SetStores(memstore.New())
+	ctx := context.Background()
+	author := newUser(t, "author")
+	voter := newUser(t, "voter")
+	stranger := newUser(t, "stranger")
+
+	post := models.Post{UserID: author, Content: "lunch?", Privacy: models.PrivacyAlmostPrivate, AllowedFollowers: []int{voter},
+		Poll: &models.Poll{HideResults: true, Options: []models.PollOption{{Text: "pizza"}, {Text: "sushi"}}}}
+	if err := stores.Posts.Create(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+	id, options := post.Poll.ID, post.Poll.Options
+	vote := func(ids ...int) string {
+		body, _ := json.Marshal(voteRequest{OptionIDs: ids})
+		return string(body)
+	}
+
+	if code, _ := call(t, GetPoll, http.MethodGet, id, "", stranger); code != http.StatusNotFound {
+		t.Errorf("poll of a post the user can't see: status %d, want 404", code)
+	}
+	if code, _ := call(t, Vote, http.MethodPut, id, vote(options[0].ID), stranger); code != http.StatusNotFound {
+		t.Errorf("vote on a post the user can't see: status %d, want 404", code)
+	}
+
+	// the author sees the results, the others only after voting
+	if code, poll := call(t, GetPoll, http.MethodGet, id, "", voter); code != http.StatusOK || !poll.ResultsHidden {
+		t.Errorf("poll before voting: status %d, %+v", code, poll)
+	}
+	if code, _ := call(t, Vote, http.MethodPut, id, vote(options[0].ID, options[1].ID), voter); code != http.StatusBadRequest {
+		t.Errorf("two options on a single choice poll: status %d, want 400", code)
+	}
+	if code, _ := call(t, Vote, http.MethodPut, id, vote(options[0].ID+100), voter); code != http.StatusBadRequest {
+		t.Errorf("unknown option: status %d, want 400", code)
+	}
+	code, poll := call(t, Vote, http.MethodPut, id, vote(options[1].ID), voter)
+	if code != http.StatusOK || poll.ResultsHidden || poll.Options[1].Votes != 1 || poll.Voters != 1 {
+		t.Errorf("after voting: status %d, %+v", code, poll)
+	}
+	if _, poll := call(t, GetPoll, http.MethodGet, id, "", author); poll.ResultsHidden || poll.Options[1].Votes != 1 {
+		t.Errorf("the author's view: %+v", poll)
+	}
+
+	// a closed poll keeps its votes
+	past := time.Now().Add(-time.Minute)
+	closed := models.Post{UserID: author, Content: "too late", Privacy: models.PrivacyPublic,
+		Poll: &models.Poll{ClosesAt: &past, Options: []models.PollOption{{Text: "yes"}, {Text: "no"}}}}
+	if err := stores.Posts.Create(ctx, &closed); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := call(t, Vote, http.MethodPut, closed.Poll.ID, vote(closed.Poll.Options[0].ID), voter); code != http.StatusConflict {
+		t.Errorf("vote on a closed poll: status %d, want 409", code)
+	}
+	if code, _ := call(t, Unvote, http.MethodDelete, closed.Poll.ID, "", voter); code != http.StatusConflict {
+		t.Errorf("unvote on a closed poll: status %d, want 409", code)
+	}
+}
+
+func TestValid(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	long := strings.Repeat("x", maxOptionLength+1)
+	for name, poll := range map[string]models.Poll{
+		"one option":     {Options: []models.PollOption{{Text: "only"}}},
+		"empty option":   {Options: []models.PollOption{{Text: "a"}, {Text: "  "}}},
+		"long option":    {Options: []models.PollOption{{Text: "a"}, {Text: long}}},
+		"closed":         {ClosesAt: &past, Options: []models.PollOption{{Text: "a"}, {Text: "b"}}},
+		"eleven options": {Options: make([]models.PollOption, models.PollMaxOptions+1)},
+	} {
+		if w := httptest.NewRecorder(); Valid(w, &poll) || w.Code != http.StatusBadRequest {
+			t.Errorf("%s: accepted, status %d", name, w.Code)
+		}
+	}
+
+	poll := models.Poll{ID: 9, Voters: 3, Options: []models.PollOption{{ID: 4, Text: " a ", Votes: 2}, {Text: "b"}}}
+	if w := httptest.NewRecorder(); !Valid(w, &poll) {
+		t.Fatalf("valid poll rejected: %s", w.Body)
+	}
+	if poll.ID != 0 || poll.Voters != 0 || poll.Options[0] != (models.PollOption{Text: "a"}) {
+		t.Errorf("Valid kept what the client can't set: %+v", poll)
+	}
+}
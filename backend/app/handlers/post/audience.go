@@ -0,0 +1,90 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+// maxAudienceLists is how many audience lists an almost_private post can be shared with
+const maxAudienceLists = 10
+
+// pruneJob drops the almost_private grants an unfollow should have taken along. Unfollow does it
+// itself now, the job cleans up what was left before and whatever slips through
+const pruneJob = "posts.prune_audience"
+
+const pruneInterval = 24 * time.Hour
+
+// validAudience checks the audience of an almost_private post of post.UserID: the followers picked
+// one by one must follow the author, the lists must be the author's own, each counted once.
+// Other privacies drop both, the store would ignore them anyway
+func validAudience(w http.ResponseWriter, ctx context.Context, post *models.Post) bool {
+	if post.Privacy != models.PrivacyAlmostPrivate {
+		post.AllowedFollowers, post.AudienceLists = nil, nil
+		return true
+	}
+	post.AllowedFollowers, post.AudienceLists = unique(post.AllowedFollowers), unique(post.AudienceLists)
+
+	if len(post.AllowedFollowers) > 0 {
+		followers, err := stores.Follows.Followers(ctx, post.UserID)
+		if err != nil {
+			log.Println("Error querying followers:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to check the audience")
+			return false
+		}
+		for _, id := range post.AllowedFollowers {
+			if !slices.ContainsFunc(followers, func(u models.User) bool { return u.ID == id }) {
+				response.Invalid(w, "Only followers can be picked to see the post",
+					response.FieldError{Field: "allowed_followers", Message: "must be users who follow you"})
+				return false
+			}
+		}
+	}
+
+	if len(post.AudienceLists) > maxAudienceLists {
+		response.Invalid(w, "Too many audience lists",
+			response.FieldError{Field: "audience_lists", Message: "at most " + strconv.Itoa(maxAudienceLists) + " lists"})
+		return false
+	}
+	for _, id := range post.AudienceLists {
+		list, err := stores.Audiences.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && list.UserID != post.UserID) {
+			response.Invalid(w, "Unknown audience list",
+				response.FieldError{Field: "audience_lists", Message: "must be your own lists"})
+			return false
+		}
+		if err != nil {
+			log.Println("Error loading audience list:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to check the audience")
+			return false
+		}
+	}
+	return true
+}
+
+func unique(ids []int) []int {
+	seen := []int{}
+	for _, id := range ids {
+		if !slices.Contains(seen, id) {
+			seen = append(seen, id)
+		}
+	}
+	return seen
+}
+
+// pruneAudience runs posts.prune_audience
+func pruneAudience(ctx context.Context, job models.Job) error {
+	n, err := stores.Posts.PruneAudience(ctx)
+	if n > 0 {
+		log.Printf("Pruned %d almost_private grants that no follow backs anymore", n)
+	}
+	return err
+}
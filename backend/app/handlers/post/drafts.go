@@ -0,0 +1,207 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/preview"
+	"social-network/app/jobs"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+// publishJob is the job type that publishes the scheduled posts that are due
+const publishJob = "posts.publish"
+
+// every scheduled post queues its own run, this one only catches the runs that got lost
+const publishSweep = 5 * time.Minute
+
+// RegisterJobs adds the posts.publish, posts.prune_audience and tags.backfill jobs to the runner
+func RegisterJobs(r *jobs.Runner) {
+	r.Every(publishJob, publishSweep, publishDue)
+	r.Every(pruneJob, pruneInterval, pruneAudience)
+	r.Handle(backfillJob, backfillTags)
+}
+
+// publishDue publishes the scheduled posts whose time has come, the mentions only notify from now
+// on. Publishing twice is a no-op, so is a run of a post that was rescheduled or published by hand
+func publishDue(ctx context.Context, job models.Job) error {
+	posts, err := stores.Posts.PublishDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		mention.Post(ctx, post, nil)
+	}
+	return nil
+}
+
+// schedule queues the posts.publish run of a scheduled post. Posts due in the same second share a
+// run, a failed enqueue is only logged: the periodic run publishes the post a bit late
+func schedule(ctx context.Context, post models.Post) {
+	if post.Status != models.PostScheduled {
+		return
+	}
+	_, err := jobs.Enqueue(ctx, stores.Jobs, publishJob, nil, jobs.RunAt(*post.PublishAt),
+		jobs.UniqueKey(fmt.Sprintf("%s:%d", publishJob, post.PublishAt.Unix())))
+	if err != nil && !errors.Is(err, store.ErrConflict) {
+		log.Println("Error scheduling post:", err)
+	}
+}
+
+// CreateDraft saves a post without publishing it. The body is CreatePost's, with "publish_at"
+// (RFC 3339, in the future) the post is scheduled and goes out by itself, without it stays a draft
+func CreateDraft(w http.ResponseWriter, r *http.Request) {
+	post, ok := draftBody(w, r)
+	if !ok {
+		return
+	}
+
+	if err := stores.Posts.Create(r.Context(), &post); err != nil {
+		log.Println("Error creating draft:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to save draft")
+		return
+	}
+	schedule(r.Context(), post)
+	response.JSON(w, http.StatusCreated, map[string]interface{}{"post": post})
+}
+
+// ListDrafts returns the user's drafts and scheduled posts, newest first
+func ListDrafts(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.CurrentUserID(r)
+	posts, err := stores.Posts.Drafts(r.Context(), userID)
+	if err == nil {
+		err = poll.Posts(r.Context(), posts, userID)
+	}
+	if err != nil {
+		log.Println("Error querying drafts:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch drafts")
+		return
+	}
+	response.List(w, r, "", posts, nil)
+}
+
+// UpdateDraft replaces a draft or scheduled post, the body is CreateDraft's without the poll: the
+// one the draft was created with stays. Leaving publish_at out turns a scheduled post back into a draft
+func UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	current, ok := ownDraft(w, r)
+	if !ok {
+		return
+	}
+	post, ok := draftBody(w, r)
+	if !ok {
+		return
+	}
+	post.ID, post.Poll = current.ID, nil
+
+	err := stores.Posts.UpdateDraft(r.Context(), &post)
+	if errors.Is(err, store.ErrNotFound) { // published or deleted in the meantime
+		response.Error(w, http.StatusNotFound, "Draft not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error updating draft:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to save draft")
+		return
+	}
+	post.Username, post.Avatar = current.Username, current.Avatar
+	schedule(r.Context(), post)
+	response.JSON(w, http.StatusOK, map[string]interface{}{"post": post})
+}
+
+// DeleteDraft removes a draft or scheduled post and its images
+func DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	post, ok := ownDraft(w, r)
+	if !ok {
+		return
+	}
+	deletePost(w, r, post.ID)
+}
+
+// PublishDraft publishes a draft or scheduled post now, like CreatePost would have
+func PublishDraft(w http.ResponseWriter, r *http.Request) {
+	draft, ok := ownDraft(w, r)
+	if !ok {
+		return
+	}
+	post, err := stores.Posts.Publish(r.Context(), draft.ID)
+	if errors.Is(err, store.ErrNotFound) { // the job was faster
+		response.Error(w, http.StatusNotFound, "Draft not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error publishing draft:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to publish draft")
+		return
+	}
+	mention.Post(r.Context(), post, nil)
+	posts := []models.Post{post}
+	if err := poll.Posts(r.Context(), posts, post.UserID); err != nil {
+		log.Println("Error querying poll:", err)
+	}
+	preview.Posts(r.Context(), posts)
+	response.JSON(w, http.StatusOK, map[string]interface{}{"post": posts[0]})
+}
+
+// draftBody decodes and checks the post of CreateDraft and UpdateDraft, it answers the errors.
+// The post is the current user's
+func draftBody(w http.ResponseWriter, r *http.Request) (models.Post, bool) {
+	var post models.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return post, false
+	}
+	post.RepostOf = nil // reposts are published right away
+	post.UserID = middleware.CurrentUserID(r)
+	if !validPost(w, &post) || !validAudience(w, r.Context(), &post) {
+		return post, false
+	}
+
+	post.Status = models.PostDraft
+	if post.PublishAt != nil {
+		at := post.PublishAt.Truncate(time.Second)
+		if !at.After(time.Now()) {
+			response.Invalid(w, "publish_at must be in the future", response.FieldError{Field: "publish_at", Message: "must be in the future"})
+			return post, false
+		}
+		post.Status, post.PublishAt = models.PostScheduled, &at
+	}
+	if post.PublishAt != nil && post.Poll != nil && post.Poll.ClosesAt != nil && !post.Poll.ClosesAt.After(*post.PublishAt) {
+		response.Invalid(w, "The poll must close after the post is published",
+			response.FieldError{Field: "poll.closes_at", Message: "must be after publish_at"})
+		return post, false
+	}
+	return post, true
+}
+
+// ownDraft reads the {id} draft or scheduled post of the user. Published posts and the drafts of
+// others are a 404, nobody but the author knows a draft exists
+func ownDraft(w http.ResponseWriter, r *http.Request) (models.Post, bool) {
+	id := params.PathID(r, "id")
+	if id <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid Post ID")
+		return models.Post{}, false
+	}
+	post, err := stores.Posts.Get(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) ||
+		(err == nil && (post.UserID != middleware.CurrentUserID(r) || post.Status == models.PostPublished)) {
+		response.Error(w, http.StatusNotFound, "Draft not found")
+		return models.Post{}, false
+	}
+	if err != nil {
+		log.Println("Error loading draft:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch draft")
+		return models.Post{}, false
+	}
+	return post, true
+}
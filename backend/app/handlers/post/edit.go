@@ -0,0 +1,176 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-network/app/handlers/images"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/preview"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+// UpdatePost replaces the content, image and privacy (with the almost_private audience) of the
+// user's own post. The body is the same as CreatePost's, the old version is kept as a revision
+func UpdatePost(w http.ResponseWriter, r *http.Request) {
+	current, ok := ownPost(w, r)
+	if !ok {
+		return
+	}
+
+	var post models.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	post.RepostOf, post.Status, post.PublishAt = current.RepostOf, current.Status, nil
+	post.Poll = nil // the poll can't be changed once people voted
+	post.UserID = current.UserID
+	if !validPost(w, &post) || !validAudience(w, r.Context(), &post) || !repostStaysInAudience(w, r, post) {
+		return
+	}
+	post.ID = current.ID
+
+	dropped, err := stores.Posts.Update(r.Context(), &post)
+	if errors.Is(err, store.ErrNotFound) { // deleted in the meantime
+		response.Error(w, http.StatusNotFound, "Post not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error updating post:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to update post")
+		return
+	}
+	removeImages(dropped)
+	post.Username, post.Avatar = current.Username, current.Avatar
+	mention.Post(r.Context(), post, current.Mentions)
+	posts := []models.Post{post} // the link may have changed
+	preview.Posts(r.Context(), posts)
+	post = posts[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"post": post,
+	})
+}
+
+// DeletePost removes the user's own post, its comments and history, and the uploaded images
+// they used
+func DeletePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := ownPost(w, r)
+	if !ok {
+		return
+	}
+	deletePost(w, r, post.ID)
+}
+
+// deletePost is the end of DeletePost and DeleteDraft, once the post is known to be the user's
+func deletePost(w http.ResponseWriter, r *http.Request, id int) {
+	paths, err := stores.Posts.Delete(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Post not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error deleting post:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to delete post")
+		return
+	}
+
+	removeImages(paths)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post deleted"})
+}
+
+// removeImages deletes the files the store gave up. The rows are gone already, a file that
+// can't be removed is only logged
+func removeImages(paths []string) {
+	for _, path := range paths {
+		if err := images.Remove(path); err != nil {
+			log.Println("Error removing post image:", err)
+		}
+	}
+}
+
+// GetPostRevisions returns the edit history of a post, oldest version first
+func GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	post, ok := visiblePost(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := visibleRevisions(r.Context(), post, middleware.CurrentUserID(r))
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch post history")
+		log.Println("Error querying post revisions:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"post_id":   post.ID,
+		"revisions": revisions,
+	})
+}
+
+// repostStaysInAudience applies Repost's audience rule to the edit of a repost, a deleted original
+// has no audience left to protect
+func repostStaysInAudience(w http.ResponseWriter, r *http.Request, post models.Post) bool {
+	if post.RepostOf == nil {
+		return true
+	}
+	original, err := stores.Posts.Get(r.Context(), *post.RepostOf)
+	if errors.Is(err, store.ErrNotFound) {
+		return true
+	}
+	if err != nil {
+		log.Println("Error loading reposted post:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch post")
+		return false
+	}
+	return withinAudience(w, r.Context(), original, post)
+}
+
+// ownPost is visiblePost for the author only, the others get a 403
+func ownPost(w http.ResponseWriter, r *http.Request) (models.Post, bool) {
+	post, ok := visiblePost(w, r)
+	if !ok {
+		return post, false
+	}
+	if post.UserID != middleware.CurrentUserID(r) {
+		response.Error(w, http.StatusForbidden, "Only the author can change this post")
+		return post, false
+	}
+	return post, true
+}
+
+// visibleRevisions filters the history of a post for the viewer. An edit can open a post up
+// (private -> public), so the old versions are not shown to everybody who sees the post now:
+// the author sees all of them, the others only the public versions and, when they follow the
+// author, the private ones. almost_private versions stay with the author, their audience isn't
+// kept in post_revisions
+func visibleRevisions(ctx context.Context, post models.Post, viewerID int) ([]models.PostRevision, error) {
+	revisions, err := stores.Posts.Revisions(ctx, post.ID)
+	if err != nil || post.UserID == viewerID || len(revisions) == 0 {
+		return revisions, err
+	}
+
+	following, err := stores.Follows.IsFollowing(ctx, viewerID, post.UserID)
+	if err != nil {
+		return nil, err
+	}
+	visible := []models.PostRevision{}
+	for _, rev := range revisions {
+		if rev.Privacy == models.PrivacyPublic || (rev.Privacy == models.PrivacyPrivate && following) {
+			visible = append(visible, rev)
+		}
+	}
+	return visible, nil
+}
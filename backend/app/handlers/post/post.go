@@ -7,94 +7,71 @@ import (
 	"strconv"
 	"strings"
 
+	"social-network/app/handlers/images"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/markdown"
 	"social-network/app/middleware"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
-func CreatePost(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+var stores *store.Stores
+
+// SetStores gives the post handlers their data layer
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// feedSize is how many posts a page of GetFeedPosts has by default
+const feedSize = 20
+
+// maxContentLength counts what the reader sees, the markup of a Markdown post is free
+const maxContentLength = 400
 
+func CreatePost(w http.ResponseWriter, r *http.Request) {
 	var post models.Post
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	post.RepostOf = nil // reposts go through Repost, which checks the original's audience
+	// drafts and scheduled posts go through CreateDraft
+	post.Status, post.PublishAt = models.PostPublished, nil
 
-	// extra validation ----------------------------------------------------------------
-	if post.Image == nil && strings.TrimSpace(post.Content) == "" {
-		http.Error(w, "Image or text is required", http.StatusBadRequest)
-		return
-	}
-	if len(post.Content) > 400 {
-		http.Error(w, "Content is too long (max 400 characters)", http.StatusBadRequest)
-		return
-	}
-	if post.Image != nil && *post.Image != "" && !strings.HasPrefix(*post.Image, "/images") {
-		http.Error(w, "Invalid image path", http.StatusBadRequest)
+	if !validPost(w, &post) {
 		return
 	}
 
-	cookieValue := middleware.GetCookieValue(r)
-	userID := middleware.GetUserId(cookieValue)
+	userID := middleware.CurrentUserID(r)
 	if userID <= 0 {
-		http.Error(w, "Not an authorized user", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Not an authorized user")
 		return
 	}
 
 	// set the authenticated user as the post creator (for security reasons) ----------------------
 	post.UserID = userID
-
-	// Privacy value validation -------------------------------------------------------------------
-	if post.Privacy != models.PrivacyPublic &&
-		post.Privacy != models.PrivacyAlmostPrivate &&
-		post.Privacy != models.PrivacyPrivate {
-		http.Error(w, "Invalid privacy value", http.StatusBadRequest)
+	if !validAudience(w, r.Context(), &post) {
 		return
 	}
 
 	// Insert post into database ------------------------------------------------------------------
-	query := `INSERT INTO posts (content, image, privacy, user_id, group_id, created_at) 
-	VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
-
-	result, err := db.Database.Exec(query,
-		post.Content,
-		post.Image,
-		post.Privacy,
-		post.UserID,
-		post.GroupID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to create post", http.StatusInternalServerError)
-		return
-	}
-
-	// get the ID of the newly created post -------------------------------------------------------
-	// i like this lastinsertid function
-	postID, err := result.LastInsertId()
-	if err != nil {
-		http.Error(w, "Failed to get post ID", http.StatusInternalServerError)
+	// for almost_private posts the store also saves the allowed followers (post_visibility table)
+	// and the audience lists
+	if err := stores.Posts.Create(r.Context(), &post); err != nil {
+		log.Println("Error creating post:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to create post")
 		return
 	}
-	post.ID = int(postID)
-
-	// Handle almost_private posts - insert allowed followers into post_visibility table
-	if post.Privacy == models.PrivacyAlmostPrivate && len(post.AllowedFollowers) > 0 {
-		for _, followerID := range post.AllowedFollowers {
-			_, err := db.Database.Exec(
-				`INSERT INTO post_visibility (post_id, user_id) VALUES (?, ?)`,
-				post.ID, followerID,
-			)
-			if err != nil {
-				log.Printf("Failed to insert post visibility for user %d: %v", followerID, err)
-				// Continue with other followers even if one fails
-			}
-		}
-	}
+	mention.Post(r.Context(), post, nil)
+	posts := []models.Post{post} // queues the link preview, it's there if the link was shared before
+	preview.Posts(r.Context(), posts)
+	post = posts[0]
 
 	// Return created post as JSON in the form the front end expects -------------------------------
 	w.Header().Set("Content-Type", "application/json")
@@ -103,173 +80,204 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// get posts of people followed by the user, posts from public profile and user's own posts
-func GetFeedPosts(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// validPost checks what CreatePost, UpdatePost and Repost accept, and answers 422 when it's wrong.
+// Only new posts can have a poll
+func validPost(w http.ResponseWriter, post *models.Post) bool {
+	// extra validation ----------------------------------------------------------------
+	// a repost can be empty, it shows the shared post
+	if post.RepostOf == nil && post.Image == nil && len(post.Attachments) == 0 && strings.TrimSpace(post.Content) == "" {
+		response.Invalid(w, "Image or text is required", response.FieldError{Field: "content", Message: "is required without an image"})
+		return false
+	}
+	html, ok := markdown.Valid(w, &post.Format, post.Content, maxContentLength)
+	if !ok {
+		return false
+	}
+	post.ContentHTML = html
+	var image string
+	if post.Image != nil {
+		image = *post.Image
+	}
+	image, ok = images.Attach(w, &post.Attachments, image)
+	if !ok {
+		return false
+	}
+	post.Image = nil
+	if image != "" {
+		post.Image = &image
 	}
 
-	cookieValue := middleware.GetCookieValue(r)
-	userID := middleware.GetUserId(cookieValue)
+	// Privacy value validation -------------------------------------------------------------------
+	if post.Privacy != models.PrivacyPublic &&
+		post.Privacy != models.PrivacyAlmostPrivate &&
+		post.Privacy != models.PrivacyPrivate {
+		response.Invalid(w, "Invalid privacy value", response.FieldError{Field: "privacy", Message: "must be public, almost_private or private"})
+		return false
+	}
+	return poll.Valid(w, post.Poll)
+}
+
+// get posts of people followed by the user, posts from public profile and user's own posts
+func GetFeedPosts(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.CurrentUserID(r)
 	if userID <= 0 {
-		http.Error(w, "Not an authorized user", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Not an authorized user")
 		return
 	}
 
-	// Query for posts:
-	// - Public posts from followed users or public profiles
-	// - Almost private posts where current user is in the allowed list (post_visibility table)
-	// - Private posts only from the user themselves
+	// Visible posts (see store.PostStore for the rules):
 	// - User's own posts regardless of privacy
-	query := `
-	SELECT DISTINCT p.id, p.content, p.image, p.privacy, p.user_id, p.created_at, u.username, u.avatar
-	FROM posts p
-	JOIN users u ON p.user_id = u.id
-	LEFT JOIN followers f ON p.user_id = f.followed_id AND f.follower_id = ?
-	LEFT JOIN post_visibility pv ON p.id = pv.post_id AND pv.user_id = ?
-	WHERE 
-	    p.group_id IS NULL AND (
-	        p.privacy = 'public'
-	        OR (p.privacy = 'almost_private' AND pv.user_id IS NOT NULL)
-	        OR (p.privacy = 'private' AND f.follower_id IS NOT NULL)
-	        OR (p.user_id = ?)
-	    )
-	ORDER BY p.created_at DESC
-	LIMIT 20;`
-
-	rows, err := db.Database.Query(query, userID, userID, userID)
+	// - Public posts
+	// - Almost private posts where current user is in the allowed list (post_visibility table)
+	// - Private posts from users the current user follows
+	page, err := params.Page(r, feedSize)
 	if err != nil {
-		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		response.BadPage(w, err)
+		return
+	}
+	if page.Limit == 0 { // the old frontend route never got more than one page either
+		page.Limit = feedSize
+	}
+	posts, next, err := stores.Posts.Feed(r.Context(), userID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch posts")
 		log.Println("Error querying posts:", err)
 		return
 	}
-	defer rows.Close()
-
-	var posts []models.Post
-	for rows.Next() {
-		var post models.Post
-		var username, avatar string
-		if err := rows.Scan(&post.ID, &post.Content, &post.Image, &post.Privacy, &post.UserID, &post.CreatedAt, &username, &avatar); err != nil {
-			http.Error(w, "Failed to parse posts", http.StatusInternalServerError)
-			log.Println("Error scanning post row:", err)
-			return
-		}
-		post.Username = username
-		post.Avatar = avatar
-		posts = append(posts, post)
+	if err := reaction.Posts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		log.Println("Error querying reactions:", err)
+		return
 	}
+	if err := WithOriginals(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch shared posts")
+		log.Println("Error querying reposted posts:", err)
+		return
+	}
+	if err := poll.Posts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch polls")
+		log.Println("Error querying polls:", err)
+		return
+	}
+	preview.Posts(r.Context(), posts)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"posts": posts,
-	})
+	response.List(w, r, "posts", posts, next)
 }
 
 // get post data handler - for post view page and comments
 func GetPostHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	post, ok := visiblePost(w, r)
+	if !ok {
 		return
 	}
 
-	postIDStr := r.URL.Query().Get("post_id")
-	if postIDStr == "" {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
-		return
-	}
-	//check if postID exists and is valid integer
-	postID, err := strconv.Atoi(postIDStr)
-	if err != nil || postID <= 0 {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+	//query comments for the post
+	comments, _, err := stores.Posts.Comments(r.Context(), post.ID, store.Page{})
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch comments")
+		log.Println("Error querying comments:", err)
 		return
 	}
 
-	var post models.Post
-
-	query := `
-	SELECT p.id, p.content, p.image, p.privacy, p.user_id, p.created_at, u.username, u.avatar
-	FROM posts p
-	JOIN users u ON p.user_id = u.id
-	WHERE p.id = ?;`
-
-	err = db.Database.QueryRow(query, postID).Scan(&post.ID, &post.Content, &post.Image, &post.Privacy, &post.UserID, &post.CreatedAt, &post.Username, &post.Avatar)
+	// the counts and the user's own reaction, for the post and each comment
+	viewerID := middleware.CurrentUserID(r)
+	posts := []models.Post{post}
+	err = reaction.Posts(r.Context(), posts, viewerID)
+	if err == nil {
+		err = reaction.Comments(r.Context(), comments, viewerID)
+	}
 	if err != nil {
-		http.Error(w, "Post not found", http.StatusNotFound)
-		log.Println("Error querying post:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		log.Println("Error querying reactions:", err)
 		return
 	}
-
-	//query comments for the post
-	commentsQuery := `
-	SELECT c.id, c.content, c.image, c.user_id, c.created_at, u.username, u.avatar
-	FROM comments c
-	JOIN users u ON c.user_id = u.id
-	WHERE c.post_id = ?
-	ORDER BY c.created_at ASC;`
-
-	rows, err := db.Database.Query(commentsQuery, postID)
-	if err != nil {
-		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
-		log.Println("Error querying comments:", err)
+	if err := WithOriginals(r.Context(), posts, viewerID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch shared post")
+		log.Println("Error querying reposted post:", err)
+		return
+	}
+	if err := poll.Posts(r.Context(), posts, viewerID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch poll")
+		log.Println("Error querying poll:", err)
 		return
 	}
-	defer rows.Close()
+	preview.Posts(r.Context(), posts)
+	post = posts[0]
 
-	var comments []models.Comment
-	for rows.Next() {
-		var comment models.Comment
-		if err := rows.Scan(&comment.ID, &comment.Content, &comment.Image, &comment.UserID, &comment.CreatedAt, &comment.Username, &comment.Avatar); err != nil {
-			http.Error(w, "Failed to parse comments", http.StatusInternalServerError)
-			log.Println("Error scanning comment row:", err)
-			return
-		}
-		comments = append(comments, comment)
+	// earlier versions of an edited post, empty for the others
+	revisions, err := visibleRevisions(r.Context(), post, middleware.CurrentUserID(r))
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch post history")
+		log.Println("Error querying post revisions:", err)
+		return
 	}
 
 	// Return post with comments as JSON
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"post":     post,
-		"comments": comments,
+		"post":      post,
+		"comments":  comments,
+		"revisions": revisions,
 	})
 }
 
-// for private post options - get followers of the user
-func GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// visiblePost reads the post of the request (/posts/{id} or ?post_id=) and answers 404 when the
+// user isn't allowed to see it
+func visiblePost(w http.ResponseWriter, r *http.Request) (models.Post, bool) {
+	postIDStr := params.Get(r, "id", "post_id")
+	if postIDStr == "" {
+		response.Error(w, http.StatusBadRequest, "Post ID is required")
+		return models.Post{}, false
+	}
+	//check if postID exists and is valid integer
+	postID, err := strconv.Atoi(postIDStr)
+	if err != nil || postID <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid Post ID")
+		return models.Post{}, false
 	}
 
-	userID := r.Context().Value("ctxUserID").(int)
-	if userID == 0 {
-		http.Error(w, "Not an authorized user", http.StatusBadRequest)
-		return
+	// a post the user isn't allowed to see is reported as missing
+	visible, err := stores.Posts.CanView(r.Context(), postID, middleware.CurrentUserID(r))
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch post")
+		log.Println("Error checking post visibility:", err)
+		return models.Post{}, false
+	}
+	if !visible {
+		response.Error(w, http.StatusNotFound, "Post not found")
+		return models.Post{}, false
 	}
 
-	var followers []Followers
+	post, err := stores.Posts.Get(r.Context(), postID)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Post not found")
+		log.Println("Error querying post:", err)
+		return models.Post{}, false
+	}
+	return post, true
+}
 
-	query := `
-	SELECT u.id, u.username, u.first_name, u.last_name
-	FROM users u
-	JOIN followers f ON u.id = f.follower_id
-	WHERE f.followed_id = ?;`
+// for private post options - get followers of the user
+func GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.CurrentUserID(r)
+	if userID == 0 {
+		response.Error(w, http.StatusBadRequest, "Not an authorized user")
+		return
+	}
 
-	rows, err := db.Database.Query(query, userID)
+	users, err := stores.Follows.Followers(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to fetch followers", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch followers")
 		log.Println("Error querying followers:", err)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var follower Followers
-		if err := rows.Scan(&follower.ID, &follower.Username, &follower.FirstName, &follower.LastName); err != nil {
-			http.Error(w, "Failed to parse followers", http.StatusInternalServerError)
-			log.Println("Error scanning follower row:", err)
-			return
+
+	var followers []Followers
+	for _, u := range users {
+		follower := Followers{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName}
+		if u.Username != nil {
+			follower.Username = *u.Username
 		}
 		followers = append(followers, follower)
 	}
@@ -0,0 +1,435 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/store/memstore"
+)
+
+// useMemstore gives the handlers a fresh memstore, the feed and the post also read the reactions
+func useMemstore() {
+	s := memstore.New()
+	SetStores(s)
+	poll.SetStores(s)
+	preview.SetStores(s)
+	reaction.SetStores(s)
+	mention.SetStores(s)
+	generalfuncs.SetStores(s)
+}
+
+// asUser builds a request the way RequireAuth hands it to the handlers
+func asUser(method, target string, userID int) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	return r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+}
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func newPost(t *testing.T, userID int, privacy string, allowed ...int) int {
+	t.Helper()
+	p := models.Post{UserID: userID, Content: privacy, Privacy: privacy, AllowedFollowers: allowed}
+	if err := stores.Posts.Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p.ID
+}
+
+func TestGetFeedPostsVisibility(t *testing.T) {
+	useMemstore()
+	ctx := context.Background()
+
+	author := newUser(t, "author")
+	follower := newUser(t, "follower")
+	chosen := newUser(t, "chosen")
+	stranger := newUser(t, "stranger")
+	stores.Follows.Follow(ctx, follower, author)
+	stores.Follows.Follow(ctx, chosen, author)
+
+	newPost(t, author, models.PrivacyPublic)
+	newPost(t, author, models.PrivacyPrivate)
+	newPost(t, author, models.PrivacyAlmostPrivate, chosen)
+
+	tests := []struct {
+		name   string
+		viewer int
+		want   []string
+	}{
+		{"author sees everything", author, []string{"almost_private", "private", "public"}},
+		{"follower sees private", follower, []string{"private", "public"}},
+		{"chosen follower sees almost_private", chosen, []string{"almost_private", "private", "public"}},
+		{"stranger sees public only", stranger, []string{"public"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			GetFeedPosts(w, asUser(http.MethodGet, "/posts", tt.viewer))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			var body struct {
+				Posts []models.Post `json:"items"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range body.Posts {
+				got = append(got, p.Privacy)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("feed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPostHandlerHidesInvisiblePosts(t *testing.T) {
+	useMemstore()
+	author := newUser(t, "author")
+	stranger := newUser(t, "stranger")
+	private := newPost(t, author, models.PrivacyPrivate)
+
+	target := "/post?post_id=" + strconv.Itoa(private)
+
+	w := httptest.NewRecorder()
+	GetPostHandler(w, asUser(http.MethodGet, target, stranger))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("stranger got status %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	GetPostHandler(w, asUser(http.MethodGet, target, author))
+	if w.Code != http.StatusOK {
+		t.Errorf("author got status %d, want 200", w.Code)
+	}
+}
+
+func TestEditPostKeepsHistoryFromNewViewers(t *testing.T) {
+	useMemstore()
+	author := newUser(t, "author")
+	stranger := newUser(t, "stranger")
+	id := newPost(t, author, models.PrivacyPrivate)
+	target := "/posts/" + strconv.Itoa(id)
+
+	edit := func(userID int, body string) *httptest.ResponseRecorder {
+		r := asUser(http.MethodPut, target, userID)
+		r.SetPathValue("id", strconv.Itoa(id))
+		r.Body = io.NopCloser(strings.NewReader(body))
+		w := httptest.NewRecorder()
+		UpdatePost(w, r)
+		return w
+	}
+	if w := edit(author, `{"content":"now public","privacy":"public"}`); w.Code != http.StatusOK {
+		t.Fatalf("author edit: status %d: %s", w.Code, w.Body)
+	}
+	if w := edit(stranger, `{"content":"hacked","privacy":"public"}`); w.Code != http.StatusForbidden {
+		t.Errorf("stranger edit: status %d, want 403", w.Code)
+	}
+
+	history := func(userID int) (models.Post, []models.PostRevision) {
+		r := asUser(http.MethodGet, target, userID)
+		r.SetPathValue("id", strconv.Itoa(id))
+		w := httptest.NewRecorder()
+		GetPostHandler(w, r)
+		var body struct {
+			Post      models.Post           `json:"post"`
+			Revisions []models.PostRevision `json:"revisions"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("status %d: %v", w.Code, err)
+		}
+		return body.Post, body.Revisions
+	}
+	post, revisions := history(author)
+	if post.Content != "now public" || post.EditedAt == nil || len(revisions) != 1 {
+		t.Errorf("author sees %+v with %d revisions", post, len(revisions))
+	}
+	// the stranger sees the post now, but not what it said while it was private
+	if _, revisions := history(stranger); len(revisions) != 0 {
+		t.Errorf("stranger sees the private version: %+v", revisions)
+	}
+}
+
+func TestMarkdownPost(t *testing.T) {
+	useMemstore()
+	author := newUser(t, "author")
+	create := func(body string) (int, models.Post) {
+		r := asUser(http.MethodPost, "/posts", author)
+		r.Body = io.NopCloser(strings.NewReader(body))
+		w := httptest.NewRecorder()
+		CreatePost(w, r)
+		var resp struct {
+			Post models.Post `json:"post"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Post
+	}
+
+	// 400 visible characters, the link markup on top doesn't count
+	content := "**" + strings.Repeat("a", 396) + "** [go](https://go.dev/doc/effective_go)"
+	code, post := create(`{"content":"` + content + `","format":"markdown","privacy":"public"}`)
+	if code != http.StatusOK || post.Format != models.FormatMarkdown || post.ContentHTML == nil ||
+		!strings.HasPrefix(*post.ContentHTML, "<p><strong>aaa") || post.Content != content {
+		t.Errorf("markdown post: status %d, %+v", code, post)
+	}
+	if code, _ := create(`{"content":"` + content + `","privacy":"public"}`); code != http.StatusBadRequest {
+		t.Errorf("the same as plain text: status %d, want 400", code)
+	}
+	// the HTML is the server's, a plain post can't bring its own
+	code, post = create(`{"content":"<b>x</b>","content_html":"<script></script>","privacy":"public"}`)
+	if code != http.StatusOK || post.ContentHTML != nil || post.Format != "" {
+		t.Errorf("plain post with content_html: status %d, %+v", code, post)
+	}
+}
+
+func TestAudienceMustFollow(t *testing.T) {
+	useMemstore()
+	ctx := context.Background()
+	author := newUser(t, "author")
+	follower := newUser(t, "follower")
+	stranger := newUser(t, "stranger")
+	stores.Follows.Follow(ctx, follower, author)
+	create := func(allowed ...int) (int, models.Post) {
+		ids, _ := json.Marshal(allowed)
+		r := asUser(http.MethodPost, "/posts", author)
+		r.Body = io.NopCloser(strings.NewReader(`{"content":"x","privacy":"almost_private","allowed_followers":` + string(ids) + `}`))
+		w := httptest.NewRecorder()
+		CreatePost(w, r)
+		var resp struct {
+			Post models.Post `json:"post"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Post
+	}
+
+	if code, _ := create(follower, stranger); code != http.StatusBadRequest {
+		t.Errorf("a non follower in the audience: status %d, want 400", code)
+	}
+	code, post := create(follower, follower)
+	if code != http.StatusOK || fmt.Sprint(post.AllowedFollowers) != fmt.Sprint([]int{follower}) {
+		t.Fatalf("followers only: status %d, %+v", code, post)
+	}
+	if ok, _ := stores.Posts.CanView(ctx, post.ID, follower); !ok {
+		t.Fatal("the picked follower can't see the post")
+	}
+	stores.Follows.Unfollow(ctx, follower, author)
+	if ok, _ := stores.Posts.CanView(ctx, post.ID, follower); ok {
+		t.Error("the post is still visible after the unfollow")
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	useMemstore()
+	author := newUser(t, "author")
+	stranger := newUser(t, "stranger")
+	id := newPost(t, author, models.PrivacyPublic)
+
+	remove := func(userID int) int {
+		r := asUser(http.MethodDelete, "/posts/"+strconv.Itoa(id), userID)
+		r.SetPathValue("id", strconv.Itoa(id))
+		w := httptest.NewRecorder()
+		DeletePost(w, r)
+		return w.Code
+	}
+	if code := remove(stranger); code != http.StatusForbidden {
+		t.Errorf("stranger delete: status %d, want 403", code)
+	}
+	if code := remove(author); code != http.StatusOK {
+		t.Errorf("author delete: status %d, want 200", code)
+	}
+	if code := remove(author); code != http.StatusNotFound {
+		t.Errorf("second delete: status %d, want 404", code)
+	}
+}
+
+func TestRepost(t *testing.T) {
+	useMemstore()
+	ctx := context.Background()
+	author := newUser(t, "author")
+	follower := newUser(t, "follower")
+	other := newUser(t, "other")
+	stores.Follows.Follow(ctx, follower, author)
+	stores.Follows.Follow(ctx, other, follower)
+
+	repost := func(userID, postID int, body string) (int, models.Post) {
+		t.Helper()
+		r := asUser(http.MethodPost, "/posts/"+strconv.Itoa(postID)+"/reposts", userID)
+		r.Body = io.NopCloser(strings.NewReader(body))
+		r.SetPathValue("id", strconv.Itoa(postID))
+		w := httptest.NewRecorder()
+		Repost(w, r)
+		var resp struct{ Post models.Post }
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Post
+	}
+
+	public := newPost(t, author, models.PrivacyPublic)
+	code, shared := repost(follower, public, `{"privacy":"public"}`)
+	if code != http.StatusCreated || *shared.RepostOf != public || shared.Original == nil || shared.Original.ID != public {
+		t.Fatalf("plain repost: status %d, %+v", code, shared)
+	}
+	// sharing the repost shares the original
+	_, again := repost(other, shared.ID, `{"content":"look","privacy":"public"}`)
+	if again.RepostOf == nil || *again.RepostOf != public {
+		t.Errorf("repost of a repost points at %v, want %d", again.RepostOf, public)
+	}
+	notifications, _, _ := stores.Notifications.List(ctx, author, store.Page{})
+	if len(notifications) != 2 || notifications[0].Type != "repost" || *notifications[0].PostID != again.ID {
+		t.Errorf("author notifications = %+v", notifications)
+	}
+
+	// a private post can't leave the author's followers
+	private := newPost(t, author, models.PrivacyPrivate)
+	if code, _ := repost(follower, private, `{"privacy":"public"}`); code != http.StatusBadRequest {
+		t.Errorf("public repost of a private post: status %d, want 400", code)
+	}
+	if code, _ := repost(follower, private, fmt.Sprintf(`{"privacy":"almost_private","allowed_followers":[%d]}`, other)); code != http.StatusBadRequest {
+		t.Errorf("sharing a private post with a non follower: status %d, want 400", code)
+	}
+	if code, _ := repost(follower, private, `{"privacy":"almost_private","allowed_followers":[]}`); code != http.StatusCreated {
+		t.Errorf("sharing a private post with nobody new: status %d, want 201", code)
+	}
+	// the author isn't in that repost's audience, the notification leads to the original
+	if notifications, _, _ := stores.Notifications.List(ctx, author, store.Page{}); len(notifications) != 3 || *notifications[0].PostID != private {
+		t.Errorf("notification of a repost the author can't see = %+v", notifications)
+	}
+	if code, _ := repost(other, private, `{"privacy":"public"}`); code != http.StatusNotFound {
+		t.Errorf("sharing a post one can't see: status %d, want 404", code)
+	}
+
+	// once the original is gone the repost stays, without it
+	stores.Posts.Delete(ctx, public)
+	r := asUser(http.MethodGet, "/posts/"+strconv.Itoa(shared.ID), follower)
+	r.SetPathValue("id", strconv.Itoa(shared.ID))
+	w := httptest.NewRecorder()
+	GetPostHandler(w, r)
+	var got struct{ Post models.Post }
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Post.RepostOf == nil || got.Post.Original != nil {
+		t.Errorf("repost of a deleted post: status %d, %+v", w.Code, got.Post)
+	}
+}
+
+func TestTagPage(t *testing.T) {
+	useMemstore()
+	ctx := context.Background()
+	author := newUser(t, "author")
+	reader := newUser(t, "reader")
+	for _, content := range []string{"learning #Go", "#go is private"} {
+		privacy := models.PrivacyPublic
+		if content == "#go is private" {
+			privacy = models.PrivacyPrivate
+		}
+		if err := stores.Posts.Create(ctx, &models.Post{UserID: author, Content: content, Privacy: privacy}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tagPage := func(tag string) (int, []models.Post) {
+		t.Helper()
+		r := asUser(http.MethodGet, "/tags/"+tag+"/posts", reader)
+		r.SetPathValue("tag", tag)
+		w := httptest.NewRecorder()
+		GetTagPosts(w, r)
+		var resp struct{ Items []models.Post }
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Items
+	}
+	if code, posts := tagPage("GO"); code != http.StatusOK || len(posts) != 1 || posts[0].Content != "learning #Go" {
+		t.Errorf("tag page: status %d, %+v", code, posts)
+	}
+	if code, _ := tagPage("not-a-tag"); code != http.StatusBadRequest {
+		t.Errorf("invalid tag: status %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	GetTrendingTags(w, asUser(http.MethodGet, "/tags/trending", reader))
+	var trending struct{ Tags []models.TrendingTag }
+	json.NewDecoder(w.Body).Decode(&trending)
+	if len(trending.Tags) != 1 || trending.Tags[0].Tag != "go" || trending.Tags[0].Uses != 1 {
+		t.Errorf("trending for the reader = %+v", trending.Tags)
+	}
+}
+
+func TestScheduledPost(t *testing.T) {
+	useMemstore()
+	ctx := context.Background()
+	author := newUser(t, "author")
+	reader := newUser(t, "reader")
+
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	r := asUser(http.MethodPost, "/drafts", author)
+	r.Body = io.NopCloser(strings.NewReader(`{"content": "out later @reader", "privacy": "public", "publish_at": "` + at.Format(time.RFC3339) + `"}`))
+	w := httptest.NewRecorder()
+	CreateDraft(w, r)
+	var created struct{ Post models.Post }
+	json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != http.StatusCreated || created.Post.Status != models.PostScheduled {
+		t.Fatalf("create: status %d, %+v", w.Code, created.Post)
+	}
+	queued, _ := stores.Jobs.List(ctx, models.JobPending, 10)
+	if len(queued) != 1 || queued[0].Type != publishJob || queued[0].RunAt != at.Unix() {
+		t.Errorf("queued jobs = %+v, want posts.publish at %d", queued, at.Unix())
+	}
+
+	notified := func() int {
+		all, _, _ := stores.Notifications.List(ctx, reader, store.Page{})
+		return len(all)
+	}
+	if n := notified(); n != 0 {
+		t.Errorf("reader got %d notifications before the post is out", n)
+	}
+
+	publish := func(userID int) int {
+		r := asUser(http.MethodPost, "/drafts/"+strconv.Itoa(created.Post.ID)+"/publish", userID)
+		r.SetPathValue("id", strconv.Itoa(created.Post.ID))
+		w := httptest.NewRecorder()
+		PublishDraft(w, r)
+		return w.Code
+	}
+	if code := publish(reader); code != http.StatusNotFound {
+		t.Errorf("publishing someone else's draft: status %d, want 404", code)
+	}
+	if code := publish(author); code != http.StatusOK {
+		t.Fatalf("publish: status %d", code)
+	}
+	if n := notified(); n != 1 {
+		t.Errorf("reader got %d notifications once the post is out, want the mention", n)
+	}
+	w = httptest.NewRecorder()
+	GetFeedPosts(w, asUser(http.MethodGet, "/posts", reader))
+	var feed struct{ Items []models.Post }
+	json.NewDecoder(w.Body).Decode(&feed)
+	if len(feed.Items) != 1 || feed.Items[0].ID != created.Post.ID {
+		t.Errorf("reader's feed = %+v, want the published post", feed.Items)
+	}
+	if code := publish(author); code != http.StatusNotFound {
+		t.Errorf("publishing twice: status %d, want 404", code)
+	}
+}
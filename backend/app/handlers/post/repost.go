@@ -0,0 +1,197 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/handlers/mention"
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/preview"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+// Repost shares a post the user can see on their own profile. Without content it is a plain
+// repost, with content a quote post. The body is CreatePost's without the required content:
+// {"content": "...", "privacy": "public", "allowed_followers": [...]}
+func Repost(w http.ResponseWriter, r *http.Request) {
+	original, ok := visiblePost(w, r)
+	if !ok {
+		return
+	}
+	userID := middleware.CurrentUserID(r)
+
+	var post models.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// reposting a plain repost shares the post underneath, a quote post is shared as it is
+	if original.RepostOf != nil && isPlainRepost(original) {
+		shared, err := sharedPost(r.Context(), *original.RepostOf, userID)
+		if err != nil {
+			log.Println("Error loading reposted post:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to fetch post")
+			return
+		}
+		if shared == nil {
+			response.Error(w, http.StatusNotFound, "Post not found")
+			return
+		}
+		original = *shared
+	}
+
+	post.RepostOf, post.Poll = &original.ID, nil
+	if !validPost(w, &post) || !withinAudience(w, r.Context(), original, post) {
+		return
+	}
+	post.UserID = userID
+	post.Status, post.PublishAt = models.PostPublished, nil
+	if !validAudience(w, r.Context(), &post) {
+		return
+	}
+
+	if err := stores.Posts.Create(r.Context(), &post); err != nil {
+		log.Println("Error creating repost:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to create post")
+		return
+	}
+
+	if original.UserID != userID {
+		notifyAuthor(r.Context(), original, userID, post.ID)
+	}
+	mention.Post(r.Context(), post, nil)
+
+	post.Original = &original
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"post": post,
+	})
+}
+
+// notifyAuthor tells the original's author who shared it, the repost is saved either way. The
+// notification leads to the repost, or to the original when the repost's audience leaves the
+// author out
+func notifyAuthor(ctx context.Context, original models.Post, userID, repostID int) {
+	postID := repostID
+	visible, err := stores.Posts.CanView(ctx, repostID, original.UserID)
+	if err != nil {
+		log.Println("Error checking the repost's audience:", err)
+		return
+	}
+	if !visible {
+		postID = original.ID
+	}
+
+	user, err := stores.Users.Get(ctx, userID)
+	if err != nil {
+		log.Println("Error loading reposting user:", err)
+		return
+	}
+	name := user.FirstName
+	if user.Username != nil && *user.Username != "" {
+		name = *user.Username
+	}
+	err = generalfuncs.CreateNotification(ctx, models.Notification{
+		UserID:       original.UserID,
+		Type:         "repost",
+		SenderID:     &userID,
+		SenderName:   &name,
+		SenderAvatar: user.Avatar,
+		PostID:       &postID,
+	})
+	if err != nil {
+		log.Println("Error creating repost notification:", err)
+	}
+}
+
+func isPlainRepost(p models.Post) bool {
+	return p.Content == "" && p.Image == nil
+}
+
+// withinAudience keeps a repost inside the audience of the post it shares. Public posts can
+// be shared with anyone. The others only with almost_private and followers who can already
+// see the original, not lists, so a repost never shows it to someone new
+func withinAudience(w http.ResponseWriter, ctx context.Context, original, repost models.Post) bool {
+	if original.Privacy == models.PrivacyPublic {
+		return true
+	}
+	if repost.Privacy != models.PrivacyAlmostPrivate {
+		response.Invalid(w, "A post that isn't public can only be shared with people who can see it",
+			response.FieldError{Field: "privacy", Message: "must be almost_private to share a " + original.Privacy + " post"})
+		return false
+	}
+	// who is in a list changes after the repost, the original's audience wouldn't hold
+	if len(repost.AudienceLists) > 0 {
+		response.Invalid(w, "A post that isn't public can only be shared with people who can see it",
+			response.FieldError{Field: "audience_lists", Message: "can't be used to share a " + original.Privacy + " post"})
+		return false
+	}
+	for _, followerID := range repost.AllowedFollowers {
+		visible, err := stores.Posts.CanView(ctx, original.ID, followerID)
+		if err != nil {
+			log.Println("Error checking post visibility:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to check the audience")
+			return false
+		}
+		if !visible {
+			response.Invalid(w, "A post that isn't public can only be shared with people who can see it",
+				response.FieldError{Field: "allowed_followers", Message: "can only contain followers who see the original post"})
+			return false
+		}
+	}
+	return true
+}
+
+// sharedPost returns the post a repost points at when the viewer may see it, nil when it was
+// deleted or isn't visible (anymore)
+func sharedPost(ctx context.Context, postID, viewerID int) (*models.Post, error) {
+	visible, err := stores.Posts.CanView(ctx, postID, viewerID)
+	if err != nil || !visible {
+		return nil, err
+	}
+	original, err := stores.Posts.Get(ctx, postID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the poll of a shared post can be voted on from the repost
+	originals := []models.Post{original}
+	if err := poll.Posts(ctx, originals, viewerID); err != nil {
+		return nil, err
+	}
+	preview.Posts(ctx, originals)
+	return &originals[0], nil
+}
+
+// WithOriginals embeds the shared post into the reposts and quote posts, when the viewer can see it.
+// A deleted or hidden original leaves Original empty, the frontend shows "not available" for it
+func WithOriginals(ctx context.Context, posts []models.Post, viewerID int) error {
+	seen := map[int]*models.Post{}
+	for i := range posts {
+		id := posts[i].RepostOf
+		if id == nil {
+			continue
+		}
+		original, ok := seen[*id]
+		if !ok {
+			var err error
+			if original, err = sharedPost(ctx, *id, viewerID); err != nil {
+				return err
+			}
+			seen[*id] = original
+		}
+		posts[i].Original = original
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package post
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/hashtag"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+)
+
+const (
+	// the trending tags only look at the last day, and a use is worth half as much every 6 hours
+	trendingWindow   = 24 * time.Hour
+	trendingHalfLife = 6 * time.Hour
+	trendingSize     = 10
+	trendingMax      = 50
+)
+
+// trendingWeights is what a use is worth by its age in hours
+var trendingWeights = hashtag.Weights(trendingWindow, trendingHalfLife)
+
+// backfillJob tags the posts written before the tags were saved, migration 000034 queues it once
+const backfillJob = "tags.backfill"
+
+// backfillTags runs tags.backfill
+func backfillTags(ctx context.Context, job models.Job) error {
+	n, err := stores.Tags.Backfill(ctx)
+	if n > 0 {
+		log.Printf("Backfilled the tags of %d posts", n)
+	}
+	return err
+}
+
+// GetTagPosts is the tag page: GET /tags/{tag}/posts lists the posts with the tag, with the
+// feed's visibility rules and paging. The tag is matched without its case and '#'
+func GetTagPosts(w http.ResponseWriter, r *http.Request) {
+	tag := hashtag.Normalize(r.PathValue("tag"))
+	if tag == "" {
+		response.Error(w, http.StatusBadRequest, "Invalid tag")
+		return
+	}
+	userID := middleware.CurrentUserID(r)
+
+	page, err := params.Page(r, feedSize)
+	if err != nil {
+		response.BadPage(w, err)
+		return
+	}
+	posts, next, err := stores.Tags.Posts(r.Context(), tag, userID, page)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch posts")
+		log.Println("Error querying tagged posts:", err)
+		return
+	}
+	if err := reaction.Posts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		log.Println("Error querying reactions:", err)
+		return
+	}
+	if err := WithOriginals(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch shared posts")
+		log.Println("Error querying reposted posts:", err)
+		return
+	}
+	if err := poll.Posts(r.Context(), posts, userID); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch polls")
+		log.Println("Error querying polls:", err)
+		return
+	}
+	preview.Posts(r.Context(), posts)
+
+	response.List(w, r, "", posts, next)
+}
+
+// GetTrendingTags returns the tags used most in the last day, recent uses weigh more (see
+// hashtag.Weights). Only the posts and group posts the user can see count, so two users can
+// get different lists. ?limit= takes up to 50 tags, 10 by default
+func GetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	limit := trendingSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > trendingMax {
+			response.Invalid(w, "Invalid limit", response.FieldError{Field: "limit", Message: "must be between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+
+	trending, err := stores.Tags.Trending(r.Context(), middleware.CurrentUserID(r), time.Now(), trendingWeights, limit)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to fetch tags")
+		log.Println("Error querying trending tags:", err)
+		return
+	}
+	for i := range trending {
+		trending[i].Score = math.Round(trending[i].Score*1000) / 1000
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"tags": trending,
+	})
+}
@@ -0,0 +1,145 @@
+// Package preview attaches the link previews to posts and messages. The pages are fetched by the
+// links.unfurl job (see app/linkpreview), never while a request waits: a link missing from the
+// cache or stale there is queued and shows its preview from the next read on
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"social-network/app/jobs"
+	"social-network/app/linkpreview"
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the previews are cached in
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// fetcher is replaced by the tests, with the client of their httptest server
+var fetcher = linkpreview.NewFetcher()
+
+const (
+	unfurlJob  = "links.unfurl"
+	cleanupJob = "links.cleanup"
+)
+
+const (
+	ttl       = 24 * time.Hour
+	failedTTL = time.Hour // a site that was down gets another chance sooner
+	keep      = 30 * 24 * time.Hour
+)
+
+// RegisterJobs adds the fetch of the previews and the cleanup of the ones nobody read in a while
+func RegisterJobs(r *jobs.Runner) {
+	r.Handle(unfurlJob, unfurl)
+	r.Every(cleanupJob, 24*time.Hour, func(ctx context.Context, job models.Job) error {
+		_, err := stores.LinkPreviews.DeleteBefore(ctx, time.Now().Add(-keep))
+		return err
+	})
+}
+
+type unfurlPayload struct {
+	URL string `json:"url"`
+}
+
+// unfurl fetches one page. A page that can't be fetched is cached empty rather than retried,
+// it's tried again once failedTTL is over and someone reads the link
+func unfurl(ctx context.Context, job models.Job) error {
+	var payload unfurlPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	p, err := fetcher.Fetch(ctx, payload.URL)
+	if err != nil {
+		log.Printf("Link preview of %s failed: %v", payload.URL, err)
+		p = models.LinkPreview{URL: payload.URL}
+	}
+	p.FetchedAt = time.Now()
+	return stores.LinkPreviews.Save(ctx, p)
+}
+
+func stale(p models.LinkPreview, now time.Time) bool {
+	if p.Empty() {
+		return now.Sub(p.FetchedAt) > failedTTL
+	}
+	return now.Sub(p.FetchedAt) > ttl
+}
+
+// lookup returns the cached previews of the texts' first links, by text index, and queues the
+// links that are missing or stale. A stale preview is still shown until the new one is in
+func lookup(ctx context.Context, texts []string) map[int]*models.LinkPreview {
+	urls, byText := []string{}, map[int]string{}
+	for i, text := range texts {
+		if u := linkpreview.FirstURL(text); u != "" {
+			urls, byText[i] = append(urls, u), u
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	cached, err := stores.LinkPreviews.Get(ctx, urls)
+	if err != nil { // previews are extras, the posts go out without them
+		log.Println("Error querying link previews:", err)
+		return nil
+	}
+
+	now, queued := time.Now(), map[string]bool{}
+	found := map[int]*models.LinkPreview{}
+	for i, u := range byText {
+		p, ok := cached[u]
+		if (!ok || stale(p, now)) && !queued[u] {
+			queue(ctx, u)
+			queued[u] = true
+		}
+		if ok && !p.Empty() {
+			found[i] = &p
+		}
+	}
+	return found
+}
+
+func queue(ctx context.Context, url string) {
+	_, err := jobs.Enqueue(ctx, stores.Jobs, unfurlJob, unfurlPayload{URL: url},
+		jobs.UniqueKey(unfurlJob+":"+url), jobs.MaxAttempts(1))
+	if err != nil && !errors.Is(err, store.ErrConflict) {
+		log.Println("Error queueing link preview:", err)
+	}
+}
+
+// fill sets the preview of each item, text and target pick the text and the field of an item
+func fill[T any](ctx context.Context, items []T, text func(*T) string, target func(*T) **models.LinkPreview) {
+	texts := make([]string, len(items))
+	for i := range items {
+		texts[i] = text(&items[i])
+	}
+	found := lookup(ctx, texts)
+	for i := range items {
+		*target(&items[i]) = found[i]
+	}
+}
+
+// Posts sets the Preview of the posts
+func Posts(ctx context.Context, posts []models.Post) {
+	fill(ctx, posts, func(p *models.Post) string { return p.Content },
+		func(p *models.Post) **models.LinkPreview { return &p.Preview })
+}
+
+// Messages sets the Preview of private messages
+func Messages(ctx context.Context, messages []models.Message) {
+	fill(ctx, messages, func(m *models.Message) string { return m.Content },
+		func(m *models.Message) **models.LinkPreview { return &m.Preview })
+}
+
+// GroupMessages sets the Preview of group chat messages
+func GroupMessages(ctx context.Context, messages []models.GroupMessage) {
+	fill(ctx, messages, func(m *models.GroupMessage) string { return m.Content },
+		func(m *models.GroupMessage) **models.LinkPreview { return &m.Preview })
+}
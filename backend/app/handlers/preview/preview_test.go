@@ -0,0 +1,74 @@
+package preview
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-network/app/linkpreview"
+	"social-network/app/models"
+	"social-network/app/store/memstore"
+)
+
+func TestUnfurl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/article" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<head><meta property="og:title" content="Article"></head>`))
+	}))
+	defer server.Close()
+	SetStores(memstore.New())
+	fetcher = &linkpreview.Fetcher{Client: server.Client()}
+	ctx := context.Background()
+
+	// unfurl runs what the first read queued
+	run := func() int {
+		t.Helper()
+		queued, err := stores.Jobs.List(ctx, "pending", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, job := range queued {
+			if err := unfurl(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			stores.Jobs.Complete(ctx, job.ID, 0)
+		}
+		return len(queued)
+	}
+
+	posts := []models.Post{
+		{Content: "read " + server.URL + "/article!"},
+		{Content: "same " + server.URL + "/article"},
+		{Content: "gone " + server.URL + "/missing"},
+		{Content: "no link"},
+	}
+	Posts(ctx, posts)
+	if posts[0].Preview != nil {
+		t.Errorf("preview before the fetch: %+v", posts[0].Preview)
+	}
+	if n := run(); n != 2 {
+		t.Fatalf("%d jobs queued, want one per link", n)
+	}
+
+	Posts(ctx, posts)
+	for i, want := range []string{"Article", "Article", "", ""} {
+		if got := posts[i].Preview; (got == nil) != (want == "") || (got != nil && got.Title != want) {
+			t.Errorf("post %d preview = %+v, want %q", i, got, want)
+		}
+	}
+	// the broken link is cached empty, it isn't fetched again right away
+	if n := run(); n != 0 {
+		t.Errorf("%d jobs queued by a fresh cache, want none", n)
+	}
+
+	messages := []models.Message{{Content: server.URL + "/article"}}
+	Messages(ctx, messages)
+	if messages[0].Preview == nil || messages[0].Preview.URL != server.URL+"/article" {
+		t.Errorf("message preview = %+v", messages[0].Preview)
+	}
+}
@@ -1,14 +1,21 @@
 package profile
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
 	"social-network/app/middleware"
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
 // Profile struct for frontend
@@ -26,13 +33,9 @@ type Profile struct {
 }
 
 func ProfileHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	currentUserID := middleware.GetUserId(middleware.GetCookieValue(r))
-	userProfileID := r.URL.Query().Get("user_id")
+	ctx := r.Context()
+	currentUserID := middleware.CurrentUserID(r)
+	userProfileID := params.Get(r, "id", "user_id")
 
 	var userID int
 	var err error
@@ -41,19 +44,18 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		userID, err = strconv.Atoi(userProfileID)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			response.Error(w, http.StatusBadRequest, "Invalid user ID")
 			return
 		}
 	}
 	//check if user exists
-	var exists int
-	err = db.Database.QueryRow("SELECT 1 FROM users WHERE id = ?", userID).Scan(&exists)
+	user, err := stores.Users.Get(ctx, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
+		if errors.Is(err, store.ErrNotFound) {
+			response.Error(w, http.StatusNotFound, "User not found")
 			return
 		}
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
@@ -61,193 +63,129 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 	if userID == currentUserID {
 		// Owner: full profile
-		profileData = getProfileData(userID, true)
+		profileData = getProfileData(ctx, user, currentUserID, true)
 	} else {
-		isPublic := getProfilePrivacy(userID)
-
-		if isPublic {
+		if !user.IsPrivate {
 			// Public profile → full profile visible
-			profileData = getProfileData(userID, false)
+			profileData = getProfileData(ctx, user, currentUserID, false)
 		} else {
 			// Private profile → check if current user is a follower
-			var isFollower bool
-			err := db.Database.QueryRow(
-				"SELECT COUNT(*) > 0 FROM followers WHERE followed_id = ? AND follower_id = ?",
-				userID, currentUserID,
-			).Scan(&isFollower)
+			isFollower, err := stores.Follows.IsFollowing(ctx, currentUserID, userID)
 			if err != nil {
-				http.Error(w, "Database error", http.StatusInternalServerError)
+				response.Error(w, http.StatusInternalServerError, "Database error")
 				return
 			}
 
 			if !isFollower {
 				// Private profile, not a follower → only basic info + counts
-				profileData = getPrivateProfileInfo(userID)
-				profileData.FollowStatus = getFollowStatus(userID, currentUserID)
-				writeJSON(w, http.StatusOK, profileData)
+				profileData = getPrivateProfileInfo(ctx, user)
+				profileData.FollowStatus = getFollowStatus(ctx, userID, currentUserID)
+				response.JSON(w, http.StatusOK, profileData)
 				return
 			}
 
 			// Private profile, follower → full profile visible
-			profileData = getProfileData(userID, false)
+			profileData = getProfileData(ctx, user, currentUserID, false)
 		}
 
 		// Always set follow status for other users
-		profileData.FollowStatus = getFollowStatus(userID, currentUserID)
+		profileData.FollowStatus = getFollowStatus(ctx, userID, currentUserID)
 	}
 
-	writeJSON(w, http.StatusOK, profileData)
+	response.JSON(w, http.StatusOK, profileData)
 }
 
 // ------------------- HELPERS -------------------
 
-func getProfileData(userID int, isOwner bool) Profile {
-	followers := GetUserFollowers(userID)
-	following := GetUserFollowing(userID)
-	posts := getUserPosts(userID)
+func getProfileData(ctx context.Context, user models.User, viewerID int, isOwner bool) Profile {
+	followers := GetUserFollowers(ctx, user.ID)
+	following := GetUserFollowing(ctx, user.ID)
+	posts := getUserPosts(ctx, user.ID, viewerID)
 
 	return Profile{
-		User:      getUserInfo(userID),
+		User:      user,
 		Posts:     posts,
 		Followers: followers,
 		Following: following,
-		Public:    getProfilePrivacy(userID),
+		Public:    !user.IsPrivate,
 		Owner:     isOwner,
 	}
 }
 
-func getUserInfo(userID int) models.User {
-	var user models.User
-	err := db.Database.QueryRow(
-		`SELECT id, email, first_name, last_name, date_of_birth, avatar, username, about_me, is_private, created_at
-		 FROM users WHERE id = ?`, userID,
-	).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.DateOfBirth,
-		&user.Avatar, &user.Username, &user.AboutMe, &user.IsPrivate, &user.CreatedAt)
-	if err != nil {
-		fmt.Println("Error scanning user info:", err)
-	}
-	return user
-}
-
-func getUserPosts(userID int) []models.Post {
-	rows, err := db.Database.Query("SELECT id, user_id, content, image, created_at FROM posts WHERE user_id = ?", userID)
+// getUserPosts only returns the posts the viewer is allowed to see (same rules as the feed)
+func getUserPosts(ctx context.Context, userID, viewerID int) []models.Post {
+	posts, err := stores.Posts.ByAuthor(ctx, userID, viewerID)
 	if err != nil {
 		fmt.Println("Error querying posts:", err)
 		return []models.Post{}
 	}
-	defer rows.Close()
-
-	var posts []models.Post
-	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.Image, &post.CreatedAt); err != nil {
-			fmt.Println("Error scanning post:", err)
-			continue
-		}
-		posts = append(posts, post)
+	// the same extras as the feed: reactions, what the reposts share, polls and link previews
+	if err := reaction.Posts(ctx, posts, viewerID); err != nil {
+		fmt.Println("Error querying reactions:", err)
+	}
+	if err := post.WithOriginals(ctx, posts, viewerID); err != nil {
+		fmt.Println("Error querying reposted posts:", err)
 	}
+	if err := poll.Posts(ctx, posts, viewerID); err != nil {
+		fmt.Println("Error querying polls:", err)
+	}
+	preview.Posts(ctx, posts)
 	return posts
 }
 
-func GetUserFollowers(userID int) []models.User {
-	rows, err := db.Database.Query("SELECT id, username, avatar FROM users WHERE id IN (SELECT follower_id FROM followers WHERE followed_id = ?)", userID)
+func GetUserFollowers(ctx context.Context, userID int) []models.User {
+	followers, err := stores.Follows.Followers(ctx, userID)
 	if err != nil {
 		fmt.Println("Error querying followers:", err)
 		return []models.User{}
 	}
-	defer rows.Close()
-
-	var followers []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Avatar); err != nil {
-			fmt.Println("Error scanning follower:", err)
-			continue
-		}
-		user.UnreadCount = getUnreadCount(userID, user.ID)
-		followers = append(followers, user)
+	for i := range followers {
+		followers[i].UnreadCount = getUnreadCount(ctx, userID, followers[i].ID)
 	}
 	return followers
 }
 
-func GetUserFollowing(userID int) []models.User {
-	rows, err := db.Database.Query("SELECT id, username, avatar FROM users WHERE id IN (SELECT followed_id FROM followers WHERE follower_id = ?)", userID)
+func GetUserFollowing(ctx context.Context, userID int) []models.User {
+	following, err := stores.Follows.Following(ctx, userID)
 	if err != nil {
 		fmt.Println("Error querying following:", err)
 		return []models.User{}
 	}
-	defer rows.Close()
-
-	var following []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Avatar); err != nil {
-			fmt.Println("Error scanning following user:", err)
-			continue
-		}
-		user.UnreadCount = getUnreadCount(userID, user.ID)
-		following = append(following, user)
+	for i := range following {
+		following[i].UnreadCount = getUnreadCount(ctx, userID, following[i].ID)
 	}
 	return following
 }
 
-func getProfilePrivacy(userID int) bool {
-	var isPrivate bool
-	err := db.Database.QueryRow("SELECT is_private FROM users WHERE id = ?", userID).Scan(&isPrivate)
+func getFollowStatus(ctx context.Context, profileUserID, currentUserID int) string {
+	following, err := stores.Follows.IsFollowing(ctx, currentUserID, profileUserID)
 	if err != nil {
-		fmt.Println("Error fetching privacy:", err)
-		return false
-	}
-	return !isPrivate // true if public
-}
-
-func getFollowStatus(profileUserID, currentUserID int) string {
-	var exists int
-	err := db.Database.QueryRow(
-		"SELECT 1 FROM followers WHERE follower_id = ? AND followed_id = ?",
-		currentUserID, profileUserID,
-	).Scan(&exists)
-	if err == nil && exists == 1 {
-		return "following"
-	}
-	if err != nil && err != sql.ErrNoRows {
 		fmt.Println("DB error checking followers:", err)
 		return "none"
 	}
+	if following {
+		return "following"
+	}
 
 	// Check pending request
-	err = db.Database.QueryRow(
-		`SELECT 1 FROM follow_user_requests WHERE requester_id = ? AND userToFollow_id = ? AND status='pending'`,
-		currentUserID, profileUserID,
-	).Scan(&exists)
-	if err == nil && exists == 1 {
+	pending, err := stores.Follows.HasPendingRequest(ctx, currentUserID, profileUserID)
+	if err == nil && pending {
 		return "requested"
 	}
 
 	return "none"
 }
 
-func getPrivateProfileInfo(userID int) Profile {
-	user := getUserInfo(userID)
-
-	var postsCount, followersCount, followingCount int
-
-	// Count posts
-	err := db.Database.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ?", userID).Scan(&postsCount)
+func getPrivateProfileInfo(ctx context.Context, user models.User) Profile {
+	// counts fall back to 0 on error
+	postsCount, err := stores.Posts.CountByAuthor(ctx, user.ID)
 	if err != nil {
 		postsCount = 0
 	}
 
-	// Count followers
-	err = db.Database.QueryRow("SELECT COUNT(*) FROM followers WHERE followed_id = ?", userID).Scan(&followersCount)
-	if err != nil {
-		followersCount = 0
-	}
-
-	// Count following
-	err = db.Database.QueryRow("SELECT COUNT(*) FROM followers WHERE follower_id = ?", userID).Scan(&followingCount)
+	followersCount, followingCount, err := stores.Follows.Counts(ctx, user.ID)
 	if err != nil {
-		followingCount = 0
+		followersCount, followingCount = 0, 0
 	}
 
 	return Profile{
@@ -269,12 +207,8 @@ func getPrivateProfileInfo(userID int) Profile {
 }
 
 // the receiver is the current user and the senderID is the user at conversation chatsidebar
-func getUnreadCount(receiverID int, senderID int) int {
-	var count int
-	err := db.Database.QueryRow(
-		"SELECT COUNT(*) FROM messages WHERE receiver_id = ? AND sender_id = ? AND is_read = 0",
-		receiverID, senderID,
-	).Scan(&count)
+func getUnreadCount(ctx context.Context, receiverID int, senderID int) int {
+	count, err := stores.Chat.UnreadCount(ctx, receiverID, senderID)
 	if err != nil {
 		fmt.Println("Error fetching unread count:", err)
 		return 0
@@ -0,0 +1,131 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"social-network/app/handlers/poll"
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
+	"social-network/app/handlers/reaction"
+	"social-network/app/models"
+	"social-network/app/store/memstore"
+)
+
+func asUser(target string, userID int) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	return r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+}
+
+func newUser(t *testing.T, name string, isPrivate bool) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01", IsPrivate: isPrivate,
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func getProfile(t *testing.T, userID, viewerID int) Profile {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ProfileHandler(w, asUser("/profile?user_id="+strconv.Itoa(userID), viewerID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var p Profile
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProfileHandlerVisibility(t *testing.T) {
+	s := memstore.New()
+	SetStores(s)
+	poll.SetStores(s)
+	post.SetStores(s)
+	preview.SetStores(s)
+	reaction.SetStores(s)
+	ctx := context.Background()
+
+	owner := newUser(t, "owner", true)
+	follower := newUser(t, "follower", false)
+	stranger := newUser(t, "stranger", false)
+	requester := newUser(t, "requester", false)
+	stores.Follows.Follow(ctx, follower, owner)
+	stores.Follows.CreateRequest(ctx, requester, owner)
+
+	for _, privacy := range []string{models.PrivacyPublic, models.PrivacyPrivate} {
+		p := models.Post{UserID: owner, Content: privacy, Privacy: privacy}
+		if privacy == models.PrivacyPublic {
+			p.Poll = &models.Poll{Options: []models.PollOption{{Text: "yes"}, {Text: "no"}}}
+		}
+		if err := stores.Posts.Create(ctx, &p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stores.Reactions.React(ctx, models.Reaction{UserID: follower, TargetType: models.TargetPost, TargetID: p.ID, Type: "love"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("stranger only gets counts of a private profile", func(t *testing.T) {
+		p := getProfile(t, owner, stranger)
+		if p.Posts != nil || p.Followers != nil || p.Email != "" {
+			t.Errorf("private details leaked: %+v", p)
+		}
+		if p.PostsCount != 2 || p.FollowersCount != 1 || p.FollowStatus != "none" {
+			t.Errorf("counts = %d posts, %d followers, status %q", p.PostsCount, p.FollowersCount, p.FollowStatus)
+		}
+	})
+
+	t.Run("pending requester sees requested", func(t *testing.T) {
+		if p := getProfile(t, owner, requester); p.FollowStatus != "requested" || p.Posts != nil {
+			t.Errorf("profile for requester = %+v", p)
+		}
+	})
+
+	t.Run("follower gets the full profile", func(t *testing.T) {
+		p := getProfile(t, owner, follower)
+		if len(p.Posts) != 2 || len(p.Followers) != 1 || p.FollowStatus != "following" || p.Owner {
+			t.Errorf("profile for follower = %+v", p)
+		}
+		// the posts come with what the feed adds to them
+		for _, post := range p.Posts {
+			if post.Reactions["love"] != 1 || post.MyReaction != "love" {
+				t.Errorf("reactions of post %d = %v, mine %q", post.ID, post.Reactions, post.MyReaction)
+			}
+			if (post.Privacy == models.PrivacyPublic) != (post.Poll != nil) {
+				t.Errorf("poll of the %s post = %+v", post.Privacy, post.Poll)
+			}
+		}
+	})
+
+	t.Run("owner gets the full profile", func(t *testing.T) {
+		if p := getProfile(t, owner, owner); !p.Owner || len(p.Posts) != 2 {
+			t.Errorf("profile for owner = %+v", p)
+		}
+	})
+
+	t.Run("public profile hides private posts from strangers", func(t *testing.T) {
+		stores.Users.SetPrivacy(ctx, owner, false)
+		p := getProfile(t, owner, stranger)
+		if len(p.Posts) != 1 || p.Posts[0].Privacy != models.PrivacyPublic {
+			t.Errorf("posts for stranger = %+v", p.Posts)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ProfileHandler(w, asUser("/profile?user_id=999", stranger))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status %d, want 404", w.Code)
+		}
+	})
+}
@@ -4,19 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"social-network/app/middleware"
-	"social-network/db"
+	"social-network/app/response"
 )
 
 //change profile privacy /profile/privacy
 func UpdateProfilePrivacyHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	currentUserID := middleware.GetUserId(middleware.GetCookieValue(r))
+	currentUserID := middleware.CurrentUserID(r)
 	if currentUserID == 0 {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -25,17 +20,12 @@ func UpdateProfilePrivacyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	_, err := db.Database.Exec(
-		"UPDATE users SET is_private = ? WHERE id = ?",
-		reqBody.IsPrivate,
-		currentUserID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to update profile privacy", http.StatusInternalServerError)
+	if err := stores.Users.SetPrivacy(r.Context(), currentUserID, reqBody.IsPrivate); err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to update profile privacy")
 		return
 	}
 
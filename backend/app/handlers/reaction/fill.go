@@ -0,0 +1,62 @@
+package reaction
+
+import (
+	"context"
+
+	"social-network/app/models"
+)
+
+// Fill sets the reaction summary of every item for the viewer, with one query for all of them.
+// at returns the id of an item and where its summary goes
+func Fill[T any](ctx context.Context, targetType string, items []T, viewerID int, at func(*T) (int, *models.ReactionSummary)) error {
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i], _ = at(&items[i])
+	}
+	summaries, err := stores.Reactions.Summaries(ctx, targetType, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		id, summary := at(&items[i])
+		*summary = summaries[id]
+		if summary.Reactions == nil {
+			summary.Reactions = map[string]int{}
+		}
+	}
+	return nil
+}
+
+func Posts(ctx context.Context, posts []models.Post, viewerID int) error {
+	return Fill(ctx, models.TargetPost, posts, viewerID, func(p *models.Post) (int, *models.ReactionSummary) {
+		return p.ID, &p.ReactionSummary
+	})
+}
+
+func Comments(ctx context.Context, comments []models.Comment, viewerID int) error {
+	return Fill(ctx, models.TargetComment, comments, viewerID, func(c *models.Comment) (int, *models.ReactionSummary) {
+		return c.ID, &c.ReactionSummary
+	})
+}
+
+// GroupPosts fills the posts and the comments that come with them
+func GroupPosts(ctx context.Context, posts []models.GroupPost, viewerID int) error {
+	err := Fill(ctx, models.TargetGroupPost, posts, viewerID, func(p *models.GroupPost) (int, *models.ReactionSummary) {
+		return p.ID, &p.ReactionSummary
+	})
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if err := GroupComments(ctx, posts[i].Comments, viewerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GroupComments(ctx context.Context, comments []models.GroupComment, viewerID int) error {
+	return Fill(ctx, models.TargetGroupComment, comments, viewerID, func(c *models.GroupComment) (int, *models.ReactionSummary) {
+		return c.ID, &c.ReactionSummary
+	})
+}
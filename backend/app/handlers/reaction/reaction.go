@@ -0,0 +1,252 @@
+// Package reaction handles the emoji reactions on posts, comments, group posts and group comments.
+// Every target type gets the same PUT (react or change the reaction) and DELETE routes, see React
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/handlers/websocket"
+	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/params"
+	"social-network/app/response"
+	"social-network/app/store"
+	"social-network/app/telemetry"
+)
+
+var stores *store.Stores
+
+// SetStores wires the stores the reaction handlers and the Fill helpers use
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+type reactRequest struct {
+	Type string `json:"type"`
+}
+
+// React returns the PUT handler of a target type, the body is {"type": "like"}.
+// A user has one reaction per target, reacting again replaces it. The answer is the new summary
+func React(targetType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetID, target, ok := reachable(w, r, targetType)
+		if !ok {
+			return
+		}
+
+		var req reactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if _, known := models.ReactionTypes[req.Type]; !known {
+			response.Invalid(w, "Unknown reaction", response.FieldError{Field: "type", Message: "must be one of like, love, haha, wow, sad, angry"})
+			return
+		}
+
+		userID := middleware.CurrentUserID(r)
+		added, err := stores.Reactions.React(r.Context(), models.Reaction{
+			UserID: userID, TargetType: targetType, TargetID: targetID, Type: req.Type,
+		})
+		if err != nil {
+			log.Println("Error saving reaction:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to save reaction")
+			return
+		}
+
+		// changing the reaction doesn't notify the owner again
+		if added && target.OwnerID != userID {
+			notifyOwner(r.Context(), userID, targetType, targetID, target)
+		}
+		answer(w, r, targetType, targetID, target)
+	}
+}
+
+// Unreact returns the DELETE handler of a target type, removing a reaction that isn't there is fine
+func Unreact(targetType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetID, target, ok := reachable(w, r, targetType)
+		if !ok {
+			return
+		}
+
+		if _, err := stores.Reactions.Unreact(r.Context(), middleware.CurrentUserID(r), targetType, targetID); err != nil {
+			log.Println("Error removing reaction:", err)
+			response.Error(w, http.StatusInternalServerError, "Failed to remove reaction")
+			return
+		}
+		answer(w, r, targetType, targetID, target)
+	}
+}
+
+// answer writes the summary of the target for the user and pushes the new counts to the
+// users looking at it
+func answer(w http.ResponseWriter, r *http.Request, targetType string, targetID int, target models.ReactionTarget) {
+	mine, err := summary(r.Context(), targetType, targetID, middleware.CurrentUserID(r))
+	if err != nil {
+		log.Println("Error counting reactions:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to count reactions")
+		return
+	}
+	broadcast(r.Context(), targetType, targetID, target, mine.Reactions)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mine)
+}
+
+func summary(ctx context.Context, targetType string, targetID, viewerID int) (models.ReactionSummary, error) {
+	summaries, err := stores.Reactions.Summaries(ctx, targetType, []int{targetID}, viewerID)
+	if err != nil {
+		return models.ReactionSummary{}, err
+	}
+	s := summaries[targetID]
+	if s.Reactions == nil {
+		s.Reactions = map[string]int{}
+	}
+	return s, nil
+}
+
+// targetNames are used in the error messages
+var targetNames = map[string]string{
+	models.TargetPost:         "Post",
+	models.TargetComment:      "Comment",
+	models.TargetGroupPost:    "Post",
+	models.TargetGroupComment: "Comment",
+}
+
+// reachable reads the target of the request and checks the user may see it: regular posts and
+// their comments follow the post's privacy, group ones need a membership. A target the user
+// can't see is reported as missing, like GetPostHandler does
+func reachable(w http.ResponseWriter, r *http.Request, targetType string) (int, models.ReactionTarget, bool) {
+	// PUT /posts/{id}/reactions, /comments/{id}/reactions,
+	// /groups/{id}/posts/{postID}/reactions and /groups/{id}/comments/{commentID}/reactions
+	var targetID, groupID int
+	switch targetType {
+	case models.TargetGroupPost:
+		targetID, groupID = params.PathID(r, "postID"), params.PathID(r, "id")
+	case models.TargetGroupComment:
+		targetID, groupID = params.PathID(r, "commentID"), params.PathID(r, "id")
+	default:
+		targetID = params.PathID(r, "id")
+	}
+	if targetID <= 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid "+targetNames[targetType]+" ID")
+		return 0, models.ReactionTarget{}, false
+	}
+	notFound := targetNames[targetType] + " not found"
+
+	target, err := stores.Reactions.Target(r.Context(), targetType, targetID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && target.GroupID != groupID) {
+		response.Error(w, http.StatusNotFound, notFound)
+		return 0, models.ReactionTarget{}, false
+	}
+	if err != nil {
+		log.Println("Error loading reaction target:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to check the reaction target")
+		return 0, models.ReactionTarget{}, false
+	}
+
+	userID := middleware.CurrentUserID(r)
+	if target.GroupID != 0 {
+		isMember, _ := stores.Groups.IsMember(r.Context(), target.GroupID, userID)
+		if !isMember {
+			response.Error(w, http.StatusForbidden, "Must be a member to react")
+			return 0, models.ReactionTarget{}, false
+		}
+		return targetID, target, true
+	}
+	visible, err := stores.Posts.CanView(r.Context(), target.PostID, userID)
+	if err != nil {
+		log.Println("Error checking post visibility:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to check the reaction target")
+		return 0, models.ReactionTarget{}, false
+	}
+	if !visible {
+		response.Error(w, http.StatusNotFound, notFound)
+		return 0, models.ReactionTarget{}, false
+	}
+	return targetID, target, true
+}
+
+// notifyOwner sends the "reaction" notification, there is one unread notification per target:
+// the next reaction updates it with its sender and the number of people who reacted.
+// It is best effort, the reaction is saved either way
+func notifyOwner(ctx context.Context, userID int, targetType string, targetID int, target models.ReactionTarget) {
+	user, err := stores.Users.Get(ctx, userID)
+	if err != nil {
+		log.Println("Error loading reacting user:", err)
+		return
+	}
+	name := user.FirstName
+	if user.Username != nil && *user.Username != "" {
+		name = *user.Username
+	}
+
+	// the owner's own reaction doesn't count
+	owners, err := summary(ctx, targetType, targetID, target.OwnerID)
+	if err != nil {
+		log.Println("Error counting reactions:", err)
+		return
+	}
+	count := 0
+	for _, n := range owners.Reactions {
+		count += n
+	}
+	if owners.MyReaction != "" {
+		count--
+	}
+
+	n := models.Notification{
+		UserID:       target.OwnerID,
+		Type:         "reaction",
+		SenderID:     &userID,
+		SenderName:   &name,
+		SenderAvatar: user.Avatar,
+		SenderCount:  count,
+		TargetType:   &targetType,
+		TargetID:     &targetID,
+	}
+	// post_id is what the frontend opens, and what PostStore.Delete cleans up by
+	if target.GroupID == 0 {
+		n.PostID = &target.PostID
+	} else {
+		n.GroupID = &target.GroupID
+	}
+	if err := generalfuncs.CreateNotification(ctx, n); err != nil {
+		log.Println("Error creating reaction notification:", err)
+	}
+}
+
+// broadcast sends the new counts as a "reaction_update" to the online users who can see the
+// target: the group members, or whoever may see the post. It runs after the response
+func broadcast(ctx context.Context, targetType string, targetID int, target models.ReactionTarget, counts map[string]int) {
+	msg := websocket.WebSocketMessage{
+		Type: "reaction_update",
+		Data: map[string]interface{}{
+			"target_type": targetType,
+			"target_id":   targetID,
+			"post_id":     target.PostID,
+			"group_id":    target.GroupID,
+			"reactions":   counts,
+		},
+	}
+
+	telemetry.Go(ctx, "broadcast reaction update", func(ctx context.Context) {
+		if target.GroupID != 0 {
+			memberIDs, err := stores.Groups.MemberIDs(ctx, target.GroupID, 0)
+			if err != nil {
+				log.Println("Error querying group members:", err)
+				return
+			}
+			websocket.SendToUsers(ctx, memberIDs, msg)
+			return
+		}
+
+		generalfuncs.SendToPostViewers(ctx, target.PostID, msg)
+	})
+}
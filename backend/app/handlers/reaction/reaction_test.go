@@ -0,0 +1,122 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"social-network/app/generalfuncs"
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/store/memstore"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	s := memstore.New()
+	SetStores(s)
+	generalfuncs.SetStores(s)
+}
+
+func newUser(t *testing.T, name string) int {
+	t.Helper()
+	id, err := stores.Users.Create(context.Background(), models.RegisterStruct{
+		Email: name + "@test.com", FirstName: name, Username: name, DateOfBirth: "2000-01-01",
+	}, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// react sends PUT /posts/{id}/reactions as the user, an empty reaction is the DELETE
+func react(t *testing.T, postID, userID int, reaction string) (int, models.ReactionSummary) {
+	t.Helper()
+	method, handler, body := http.MethodDelete, Unreact(models.TargetPost), ""
+	if reaction != "" {
+		method, handler, body = http.MethodPut, React(models.TargetPost), `{"type":"`+reaction+`"}`
+	}
+	r := httptest.NewRequest(method, "/posts/"+strconv.Itoa(postID)+"/reactions", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+	r.SetPathValue("id", strconv.Itoa(postID))
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var summary models.ReactionSummary
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&summary); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, summary
+}
+
+func TestReactions(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	author := newUser(t, "author")
+	fan := newUser(t, "fan")
+	other := newUser(t, "other")
+	post := models.Post{UserID: author, Content: "hi", Privacy: models.PrivacyPublic}
+	if err := stores.Posts.Create(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := react(t, post.ID, fan, "meh"); code != http.StatusBadRequest {
+		t.Errorf("unknown reaction: status %d", code)
+	}
+
+	// changing the reaction replaces it
+	react(t, post.ID, fan, "like")
+	code, summary := react(t, post.ID, fan, "love")
+	if code != http.StatusOK || summary.MyReaction != "love" || summary.Reactions["love"] != 1 || summary.Reactions["like"] != 0 {
+		t.Errorf("changed reaction: status %d, %+v", code, summary)
+	}
+	_, summary = react(t, post.ID, other, "love")
+	if summary.Reactions["love"] != 2 {
+		t.Errorf("second user: %+v", summary)
+	}
+
+	// the author got one notification for both, naming the latest reactor
+	notifications, _, _ := stores.Notifications.List(ctx, author, store.Page{})
+	if len(notifications) != 1 {
+		t.Fatalf("author has %d notifications, want 1 aggregated", len(notifications))
+	}
+	if n := notifications[0]; n.Type != "reaction" || n.SenderCount != 2 || *n.SenderID != other || *n.PostID != post.ID {
+		t.Errorf("aggregated notification: %+v", n)
+	}
+
+	_, summary = react(t, post.ID, fan, "")
+	if summary.MyReaction != "" || summary.Reactions["love"] != 1 {
+		t.Errorf("after removing: %+v", summary)
+	}
+
+	posts := []models.Post{post}
+	if err := Posts(ctx, posts, other); err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].MyReaction != "love" || posts[0].Reactions["love"] != 1 {
+		t.Errorf("filled post: %+v", posts[0].ReactionSummary)
+	}
+}
+
+func TestCannotReactToInvisiblePost(t *testing.T) {
+	setup(t)
+	author := newUser(t, "author")
+	stranger := newUser(t, "stranger")
+	post := models.Post{UserID: author, Content: "secret", Privacy: models.PrivacyPrivate}
+	if err := stores.Posts.Create(context.Background(), &post); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := react(t, post.ID, stranger, "like"); code != http.StatusNotFound {
+		t.Errorf("stranger on a private post: status %d, want 404", code)
+	}
+	if code, _ := react(t, post.ID+100, author, "like"); code != http.StatusNotFound {
+		t.Errorf("missing post: status %d, want 404", code)
+	}
+}
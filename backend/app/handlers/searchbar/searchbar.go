@@ -6,9 +6,17 @@ import (
 	"strings"
 
 	"social-network/app/models"
-	"social-network/db"
+	"social-network/app/response"
+	"social-network/app/store"
 )
 
+var stores *store.Stores
+
+// SetStores sets the user store the search bar queries
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
 // =================TO DO==================
 // implement persistent storage (database) for users
 // optimize search algorithm for large datasets
@@ -30,35 +38,27 @@ func SearchBarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query = "%" + query + "%"
-
-	rows, err := db.Database.Query(`
-		SELECT id, username, first_name, last_name , avatar
-		FROM users
-		WHERE username LIKE ? OR first_name LIKE ? OR last_name LIKE ?
-		LIMIT 10
-	`, query, query, query)
-
+	found, err := stores.Users.Search(r.Context(), query, 10)
 	if err != nil {
-		http.Error(w, "search failed", http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "search failed")
 		return
 	}
-	defer rows.Close()
 
 	var users []map[string]any
 
-	for rows.Next() {
-		var id int
-		var username, firstName, lastName, avatar string
-
-		if err := rows.Scan(&id, &username, &firstName, &lastName, &avatar); err != nil {
-			continue
+	for _, u := range found {
+		var username, avatar string
+		if u.Username != nil {
+			username = *u.Username
+		}
+		if u.Avatar != nil {
+			avatar = *u.Avatar
 		}
 
 		users = append(users, map[string]any{
-			"id":       id,
+			"id":       u.ID,
 			"username": username,
-			"name":     firstName + " " + lastName,
+			"name":     u.FirstName + " " + u.LastName,
 			"avatar":   avatar,
 		})
 	}
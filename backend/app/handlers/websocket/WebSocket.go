@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"strconv"
@@ -8,6 +9,11 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+
+	"social-network/app/ratelimit"
+	"social-network/app/response"
+	"social-network/app/telemetry"
 )
 
 type WebSocketMessage struct {
@@ -108,7 +114,12 @@ func (h *WebSocketHub) broadcastPresence(userID string, online bool) {
 }
 
 // broadcast to only one user
-func (h *WebSocketHub) SendToUser(userID string, msg WebSocketMessage) {
+// the span shows how many of the user's sockets got the message and how many were full
+func (h *WebSocketHub) SendToUser(ctx context.Context, userID string, msg WebSocketMessage) {
+	_, span := telemetry.Start(ctx, "ws.SendToUser",
+		attribute.String("ws.user_id", userID), attribute.String("ws.message_type", msg.Type))
+	defer span.End()
+
 	h.mu.RLock()
 	clients := h.userIndex[userID]
 	h.mu.RUnlock()
@@ -118,20 +129,28 @@ func (h *WebSocketHub) SendToUser(userID string, msg WebSocketMessage) {
 		return
 	}
 
+	sent, dropped := 0, 0
 	for client := range clients {
 		select {
 		case client.send <- msg:
+			sent++
 			log.Printf("[WS] Sent %s to user %s", msg.Type, userID)
 		default:
+			dropped++
 			log.Printf("[WS] Channel full for user %s", userID)
 		}
 	}
+	span.SetAttributes(attribute.Int("ws.sent", sent), attribute.Int("ws.dropped", dropped))
 }
 
 // send to multiple users from group members list
-func SendToUsers(userIDs []int, msg WebSocketMessage) {
+func SendToUsers(ctx context.Context, userIDs []int, msg WebSocketMessage) {
+	ctx, span := telemetry.Start(ctx, "ws.SendToUsers",
+		attribute.Int("ws.recipients", len(userIDs)), attribute.String("ws.message_type", msg.Type))
+	defer span.End()
+
 	for _, userID := range userIDs {
-		Hub.SendToUser(strconv.Itoa(userID), msg)
+		Hub.SendToUser(ctx, strconv.Itoa(userID), msg)
 	}
 }
 
@@ -148,8 +167,9 @@ func (h *WebSocketHub) GetOnlineUsers() []string {
 }
 
 // Exported function for other packages to send private messages
-func SendToUser(userID string, msg WebSocketMessage) {
-	Hub.SendToUser(userID, msg)
+// ctx only carries the trace, sending never blocks
+func SendToUser(ctx context.Context, userID string, msg WebSocketMessage) {
+	Hub.SendToUser(ctx, userID, msg)
 }
 
 var upgrader = websocket.Upgrader{
@@ -163,7 +183,7 @@ var upgrader = websocket.Upgrader{
 func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	userIDint, ok := r.Context().Value("ctxUserID").(int)
 	if !ok || userIDint == 0 {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	userID := strconv.Itoa(userIDint)
@@ -222,8 +242,28 @@ func (c *WebSocketClient) readPump() {
 		// Handle ping from client
 		if msg.Type == "ping" {
 			c.send <- WebSocketMessage{Type: "pong"}
+			continue
 		}
+		// the messages themselves are sent over HTTP for now and the socket pushes them out, but
+		// any other frame counts as one so the read loop can't be used to flood the server
+		c.allowMessage()
+	}
+}
+
+// allowMessage takes a token of the messages policy for whatever the client sends besides a ping,
+// from the same bucket as POST /users/{id}/messages: a socket is no way around the limit. Over
+// the limit the frame is dropped and the client gets a rate_limited frame with retry_after
+func (c *WebSocketClient) allowMessage() bool {
+	userID, _ := strconv.Atoi(c.UserID)
+	res := ratelimit.TakeUser(context.Background(), ratelimit.Messages, userID)
+	if res.Allowed {
+		return true
+	}
+	select {
+	case c.send <- WebSocketMessage{Type: "rate_limited", Data: map[string]int{"retry_after": ratelimit.RetryAfterSeconds(res)}}:
+	default: // a client that floods doesn't read either, its channel is full
 	}
+	return false
 }
 
 func (c *WebSocketClient) writePump() {
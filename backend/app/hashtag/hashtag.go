@@ -0,0 +1,65 @@
+// Package hashtag finds the #tags of a post's content and weighs the uses of the trending ones.
+// The stores call Parse when a post or group post is written, the tags end up in post_tags
+package hashtag
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxLength is the longest tag kept, longer ones are dropped rather than cut
+const MaxLength = 50
+
+// Parse returns the tags of the content, lowercased, without the '#', without duplicates and in
+// the order they first appear. A tag is a '#' at the start or after a non word character,
+// followed by letters, digits and '_' with at least one letter ("#1" is not a tag)
+func Parse(content string) []string {
+	tags := []string{}
+	runes := []rune(content)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isWord(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWord(runes[end]) {
+			end++
+		}
+		tag := strings.ToLower(string(runes[i+1 : end]))
+		i = end - 1
+		if tag == "" || len([]rune(tag)) > MaxLength || !strings.ContainsFunc(tag, unicode.IsLetter) {
+			continue
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Normalize turns the tag of a URL ("Go", "#go") into the stored form, "" if it can't be a tag
+func Normalize(tag string) string {
+	tag = strings.TrimPrefix(tag, "#")
+	if tags := Parse("#" + tag); len(tags) == 1 && tags[0] == strings.ToLower(tag) {
+		return tags[0]
+	}
+	return ""
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Weights returns what a use of a tag is worth by its age in whole hours, for the uses of the
+// window: weights[h] is 0.5^(h/halfLife), a use from one half-life ago is worth half a use from
+// now, so a tag that was busy yesterday loses to one that is busy now. The stores rank the tags
+// with it (store.TagStore.Trending), older uses don't count at all
+func Weights(window, halfLife time.Duration) []float64 {
+	weights := make([]float64, int(window/time.Hour))
+	for h := range weights {
+		weights[h] = math.Pow(0.5, float64(h)/halfLife.Hours())
+	}
+	return weights
+}
@@ -0,0 +1,43 @@
+package hashtag
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"#Go is #fun, #go!":          "[go fun]",
+		"mail a#b or #1 or # alone":  "[]",
+		"(#café) #snake_case #2024s": "[café snake_case 2024s]",
+		"##double":                   "[double]",
+	}
+	for content, want := range tests {
+		if got := fmt.Sprint(Parse(content)); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", content, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for tag, want := range map[string]string{"Go": "go", "#Go": "go", "go lang": "", "42": "", "": ""} {
+		if got := Normalize(tag); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestWeights(t *testing.T) {
+	weights := Weights(24*time.Hour, 6*time.Hour)
+	if len(weights) != 24 {
+		t.Fatalf("%d weights, want one per hour of the window", len(weights))
+	}
+	// a use loses half its worth every half-life
+	for h, want := range map[int]float64{0: 1, 6: 0.5, 12: 0.25, 18: 0.125} {
+		if got := weights[h]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("weight of a use %dh old = %v, want %v", h, got, want)
+		}
+	}
+}
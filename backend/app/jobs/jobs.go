@@ -0,0 +1,264 @@
+// Package jobs runs deferred and periodic work in the background.
+//
+// Jobs are rows of the jobs table (store.JobStore), so they survive a restart. Anything can queue
+// one with Enqueue, the Runner started from main.go picks them up once their run_at is reached:
+//
+//	jobs.Enqueue(ctx, stores.Jobs, "events.remind", payload, jobs.RunAt(event.Add(-time.Hour)))
+//
+// A job that returns an error is retried with a growing delay until it runs out of attempts,
+// then it stays in the table as failed (see GET /admin/jobs).
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/telemetry"
+)
+
+// Handler does the work of one job type, the payload is in job.Payload
+type Handler func(ctx context.Context, job models.Job) error
+
+const (
+	DefaultMaxAttempts = 5
+
+	// retries wait 30s, 1m, 2m, 4m... but never more than maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+
+	// a job still marked running after this long belonged to a worker that died
+	staleAfter = 15 * time.Minute
+)
+
+// Option changes a job before it is queued
+type Option func(job *models.Job)
+
+// RunAt delays the job until t
+func RunAt(t time.Time) Option {
+	return func(job *models.Job) { job.RunAt = t.Unix() }
+}
+
+// UniqueKey keeps a second job with the same key out of the queue while the first one is pending or running
+func UniqueKey(key string) Option {
+	return func(job *models.Job) { job.UniqueKey = &key }
+}
+
+// MaxAttempts is how many times the job runs before it is marked failed
+func MaxAttempts(n int) Option {
+	return func(job *models.Job) { job.MaxAttempts = n }
+}
+
+// Enqueue queues a job of the given type, payload is stored as JSON (nil for none).
+// It returns store.ErrConflict when the unique key is already queued
+func Enqueue(ctx context.Context, jobs store.JobStore, jobType string, payload any, opts ...Option) (models.Job, error) {
+	now := time.Now().Unix()
+	job := models.Job{Type: jobType, MaxAttempts: DefaultMaxAttempts, RunAt: now, CreatedAt: now}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return job, fmt.Errorf("jobs: encode %s payload: %w", jobType, err)
+		}
+		job.Payload = raw
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+	err := jobs.Enqueue(ctx, &job)
+	return job, err
+}
+
+// backoff is the delay before the next try of a job that failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// Runner is the worker pool
+type Runner struct {
+	store    store.JobStore
+	handlers map[string]Handler
+	periodic map[string]time.Duration
+
+	// how long an idle worker sleeps before looking for due jobs again
+	PollInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewRunner(jobs store.JobStore) *Runner {
+	return &Runner{
+		store:        jobs,
+		handlers:     map[string]Handler{},
+		periodic:     map[string]time.Duration{},
+		PollInterval: time.Second,
+	}
+}
+
+// Handle registers the handler of a job type, call it before Start
+func (r *Runner) Handle(jobType string, h Handler) {
+	r.handlers[jobType] = h
+}
+
+// Every registers a job that runs once per interval. The next run is queued when the
+// previous one is finished, so a slow run never overlaps with the next one
+func (r *Runner) Every(jobType string, interval time.Duration, h Handler) {
+	r.handlers[jobType] = h
+	r.periodic[jobType] = interval
+}
+
+// Start requeues the jobs a previous process left running, queues the periodic jobs
+// that aren't queued yet and starts the workers
+func (r *Runner) Start(workers int) {
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.quit = make(chan struct{})
+
+	if n, err := r.store.RequeueStale(r.ctx, time.Now().Add(-staleAfter).Unix()); err != nil {
+		log.Printf("[JOBS] requeue stale jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("[JOBS] requeued %d stale jobs", n)
+	}
+	for jobType := range r.periodic {
+		r.schedule(r.ctx, jobType, time.Now())
+	}
+
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+	log.Printf("[JOBS] %d workers started", workers)
+}
+
+// Stop lets the running jobs finish and waits for the workers. When ctx ends first the
+// jobs' context is cancelled, they are picked up again on the next start
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.quit == nil {
+		return nil
+	}
+	close(r.quit)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.quit:
+			return
+		default:
+		}
+
+		job, err := r.store.Claim(r.ctx, time.Now().Unix())
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				log.Printf("[JOBS] claim: %v", err)
+			}
+			select {
+			case <-r.quit:
+				return
+			case <-time.After(r.PollInterval):
+			}
+			continue
+		}
+		r.run(job)
+	}
+}
+
+// run executes one claimed job and records the outcome
+func (r *Runner) run(job models.Job) {
+	// every job is its own trace, the queries it makes show up under it
+	ctx, span := telemetry.Start(r.ctx, "job "+job.Type,
+		attribute.Int("job.id", job.ID), attribute.Int("job.attempt", job.Attempts))
+	err := r.call(ctx, job)
+	telemetry.End(span, err)
+	now := time.Now()
+	// the outcome is saved even when Stop cancelled the job's context
+	ctx = context.WithoutCancel(ctx)
+
+	switch {
+	case err == nil:
+		if err := r.store.Complete(ctx, job.ID, now.Unix()); err != nil {
+			log.Printf("[JOBS] complete %s #%d: %v", job.Type, job.ID, err)
+		}
+	case job.Attempts < job.MaxAttempts:
+		retryAt := now.Add(backoff(job.Attempts))
+		log.Printf("[JOBS] %s #%d failed (attempt %d/%d), retrying at %s: %v",
+			job.Type, job.ID, job.Attempts, job.MaxAttempts, retryAt.Format(time.TimeOnly), err)
+		if err := r.store.Retry(ctx, job.ID, now.Unix(), retryAt.Unix(), err.Error()); err != nil {
+			log.Printf("[JOBS] retry %s #%d: %v", job.Type, job.ID, err)
+		}
+		return
+	default:
+		log.Printf("[JOBS] %s #%d failed for good after %d attempts: %v", job.Type, job.ID, job.Attempts, err)
+		if err := r.store.Fail(ctx, job.ID, now.Unix(), err.Error()); err != nil {
+			log.Printf("[JOBS] fail %s #%d: %v", job.Type, job.ID, err)
+		}
+	}
+
+	if interval, ok := r.periodic[job.Type]; ok {
+		r.schedule(ctx, job.Type, now.Add(interval))
+	}
+}
+
+// call runs the handler, a panic counts as a failed attempt instead of killing the worker
+func (r *Runner) call(ctx context.Context, job models.Job) (err error) {
+	h, ok := r.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %q", job.Type)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// schedule queues the next run of a periodic job, the unique key makes it a no-op when one is queued already
+func (r *Runner) schedule(ctx context.Context, jobType string, at time.Time) {
+	_, err := Enqueue(ctx, r.store, jobType, nil, RunAt(at), UniqueKey("every:"+jobType))
+	if err != nil && !errors.Is(err, store.ErrConflict) {
+		log.Printf("[JOBS] schedule %s: %v", jobType, err)
+	}
+}
+
+/* notes:
+
+- job types are plain strings, "<area>.<what>" (sessions.cleanup, jobs.cleanup), the handlers are
+	registered in main.go through RegisterMaintenance and friends
+
+- a handler must be safe to run twice: a process killed in the middle of a job leaves it running,
+	the next start puts it back to pending and it runs again
+
+- payloads are JSON, handlers decode job.Payload into their own struct
+
+*/
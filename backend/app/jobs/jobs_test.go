@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/store/memstore"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 20: time.Hour}
+	for attempts, want := range cases {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestRunnerRetriesThenFails(t *testing.T) {
+	stores := memstore.New()
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	r := NewRunner(stores.Jobs)
+	r.PollInterval = 5 * time.Millisecond
+	r.Handle("flaky", func(ctx context.Context, job models.Job) error {
+		calls.Add(1)
+		return errors.New("still broken")
+	})
+	r.Handle("ok", func(ctx context.Context, job models.Job) error { return nil })
+
+	if _, err := Enqueue(ctx, stores.Jobs, "ok", map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	// one attempt only, so the failure is final without waiting for the backoff
+	flaky, err := Enqueue(ctx, stores.Jobs, "flaky", nil, MaxAttempts(1), UniqueKey("flaky"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Enqueue(ctx, stores.Jobs, "flaky", nil, UniqueKey("flaky")); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("duplicate unique key: %v", err)
+	}
+
+	r.Start(2)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		counts, _ := stores.Jobs.Counts(ctx)
+		if counts[models.JobDone] == 1 && counts[models.JobFailed] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs not finished: %v", counts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, _ := stores.Jobs.List(ctx, models.JobFailed, 10)
+	if len(failed) != 1 || failed[0].ID != flaky.ID || *failed[0].LastError != "still broken" || calls.Load() != 1 {
+		t.Errorf("failed jobs = %+v, calls = %d", failed, calls.Load())
+	}
+}
+
+func TestRunnerSchedulesPeriodicJobs(t *testing.T) {
+	stores := memstore.New()
+	ctx := context.Background()
+
+	ran := make(chan struct{}, 1)
+	r := NewRunner(stores.Jobs)
+	r.PollInterval = 5 * time.Millisecond
+	r.Every("tick", time.Hour, func(ctx context.Context, job models.Job) error {
+		ran <- struct{}{}
+		return nil
+	})
+	r.Start(1)
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("periodic job never ran")
+	}
+	if err := r.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next run is queued an hour later
+	pending, _ := stores.Jobs.List(ctx, models.JobPending, 10)
+	if len(pending) != 1 || pending[0].Type != "tick" || pending[0].RunAt < time.Now().Add(59*time.Minute).Unix() {
+		t.Errorf("next run = %+v", pending)
+	}
+}
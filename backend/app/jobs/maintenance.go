@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+// how long finished jobs stay visible in the admin view
+const keepDoneJobs = 7 * 24 * time.Hour
+
+// RegisterMaintenance adds the housekeeping jobs every deployment needs
+func RegisterMaintenance(r *Runner, stores *store.Stores) {
+	// sessions are only checked by id, an expired one would still work without this
+	r.Every("sessions.cleanup", time.Hour, func(ctx context.Context, job models.Job) error {
+		n, err := stores.Users.DeleteExpiredSessions(ctx, time.Now())
+		if err == nil && n > 0 {
+			log.Printf("[JOBS] deleted %d expired sessions", n)
+		}
+		return err
+	})
+
+	// the keys are only checked for expiry when they are sent again, most never are
+	r.Every("idempotency.cleanup", time.Hour, func(ctx context.Context, job models.Job) error {
+		_, err := stores.Idempotency.DeleteExpired(ctx, time.Now().Unix())
+		return err
+	})
+
+	r.Every("jobs.cleanup", 24*time.Hour, func(ctx context.Context, job models.Job) error {
+		_, err := stores.Jobs.DeleteFinished(ctx, time.Now().Add(-keepDoneJobs).Unix())
+		return err
+	})
+}
@@ -0,0 +1,259 @@
+// Package linkpreview unfurls links: it finds the first URL of a text and reads the OpenGraph and
+// Twitter card tags of the page behind it. Fetching a URL a user typed is fetching it from inside
+// our network, so the default client refuses private addresses (see NewClient) and every fetch is
+// capped in time, size and redirects
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"social-network/app/models"
+)
+
+// the limits of a fetch
+const (
+	Timeout      = 5 * time.Second
+	MaxBytes     = 512 << 10 // the <head> is at the start of the page, the rest is never read
+	MaxRedirects = 3
+	MaxURLLength = 2048
+)
+
+const (
+	maxTitle       = 300
+	maxDescription = 1000
+)
+
+var (
+	// ErrBlocked is returned for a URL the fetcher must not reach: not http(s), or an address
+	// that isn't public
+	ErrBlocked = errors.New("linkpreview: address not allowed")
+	// ErrNotHTML is returned when the page is something else than HTML, an image or a download
+	ErrNotHTML = errors.New("linkpreview: not an HTML page")
+
+	errTooManyRedirects = errors.New("linkpreview: too many redirects")
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// FirstURL returns the first http(s) URL of the text, "" if there is none. The punctuation that
+// ends a sentence right after a link isn't part of it
+func FirstURL(text string) string {
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}'")
+		if len(match) > MaxURLLength {
+			continue
+		}
+		if u, err := url.Parse(match); err == nil && allowed(u) {
+			return match
+		}
+	}
+	return ""
+}
+
+// Fetcher reads the previews through Client, tests hand it the client of an httptest server
+type Fetcher struct {
+	Client *http.Client
+}
+
+// NewFetcher returns a Fetcher with the client of NewClient
+func NewFetcher() *Fetcher {
+	return &Fetcher{Client: NewClient()}
+}
+
+// NewClient returns a client that only connects to public addresses. The check runs on the
+// address being dialed, after DNS, so a name resolving to 127.0.0.1 or a redirect to an
+// internal host is refused the same way as a literal private IP
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: Timeout, Control: dialControl}
+	return &http.Client{
+		Timeout: Timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // a proxy would do the dialing, around the check
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   Timeout,
+			ResponseHeaderTimeout: Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+	}
+}
+
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlocked
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !Public(ip) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// the ranges that are neither private nor loopback for netip but aren't the internet either
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, it embeds any IPv4 address
+}
+
+// Public reports whether ip is a public unicast address, one the fetcher may connect to
+func Public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func allowed(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() != "" && u.User == nil
+}
+
+// Fetch reads the preview of the page at rawURL. At most MaxRedirects redirects are followed,
+// each to an http(s) URL, and only MaxBytes of an HTML answer are read. The preview's URL is
+// rawURL, whatever page it ended up on
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (models.LinkPreview, error) {
+	preview := models.LinkPreview{URL: rawURL}
+	u, err := url.Parse(rawURL)
+	if err != nil || !allowed(u) {
+		return preview, ErrBlocked
+	}
+
+	client := *f.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > MaxRedirects {
+			return errTooManyRedirects
+		}
+		if !allowed(req.URL) {
+			return ErrBlocked
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return preview, err
+	}
+	req.Header.Set("User-Agent", "social-network-linkpreview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := client.Do(req)
+	if err != nil {
+		return preview, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return preview, fmt.Errorf("linkpreview: %s answered %d", u.Host, resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return preview, ErrNotHTML
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes))
+	if err != nil {
+		return preview, err
+	}
+	preview = Parse(string(body), resp.Request.URL)
+	preview.URL = rawURL
+	return preview, nil
+}
+
+var (
+	metaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern  = regexp.MustCompile(`(?s)([a-zA-Z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// Parse reads the preview out of the page, page is where it was served from: relative image
+// URLs are resolved against it and its host is the site name when the page doesn't give one.
+// OpenGraph tags win over Twitter cards, which win over <title> and the description
+func Parse(page string, base *url.URL) models.LinkPreview {
+	if end := strings.Index(strings.ToLower(page), "</head>"); end >= 0 {
+		page = page[:end]
+	}
+	tags := map[string]string{}
+	for _, meta := range metaPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, a := range attrPattern.FindAllStringSubmatch(meta, -1) {
+			attrs[strings.ToLower(a[1])] = a[2] + a[3] + a[4]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := tags[key]; key != "" && !seen { // the first og:image is the main one
+			tags[key] = clean(attrs["content"])
+		}
+	}
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if tags[k] != "" {
+				return tags[k]
+			}
+		}
+		return ""
+	}
+
+	var preview models.LinkPreview
+	preview.Title = first("og:title", "twitter:title")
+	if preview.Title == "" {
+		if m := titlePattern.FindStringSubmatch(page); m != nil {
+			preview.Title = clean(m[1])
+		}
+	}
+	preview.Title = truncate(preview.Title, maxTitle)
+	preview.Description = truncate(first("og:description", "twitter:description", "description"), maxDescription)
+	preview.SiteName = truncate(first("og:site_name"), maxTitle)
+	if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		u, err := url.Parse(image)
+		if err == nil && base != nil {
+			u = base.ResolveReference(u)
+		}
+		if err == nil && allowed(u) && len(u.String()) <= MaxURLLength {
+			preview.Image = u.String()
+		}
+	}
+	if preview.SiteName == "" && base != nil {
+		preview.SiteName = base.Hostname()
+	}
+	return preview
+}
+
+// clean unescapes the entities and folds the whitespace of a tag's text
+func clean(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
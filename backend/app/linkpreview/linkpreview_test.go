@@ -0,0 +1,97 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestFirstURL(t *testing.T) {
+	tests := map[string]string{
+		"read https://go.dev/blog/x. and http://b.example": "https://go.dev/blog/x",
+		"(see http://example.com/a?b=c)":                   "http://example.com/a?b=c",
+		"ftp://files.example and www.example.com":          "",
+		`<a href="https://x.example/">`:                    "https://x.example/",
+		"no link here":                                     "",
+	}
+	for text, want := range tests {
+		if got := FirstURL(text); got != want {
+			t.Errorf("FirstURL(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34": true, "2606:4700::1111": true,
+		"127.0.0.1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false,
+		"169.254.169.254": false, "100.64.0.1": false, "0.0.0.0": false, "::1": false,
+		"fe80::1": false, "fd00::1": false, "::ffff:127.0.0.1": false, "64:ff9b::a00:1": false,
+	} {
+		if got := Public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+const page = `<!doctype html><html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="The &amp; title">
+<meta name="twitter:title" content="Twitter title">
+<meta name='description' content='  A   description '>
+<meta content="/img/card.png" property="og:image">
+</head><body><meta property="og:title" content="not in the head"></body></html>`
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat(" ", MaxBytes) + `<title>too far</title>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	ctx := context.Background()
+	f := &Fetcher{Client: server.Client()}
+
+	p, err := f.Fetch(ctx, server.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "The & title" || p.Description != "A description" || p.Image != server.URL+"/img/card.png" ||
+		p.URL != server.URL+"/page" || p.SiteName != "127.0.0.1" {
+		t.Errorf("preview = %+v", p)
+	}
+
+	if _, err := f.Fetch(ctx, server.URL+"/image"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("image: err = %v, want ErrNotHTML", err)
+	}
+	if _, err := f.Fetch(ctx, server.URL+"/loop"); !errors.Is(err, errTooManyRedirects) {
+		t.Errorf("redirect loop: err = %v, want too many redirects", err)
+	}
+	if p, err := f.Fetch(ctx, server.URL+"/huge"); err != nil || p.Title != "" {
+		t.Errorf("past MaxBytes: %+v, %v, want an empty preview", p, err)
+	}
+
+	// the default client refuses the test server, it's on the loopback
+	if _, err := NewFetcher().Fetch(ctx, server.URL+"/page"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("default client on 127.0.0.1: err = %v, want ErrBlocked", err)
+	}
+	if _, err := f.Fetch(ctx, "file:///etc/passwd"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("file URL: err = %v, want ErrBlocked", err)
+	}
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"social-network/app/models"
+)
+
+// InvalidError is why Content refuses a text, the handlers answer it as a validation error
+type InvalidError struct {
+	Message string // for the user, "Content is too long (max 500 characters)"
+	Field   string // the request field at fault, content or format
+	Reason  string // what is wrong with the field, "max 500 characters"
+}
+
+func (e *InvalidError) Error() string {
+	return e.Message
+}
+
+// Content checks the content of a post, comment or message in its format, its errors are
+// *InvalidError. max limits the visible characters, 0 for no limit: the markup of a Markdown
+// text doesn't count. It returns the HTML of Markdown content, nil for plain text, and leaves
+// *format at models.FormatMarkdown or "" for plain text
+func Content(format *string, content string, max int) (html *string, err error) {
+	switch *format {
+	case "", models.FormatPlain:
+		*format = ""
+		if max > 0 && utf8.RuneCountInString(content) > max {
+			return nil, tooLong(max)
+		}
+		return nil, nil
+	case models.FormatMarkdown:
+	default:
+		return nil, &InvalidError{Message: "Invalid format", Field: "format", Reason: "must be plain or markdown"}
+	}
+
+	if len(content) > MaxSource {
+		return nil, &InvalidError{Message: "Content is too long", Field: "content",
+			Reason: fmt.Sprintf("max %d bytes of Markdown", MaxSource)}
+	}
+	rendered := Render(content)
+	if max > 0 && rendered.Length() > max {
+		return nil, tooLong(max)
+	}
+	return &rendered.HTML, nil
+}
+
+func tooLong(max int) *InvalidError {
+	return &InvalidError{Message: fmt.Sprintf("Content is too long (max %d characters)", max), Field: "content",
+		Reason: fmt.Sprintf("max %d characters", max)}
+}
@@ -0,0 +1,235 @@
+// Package markdown renders the small Markdown dialect posts, comments and messages can opt into:
+// **bold**, *italic* or _italic_, `code` and ``` code blocks, [links](https://example.com) and
+// lists of "- " or "1. " lines. Anything else stays text. The HTML is built from escaped text and
+// the handful of tags above, never copied from the source, so there is no markup of the user's
+// to sanitize: <script> comes out as &lt;script&gt;
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSource caps the source of a Markdown text, the visible length is checked by the callers
+const MaxSource = 4000
+
+// Rendered is a source turned into HTML, with the text the reader sees
+type Rendered struct {
+	HTML string
+	Text string // blocks, list items and line breaks are one "\n" each
+}
+
+// Length is the number of characters the reader sees, what the length limits count
+func (r Rendered) Length() int {
+	return utf8.RuneCountInString(r.Text)
+}
+
+type writer struct {
+	html, text strings.Builder
+	blocks     int
+}
+
+func (w *writer) literal(s string) {
+	w.html.WriteString(html.EscapeString(s))
+	w.text.WriteString(s)
+}
+
+func (w *writer) tag(s string) {
+	w.html.WriteString(s)
+}
+
+// block starts a paragraph, list or code block on a new line of the text
+func (w *writer) block(tag string) {
+	if w.blocks > 0 {
+		w.text.WriteByte('\n')
+	}
+	w.blocks++
+	w.html.WriteString(tag)
+}
+
+// Render turns the source into HTML
+func Render(src string) Rendered {
+	var w writer
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		w.block("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				w.tag("<br>")
+				w.text.WriteByte('\n')
+			}
+			inline(&w, line, false)
+		}
+		w.tag("</p>")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch _, kind, start := listItem(line); {
+		case strings.HasPrefix(line, "```"):
+			flush()
+			code := []string{}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			w.block("<pre><code>")
+			w.literal(strings.Join(code, "\n"))
+			w.tag("</code></pre>")
+		case line == "":
+			flush()
+		case kind != "":
+			flush()
+			if kind == "ol" && start != 1 {
+				w.block(`<ol start="` + strconv.Itoa(start) + `">`)
+			} else {
+				w.block("<" + kind + ">")
+			}
+			for first := true; i < len(lines); i, first = i+1, false {
+				item, k, _ := listItem(strings.TrimSpace(lines[i]))
+				if k != kind {
+					break
+				}
+				if !first {
+					w.text.WriteByte('\n')
+				}
+				w.tag("<li>")
+				inline(&w, item, false)
+				w.tag("</li>")
+			}
+			i--
+			w.tag("</" + kind + ">")
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	return Rendered{HTML: w.html.String(), Text: w.text.String()}
+}
+
+// listItem splits a "- item" or "3. item" line, kind is ul or ol and "" for other lines. start is
+// the number of an ordered item
+func listItem(line string) (item, kind string, start int) {
+	if len(line) > 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return strings.TrimSpace(line[2:]), "ul", 0
+	}
+	digits := 0
+	for digits < len(line) && digits < 9 && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && len(line) > digits+2 && (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		start, _ = strconv.Atoi(line[:digits])
+		return strings.TrimSpace(line[digits+2:]), "ol", start
+	}
+	return "", "", 0
+}
+
+const escapable = "\\`*_[]()#+-.!>"
+
+// inline renders the spans of a line, links can't be nested
+func inline(w *writer, s string, inLink bool) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			w.literal(s[i+1 : i+2])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				w.tag("<code>")
+				w.literal(s[i+1 : i+1+end])
+				w.tag("</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"):
+			if end := closing(s, i+2, "**"); end >= 0 {
+				w.tag("<strong>")
+				inline(w, s[i+2:end], inLink)
+				w.tag("</strong>")
+				i = end + 2
+				continue
+			}
+		case c == '*' || (c == '_' && (i == 0 || !isWord(s[i-1]))):
+			if end := closing(s, i+1, s[i:i+1]); end >= 0 {
+				w.tag("<em>")
+				inline(w, s[i+1:end], inLink)
+				w.tag("</em>")
+				i = end + 1
+				continue
+			}
+		case c == '[' && !inLink:
+			if text, href, n := link(s[i:]); n > 0 {
+				w.tag(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+				inline(w, text, true)
+				w.tag("</a>")
+				i += n
+				continue
+			}
+		}
+		// the rest of a plain run in one go, up to the next character that may start a span
+		end := i + 1
+		for end < len(s) && strings.IndexByte("\\`*_[", s[end]) < 0 {
+			end++
+		}
+		w.literal(s[i:end])
+		i = end
+	}
+}
+
+// closing finds the marker that ends a span opened right before from, -1 when there is none.
+// The span can't be empty or start or end with a space, code spans inside are skipped over
+func closing(s string, from int, marker string) int {
+	if from >= len(s) || s[from] == ' ' {
+		return -1
+	}
+	for j := from + 1; j+len(marker) <= len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == '`':
+			if end := strings.IndexByte(s[j+1:], '`'); end >= 0 {
+				j += end + 1
+			}
+		case !strings.HasPrefix(s[j:], marker) || s[j-1] == ' ':
+		case marker == "*" && j+1 < len(s) && s[j+1] == '*': // the ** of a bold span inside
+			j++
+		case marker == "_" && j+1 < len(s) && isWord(s[j+1]): // snake_case
+		default:
+			return j
+		}
+	}
+	return -1
+}
+
+// link reads a [text](url) at the start of s, n is its length and 0 when it isn't a link. Only
+// http(s) and mailto URLs make links, javascript: and friends stay text
+func link(s string) (text, href string, n int) {
+	closeText := strings.IndexByte(s, ']')
+	if closeText <= 1 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0
+	}
+	closeURL := strings.IndexByte(s[closeText:], ')')
+	if closeURL < 0 {
+		return "", "", 0
+	}
+	href = s[closeText+2 : closeText+closeURL]
+	u, err := url.Parse(href)
+	if err != nil || strings.ContainsAny(href, " \t") || !(u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "mailto") ||
+		(u.Scheme != "mailto" && u.Host == "") {
+		return "", "", 0
+	}
+	return s[1:closeText], href, closeText + closeURL + 1
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-network/app/models"
+)
+
+func TestRender(t *testing.T) {
+	tests := map[string]string{
+		"**bold** and *it* and _it_":          "<p><strong>bold</strong> and <em>it</em> and <em>it</em></p>",
+		"snake_case_name * 2":                 "<p>snake_case_name * 2</p>",
+		"**a *b* c**":                         "<p><strong>a <em>b</em> c</strong></p>",
+		"`a*b*` \\*no\\*":                     "<p><code>a*b*</code> *no*</p>",
+		"one\ntwo\n\nthree":                   "<p>one<br>two</p><p>three</p>",
+		"- a\n- **b**\n\n3. c\n4. d":          "<ul><li>a</li><li><strong>b</strong></li></ul><ol start=\"3\"><li>c</li><li>d</li></ol>",
+		"```go\nx := <y>\n```":                "<pre><code>x := &lt;y&gt;</code></pre>",
+		"[go](https://go.dev/?a=1&b=2)":       `<p><a href="https://go.dev/?a=1&amp;b=2" rel="nofollow noopener noreferrer">go</a></p>`,
+		"[**x**](http://a.example)":           `<p><a href="http://a.example" rel="nofollow noopener noreferrer"><strong>x</strong></a></p>`,
+		"[x](javascript:alert(1))":            "<p>[x](javascript:alert(1))</p>",
+		`<script>alert("x")</script>`:         "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>",
+		`[<b>](https://x.example/"onclick=")`: `<p><a href="https://x.example/&#34;onclick=&#34;" rel="nofollow noopener noreferrer">&lt;b&gt;</a></p>`,
+		"** not bold** *":                     "<p>** not bold** *</p>",
+	}
+	for src, want := range tests {
+		if got := Render(src).HTML; got != want {
+			t.Errorf("Render(%q)\n got %s\nwant %s", src, got, want)
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	r := Render("**héllo** [site](https://example.com/a/very/long/path)\n- a\n- b")
+	if r.Text != "héllo site\na\nb" || r.Length() != 14 {
+		t.Errorf("Text = %q, Length = %d", r.Text, r.Length())
+	}
+}
+
+func TestContent(t *testing.T) {
+	check := func(format, content string, max int) (*string, string, string) {
+		html, err := Content(&format, content, max)
+		var invalid *InvalidError
+		if errors.As(err, &invalid) {
+			return html, format, invalid.Field
+		}
+		if err != nil {
+			t.Fatalf("error %v is not an *InvalidError", err)
+		}
+		return html, format, ""
+	}
+
+	// the link markup doesn't count, the same text as plain does
+	link := "[" + strings.Repeat("x", 10) + "](https://example.com/" + strings.Repeat("y", 50) + ")"
+	if html, format, field := check(models.FormatMarkdown, link, 10); html == nil || format != models.FormatMarkdown || field != "" {
+		t.Errorf("markdown within the limit: %v, %q, invalid %q", html, format, field)
+	}
+	if _, _, field := check(models.FormatPlain, link, 10); field != "content" {
+		t.Errorf("plain over the limit: invalid %q, want content", field)
+	}
+	if html, format, field := check("", "é", 1); html != nil || format != "" || field != "" {
+		t.Errorf("plain: %v, %q, invalid %q, want no HTML", html, format, field)
+	}
+	if _, _, field := check("html", "x", 0); field != "format" {
+		t.Errorf("unknown format: invalid %q, want format", field)
+	}
+	if _, _, field := check(models.FormatMarkdown, strings.Repeat("*", MaxSource+1), 0); field != "content" {
+		t.Errorf("markdown over MaxSource: invalid %q, want content", field)
+	}
+}
+
+func TestValid(t *testing.T) {
+	w := httptest.NewRecorder()
+	format := "html"
+	if _, ok := Valid(w, &format, "x", 0); ok || w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"format"`) {
+		t.Errorf("unknown format: ok %v, status %d: %s", ok, w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	format = models.FormatMarkdown
+	if html, ok := Valid(w, &format, "**hi**", 10); !ok || html == nil || w.Body.Len() != 0 {
+		t.Errorf("valid markdown: %v, %v, wrote %q", html, ok, w.Body)
+	}
+}
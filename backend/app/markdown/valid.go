@@ -0,0 +1,26 @@
+package markdown
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"social-network/app/response"
+)
+
+// Valid is Content for the handlers: it answers the validation error, or a 500 for anything
+// else, and reports whether the request can go on
+func Valid(w http.ResponseWriter, format *string, content string, max int) (*string, bool) {
+	html, err := Content(format, content, max)
+	var invalid *InvalidError
+	if errors.As(err, &invalid) {
+		response.Invalid(w, invalid.Message, response.FieldError{Field: invalid.Field, Message: invalid.Reason})
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Error checking content:", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to check the content")
+		return nil, false
+	}
+	return html, true
+}
@@ -0,0 +1,56 @@
+// Package mention finds the @usernames of posts, comments and group messages. The stores keep
+// the ones that match a user (see models.Mention), app/handlers/mention notifies them
+package mention
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"social-network/app/models"
+)
+
+// Parse returns the @usernames of the content in order, with their UTF-16 offset and length
+// and without a user id: the stores fill it in and drop the names nobody has.
+// A mention is a '@' at the start or after a character that can't be in a username (so
+// "me@mail.com" isn't one), followed by letters, digits, '_', '.' and '-'. A trailing '.' or
+// '-' ends the sentence, not the name: "thanks @bob." mentions bob
+func Parse(content string) []models.Mention {
+	mentions := []models.Mention{}
+	runes := []rune(content)
+	offset := 0 // in UTF-16 code units, of runes[i]
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isNameChar(runes[i-1])) {
+			offset += utf16Len(runes[i])
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isNameChar(runes[end]) {
+			end++
+		}
+		name := strings.TrimRight(string(runes[i+1:end]), ".-")
+		length := 1
+		for _, r := range name {
+			length += utf16Len(r)
+		}
+		if name != "" {
+			mentions = append(mentions, models.Mention{Username: name, Offset: offset, Length: length})
+		}
+		for _, r := range runes[i:end] {
+			offset += utf16Len(r)
+		}
+		i = end - 1
+	}
+	return mentions
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func utf16Len(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1 // invalid runes are written as U+FFFD
+}
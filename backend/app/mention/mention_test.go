@@ -0,0 +1,20 @@
+package mention
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"hi @bob and @Alice_2":       "[{0 bob 3 4} {0 Alice_2 12 8}]",
+		"thanks @bob. mail me@x.com": "[{0 bob 7 4}]",
+		"@ alone, @@twice":           "[{0 twice 10 6}]",
+		"👋 @zoé-":                    "[{0 zoé 3 4}]", // the emoji is two UTF-16 units
+	}
+	for content, want := range tests {
+		if got := fmt.Sprint(Parse(content)); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", content, got, want)
+		}
+	}
+}
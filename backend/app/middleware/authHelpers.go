@@ -5,40 +5,35 @@ import (
 	"net/http"
 	"time"
 
-	"social-network/db"
+	"social-network/app/response"
+	"social-network/app/store"
 
 	"github.com/google/uuid"
 )
 
-// create a session
-func CreateSession(userID int, w http.ResponseWriter) {
-	// deleting any existing session:
-	_, err := db.Database.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
-	if err != nil {
-		http.Error(w, "Failed to remove old session", http.StatusInternalServerError)
-		return
-	}
+var stores *store.Stores
+
+// SetStores hands the data layer to the middleware, called once from server.SetupRoutes
+func SetStores(s *store.Stores) {
+	stores = s
+}
 
-	// generating a new Session ID and storing it in DB:
+// create a session
+func CreateSession(userID int, w http.ResponseWriter, r *http.Request) {
+	// generating a new Session ID and storing it in DB (any existing session of the user is replaced):
 	sessionID := uuid.New().String()
 	expirationTime := time.Now().Add(24 * time.Hour) // 1-day session
 
-	_, err = db.Database.Exec("INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)",
-		sessionID, userID, expirationTime)
+	err := stores.Users.CreateSession(r.Context(), userID, sessionID, expirationTime)
 	if err != nil {
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		log.Printf("CreateSession error: %v", err)
+		response.Error(w, http.StatusInternalServerError, "Failed to create session")
 		return
 	}
 
 	// creating a cookie
-	cookie := http.Cookie{
-		Name:     "session_token",
-		Value:    sessionID,
-		Expires:  expirationTime,
-		HttpOnly: true,                    // we use this to prevent JavaScript access
-		SameSite: http.SameSiteStrictMode, // to prevent CSRF attacks
-		Path:     "/",                     // this way the cookie is available across the entire site
-	}
+	cookie := sessionCookie(r, sessionID)
+	cookie.Expires = expirationTime
 
 	// setting the cookie in the user's browser
 	http.SetCookie(w, &cookie)
@@ -55,11 +50,12 @@ func GetCookieValue(r *http.Request) string {
 }
 
 // check if user exists in DB using his cookie.value (session ID)
-// THIS FUNCTION IS NOT NECESSARY WHEN YOU HAVE AUTH.MIDDLEWARE
-// too late to change it now tho
-func GetUserId(cookieValue string) int { // cookieValue = session ID
-	var userId int
-	err := db.Database.QueryRow("SELECT user_id FROM sessions WHERE id = ?", cookieValue).Scan(&userId)
+// handlers behind RequireAuth should use CurrentUserID instead
+func GetUserId(r *http.Request, cookieValue string) int { // cookieValue = session ID
+	if cookieValue == "" {
+		return -1
+	}
+	userId, err := stores.Users.SessionUser(r.Context(), cookieValue)
 	if err != nil {
 		log.Printf("GetUserId error: %v", err)
 		return -1 // returning -1 is clearer for "not found" result
@@ -67,12 +63,17 @@ func GetUserId(cookieValue string) int { // cookieValue = session ID
 	return userId
 }
 
-func GetUsername(userID int) string {
-	var username string
-	err := db.Database.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+// CurrentUserID returns the user id RequireAuth stored in the request context, 0 if there is none
+func CurrentUserID(r *http.Request) int {
+	userID, _ := r.Context().Value("ctxUserID").(int)
+	return userID
+}
+
+func GetUsername(r *http.Request, userID int) string {
+	user, err := stores.Users.Get(r.Context(), userID)
 	if err != nil {
 		log.Printf("GetUsername error: %v", err)
 		return "" // returning empty string if not found
 	}
-	return username
+	return valueOrEmpty(user.Username)
 }
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// responses smaller than this are sent as is, compressing them saves nothing
+const minCompressSize = 1024
+
+// Compress compresses JSON responses with brotli or gzip, whichever the client prefers in
+// Accept-Encoding (brotli on a tie). Everything else (images, websocket upgrades, 304s...) passes through
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks "br", "gzip" or "" from an Accept-Encoding header
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			name = "br"
+		}
+		if (name != "br" && name != "gzip") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds the first bytes of the body back until it knows if the response is worth
+// compressing: JSON and at least minCompressSize bytes
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         io.WriteCloser // nil when the response goes out uncompressed
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+	// no body coming, or somebody else encoded it already
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		cw.Header().Get("Content-Encoding") != "" || !isJSONType(cw.Header().Get("Content-Type")) {
+		cw.passThrough()
+		return
+	}
+	// even a small JSON answer depends on Accept-Encoding, the next one from the same URL may be compressed
+	cw.Header().Add("Vary", "Accept-Encoding")
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= minCompressSize {
+		cw.startCompression()
+		if _, err := cw.enc.Write(cw.buf); err != nil {
+			return 0, err
+		}
+		cw.buf = nil
+	}
+	return len(b), nil
+}
+
+// finish sends what is still held back and closes the encoder
+func (cw *compressWriter) finish() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.passThrough()
+		cw.ResponseWriter.Write(cw.buf)
+		return
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+	}
+}
+
+func (cw *compressWriter) passThrough() {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) startCompression() {
+	cw.decided = true
+	h := cw.Header()
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.encoding == "br" {
+		cw.enc = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+	} else {
+		cw.enc = gzip.NewWriter(cw.ResponseWriter)
+	}
+}
+
+func isJSONType(contentType string) bool {
+	media, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (media == "application/json" || strings.HasSuffix(media, "+json"))
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"gzip":                   "gzip",
+		"gzip, deflate, br":      "br",
+		"br;q=0.5, gzip":         "gzip",
+		"br;q=0, gzip;q=0":       "",
+		"identity":               "",
+		"*":                      "br",
+		"deflate, gzip;q=1.0, *": "br",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	big := `{"posts":"` + strings.Repeat("a", 2*minCompressSize) + `"}`
+	serve := func(contentType, body, acceptEncoding string) *httptest.ResponseRecorder {
+		h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, body)
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("application/json", big, "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("gzip headers = %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != big {
+		t.Errorf("gzip body doesn't round trip")
+	}
+
+	w = serve("application/json", big, "br")
+	if got, _ := io.ReadAll(brotli.NewReader(w.Body)); w.Header().Get("Content-Encoding") != "br" || string(got) != big {
+		t.Errorf("brotli: %v", w.Header())
+	}
+
+	if w := serve("application/json", `{"ok":true}`, "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("small response was compressed: %v", w.Header())
+	}
+	if w := serve("image/png", big, "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != big {
+		t.Errorf("non JSON response was compressed: %v", w.Header())
+	}
+	if w := serve("application/json", big, ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != big {
+		t.Errorf("compressed without Accept-Encoding")
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag lets a GET answer 304 Not Modified when nothing changed. The handler still runs (the data is
+// per viewer, there is nothing cheaper to compare), but an unchanged body isn't sent again: the
+// response is hashed into an ETag and compared with If-None-Match.
+// The responses are private to the user and have to be revalidated every time
+func ETag(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next(rec, r)
+
+		for key, values := range rec.header {
+			w.Header()[key] = values
+		}
+		if rec.status != http.StatusOK {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(rec.body.Bytes())
+		// weak, the same JSON can go out gzip'd, brotli'd or plain (see Compress)
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		if matchesETag(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(rec.body.Bytes())
+	}
+}
+
+// matchesETag does the weak comparison of If-None-Match: W/"x" and "x" are the same tag
+func matchesETag(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse keeps the whole response in memory until ETag has looked at it
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
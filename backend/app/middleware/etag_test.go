@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-network/app/response"
+)
+
+func TestETag(t *testing.T) {
+	body := map[string]string{"title": "first"}
+	h := ETag(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusOK, body)
+	})
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/groups/1", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("first response: %d %v", first.Code, first.Header())
+	}
+
+	if w := get(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("same ETag: %d %q", w.Code, w.Body)
+	}
+	if w := get(`"other", ` + etag[2:]); w.Code != http.StatusNotModified {
+		t.Errorf("strong form in a list: %d", w.Code)
+	}
+
+	body["title"] = "changed"
+	if w := get(etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("changed body: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestETagSkipsErrors(t *testing.T) {
+	h := ETag(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, http.StatusNotFound, "Post not found")
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/posts/9", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" || w.Body.Len() == 0 {
+		t.Errorf("error response: %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/response"
+	"social-network/app/store"
+)
+
+// how long a key is remembered, a client retrying after that creates the post again
+const idempotencyTTL = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotent makes a POST safe to send twice. A request with an Idempotency-Key header runs once,
+// the response is saved and sent back as is (plus "Idempotent-Replayed: true") to every later
+// request of the same user with the same key. The same key with another body or path is refused
+// with 422, and while the first request is still running the copies get 409.
+// Requests without the header are not touched. It needs the user id, so it goes inside RequireAuth
+func Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.Error(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := &models.IdempotencyKey{
+			UserID:      CurrentUserID(r),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			CreatedAt:   now.Unix(),
+			ExpiresAt:   now.Add(idempotencyTTL).Unix(),
+		}
+
+		err = stores.Idempotency.Reserve(r.Context(), record, now.Unix())
+		if errors.Is(err, store.ErrConflict) {
+			replay(w, r, record)
+			return
+		}
+		if err != nil {
+			log.Printf("Idempotent: reserve key: %v", err)
+			response.Error(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		// the outcome is saved even if the client is gone, that's the point of the key
+		ctx := context.WithoutCancel(r.Context())
+		saved := false
+		// a response that isn't saved releases the key, a panic of the handler included: the key
+		// would answer 409 until it expires otherwise
+		defer func() {
+			if saved {
+				return
+			}
+			if err := stores.Idempotency.Release(ctx, record.UserID, key); err != nil {
+				log.Printf("Idempotent: release key: %v", err)
+			}
+		}()
+
+		rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if retryable(rec.status) {
+			return
+		}
+		record.Status = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.Body = rec.body.Bytes()
+		if err := stores.Idempotency.Save(ctx, *record); err != nil {
+			log.Printf("Idempotent: save response: %v", err)
+		}
+		saved = true
+	}
+}
+
+// retryable is true for the answers that say nothing was done and the same request may work later:
+// server errors, timeouts and rate limits (the limiter runs inside Idempotent). They aren't replayed
+func retryable(status int) bool {
+	return status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooEarly ||
+		status == http.StatusTooManyRequests
+}
+
+// replay answers a request whose key was already used
+func replay(w http.ResponseWriter, r *http.Request, record *models.IdempotencyKey) {
+	saved, err := stores.Idempotency.Get(r.Context(), record.UserID, record.Key)
+	if errors.Is(err, store.ErrNotFound) {
+		// released in the meantime, the first request failed
+		response.Error(w, http.StatusConflict, "Request with this Idempotency-Key failed, retry it")
+		return
+	}
+	if err != nil {
+		log.Printf("Idempotent: get key: %v", err)
+		response.Error(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	switch {
+	case saved.RequestHash != record.RequestHash:
+		response.ErrorCode(w, http.StatusUnprocessableEntity, "idempotency_key_reused",
+			"Idempotency-Key was already used for a different request")
+	case saved.Status == 0:
+		response.ErrorCode(w, http.StatusConflict, "request_in_progress",
+			"A request with this Idempotency-Key is still being processed")
+	default:
+		if saved.ContentType != "" {
+			w.Header().Set("Content-Type", saved.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(saved.Status)
+		w.Write(saved.Body)
+	}
+}
+
+// requestHash ties a key to one request, the path and query are in it so a key can't be reused on
+// another route or with other parameters. Without a query it hashes like before the query counted,
+// so the keys saved then still match
+func requestHash(r *http.Request, body []byte) string {
+	target := r.URL.Path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+target+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCapture passes the response through and keeps a copy of the status and body
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
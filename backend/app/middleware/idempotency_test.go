@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-network/app/response"
+	"social-network/app/store/memstore"
+)
+
+func TestIdempotentReplaysResponse(t *testing.T) {
+	SetStores(memstore.New())
+
+	created := 0
+	h := Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		created++
+		response.JSON(w, http.StatusCreated, map[string]int{"id": created})
+	})
+
+	sendTo := func(target string, userID int, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", userID))
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+	send := func(userID int, key, body string) *httptest.ResponseRecorder {
+		return sendTo("/posts", userID, key, body)
+	}
+
+	first := send(1, "abc", `{"content":"hi"}`)
+	again := send(1, "abc", `{"content":"hi"}`)
+	if created != 1 || again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Fatalf("replay ran the handler again or changed the response: created=%d %d %s", created, again.Code, again.Body)
+	}
+	if again.Header().Get("Idempotent-Replayed") != "true" || again.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v", again.Header())
+	}
+
+	if w := send(1, "abc", `{"content":"other"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, other body: %d %s", w.Code, w.Body)
+	}
+	if w := sendTo("/posts?status=draft", 1, "abc", `{"content":"hi"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, other query: %d %s", w.Code, w.Body)
+	}
+	// keys belong to a user
+	if send(2, "abc", `{"content":"hi"}`); created != 2 {
+		t.Errorf("key of another user blocked the request")
+	}
+	send(1, "", `{"content":"hi"}`)
+	send(1, "", `{"content":"hi"}`)
+	if created != 4 {
+		t.Errorf("requests without a key are deduplicated, created=%d", created)
+	}
+}
+
+func TestIdempotentForgetsServerErrors(t *testing.T) {
+	SetStores(memstore.New())
+
+	calls := 0
+	h := Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			response.Error(w, http.StatusInternalServerError, "db down")
+			return
+		}
+		response.JSON(w, http.StatusOK, nil)
+	})
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/chat/messages", strings.NewReader(`{}`))
+		r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", 1))
+		r.Header.Set("Idempotency-Key", "retry-me")
+		h(httptest.NewRecorder(), r)
+	}
+	if calls != 2 {
+		t.Errorf("a 500 should let the same key run again, calls=%d", calls)
+	}
+}
+
+func TestIdempotentReleasesRateLimitsAndPanics(t *testing.T) {
+	SetStores(memstore.New())
+
+	calls := 0
+	h := Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			response.Error(w, http.StatusTooManyRequests, "slow down")
+		case 2:
+			panic("handler bug")
+		default:
+			response.JSON(w, http.StatusCreated, nil)
+		}
+	})
+	send := func() (code int) {
+		defer func() {
+			if recover() != nil {
+				code = -1
+			}
+		}()
+		r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+		r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", 1))
+		r.Header.Set("Idempotency-Key", "limited")
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+
+	for i, want := range []int{http.StatusTooManyRequests, -1, http.StatusCreated, http.StatusCreated} {
+		if got := send(); got != want {
+			t.Errorf("request %d: %d, want %d", i+1, got, want)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("the 429 and the panic should let the key run again and the 201 be replayed, calls=%d", calls)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TrustProxy is true when the backend runs behind a reverse proxy that sets the X-Forwarded-* headers
+// (TRUST_PROXY=true). Without it those headers are ignored, any client could send them
+func TrustProxy() bool {
+	return os.Getenv("TRUST_PROXY") == "true"
+}
+
+// IsSecure tells if the client reached us over HTTPS, directly or through a trusted proxy
+func IsSecure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return TrustProxy() && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// HSTS sends Strict-Transport-Security on HTTPS responses when HSTS_MAX_AGE (seconds) is set,
+// browsers then refuse plain HTTP for the domain. Only turn it on once HTTPS works for good
+func HSTS(next http.Handler) http.Handler {
+	maxAge, _ := strconv.Atoi(os.Getenv("HSTS_MAX_AGE"))
+	if maxAge <= 0 {
+		return next
+	}
+	value := "max-age=" + strconv.Itoa(maxAge)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsSecure(r) {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionCookie is the session_token cookie, Secure whenever the request came over HTTPS
+// so the token never travels in clear text afterwards
+func sessionCookie(r *http.Request, value string) http.Cookie {
+	return http.Cookie{
+		Name:     "session_token",
+		Value:    value,
+		HttpOnly: true,                    // we use this to prevent JavaScript access
+		SameSite: http.SameSiteStrictMode, // to prevent CSRF attacks
+		Path:     "/",                     // this way the cookie is available across the entire site
+		Secure:   IsSecure(r),
+	}
+}
+
+// ClearSessionCookie expires the session cookie in the browser
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	cookie := sessionCookie(r, "")
+	cookie.MaxAge = -1
+	http.SetCookie(w, &cookie)
+}
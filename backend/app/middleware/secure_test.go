@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-network/app/store/memstore"
+)
+
+func TestSessionCookieIsSecureOverHTTPS(t *testing.T) {
+	SetStores(memstore.New())
+
+	tests := []struct {
+		name       string
+		trustProxy string
+		setup      func(r *http.Request)
+		want       bool
+	}{
+		{"plain http", "", func(r *http.Request) {}, false},
+		{"tls", "", func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, true},
+		{"proxy header without TRUST_PROXY", "", func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") }, false},
+		{"trusted proxy", "true", func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", tt.trustProxy)
+			r := httptest.NewRequest(http.MethodPost, "/login", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+			CreateSession(1, w, r)
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Secure != tt.want || !cookies[0].HttpOnly {
+				t.Fatalf("cookies = %+v", cookies)
+			}
+		})
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Setenv("HSTS_MAX_AGE", "")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	HSTS(next).ServeHTTP(w, r)
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS sent without HSTS_MAX_AGE")
+	}
+
+	t.Setenv("HSTS_MAX_AGE", "31536000")
+	w = httptest.NewRecorder()
+	HSTS(next).ServeHTTP(w, r)
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=31536000" {
+		t.Errorf("HSTS = %q", got)
+	}
+	// never over plain HTTP, browsers ignore it there anyway
+	w = httptest.NewRecorder()
+	HSTS(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS sent over plain HTTP")
+	}
+}
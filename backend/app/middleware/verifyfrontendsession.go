@@ -4,30 +4,27 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"social-network/db"
+	"social-network/app/response"
 )
 
 // for front end to verify session and get user info (useful for having user data on page load)
 func MeHandler(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session_token")
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Not authenticated"})
+		response.Error(w, http.StatusUnauthorized, "Not authenticated")
 		return
 	}
 
-	userID := GetUserId(cookie.Value)
+	userID := GetUserId(r, cookie.Value)
 	if userID <= 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Invalid session"})
+		response.Error(w, http.StatusUnauthorized, "Invalid session")
 		return
 	}
 
 	// Optionally fetch user details
-	var username, email, firstName, lastName, avatar string
-	err = db.Database.QueryRow("SELECT username, email, avatar, first_name, last_name FROM users WHERE id = ?", userID).Scan(&username, &email, &avatar, &firstName, &lastName)
+	user, err := stores.Users.Get(r.Context(), userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		response.Error(w, http.StatusInternalServerError, "Failed to load user")
 		return
 	}
 
@@ -35,11 +32,19 @@ func MeHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user": map[string]interface{}{
 			"id":         userID,
-			"username":   username,
-			"first_name": firstName,
-			"last_name":  lastName,
-			"email":      email,
-			"avatar":     avatar,
+			"username":   valueOrEmpty(user.Username),
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"email":      user.Email,
+			"avatar":     valueOrEmpty(user.Avatar),
 		},
 	})
 }
+
+// the /me payload always had plain strings, NULL columns become ""
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -1,18 +1,23 @@
-package models
-
-type GroupPost struct {
-	ID        int            `json:"id"`
-	GroupID   int            `json:"group_id"`
-	UserID    int            `json:"user_id"`
-	Content   string         `json:"content"`
-	Image     string         `json:"image,omitempty"`
-	CreatedAt int64          `json:"created_at"`
-	Author    *User          `json:"author,omitempty"`
-	Comments  []GroupComment `json:"comments,omitempty"`
-}
-
-type CreatePostRequest struct {
-	GroupID string `json:"group_id"`
-	Content string `json:"content"`
-	Image   string `json:"image,omitempty"`
-}
+package models
+
+type GroupPost struct {
+	ID          int            `json:"id"`
+	GroupID     int            `json:"group_id"`
+	UserID      int            `json:"user_id"`
+	Content     string         `json:"content"`
+	Image       string         `json:"image,omitempty"` // the first attachment
+	Attachments []Attachment   `json:"attachments,omitempty"`
+	CreatedAt   int64          `json:"created_at"`
+	Author      *UserSummary   `json:"author,omitempty"`
+	Comments    []GroupComment `json:"comments,omitempty"`
+	Poll        *Poll          `json:"poll,omitempty"`
+	ReactionSummary
+}
+
+type CreatePostRequest struct {
+	GroupID     string       `json:"group_id"`
+	Content     string       `json:"content"`
+	Image       string       `json:"image,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Poll        *Poll        `json:"poll,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Attachment is an image of a post, comment or group post, they are shown in the order of the list.
+// Width and height are 0 when the client didn't send them
+type Attachment struct {
+	ID          int    `json:"id"`
+	Path        string `json:"path"` // an uploaded /images path
+	AltText     string `json:"alt_text"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	ContentType string `json:"content_type"`
+}
+
+// MaxAttachments is how many images a post or comment can have
+const MaxAttachments = 4
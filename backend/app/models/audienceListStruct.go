@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AudienceList is a named list of users an almost_private post can be shared with. A post shared
+// with a list follows it: people added later see the post, people removed don't anymore
+type AudienceList struct {
+	ID          int           `json:"id"`
+	UserID      int           `json:"user_id"`
+	Name        string        `json:"name"`
+	MemberCount int           `json:"member_count"`
+	Members     []UserSummary `json:"members,omitempty"` // only when a single list is read
+	CreatedAt   time.Time     `json:"created_at"`
+}
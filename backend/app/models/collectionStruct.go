@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DefaultCollection is the name of the collection every user has, it can't be deleted
+const DefaultCollection = "Saved"
+
+// Collection is a private, named list of saved posts and group posts
+type Collection struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CollectionItem is a saved post (PostID) or group post (GroupPostID). The lists embed the
+// post in Post or GroupPost
+type CollectionItem struct {
+	ID           int        `json:"id"`
+	CollectionID int        `json:"collection_id"`
+	PostID       *int       `json:"post_id,omitempty"`
+	GroupPostID  *int       `json:"group_post_id,omitempty"`
+	Position     int        `json:"position"`
+	SavedAt      time.Time  `json:"saved_at"`
+	Post         *Post      `json:"post,omitempty"`
+	GroupPost    *GroupPost `json:"group_post,omitempty"`
+}
@@ -3,16 +3,21 @@ package models
 import "time"
 
 type Comment struct {
-	ID        int       `json:"id"`
-	Content   string    `json:"content"`
-	Image     *string   `json:"image,omitempty"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Username  string    `json:"username"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Avatar    *string   `json:"avatar,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	Content     string       `json:"content"`
+	Format      string       `json:"format,omitempty"`
+	ContentHTML *string      `json:"content_html,omitempty"`
+	Image       *string      `json:"image,omitempty"` // the first attachment
+	Attachments []Attachment `json:"attachments,omitempty"`
+	PostID      int          `json:"post_id"`
+	UserID      int          `json:"user_id"`
+	Username    string       `json:"username"`
+	FirstName   string       `json:"first_name"`
+	LastName    string       `json:"last_name"`
+	Avatar      *string      `json:"avatar,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Mentions    []Mention    `json:"mentions,omitempty"`
+	ReactionSummary
 }
 
 // Image is a pointer to allow NULL values in the database
@@ -11,10 +11,3 @@ type Event struct {
 	Date        time.Time `json:"date"`
 	CreatedAt   time.Time `json:"created_at"`
 }
-
-type EventResponse struct {
-	EventID     int       `json:"event_id"`
-	UserID      int       `json:"user_id"`
-	Response    string    `json:"response"` // "going" or "not_going"
-	RespondedAt time.Time `json:"responded_at"`
-}
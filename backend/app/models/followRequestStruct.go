@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type FollowRequest struct {
+	ID           int       `json:"id"`
+	RequesterID  int       `json:"requester_id"`
+	SenderName   string    `json:"sender_name,omitempty"`
+	SenderAvatar *string   `json:"sender_avatar,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
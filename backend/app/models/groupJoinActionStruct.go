@@ -11,10 +11,19 @@ type GroupInvitation struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// pending join request as shown to the group creator
 type GroupJoinRequest struct {
-	ID          int       `json:"id"`
-	GroupID     int       `json:"group_id"`
-	RequesterID int       `json:"requester_id"`
-	Status      string    `json:"status"`       // 'pending', 'approved', 'rejected'
-	CreatedAt   time.Time `json:"created_at"`
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Avatar    string `json:"avatar,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type GroupMember struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar,omitempty"`
+	Role     string `json:"role"`
+	JoinedAt int64  `json:"joined_at"`
 }
@@ -1,15 +1,20 @@
 package models
 
 type GroupMessage struct {
-	ID         int    `json:"id"`
-	GroupID    int    `json:"group_id"`
-	SenderID   int    `json:"sender_id"`
-	SenderName string `json:"sender_name,omitempty"`
-	Content    string `json:"content"`
-	CreatedAt  int64  `json:"created_at"`
+	ID          int          `json:"id"`
+	GroupID     int          `json:"group_id"`
+	SenderID    int          `json:"sender_id"`
+	SenderName  string       `json:"sender_name,omitempty"`
+	Content     string       `json:"content"`
+	Format      string       `json:"format,omitempty"`
+	ContentHTML *string      `json:"content_html,omitempty"`
+	CreatedAt   int64        `json:"created_at"`
+	Mentions    []Mention    `json:"mentions,omitempty"`
+	Preview     *LinkPreview `json:"preview,omitempty"`
 }
 
 type SendGroupMessageRequest struct {
 	GroupID int    `json:"group_id"`
 	Content string `json:"content"`
+	Format  string `json:"format"` // plain (default) or markdown
 }
@@ -0,0 +1,14 @@
+package models
+
+// IdempotencyKey is a request sent with an Idempotency-Key header and the response it got,
+// see middleware.Idempotent
+type IdempotencyKey struct {
+	UserID      int
+	Key         string
+	RequestHash string
+	Status      int // 0 while the first request is still running
+	ContentType string
+	Body        []byte
+	CreatedAt   int64
+	ExpiresAt   int64
+}
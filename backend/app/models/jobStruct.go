@@ -0,0 +1,26 @@
+package models
+
+import "encoding/json"
+
+// Job is one unit of background work, see app/jobs
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	UniqueKey   *string         `json:"unique_key,omitempty"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       int64           `json:"run_at"` // unix seconds, the job isn't picked up before
+	LastError   *string         `json:"last_error,omitempty"`
+	CreatedAt   int64           `json:"created_at"`
+	UpdatedAt   int64           `json:"updated_at"`
+}
+
+// a job goes pending -> running -> done, or back to pending while it has attempts left, then failed
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LinkPreview is the card shown under a post or message for the first link of its text
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"-"`
+}
+
+// Empty reports a page without anything to show, or one that couldn't be fetched
+func (p LinkPreview) Empty() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
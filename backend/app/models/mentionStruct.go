@@ -0,0 +1,15 @@
+package models
+
+// Mention is an @username of a post, comment or group message that matched a user.
+// Offset and Length are in UTF-16 code units, so content.slice(offset, offset + length) in the
+// frontend is the "@username"
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+// TargetGroupMessage is the target_type of a mention notification about a group message, posts
+// and comments use the reaction targets
+const TargetGroupMessage = "group_message"
@@ -11,12 +11,15 @@ type Notification struct {
 	SenderID        *int      `json:"sender_id,omitempty"`
 	SenderName      *string   `json:"sender_name,omitempty"`
 	SenderAvatar    *string   `json:"sender_avatar,omitempty"`
+	SenderCount     int       `json:"sender_count,omitempty"` // aggregated notifications: how many people it stands for
 	PostID          *int      `json:"post_id,omitempty"`
 	GroupID         *int      `json:"group_id,omitempty"`
 	GroupName       *string   `json:"group_name,omitempty"`
 	EventID         *int      `json:"event_id,omitempty"`
 	EventDate       *int64    `json:"event_date,omitempty"`
 	EventTitle      *string   `json:"event_title,omitempty"`
+	TargetType      *string   `json:"target_type,omitempty"` // reactions: what was reacted to
+	TargetID        *int      `json:"target_id,omitempty"`
 	IsRead          bool      `json:"is_read"`
 	CreatedAt       time.Time `json:"created_at"`
 }
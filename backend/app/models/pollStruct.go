@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Poll is attached to a post or a group post when it's created, it can't be edited afterwards.
+// The counts are only filled in when the user may see them, see ResultsHidden
+type Poll struct {
+	ID            int          `json:"id"`
+	PostID        *int         `json:"post_id,omitempty"`
+	GroupPostID   *int         `json:"group_post_id,omitempty"`
+	GroupID       *int         `json:"group_id,omitempty"` // the group of a group post's poll
+	AuthorID      int          `json:"-"`
+	Multiple      bool         `json:"multiple"`     // several options can be chosen
+	HideResults   bool         `json:"hide_results"` // the results stay hidden until the user votes
+	ClosesAt      *time.Time   `json:"closes_at,omitempty"`
+	Closed        bool         `json:"closed"`
+	ResultsHidden bool         `json:"results_hidden"` // votes and voters are 0 because the user can't see them yet
+	Options       []PollOption `json:"options"`
+	Voters        int          `json:"voters"`
+	MyVotes       []int        `json:"my_votes"` // ids of the options the user chose
+}
+
+type PollOption struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+const (
+	PollMinOptions = 2
+	PollMaxOptions = 10
+)
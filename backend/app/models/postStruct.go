@@ -3,16 +3,29 @@ package models
 import "time"
 
 type Post struct {
-	ID               int       `json:"id"`
-	Content          string    `json:"content,omitempty"`
-	Image            *string   `json:"image,omitempty"`
-	Privacy          string    `json:"privacy"`
-	UserID           int       `json:"user_id"`
-	GroupID          *int      `json:"group_id,omitempty"` // nil for regular posts or set for group posts
-	CreatedAt        time.Time `json:"created_at"`
-	Username         string    `json:"username"`
-	Avatar           string    `json:"avatar"`
-	AllowedFollowers []int     `json:"allowed_followers,omitempty"` // for almost_private posts
+	ID               int          `json:"id"`
+	Content          string       `json:"content,omitempty"`
+	Format           string       `json:"format,omitempty"`       // markdown, or plain text when empty
+	ContentHTML      *string      `json:"content_html,omitempty"` // the rendered Markdown
+	Image            *string      `json:"image,omitempty"`        // the first attachment, for the clients that show one image
+	Attachments      []Attachment `json:"attachments,omitempty"`
+	Privacy          string       `json:"privacy"`
+	UserID           int          `json:"user_id"`
+	GroupID          *int         `json:"group_id,omitempty"` // nil for regular posts or set for group posts
+	CreatedAt        time.Time    `json:"created_at"`
+	EditedAt         *time.Time   `json:"edited_at,omitempty"` // set once the post has been edited
+	Username         string       `json:"username"`
+	Avatar           string       `json:"avatar"`
+	AllowedFollowers []int        `json:"allowed_followers,omitempty"` // for almost_private posts
+	AudienceLists    []int        `json:"audience_lists,omitempty"`    // almost_private too, the author's lists
+	RepostOf         *int         `json:"repost_of,omitempty"`         // reposts and quote posts: the shared post
+	Original         *Post        `json:"original,omitempty"`          // the shared post, missing when deleted or not visible
+	Mentions         []Mention    `json:"mentions,omitempty"`
+	Status           string       `json:"status"`               // published, or draft and scheduled until then
+	PublishAt        *time.Time   `json:"publish_at,omitempty"` // when a scheduled post goes out
+	Poll             *Poll        `json:"poll,omitempty"`
+	Preview          *LinkPreview `json:"preview,omitempty"` // of the first link of the content
+	ReactionSummary
 }
 
 // Image and GroupID are pointers to allow NULL values in the database
@@ -24,3 +37,29 @@ const (
 	PrivacyAlmostPrivate = "almost_private"
 	PrivacyPrivate       = "private"
 )
+
+// the formats of the content of posts, comments and messages, Markdown ones also have the HTML
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// only published posts are visible, drafts and scheduled posts stay with their author
+const (
+	PostPublished = "published"
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+)
+
+// PostRevision is an earlier version of an edited post
+type PostRevision struct {
+	ID          int       `json:"id"`
+	PostID      int       `json:"post_id"`
+	Content     string    `json:"content,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	ContentHTML *string   `json:"content_html,omitempty"`
+	Image       *string   `json:"image,omitempty"`
+	Privacy     string    `json:"privacy"`
+	CreatedAt   time.Time `json:"created_at"`  // when this version was published
+	ReplacedAt  time.Time `json:"replaced_at"` // when the edit replaced it
+}
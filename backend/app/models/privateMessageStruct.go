@@ -3,13 +3,17 @@ package models
 type SendMessageRequest struct {
 	ReceiverID int    `json:"receiver_id"`
 	Content    string `json:"content"`
+	Format     string `json:"format"` // plain (default) or markdown
 }
 
 type Message struct {
-	ID         int    `json:"id"`
-	SenderID   int    `json:"sender_id"`
-	ReceiverID int    `json:"receiver_id"`
-	Content    string `json:"content"`
-	CreatedAt  int64  `json:"created_at"`
-	IsRead     bool   `json:"is_read"`
+	ID          int          `json:"id"`
+	SenderID    int          `json:"sender_id"`
+	ReceiverID  int          `json:"receiver_id"`
+	Content     string       `json:"content"`
+	Format      string       `json:"format,omitempty"`
+	ContentHTML *string      `json:"content_html,omitempty"`
+	CreatedAt   int64        `json:"created_at"`
+	IsRead      bool         `json:"is_read"`
+	Preview     *LinkPreview `json:"preview,omitempty"`
 }
@@ -0,0 +1,41 @@
+package models
+
+// Reaction is one user's emoji on a post, a comment, a group post or a group comment
+type Reaction struct {
+	UserID     int    `json:"user_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	Type       string `json:"type"`
+}
+
+// the things that can be reacted to, target_id points into the matching table
+const (
+	TargetPost         = "post"          // posts
+	TargetComment      = "comment"       // comments
+	TargetGroupPost    = "group_post"    // group_posts
+	TargetGroupComment = "group_comment" // group_post_comments
+)
+
+// ReactionTypes is the fixed set of reactions, the frontend maps them to their emoji
+var ReactionTypes = map[string]string{
+	"like":  "👍",
+	"love":  "❤️",
+	"haha":  "😂",
+	"wow":   "😮",
+	"sad":   "😢",
+	"angry": "😡",
+}
+
+// ReactionSummary is embedded in the things that can be reacted to.
+// Reactions counts them per type, MyReaction is the viewer's own ("" if none)
+type ReactionSummary struct {
+	Reactions  map[string]int `json:"reactions"`
+	MyReaction string         `json:"my_reaction,omitempty"`
+}
+
+// ReactionTarget is what a reaction is attached to, as far as permissions and notifications care
+type ReactionTarget struct {
+	OwnerID int // who wrote it
+	PostID  int // the post itself or the post of the comment (a group_posts id for the group types)
+	GroupID int // 0 outside groups
+}
@@ -0,0 +1,8 @@
+package models
+
+// TrendingTag is a tag with its decayed score, Uses is the plain number of posts in the window
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+	Uses  int     `json:"uses"`
+}
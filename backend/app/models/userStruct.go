@@ -22,3 +22,11 @@ type User struct {
 
 // Avatar, Username, AboutMe are pointers (*string) to allow NULL values in the database
 // omitempty: JSON tag: excludes these fields from JSON response when nil
+
+// UserSummary is the short author/member info embedded in group posts, comments and user lists
+type UserSummary struct {
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id,omitempty"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar,omitempty"`
+}
@@ -0,0 +1,171 @@
+// Package openapi holds the OpenAPI 3 document of the API (openapi.json) and the middleware
+// that checks incoming requests against it.
+//
+// openapi.json is written by hand, every route registered in server.SetupRoutes must have an
+// operation in it (server/routes_test.go fails otherwise).
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var raw []byte
+
+// Document is the part of the spec the validator needs, the rest of openapi.json
+// (responses, descriptions...) is only there for the people reading it
+type Document struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"` // #/components/parameters/..., replaced by the target in mustParse
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // query or path
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+// Schema supports the keywords openapi.json uses, anything else is ignored
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Nullable   bool               `json:"nullable"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	AllOf      []*Schema          `json:"allOf"`
+	Enum       []any              `json:"enum"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+}
+
+var spec = mustParse(raw)
+
+func mustParse(b []byte) *Document {
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		// the file is embedded, so this only happens if somebody commits broken json
+		panic("openapi: openapi.json is invalid: " + err.Error())
+	}
+	// shared parameters (limit, cursor...) are referenced, the validator wants them inline
+	for _, methods := range doc.Paths {
+		for _, op := range methods {
+			for i, p := range op.Parameters {
+				if p.Ref == "" {
+					continue
+				}
+				target, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+				if !ok {
+					panic("openapi: unknown parameter " + p.Ref)
+				}
+				op.Parameters[i] = *target
+			}
+		}
+	}
+	return &doc
+}
+
+// Spec returns the parsed document
+func Spec() *Document {
+	return spec
+}
+
+// ServeSpec answers GET /openapi.json with the raw document
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(raw)
+}
+
+// Operations lists every operation as "METHOD /path", sorted
+func (d *Document) Operations() []string {
+	ops := []string{}
+	for path, methods := range d.Paths {
+		for method := range methods {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// Find returns the operation matching the method and path of a request (without the /api/v1 prefix)
+// and the values of its path parameters, nil if the spec doesn't describe it
+func (d *Document) Find(method, path string) (*Operation, map[string]string) {
+	method = strings.ToLower(method)
+	if op := d.Paths[path][method]; op != nil {
+		return op, nil
+	}
+	for template, methods := range d.Paths {
+		op := methods[method]
+		if op == nil || !strings.Contains(template, "{") {
+			continue
+		}
+		if params, ok := matchTemplate(template, path); ok {
+			return op, params
+		}
+	}
+	return nil, nil
+}
+
+// matchTemplate matches "/posts/{id}" against "/posts/12", a {name} segment matches any single segment
+func matchTemplate(template, path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(template, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = got[i]
+			continue
+		}
+		if segment != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+/* notes:
+
+- openapi.json is the source of truth for the request side (parameters and bodies), the response
+	schemas describe what the handlers send today but nothing checks them
+
+- the paths in the document don't have the /api/v1 prefix, it is in "servers" instead,
+	so a path in openapi.json is exactly the pattern registered on the inner mux
+
+- to look at it: GET /openapi.json and paste it in any swagger/redoc viewer
+
+*/
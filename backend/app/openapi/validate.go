@@ -0,0 +1,275 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"social-network/app/response"
+)
+
+// Validate checks the query, path parameters and JSON body of every request against the spec
+// before it reaches next, a request that doesn't match gets 400 validation_failed with one entry
+// per bad field. Routes or methods the spec doesn't know are passed through untouched.
+func Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, pathParams := spec.Find(r.Method, r.URL.Path)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var fields []response.FieldError
+		for _, p := range op.Parameters {
+			value := ""
+			switch p.In {
+			case "query":
+				value = r.URL.Query().Get(p.Name)
+			case "path":
+				value = pathParams[p.Name]
+			default:
+				continue
+			}
+			fields = append(fields, spec.checkParam(p, value)...)
+		}
+
+		if op.RequestBody != nil && isJSON(r) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			// the handler decodes the body again
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if media, ok := op.RequestBody.Content["application/json"]; ok {
+				bodyFields, ok := spec.checkBody(op.RequestBody.Required, media.Schema, body)
+				if !ok {
+					response.Error(w, http.StatusBadRequest, "Invalid request body")
+					return
+				}
+				fields = append(fields, bodyFields...)
+			}
+		}
+
+		if len(fields) > 0 {
+			response.Invalid(w, "Invalid request", fields...)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isJSON is true for JSON bodies and for bodies without a Content-Type, the handlers
+// decode those as JSON too. Multipart and form bodies are left to the handlers.
+func isJSON(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	return err == nil && media == "application/json"
+}
+
+// checkParam converts the raw string to the type of the schema before checking it
+func (d *Document) checkParam(p Parameter, value string) []response.FieldError {
+	if value == "" {
+		if p.Required {
+			return []response.FieldError{{Field: p.Name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	s := d.resolve(p.Schema)
+	var v any = value
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return []response.FieldError{{Field: p.Name, Message: "must be an integer"}}
+		}
+		v = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return []response.FieldError{{Field: p.Name, Message: "must be true or false"}}
+		}
+		v = b
+	}
+
+	var fields []response.FieldError
+	d.check(s, v, p.Name, &fields)
+	return fields
+}
+
+// checkBody returns false if the body isn't JSON at all
+func (d *Document) checkBody(required bool, s *Schema, body []byte) ([]response.FieldError, bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if required {
+			return []response.FieldError{{Field: "body", Message: "is required"}}, true
+		}
+		return nil, true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keeps 1.5 apart from 1 for "integer"
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+
+	var fields []response.FieldError
+	d.check(s, v, "", &fields)
+	return fields, true
+}
+
+// check validates v against s, path is the dotted name of v in the request ("" for the body itself)
+func (d *Document) check(s *Schema, v any, path string, fields *[]response.FieldError) {
+	s = d.resolve(s)
+	if s == nil {
+		return
+	}
+	fail := func(format string, args ...any) {
+		field := path
+		if field == "" {
+			field = "body"
+		}
+		*fields = append(*fields, response.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if !s.Nullable && s.Type != "" {
+			fail("must not be null")
+		}
+		return
+	}
+	for _, part := range s.AllOf {
+		d.check(part, v, path, fields)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*fields = append(*fields, response.FieldError{Field: join(path, name), Message: "is required"})
+			}
+		}
+		// sorted so the fields come back in the same order every time
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value, ok := obj[name]; ok {
+				d.check(s.Properties[name], value, join(path, name), fields)
+			}
+		}
+
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		for i, item := range items {
+			d.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("max %d characters", *s.MaxLength)
+		}
+
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			if s.Type == "integer" {
+				fail("must be an integer")
+			} else {
+				fail("must be a number")
+			}
+			return
+		}
+		f, err := n.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				fail("must be an integer")
+				return
+			}
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be true or false")
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		fail("must be one of %v", s.Enum)
+	}
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+/* notes:
+
+- this is not a full JSON Schema validator, it knows type, required, properties, items, allOf, enum,
+	minLength, maxLength, minimum, maximum, nullable and $ref to components/schemas, which is everything
+	openapi.json uses. Adding a keyword to the document means adding it here too
+
+- the handlers keep their own checks (content or image, event date in the future...), the spec can't
+	express those and the handlers must stay safe without the middleware (tests call them directly)
+
+- it runs before RequireAuth (that one is per route), so a logged out client with a broken body
+	gets 400 instead of 401
+
+*/
@@ -0,0 +1,73 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-network/app/response"
+)
+
+func TestValidate(t *testing.T) {
+	var reached string
+	handler := Validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reached = string(body)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		fields string // the invalid fields, "" when the request must go through
+	}{
+		{"valid body", http.MethodPost, "/groups/events/respond", `{"group_id":1,"event_id":2,"response":"going"}`, ""},
+		{"enum and missing", http.MethodPost, "/groups/events/respond", `{"group_id":1,"response":"maybe"}`, "event_id response"},
+		{"wrong types", http.MethodPost, "/chat/messages", `{"receiver_id":"3","content":""}`, "content receiver_id"},
+		{"array items", http.MethodPost, "/posts", `{"privacy":"almost_private","allowed_followers":[1,"x"]}`, "allowed_followers[1]"},
+		{"nullable image", http.MethodPost, "/posts", `{"privacy":"public","content":"hi","image":null}`, ""},
+		{"missing query", http.MethodGet, "/post", "", "post_id"},
+		{"query not an integer", http.MethodGet, "/post?post_id=abc", "", "post_id"},
+		{"query enum", http.MethodPost, "/follow/request/status?follow_request_id=1&action=maybe", "", "action"},
+		{"unknown route", http.MethodPost, "/not-in-the-spec", `{`, ""},
+		{"unknown method", http.MethodPut, "/posts", `{}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = ""
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if tt.fields == "" {
+				if w.Code != http.StatusOK || reached != tt.body {
+					t.Fatalf("status %d, handler got %q: %s", w.Code, reached, w.Body)
+				}
+				return
+			}
+			var env response.Envelope
+			json.NewDecoder(w.Body).Decode(&env)
+			names := []string{}
+			for _, f := range env.Error.Fields {
+				names = append(names, f.Field)
+			}
+			if w.Code != http.StatusBadRequest || strings.Join(names, " ") != tt.fields {
+				t.Errorf("status %d, fields %v, want 400 with %s", w.Code, env.Error.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsBrokenJSON(t *testing.T) {
+	handler := Validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler reached")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d", w.Code)
+	}
+}
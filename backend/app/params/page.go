@@ -0,0 +1,56 @@
+package params
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"social-network/app/store"
+)
+
+// MaxLimit caps ?limit= on every list endpoint
+const MaxLimit = 100
+
+var ErrBadLimit = errors.New("limit must be a number between 1 and 100")
+
+type legacyKey struct{}
+
+// MarkLegacy flags a request that came in on a deprecated unversioned route
+func MarkLegacy(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), legacyKey{}, true))
+}
+
+// Unpaged reports if a list request wants the whole list in the old response shape: a request on a
+// deprecated route that doesn't ask for a page (no limit, no cursor). The current frontend
+// sends those, the /api/v1 routes always page
+func Unpaged(r *http.Request) bool {
+	legacy, _ := r.Context().Value(legacyKey{}).(bool)
+	q := r.URL.Query()
+	return legacy && !q.Has("limit") && !q.Has("cursor")
+}
+
+// Page reads ?limit= and ?cursor= of a list endpoint, limit is defaultLimit when missing.
+// Unpaged requests get the zero Page (everything)
+func Page(r *http.Request, defaultLimit int) (store.Page, error) {
+	if Unpaged(r) {
+		return store.Page{}, nil
+	}
+	page := store.Page{Limit: defaultLimit}
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return store.Page{}, ErrBadLimit
+		}
+		page.Limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := store.ParseCursor(v)
+		if err != nil {
+			return store.Page{}, err
+		}
+		page.After = &cursor
+	}
+	return page, nil
+}
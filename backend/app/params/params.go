@@ -0,0 +1,43 @@
+// Package params reads the ids of a request.
+//
+// The REST routes carry them in the path (GET /groups/{id}/events), the older routes in the
+// query string (GET /groups/events?id=) or the body. Both point at the same handlers, so the
+// handlers ask here instead of reading r.URL.Query() themselves.
+package params
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Get returns the {wildcard} of the matched route pattern, or the query value key
+// on routes without that wildcard
+func Get(r *http.Request, wildcard, key string) string {
+	if v := r.PathValue(wildcard); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(key)
+}
+
+// ID is Get as a positive int, 0 when it is missing or not a valid id
+func ID(r *http.Request, wildcard, key string) int {
+	return positive(Get(r, wildcard, key))
+}
+
+// PathID is the {wildcard} of the route as a positive int, 0 on routes without it.
+// Handlers that take the id from the body on the old routes use it to override the body:
+//
+//	if id := params.PathID(r, "id"); id > 0 {
+//		req.GroupID = id
+//	}
+func PathID(r *http.Request, wildcard string) int {
+	return positive(r.PathValue(wildcard))
+}
+
+func positive(s string) int {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0
+	}
+	return id
+}
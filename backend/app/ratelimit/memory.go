@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the buckets in a map, it is the default Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was computed
+	window time.Duration
+}
+
+// buckets untouched for this long are full again anyway, sweep() drops them
+const sweepEvery = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	limit := float64(p.Limit)
+	perToken := p.Window / time.Duration(p.Limit) // refill time of one token
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit, last: now, window: p.Window}
+		s.buckets[key] = b
+	}
+	// refill for the time since the last request
+	b.tokens = min(limit, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	res := Result{Limit: p.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((limit - b.tokens) * float64(perToken))
+	return res, nil
+}
+
+// sweep drops the buckets that have refilled completely, so the map doesn't grow
+// with every IP that ever sent a request
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepEvery {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+}
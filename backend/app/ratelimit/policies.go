@@ -0,0 +1,24 @@
+package ratelimit
+
+import "time"
+
+// the policies used in server/routes.go, generous enough that nobody clicking around notices them
+var (
+	// register and login, per IP, slows down password guessing
+	Auth = Policy{Name: "auth", Limit: 10, Window: time.Minute}
+
+	Posts    = Policy{Name: "posts", Limit: 10, Window: time.Minute}
+	Comments = Policy{Name: "comments", Limit: 30, Window: time.Minute}
+	// reacting and taking it back, every click is a request
+	Reactions = Policy{Name: "reactions", Limit: 60, Window: time.Minute}
+	// poll votes, changing the vote included
+	Votes = Policy{Name: "votes", Limit: 60, Window: time.Minute}
+	// private and group messages share the bucket
+	Messages = Policy{Name: "messages", Limit: 60, Window: time.Minute}
+	// follow, unfollow and the requests, the buttons are easy to spam
+	Follows = Policy{Name: "follows", Limit: 30, Window: time.Minute}
+	// image upload has no session check, so this one is per IP
+	Uploads = Policy{Name: "uploads", Limit: 10, Window: time.Minute}
+	// group creation, invites and join requests
+	Groups = Policy{Name: "groups", Limit: 30, Window: time.Minute}
+)
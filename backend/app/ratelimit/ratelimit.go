@@ -0,0 +1,153 @@
+// Package ratelimit throttles the routes that create things (posts, messages, follow requests,
+// uploads...) with token buckets.
+//
+// Every Policy is a bucket of Limit tokens that refills over Window, each request takes one.
+// Limit wraps a handler with a policy, the bucket belongs to the logged in user or to the
+// client IP when there is no user:
+//
+//	mux.HandleFunc("POST /posts", middleware.RequireAuth(ratelimit.Limit(ratelimit.Posts, post.CreatePost)))
+//
+// The buckets live in a Store, memory by default. Running several backends behind a load balancer
+// needs a shared one (Redis...), SetStore swaps it without touching the routes.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-network/app/middleware"
+	"social-network/app/response"
+)
+
+// Policy is one limit, Name keeps the buckets of different policies apart
+type Policy struct {
+	Name   string
+	Limit  int           // requests allowed in a burst
+	Window time.Duration // time to refill the Limit tokens
+}
+
+// Result is the state of a bucket after a request took (or failed to take) a token
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, 0 when Allowed
+}
+
+// Store keeps the buckets. Take removes one token from the bucket key of policy p if there is one
+type Store interface {
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+var (
+	limits Store = NewMemoryStore()
+	now          = time.Now // replaced in tests
+)
+
+// SetStore replaces the store the buckets are kept in, call it before serving requests
+func SetStore(s Store) {
+	limits = s
+}
+
+// Limit lets a request through to next while the client's bucket for p has tokens, otherwise it
+// answers 429 with Retry-After. Both cases get the RateLimit-* headers.
+// Put it inside RequireAuth so the bucket is the user's and not the IP's
+func Limit(p Policy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, ok := take(r.Context(), p, clientKey(r))
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit, seconds(p.Window)))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+			response.Error(w, http.StatusTooManyRequests, "Too many requests, try again later")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// TakeUser takes a token from the user's bucket for p outside of an HTTP request, the WebSocket
+// read loop uses it. It is the bucket Limit uses for the user's requests, so both count together.
+// A broken store lets the user through like it does in Limit
+func TakeUser(ctx context.Context, p Policy, userID int) Result {
+	res, ok := take(ctx, p, "user:"+strconv.Itoa(userID))
+	if !ok {
+		return Result{Allowed: true, Limit: p.Limit}
+	}
+	return res
+}
+
+// take is false when the store failed, the error is logged
+func take(ctx context.Context, p Policy, client string) (Result, bool) {
+	key := p.Name + ":" + client
+	res, err := limits.Take(ctx, key, p, now())
+	if err != nil {
+		// a broken store shouldn't take the whole API down with it
+		log.Printf("ratelimit: %s: %v", key, err)
+		return res, false
+	}
+	return res, true
+}
+
+// RetryAfterSeconds is res.RetryAfter the way the Retry-After header has it
+func RetryAfterSeconds(res Result) int {
+	return seconds(res.RetryAfter)
+}
+
+// clientKey is "user:<id>" behind RequireAuth and "ip:<address>" everywhere else
+func clientKey(r *http.Request) string {
+	if userID := middleware.CurrentUserID(r); userID > 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP is the address of the connection. X-Forwarded-For is only believed with
+// TRUST_PROXY=true, anybody can send that header when the backend is reached directly
+func clientIP(r *http.Request) string {
+	if middleware.TrustProxy() {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds up, "Retry-After: 0" would invite the client to retry right away
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+/* notes:
+
+- the limits are per backend process with the memory store, restarting resets them.
+	That's fine for one instance, with more the Store interface is the place for a shared one
+
+- websocket messages don't go through here, only the HTTP routes do
+
+- the frontend can read RateLimit-Remaining to grey out buttons before hitting 429 (the headers are
+	exposed in enableCORS)
+
+*/
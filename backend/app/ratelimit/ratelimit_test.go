@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefills(t *testing.T) {
+	s := NewMemoryStore()
+	p := Policy{Name: "test", Limit: 2, Window: time.Minute}
+	start := time.Unix(1000, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if res, _ := s.Take(ctx, "k", p, start); !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("request %d = %+v", i, res)
+		}
+	}
+	res, _ := s.Take(ctx, "k", p, start)
+	if res.Allowed || res.RetryAfter != 30*time.Second || res.Reset != time.Minute {
+		t.Fatalf("third request = %+v", res)
+	}
+	if res, _ := s.Take(ctx, "other", p, start); !res.Allowed {
+		t.Error("buckets are shared between keys")
+	}
+	// one token back after Window/Limit
+	if res, _ := s.Take(ctx, "k", p, start.Add(30*time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("after 30s = %+v", res)
+	}
+}
+
+func TestLimitAnswers429(t *testing.T) {
+	SetStore(NewMemoryStore())
+	p := Policy{Name: "test", Limit: 1, Window: 10 * time.Second}
+	h := Limit(p, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	w := send("192.0.2.1:1111")
+	if w.Code != http.StatusCreated || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "1;w=10" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+	// another port, same client
+	w = send("192.0.2.1:2222")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "10" {
+		t.Fatalf("second request: %d %v", w.Code, w.Header())
+	}
+	if w := send("192.0.2.2:1111"); w.Code != http.StatusCreated {
+		t.Errorf("other IP got %d", w.Code)
+	}
+}
+
+func TestTakeUserSharesTheBucket(t *testing.T) {
+	SetStore(NewMemoryStore())
+	p := Policy{Name: "test", Limit: 1, Window: 10 * time.Second}
+	if res := TakeUser(context.Background(), p, 7); !res.Allowed {
+		t.Fatalf("first frame = %+v", res)
+	}
+	// the socket took the user's token, their next request is limited
+	h := Limit(p, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	r := httptest.NewRequest(http.MethodPost, "/users/2/messages", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "ctxUserID", 7))
+	w := httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("request after the socket frame: %d, want 429", w.Code)
+	}
+	if res := TakeUser(context.Background(), p, 8); !res.Allowed {
+		t.Error("another user shares the bucket")
+	}
+}
@@ -0,0 +1,44 @@
+package response
+
+import (
+	"net/http"
+
+	"social-network/app/params"
+	"social-network/app/store"
+)
+
+// ListBody is the envelope of every list endpoint, NextCursor is null on the last page
+type ListBody struct {
+	Items      any     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// List writes one page of a list endpoint. Unpaged requests (see params.Unpaged) get the body
+// they always got: the items under legacyKey ({"posts": [...]}), or the bare array when
+// legacyKey is ""
+func List(w http.ResponseWriter, r *http.Request, legacyKey string, items any, next *store.Cursor) {
+	if params.Unpaged(r) {
+		if legacyKey == "" {
+			JSON(w, http.StatusOK, items)
+		} else {
+			JSON(w, http.StatusOK, map[string]any{legacyKey: items})
+		}
+		return
+	}
+
+	body := ListBody{Items: items}
+	if next != nil {
+		cursor := next.String()
+		body.NextCursor = &cursor
+	}
+	JSON(w, http.StatusOK, body)
+}
+
+// BadPage answers the error of params.Page
+func BadPage(w http.ResponseWriter, err error) {
+	field := "limit"
+	if err == store.ErrBadCursor {
+		field = "cursor"
+	}
+	Invalid(w, "Invalid page", FieldError{Field: field, Message: err.Error()})
+}
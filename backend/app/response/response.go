@@ -0,0 +1,93 @@
+// Package response writes every JSON body the API sends, so all handlers answer in the same shape.
+//
+// Errors always use the envelope
+//
+//	{"error": {"code": "not_found", "message": "Post not found", "fields": [...]}}
+//
+// code is stable and meant for programs, message is meant for people and may change,
+// fields is only present on validation errors.
+package response
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// error codes used on top of the ones derived from the status (see codeFor)
+const (
+	CodeValidation = "validation_failed"
+)
+
+// FieldError points at one invalid field of the request body or query
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ErrorBody struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+type Envelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+// JSON writes v with the given status
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("response: encode failed: %v", err)
+	}
+}
+
+// Error writes the error envelope with the default code of the status,
+// it is the drop-in replacement for http.Error
+func Error(w http.ResponseWriter, status int, message string) {
+	ErrorCode(w, status, codeFor(status), message)
+}
+
+// ErrorCode writes the error envelope with a specific code, e.g. "email_taken"
+func ErrorCode(w http.ResponseWriter, status int, code, message string) {
+	JSON(w, status, Envelope{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// Invalid answers 400 validation_failed listing every bad field
+func Invalid(w http.ResponseWriter, message string, fields ...FieldError) {
+	JSON(w, http.StatusBadRequest, Envelope{Error: ErrorBody{
+		Code:    CodeValidation,
+		Message: message,
+		Fields:  fields,
+	}})
+}
+
+// codeFor turns a status into a snake_case code: 404 -> "not_found"
+func codeFor(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return "internal_error"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+/* notes:
+
+- handlers used to answer errors in 3 different ways (plain text from http.Error, {"error": "..."} from
+	profile.writeError and RequireAuth), the frontend had to guess which one it got
+
+- the codes derived from the status are:
+	400 bad_request, 401 unauthorized, 403 forbidden, 404 not_found, 405 method_not_allowed,
+	409 conflict, 429 rate_limited, 500 internal_error
+	anything more specific (email_taken, already_following...) is passed to ErrorCode
+
+*/
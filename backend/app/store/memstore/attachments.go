@@ -0,0 +1,53 @@
+package memstore
+
+import (
+	"slices"
+
+	"social-network/app/models"
+)
+
+// attachmentRow is an attachments row, one of postID, commentID and groupPostID is set
+type attachmentRow struct {
+	attachment  models.Attachment
+	postID      int
+	commentID   int
+	groupPostID int
+}
+
+// saveAttachments is sqlstore's saveAttachments for the row's ids, its attachment is left empty
+func (m *memory) saveAttachments(owner attachmentRow, attachments []models.Attachment) {
+	m.attachments = slices.DeleteFunc(m.attachments, func(r attachmentRow) bool { return r.sameOwner(owner) })
+	for i := range attachments {
+		attachments[i].ID = m.nextID()
+		row := owner
+		row.attachment = attachments[i]
+		m.attachments = append(m.attachments, row)
+	}
+}
+
+// attachmentsOf returns the attachments of the owner in their order
+func (m *memory) attachmentsOf(owner attachmentRow) []models.Attachment {
+	var attachments []models.Attachment
+	for _, r := range m.attachments {
+		if r.sameOwner(owner) {
+			attachments = append(attachments, r.attachment)
+		}
+	}
+	return attachments
+}
+
+func (r attachmentRow) sameOwner(o attachmentRow) bool {
+	return r.postID == o.postID && r.commentID == o.commentID && r.groupPostID == o.groupPostID
+}
+
+// imageInUse is sqlstore's unusedImages for one path
+func (m *memory) imageInUse(path string) bool {
+	is := func(image *string) bool { return image != nil && *image == path }
+	return slices.ContainsFunc(m.users, func(r userRow) bool { return is(r.user.Avatar) }) ||
+		slices.ContainsFunc(m.posts, func(p models.Post) bool { return is(p.Image) }) ||
+		slices.ContainsFunc(m.revisions, func(r models.PostRevision) bool { return is(r.Image) }) ||
+		slices.ContainsFunc(m.comments, func(c models.Comment) bool { return is(c.Image) }) ||
+		slices.ContainsFunc(m.groupPosts, func(p models.GroupPost) bool { return p.Image == path }) ||
+		slices.ContainsFunc(m.groupComments, func(c models.GroupComment) bool { return c.Image == path }) ||
+		slices.ContainsFunc(m.attachments, func(r attachmentRow) bool { return r.attachment.Path == path })
+}
@@ -0,0 +1,127 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type audienceStore struct{ *memory }
+
+func (s *audienceStore) List(ctx context.Context, userID int) ([]models.AudienceList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := []models.AudienceList{}
+	for _, l := range s.audienceLists {
+		if l.UserID == userID {
+			l.MemberCount = s.memberCount(l.ID)
+			lists = append(lists, l)
+		}
+	}
+	sort.SliceStable(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return lists, nil
+}
+
+func (s *audienceStore) memberCount(listID int) int {
+	n := 0
+	for _, m := range s.listMembers {
+		if m.a == listID {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *audienceStore) Get(ctx context.Context, id int) (models.AudienceList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.audienceLists, func(l models.AudienceList) bool { return l.ID == id })
+	if i < 0 {
+		return models.AudienceList{}, store.ErrNotFound
+	}
+	l := s.audienceLists[i]
+	l.Members = []models.UserSummary{}
+	for _, m := range s.listMembers {
+		if m.a == id {
+			l.Members = append(l.Members, *s.summary(m.b))
+		}
+	}
+	sort.SliceStable(l.Members, func(i, j int) bool { return l.Members[i].Username < l.Members[j].Username })
+	l.MemberCount = len(l.Members)
+	return l, nil
+}
+
+func (s *audienceStore) Create(ctx context.Context, l *models.AudienceList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.audienceLists, func(o models.AudienceList) bool { return o.UserID == l.UserID && o.Name == l.Name }) {
+		return store.ErrConflict
+	}
+	l.ID, l.CreatedAt, l.MemberCount, l.Members = s.nextID(), now(), 0, []models.UserSummary{}
+	stored := *l
+	stored.Members = nil
+	s.audienceLists = append(s.audienceLists, stored)
+	return nil
+}
+
+func (s *audienceStore) Rename(ctx context.Context, id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.audienceLists, func(l models.AudienceList) bool { return l.ID == id })
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	l := s.audienceLists[i]
+	if slices.ContainsFunc(s.audienceLists, func(o models.AudienceList) bool { return o.UserID == l.UserID && o.Name == name && o.ID != id }) {
+		return store.ErrConflict
+	}
+	s.audienceLists[i].Name = name
+	return nil
+}
+
+func (s *audienceStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.audienceLists, func(l models.AudienceList) bool { return l.ID == id })
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.audienceLists = slices.Delete(s.audienceLists, i, i+1)
+	s.listMembers = slices.DeleteFunc(s.listMembers, func(m pair) bool { return m.a == id })
+	s.postLists = slices.DeleteFunc(s.postLists, func(l pair) bool { return l.b == id })
+	return nil
+}
+
+func (s *audienceStore) AddMembers(ctx context.Context, listID int, userIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range userIDs {
+		if _, ok := s.user(id); !ok {
+			return store.ErrNotFound
+		}
+	}
+	for _, id := range userIDs {
+		if !slices.Contains(s.listMembers, pair{listID, id}) {
+			s.listMembers = append(s.listMembers, pair{listID, id})
+		}
+	}
+	return nil
+}
+
+func (s *audienceStore) RemoveMember(ctx context.Context, listID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.listMembers)
+	s.listMembers = slices.DeleteFunc(s.listMembers, func(m pair) bool { return m == pair{listID, userID} })
+	return len(s.listMembers) < n, nil
+}
@@ -0,0 +1,95 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type chatStore struct{ *memory }
+
+func (s *chatStore) SaveMessage(ctx context.Context, msg *models.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg.ID = s.nextID()
+	msg.IsRead = false
+	s.messages = append(s.messages, *msg)
+	return nil
+}
+
+func (s *chatStore) Conversation(ctx context.Context, userID, otherID int, page store.Page) ([]models.Message, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, msg := range s.messages {
+		if (msg.SenderID == userID && msg.ReceiverID == otherID) || (msg.SenderID == otherID && msg.ReceiverID == userID) {
+			messages = append(messages, msg)
+		}
+	}
+	messages, next := paginate(messages, page, func(m models.Message) store.Cursor {
+		return store.Cursor{Time: time.Unix(m.CreatedAt, 0), ID: m.ID}
+	}, true)
+	slices.Reverse(messages)
+	return messages, next, nil
+}
+
+func (s *chatStore) MarkRead(ctx context.Context, senderID, receiverID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, msg := range s.messages {
+		if msg.SenderID == senderID && msg.ReceiverID == receiverID {
+			s.messages[i].IsRead = true
+		}
+	}
+	return nil
+}
+
+func (s *chatStore) UnreadCount(ctx context.Context, receiverID, senderID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, msg := range s.messages {
+		if msg.ReceiverID == receiverID && msg.SenderID == senderID && !msg.IsRead {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *chatStore) SaveGroupMessage(ctx context.Context, msg *models.GroupMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg.ID = s.nextID()
+	u, _ := s.user(msg.SenderID)
+	msg.SenderName = deref(u.Username)
+	if msg.SenderName == "" {
+		msg.SenderName = u.FirstName
+	}
+	msg.Mentions = nil
+	s.groupMessages = append(s.groupMessages, *msg)
+	msg.Mentions = s.saveMentions(mentionRow{groupMessageID: msg.ID}, msg.Content)
+	return nil
+}
+
+func (s *chatStore) GroupMessages(ctx context.Context, groupID int, page store.Page) ([]models.GroupMessage, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []models.GroupMessage{}
+	for _, msg := range s.groupMessages {
+		if msg.GroupID == groupID {
+			msg.Mentions = s.mentionsOf(mentionRow{groupMessageID: msg.ID})
+			messages = append(messages, msg)
+		}
+	}
+	messages, next := paginate(messages, page, func(m models.GroupMessage) store.Cursor {
+		return store.Cursor{Time: time.Unix(m.CreatedAt, 0), ID: m.ID}
+	}, true)
+	slices.Reverse(messages)
+	return messages, next, nil
+}
@@ -0,0 +1,183 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type collectionStore struct{ *memory }
+
+func (s *collectionStore) List(ctx context.Context, userID int) ([]models.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ensureDefault(userID)
+	collections := []models.Collection{}
+	for _, c := range s.collections {
+		if c.UserID == userID {
+			collections = append(collections, c)
+		}
+	}
+	sort.SliceStable(collections, func(i, j int) bool {
+		if collections[i].IsDefault != collections[j].IsDefault {
+			return collections[i].IsDefault
+		}
+		return collections[i].Name < collections[j].Name
+	})
+	return collections, nil
+}
+
+func (s *collectionStore) ensureDefault(userID int) {
+	if !slices.ContainsFunc(s.collections, func(c models.Collection) bool { return c.UserID == userID && c.IsDefault }) {
+		s.collections = append(s.collections, models.Collection{
+			ID: s.nextID(), UserID: userID, Name: models.DefaultCollection, IsDefault: true, CreatedAt: now(),
+		})
+	}
+}
+
+func (s *collectionStore) Get(ctx context.Context, id int) (models.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.collections {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return models.Collection{}, store.ErrNotFound
+}
+
+func (s *collectionStore) Create(ctx context.Context, c *models.Collection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ensureDefault(c.UserID)
+	if slices.ContainsFunc(s.collections, func(o models.Collection) bool { return o.UserID == c.UserID && o.Name == c.Name }) {
+		return store.ErrConflict
+	}
+	c.ID, c.IsDefault, c.CreatedAt = s.nextID(), false, now()
+	s.collections = append(s.collections, *c)
+	return nil
+}
+
+func (s *collectionStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.collections, func(c models.Collection) bool { return c.ID == id })
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.collections = slices.Delete(s.collections, i, i+1)
+	s.items = slices.DeleteFunc(s.items, func(item models.CollectionItem) bool { return item.CollectionID == id })
+	return nil
+}
+
+func (s *collectionStore) Add(ctx context.Context, item *models.CollectionItem, viewerID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.canSee(*item, viewerID) {
+		return false, store.ErrNotFound
+	}
+	position := 0
+	for _, saved := range s.items {
+		if saved.CollectionID != item.CollectionID {
+			continue
+		}
+		if intIs(saved.PostID, deref(item.PostID)) || intIs(saved.GroupPostID, deref(item.GroupPostID)) {
+			*item = saved
+			return false, nil
+		}
+		position = max(position, saved.Position)
+	}
+	item.ID, item.Position, item.SavedAt = s.nextID(), position+1, now()
+	item.Post, item.GroupPost = nil, nil
+	s.items = append(s.items, *item)
+	return true, nil
+}
+
+// canSee is the visibility of the saved post, the rules of Items
+func (s *collectionStore) canSee(item models.CollectionItem, viewerID int) bool {
+	if item.GroupPostID != nil {
+		i := slices.IndexFunc(s.groupPosts, func(p models.GroupPost) bool { return p.ID == *item.GroupPostID })
+		return i >= 0 && s.isMember(s.groupPosts[i].GroupID, viewerID)
+	}
+	i := slices.IndexFunc(s.posts, func(p models.Post) bool { return p.ID == *item.PostID })
+	return i >= 0 && (&postStore{s.memory}).canView(s.posts[i], viewerID)
+}
+
+func (s *collectionStore) Remove(ctx context.Context, collectionID, itemID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.items, func(item models.CollectionItem) bool {
+		return item.ID == itemID && item.CollectionID == collectionID
+	})
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.items = slices.Delete(s.items, i, i+1)
+	return nil
+}
+
+func (s *collectionStore) Reorder(ctx context.Context, collectionID int, itemIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := []int{}
+	for _, item := range s.sorted(collectionID) {
+		current = append(current, item.ID)
+	}
+	order, err := store.Reordered(current, itemIDs)
+	if err != nil {
+		return err
+	}
+	for i := range s.items {
+		if s.items[i].CollectionID == collectionID {
+			s.items[i].Position = slices.Index(order, s.items[i].ID) + 1
+		}
+	}
+	return nil
+}
+
+// sorted returns the items of the collection in position order
+func (s *collectionStore) sorted(collectionID int) []models.CollectionItem {
+	items := []models.CollectionItem{}
+	for _, item := range s.items {
+		if item.CollectionID == collectionID {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items
+}
+
+func (s *collectionStore) Items(ctx context.Context, collectionID, viewerID int) ([]models.CollectionItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := &postStore{s.memory}
+	items := []models.CollectionItem{}
+	for _, item := range s.sorted(collectionID) {
+		if !s.canSee(item, viewerID) {
+			continue
+		}
+		if item.PostID != nil {
+			i := slices.IndexFunc(s.posts, func(p models.Post) bool { return p.ID == *item.PostID })
+			post := posts.withAuthor(s.posts[i])
+			item.Post = &post
+		} else {
+			i := slices.IndexFunc(s.groupPosts, func(p models.GroupPost) bool { return p.ID == *item.GroupPostID })
+			post := s.groupPosts[i]
+			post.Author = s.summary(post.UserID)
+			post.Attachments = s.attachmentsOf(attachmentRow{groupPostID: post.ID})
+			item.GroupPost = &post
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+func (s *postStore) Drafts(ctx context.Context, authorID int) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter(func(p models.Post) bool {
+		return p.UserID == authorID && p.GroupID == nil && p.Status != models.PostPublished
+	}), nil
+}
+
+func (s *postStore) UpdateDraft(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.unpublished(post.ID)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	p := &s.posts[i]
+	p.Content, p.Format, p.ContentHTML = post.Content, post.Format, post.ContentHTML
+	p.Image, p.Privacy, p.Status, p.PublishAt = post.Image, post.Privacy, post.Status, post.PublishAt
+	if p.Status != models.PostScheduled {
+		p.PublishAt = nil
+	}
+	s.clearAudience(p.ID)
+	s.saveAudience(post)
+	s.saveTags(p.ID, 0, post.Content)
+	post.Mentions = s.saveMentions(mentionRow{postID: p.ID}, post.Content)
+	s.saveAttachments(attachmentRow{postID: p.ID}, post.Attachments)
+	post.UserID, post.CreatedAt, post.PublishAt = p.UserID, p.CreatedAt, p.PublishAt
+	return nil
+}
+
+func (s *postStore) Publish(ctx context.Context, id int) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.unpublished(id)
+	if i < 0 {
+		return models.Post{}, store.ErrNotFound
+	}
+	s.publish(i)
+	return s.withAuthor(s.posts[i]), nil
+}
+
+func (s *postStore) PublishDue(ctx context.Context, at time.Time) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := []models.Post{}
+	for i, p := range s.posts {
+		if p.Status == models.PostScheduled && p.PublishAt != nil && !p.PublishAt.After(at) {
+			s.publish(i)
+			posts = append(posts, s.withAuthor(s.posts[i]))
+		}
+	}
+	return posts, nil
+}
+
+// unpublished is the index of the draft or scheduled post, -1 when there is none
+func (s *postStore) unpublished(id int) int {
+	return slices.IndexFunc(s.posts, func(p models.Post) bool {
+		return p.ID == id && p.GroupID == nil && p.Status != models.PostPublished
+	})
+}
+
+// publish is sqlstore's publish: the post and its tags are dated now
+func (s *postStore) publish(i int) {
+	p := &s.posts[i]
+	p.Status, p.PublishAt, p.CreatedAt = models.PostPublished, nil, now()
+	for j := range s.tags {
+		if s.tags[j].postID == p.ID {
+			s.tags[j].taggedAt = now()
+		}
+	}
+}
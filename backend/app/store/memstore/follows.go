@@ -0,0 +1,180 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type followStore struct{ *memory }
+
+func (s *followStore) IsFollowing(ctx context.Context, followerID, followedID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isFollowing(followerID, followedID), nil
+}
+
+func (s *followStore) Follow(ctx context.Context, followerID, followedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isFollowing(followerID, followedID) {
+		s.followers = append(s.followers, pair{followerID, followedID})
+	}
+	return nil
+}
+
+func (s *followStore) Unfollow(ctx context.Context, followerID, followedID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.followers {
+		if f.a == followerID && f.b == followedID {
+			s.followers = append(s.followers[:i], s.followers[i+1:]...)
+			s.visibility = slices.DeleteFunc(s.visibility, func(v pair) bool {
+				return v.b == followerID && slices.ContainsFunc(s.posts, func(p models.Post) bool { return p.ID == v.a && p.UserID == followedID })
+			})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *followStore) Followers(ctx context.Context, userID int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, f := range s.followers {
+		if f.b == userID {
+			u, _ := s.user(f.a)
+			users = append(users, listed(u))
+		}
+	}
+	sortUsers(users)
+	return users, nil
+}
+
+func (s *followStore) Following(ctx context.Context, userID int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, f := range s.followers {
+		if f.a == userID {
+			u, _ := s.user(f.b)
+			users = append(users, listed(u))
+		}
+	}
+	sortUsers(users)
+	return users, nil
+}
+
+func (s *followStore) Counts(ctx context.Context, userID int) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	followers, following := 0, 0
+	for _, f := range s.followers {
+		if f.b == userID {
+			followers++
+		}
+		if f.a == userID {
+			following++
+		}
+	}
+	return followers, following, nil
+}
+
+func (s *followStore) CreateRequest(ctx context.Context, requesterID, targetID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// same as the UNIQUE (userToFollow_id, requester_id) constraint
+	for _, r := range s.followRequests {
+		if r.requesterID == requesterID && r.targetID == targetID {
+			return 0, store.ErrConflict
+		}
+	}
+	row := followRequestRow{id: s.nextID(), requesterID: requesterID, targetID: targetID, status: "pending", createdAt: now()}
+	s.followRequests = append(s.followRequests, row)
+	return int64(row.id), nil
+}
+
+func (s *followStore) HasPendingRequest(ctx context.Context, requesterID, targetID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.followRequests {
+		if r.requesterID == requesterID && r.targetID == targetID && r.status == "pending" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *followStore) PendingRequests(ctx context.Context, targetID int) ([]models.FollowRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := []models.FollowRequest{}
+	for _, r := range s.followRequests {
+		if r.targetID != targetID || r.status != "pending" {
+			continue
+		}
+		u, _ := s.user(r.requesterID)
+		name := deref(u.Username)
+		if name == "" {
+			name = u.FirstName
+		}
+		requests = append(requests, models.FollowRequest{
+			ID:           r.id,
+			RequesterID:  r.requesterID,
+			SenderName:   name,
+			SenderAvatar: u.Avatar,
+			Status:       r.status,
+			CreatedAt:    r.createdAt,
+		})
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].ID > requests[j].ID })
+	return requests, nil
+}
+
+func (s *followStore) ResolveRequest(ctx context.Context, requestID, targetID int, approve bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.followRequests {
+		if r.id != requestID || r.targetID != targetID {
+			continue
+		}
+		if r.status != "pending" {
+			return r.requesterID, store.ErrConflict
+		}
+		if approve && !s.isFollowing(r.requesterID, targetID) {
+			s.followers = append(s.followers, pair{r.requesterID, targetID})
+		}
+		s.deleteNotifications(func(n models.Notification) bool {
+			return n.FollowRequestID != nil && *n.FollowRequestID == int64(requestID)
+		})
+		s.followRequests = append(s.followRequests[:i], s.followRequests[i+1:]...)
+		return r.requesterID, nil
+	}
+	return 0, store.ErrNotFound
+}
+
+func (s *followStore) CancelRequest(ctx context.Context, requesterID, targetID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.followRequests {
+		if r.requesterID == requesterID && r.targetID == targetID && r.status == "pending" {
+			s.deleteNotifications(func(n models.Notification) bool {
+				return n.UserID == targetID && n.Type == "follow_request" && intIs(n.SenderID, requesterID)
+			})
+			s.followRequests = append(s.followRequests[:i], s.followRequests[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
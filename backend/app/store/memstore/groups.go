@@ -0,0 +1,502 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type groupStore struct{ *memory }
+
+func (s *groupStore) Create(ctx context.Context, g *models.Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g.ID = s.nextID()
+	g.CreatedAt = time.Now().Unix()
+	s.groups = append(s.groups, models.Group{
+		ID: g.ID, Groupname: g.Groupname, Title: g.Title, Description: g.Description,
+		CreatorID: g.CreatorID, CreatedAt: g.CreatedAt,
+	})
+	s.members = append(s.members, memberRow{groupID: g.ID, userID: g.CreatorID, role: "creator", joinedAt: g.CreatedAt})
+
+	g.MemberCount = 1
+	g.IsMember = true
+	g.IsCreator = true
+	return nil
+}
+
+// view fills the member count and the flags that depend on who is looking
+func (s *groupStore) view(g models.Group, viewerID int) models.Group {
+	for _, m := range s.members {
+		if m.groupID == g.ID {
+			g.MemberCount++
+		}
+	}
+	g.IsMember = s.isMember(g.ID, viewerID)
+	g.IsCreator = g.CreatorID == viewerID
+	for _, inv := range s.invites {
+		if inv.groupID == g.ID && inv.userID == viewerID && inv.status == "pending" {
+			g.IsInvited = true
+		}
+	}
+	for _, req := range s.joinRequests {
+		if req.groupID == g.ID && req.userID == viewerID && req.status == "pending" {
+			g.HasRequested = true
+		}
+	}
+	return g
+}
+
+func (s *groupStore) List(ctx context.Context, viewerID int) ([]models.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := []models.Group{}
+	for i := len(s.groups) - 1; i >= 0; i-- {
+		groups = append(groups, s.view(s.groups[i], viewerID))
+	}
+	return groups, nil
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID, viewerID int) (models.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.groups {
+		if g.ID == groupID {
+			g = s.view(g, viewerID)
+			u, _ := s.user(g.CreatorID)
+			g.Creator = &models.User{ID: g.CreatorID, Username: u.Username, Avatar: u.Avatar}
+			return g, nil
+		}
+	}
+	return models.Group{}, store.ErrNotFound
+}
+
+func (s *groupStore) MemberGroups(ctx context.Context, userID int) ([]models.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := []models.Group{}
+	for _, g := range s.groups {
+		if s.isMember(g.ID, userID) {
+			groups = append(groups, models.Group{ID: g.ID, Groupname: g.Groupname})
+		}
+	}
+	return groups, nil
+}
+
+func (s *groupStore) IsMember(ctx context.Context, groupID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isMember(groupID, userID), nil
+}
+
+func (s *groupStore) Members(ctx context.Context, groupID int) ([]models.GroupMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := []models.GroupMember{}
+	for _, m := range s.members {
+		if m.groupID != groupID {
+			continue
+		}
+		u, _ := s.user(m.userID)
+		members = append(members, models.GroupMember{
+			ID: m.userID, Username: deref(u.Username), Avatar: deref(u.Avatar), Role: m.role, JoinedAt: m.joinedAt,
+		})
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].JoinedAt < members[j].JoinedAt })
+	return members, nil
+}
+
+func (s *groupStore) MemberIDs(ctx context.Context, groupID, excludeUserID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memberIDs(groupID, excludeUserID), nil
+}
+
+func (s *groupStore) memberIDs(groupID, excludeUserID int) []int {
+	var ids []int
+	for _, m := range s.members {
+		if m.groupID == groupID && m.userID != excludeUserID {
+			ids = append(ids, m.userID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *groupStore) AddMember(ctx context.Context, groupID, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addMember(groupID, userID, role)
+}
+
+func (s *groupStore) addMember(groupID, userID int, role string) error {
+	if s.isMember(groupID, userID) {
+		return store.ErrConflict
+	}
+	s.members = append(s.members, memberRow{groupID: groupID, userID: userID, role: role, joinedAt: time.Now().Unix()})
+	return nil
+}
+
+func (s *groupStore) RemoveMember(ctx context.Context, groupID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.members {
+		if m.groupID == groupID && m.userID == userID {
+			s.members = append(s.members[:i], s.members[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ---------------------------------------------------------------------------------------------
+// invitations
+
+func (s *groupStore) HasPendingInvite(ctx context.Context, groupID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pendingInvite(groupID, userID) >= 0, nil
+}
+
+func (s *groupStore) pendingInvite(groupID, userID int) int {
+	for i, inv := range s.invites {
+		if inv.groupID == groupID && inv.userID == userID && inv.status == "pending" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *groupStore) CreateInvite(ctx context.Context, groupID, inviterID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pendingInvite(groupID, userID) >= 0 {
+		return store.ErrConflict
+	}
+	s.invites = append(s.invites, inviteRow{groupID: groupID, inviterID: inviterID, userID: userID, status: "pending"})
+	return nil
+}
+
+func (s *groupStore) AcceptInvite(ctx context.Context, groupID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pendingInvite(groupID, userID) < 0 {
+		return store.ErrNotFound
+	}
+	if err := s.addMember(groupID, userID, "member"); err != nil {
+		return err
+	}
+	s.clearInvitation(groupID, userID)
+	return nil
+}
+
+func (s *groupStore) DeclineInvite(ctx context.Context, groupID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pendingInvite(groupID, userID) < 0 {
+		return store.ErrNotFound
+	}
+	s.clearInvitation(groupID, userID)
+	return nil
+}
+
+func (s *groupStore) clearInvitation(groupID, userID int) {
+	s.deleteNotifications(func(n models.Notification) bool {
+		return n.UserID == userID && n.Type == "group_invitation" && intIs(n.GroupID, groupID)
+	})
+	kept := s.invites[:0]
+	for _, inv := range s.invites {
+		if inv.groupID != groupID || inv.userID != userID {
+			kept = append(kept, inv)
+		}
+	}
+	s.invites = kept
+}
+
+// ---------------------------------------------------------------------------------------------
+// join requests
+
+func (s *groupStore) HasPendingJoinRequest(ctx context.Context, groupID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.joinRequests {
+		if req.groupID == groupID && req.userID == userID && req.status == "pending" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *groupStore) CreateJoinRequest(ctx context.Context, groupID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.joinRequests = append(s.joinRequests, joinRequestRow{
+		id: s.nextID(), groupID: groupID, userID: userID, status: "pending", createdAt: time.Now().Unix(),
+	})
+	return nil
+}
+
+func (s *groupStore) JoinRequests(ctx context.Context, groupID int) ([]models.GroupJoinRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := []models.GroupJoinRequest{}
+	for i := len(s.joinRequests) - 1; i >= 0; i-- {
+		req := s.joinRequests[i]
+		if req.groupID != groupID || req.status != "pending" {
+			continue
+		}
+		u, _ := s.user(req.userID)
+		requests = append(requests, models.GroupJoinRequest{
+			ID: req.id, UserID: req.userID, Username: deref(u.Username), Avatar: deref(u.Avatar), CreatedAt: req.createdAt,
+		})
+	}
+	return requests, nil
+}
+
+func (s *groupStore) JoinRequestStatus(ctx context.Context, groupID, userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.joinRequests) - 1; i >= 0; i-- {
+		req := s.joinRequests[i]
+		if req.groupID == groupID && req.userID == userID {
+			return req.status, nil
+		}
+	}
+	return "", store.ErrNotFound
+}
+
+func (s *groupStore) ApproveJoinRequest(ctx context.Context, groupID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := false
+	for _, req := range s.joinRequests {
+		if req.groupID == groupID && req.userID == userID && req.status == "pending" {
+			pending = true
+		}
+	}
+	if !pending {
+		return store.ErrNotFound
+	}
+	if err := s.addMember(groupID, userID, "member"); err != nil {
+		return err
+	}
+	s.clearJoinRequest(groupID, userID)
+	return nil
+}
+
+func (s *groupStore) DeleteJoinRequest(ctx context.Context, groupID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clearJoinRequest(groupID, userID)
+	return nil
+}
+
+func (s *groupStore) clearJoinRequest(groupID, userID int) {
+	s.deleteNotifications(func(n models.Notification) bool {
+		return n.Type == "group_join_request" && intIs(n.GroupID, groupID) && intIs(n.SenderID, userID)
+	})
+	kept := s.joinRequests[:0]
+	for _, req := range s.joinRequests {
+		if req.groupID != groupID || req.userID != userID {
+			kept = append(kept, req)
+		}
+	}
+	s.joinRequests = kept
+}
+
+// ---------------------------------------------------------------------------------------------
+// posts and comments
+
+func (s *groupStore) CreatePost(ctx context.Context, post *models.GroupPost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post.ID = s.nextID()
+	post.CreatedAt = time.Now().Unix()
+	post.Author = s.summary(post.UserID)
+	s.saveTags(0, post.ID, post.Content)
+	s.saveAttachments(attachmentRow{groupPostID: post.ID}, post.Attachments)
+	s.savePoll(0, post.ID, post.Poll)
+	stored := *post
+	stored.Comments, stored.Attachments, stored.Poll = nil, nil, nil
+	s.groupPosts = append(s.groupPosts, stored)
+	return nil
+}
+
+func (s *groupStore) Posts(ctx context.Context, groupID int, page store.Page) ([]models.GroupPost, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := []models.GroupPost{}
+	for i := len(s.groupPosts) - 1; i >= 0; i-- {
+		if p := s.groupPosts[i]; p.GroupID == groupID {
+			p.Author = s.summary(p.UserID)
+			p.Attachments = s.attachmentsOf(attachmentRow{groupPostID: p.ID})
+			posts = append(posts, p)
+		}
+	}
+	posts, next := paginate(posts, page, func(p models.GroupPost) store.Cursor {
+		return store.Cursor{Time: time.Unix(p.CreatedAt, 0), ID: p.ID}
+	}, true)
+	return posts, next, nil
+}
+
+func (s *groupStore) PostInGroup(ctx context.Context, postID, groupID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.groupPosts {
+		if p.ID == postID && p.GroupID == groupID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *groupStore) CreateComment(ctx context.Context, comment *models.GroupComment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.ID = s.nextID()
+	comment.CreatedAt = time.Now().Unix()
+	comment.Author = s.summary(comment.UserID)
+	s.groupComments = append(s.groupComments, *comment)
+	return nil
+}
+
+func (s *groupStore) Comments(ctx context.Context, postID int, page store.Page) ([]models.GroupComment, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := []models.GroupComment{}
+	for _, c := range s.groupComments {
+		if c.PostID == postID {
+			c.Author = s.summary(c.UserID)
+			comments = append(comments, c)
+		}
+	}
+	comments, next := paginate(comments, page, func(c models.GroupComment) store.Cursor {
+		return store.Cursor{Time: time.Unix(c.CreatedAt, 0), ID: c.ID}
+	}, false)
+	return comments, next, nil
+}
+
+// ---------------------------------------------------------------------------------------------
+// events
+
+func (s *groupStore) CreateEvent(ctx context.Context, event *models.GroupEvent) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var groupName string
+	found := false
+	for _, g := range s.groups {
+		if g.ID == event.GroupID {
+			groupName, found = g.Groupname, true
+		}
+	}
+	if !found {
+		return nil, store.ErrNotFound
+	}
+
+	event.ID = s.nextID()
+	event.CreatedAt = time.Now().Unix()
+	u, _ := s.user(event.CreatorID)
+	event.Creator = &models.User{ID: event.CreatorID, Username: u.Username, Avatar: u.Avatar}
+
+	stored := *event
+	stored.Creator = nil
+	s.events = append(s.events, stored)
+
+	memberIDs := s.memberIDs(event.GroupID, 0)
+	for _, memberID := range memberIDs {
+		s.notifications = append(s.notifications, models.Notification{
+			ID:         s.nextID(),
+			UserID:     memberID,
+			Type:       "event_created",
+			SenderID:   ptr(event.CreatorID),
+			SenderName: u.Username,
+			GroupID:    ptr(event.GroupID),
+			GroupName:  ptr(groupName),
+			EventID:    ptr(event.ID),
+			EventTitle: ptr(event.Title),
+			EventDate:  ptr(event.EventDate),
+			CreatedAt:  time.Unix(event.CreatedAt, 0),
+		})
+	}
+	return memberIDs, nil
+}
+
+func (s *groupStore) Events(ctx context.Context, groupID, viewerID int, page store.Page) ([]models.GroupEvent, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []models.GroupEvent{}
+	for _, e := range s.events {
+		if e.GroupID != groupID {
+			continue
+		}
+		u, _ := s.user(e.CreatorID)
+		e.Creator = &models.User{ID: e.CreatorID, Username: u.Username, Avatar: u.Avatar}
+		e.Responses = []models.EventResponse{}
+		for _, r := range s.eventResponses {
+			if r.EventID != e.ID {
+				continue
+			}
+			switch r.Response {
+			case "going":
+				e.GoingCount++
+			case "not_going":
+				e.NotGoingCount++
+			}
+			if r.UserID == viewerID {
+				e.UserResponse = r.Response
+			}
+			responder, _ := s.user(r.UserID)
+			r.GroupID = groupID
+			r.User = &models.User{ID: r.UserID, Username: responder.Username, Avatar: responder.Avatar}
+			e.Responses = append(e.Responses, r)
+		}
+		events = append(events, e)
+	}
+	events, next := paginate(events, page, func(e models.GroupEvent) store.Cursor {
+		return store.Cursor{Time: time.Unix(e.EventDate, 0), ID: e.ID}
+	}, false)
+	return events, next, nil
+}
+
+func (s *groupStore) EventInGroup(ctx context.Context, eventID, groupID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.events {
+		if e.ID == eventID && e.GroupID == groupID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *groupStore) RespondToEvent(ctx context.Context, eventID, userID int, response string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	for i, r := range s.eventResponses {
+		if r.EventID == eventID && r.UserID == userID {
+			s.eventResponses[i].Response = response
+			s.eventResponses[i].CreatedAt = now
+			return nil
+		}
+	}
+	s.eventResponses = append(s.eventResponses, models.EventResponse{EventID: eventID, UserID: userID, Response: response, CreatedAt: now})
+	return nil
+}
@@ -0,0 +1,81 @@
+package memstore
+
+import (
+	"context"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type idempotencyStore struct{ *memory }
+
+func (s *idempotencyStore) Reserve(ctx context.Context, key *models.IdempotencyKey, now int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.findKey(key.UserID, key.Key); i >= 0 {
+		if s.idempotency[i].ExpiresAt > now {
+			return store.ErrConflict
+		}
+		s.idempotency = append(s.idempotency[:i], s.idempotency[i+1:]...)
+	}
+	key.Status = 0
+	s.idempotency = append(s.idempotency, *key)
+	return nil
+}
+
+func (s *idempotencyStore) Get(ctx context.Context, userID int, key string) (models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findKey(userID, key)
+	if i < 0 {
+		return models.IdempotencyKey{}, store.ErrNotFound
+	}
+	return s.idempotency[i], nil
+}
+
+func (s *idempotencyStore) Save(ctx context.Context, key models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.findKey(key.UserID, key.Key); i >= 0 {
+		row := &s.idempotency[i]
+		row.Status, row.ContentType, row.Body = key.Status, key.ContentType, key.Body
+	}
+	return nil
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, userID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.findKey(userID, key); i >= 0 {
+		s.idempotency = append(s.idempotency[:i], s.idempotency[i+1:]...)
+	}
+	return nil
+}
+
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.idempotency[:0]
+	for _, k := range s.idempotency {
+		if k.ExpiresAt > now {
+			kept = append(kept, k)
+		}
+	}
+	removed := len(s.idempotency) - len(kept)
+	s.idempotency = kept
+	return removed, nil
+}
+
+func (m *memory) findKey(userID int, key string) int {
+	for i, k := range m.idempotency {
+		if k.UserID == userID && k.Key == key {
+			return i
+		}
+	}
+	return -1
+}
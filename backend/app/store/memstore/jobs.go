@@ -0,0 +1,156 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type jobStore struct{ *memory }
+
+type jobRow struct {
+	job      models.Job
+	lockedAt int64
+}
+
+func (s *jobStore) Enqueue(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.UniqueKey != nil {
+		for _, row := range s.jobs {
+			active := row.job.Status == models.JobPending || row.job.Status == models.JobRunning
+			if active && row.job.UniqueKey != nil && *row.job.UniqueKey == *job.UniqueKey {
+				return store.ErrConflict
+			}
+		}
+	}
+
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+	job.ID = s.nextID()
+	job.Status = models.JobPending
+	job.Attempts = 0
+	job.UpdatedAt = job.CreatedAt
+	s.jobs = append(s.jobs, jobRow{job: *job})
+	return nil
+}
+
+func (s *jobStore) Claim(ctx context.Context, now int64) (models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// same order as the SQL one: earliest run_at first, then id
+	next := -1
+	for i, row := range s.jobs {
+		if row.job.Status != models.JobPending || row.job.RunAt > now {
+			continue
+		}
+		if next == -1 || row.job.RunAt < s.jobs[next].job.RunAt {
+			next = i
+		}
+	}
+	if next == -1 {
+		return models.Job{}, store.ErrNotFound
+	}
+
+	row := &s.jobs[next]
+	row.job.Status = models.JobRunning
+	row.job.Attempts++
+	row.job.UpdatedAt = now
+	row.lockedAt = now
+	return row.job, nil
+}
+
+func (s *jobStore) Complete(ctx context.Context, id int, now int64) error {
+	s.update(id, func(row *jobRow) {
+		row.job.Status = models.JobDone
+		row.job.UpdatedAt = now
+	})
+	return nil
+}
+
+func (s *jobStore) Retry(ctx context.Context, id int, now, runAt int64, lastError string) error {
+	s.update(id, func(row *jobRow) {
+		row.job.Status = models.JobPending
+		row.job.RunAt = runAt
+		row.job.LastError = &lastError
+		row.job.UpdatedAt = now
+	})
+	return nil
+}
+
+func (s *jobStore) Fail(ctx context.Context, id int, now int64, lastError string) error {
+	s.update(id, func(row *jobRow) {
+		row.job.Status = models.JobFailed
+		row.job.LastError = &lastError
+		row.job.UpdatedAt = now
+	})
+	return nil
+}
+
+func (s *jobStore) RequeueStale(ctx context.Context, lockedBefore int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requeued := 0
+	for i := range s.jobs {
+		if s.jobs[i].job.Status == models.JobRunning && s.jobs[i].lockedAt < lockedBefore {
+			s.jobs[i].job.Status = models.JobPending
+			s.jobs[i].lockedAt = 0
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (s *jobStore) DeleteFinished(ctx context.Context, before int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.jobs[:0]
+	for _, row := range s.jobs {
+		if row.job.Status == models.JobDone && row.job.UpdatedAt < before {
+			continue
+		}
+		kept = append(kept, row)
+	}
+	removed := len(s.jobs) - len(kept)
+	s.jobs = kept
+	return removed, nil
+}
+
+func (s *jobStore) List(ctx context.Context, status string, limit int) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []models.Job{}
+	for i := len(s.jobs) - 1; i >= 0 && len(jobs) < limit; i-- {
+		if status == "" || s.jobs[i].job.Status == status {
+			jobs = append(jobs, s.jobs[i].job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *jobStore) Counts(ctx context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]int{models.JobPending: 0, models.JobRunning: 0, models.JobDone: 0, models.JobFailed: 0}
+	for _, row := range s.jobs {
+		counts[row.job.Status]++
+	}
+	return counts, nil
+}
+
+func (s *jobStore) update(id int, fn func(row *jobRow)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.jobs {
+		if s.jobs[i].job.ID == id {
+			s.jobs[i].lockedAt = 0
+			fn(&s.jobs[i])
+			return
+		}
+	}
+}
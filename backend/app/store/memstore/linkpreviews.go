@@ -0,0 +1,47 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"social-network/app/models"
+)
+
+type linkPreviewStore struct{ *memory }
+
+func (s *linkPreviewStore) Get(ctx context.Context, urls []string) (map[string]models.LinkPreview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previews := map[string]models.LinkPreview{}
+	for _, u := range urls {
+		if p, ok := s.linkPreviews[u]; ok {
+			previews[u] = p
+		}
+	}
+	return previews, nil
+}
+
+func (s *linkPreviewStore) Save(ctx context.Context, p models.LinkPreview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the database keeps seconds
+	p.FetchedAt = p.FetchedAt.Truncate(time.Second)
+	s.linkPreviews[p.URL] = p
+	return nil
+}
+
+func (s *linkPreviewStore) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for u, p := range s.linkPreviews {
+		if p.FetchedAt.Unix() < before.Unix() {
+			delete(s.linkPreviews, u)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,190 @@
+// Package memstore is an in-memory implementation of the store interfaces.
+// It keeps everything in plain slices behind one mutex, which is plenty for
+// handler tests, and mirrors the behaviour of sqlstore (ordering, errors,
+// the notification clean-ups) closely enough for them to be swapped.
+package memstore
+
+import (
+	"sync"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+// New returns an empty set of stores sharing the same data
+func New() *store.Stores {
+	m := &memory{sessions: map[string]sessionRow{}, linkPreviews: map[string]models.LinkPreview{}}
+	return &store.Stores{
+		Users:         &userStore{m},
+		Posts:         &postStore{m},
+		Follows:       &followStore{m},
+		Groups:        &groupStore{m},
+		Chat:          &chatStore{m},
+		Notifications: &notificationStore{m},
+		Jobs:          &jobStore{m},
+		Idempotency:   &idempotencyStore{m},
+		Reactions:     &reactionStore{m},
+		Tags:          &tagStore{m},
+		Collections:   &collectionStore{m},
+		Polls:         &pollStore{m},
+		LinkPreviews:  &linkPreviewStore{m},
+		Audiences:     &audienceStore{m},
+	}
+}
+
+type memory struct {
+	mu  sync.Mutex
+	seq int
+
+	users          []userRow
+	sessions       map[string]sessionRow
+	posts          []models.Post
+	visibility     []pair // post id, user id
+	revisions      []models.PostRevision
+	comments       []models.Comment
+	reactions      []models.Reaction
+	tags           []tagRow
+	mentions       []mentionRow
+	attachments    []attachmentRow
+	collections    []models.Collection
+	items          []models.CollectionItem
+	polls          []models.Poll
+	pollVotes      []pollVote
+	followers      []pair // follower id, followed id
+	audienceLists  []models.AudienceList
+	listMembers    []pair // list id, user id
+	postLists      []pair // post id, list id
+	followRequests []followRequestRow
+
+	groups         []models.Group
+	members        []memberRow
+	invites        []inviteRow
+	joinRequests   []joinRequestRow
+	groupPosts     []models.GroupPost
+	groupComments  []models.GroupComment
+	events         []models.GroupEvent
+	eventResponses []models.EventResponse
+
+	messages      []models.Message
+	groupMessages []models.GroupMessage
+	notifications []models.Notification
+	jobs          []jobRow
+	idempotency   []models.IdempotencyKey
+	linkPreviews  map[string]models.LinkPreview // by url
+}
+
+type pair struct{ a, b int }
+
+type userRow struct {
+	user         models.User
+	passwordHash string
+}
+
+type sessionRow struct {
+	userID    int
+	expiresAt time.Time
+}
+
+type followRequestRow struct {
+	id          int
+	requesterID int
+	targetID    int
+	status      string
+	createdAt   time.Time
+}
+
+type memberRow struct {
+	groupID  int
+	userID   int
+	role     string
+	joinedAt int64
+}
+
+type inviteRow struct {
+	groupID   int
+	inviterID int
+	userID    int
+	status    string
+}
+
+type joinRequestRow struct {
+	id        int
+	groupID   int
+	userID    int
+	status    string
+	createdAt int64
+}
+
+// nextID hands out ids like an autoincrement column, shared by every table
+func (m *memory) nextID() int {
+	m.seq++
+	return m.seq
+}
+
+func (m *memory) user(id int) (models.User, bool) {
+	for _, row := range m.users {
+		if row.user.ID == id {
+			return row.user, true
+		}
+	}
+	return models.User{}, false
+}
+
+func (m *memory) summary(id int) *models.UserSummary {
+	u, _ := m.user(id)
+	return &models.UserSummary{ID: id, Username: deref(u.Username), Avatar: deref(u.Avatar)}
+}
+
+func (m *memory) isFollowing(followerID, followedID int) bool {
+	for _, f := range m.followers {
+		if f.a == followerID && f.b == followedID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memory) isMember(groupID, userID int) bool {
+	for _, row := range m.members {
+		if row.groupID == groupID && row.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteNotifications removes the notifications matching match and returns how many went
+func (m *memory) deleteNotifications(match func(n models.Notification) bool) int {
+	kept := m.notifications[:0]
+	removed := 0
+	for _, n := range m.notifications {
+		if match(n) {
+			removed++
+			continue
+		}
+		kept = append(kept, n)
+	}
+	m.notifications = kept
+	return removed
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func intIs(p *int, v int) bool {
+	return p != nil && *p == v
+}
+
+func stringIs(p *string, v string) bool {
+	return p != nil && *p == v
+}
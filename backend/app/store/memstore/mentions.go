@@ -0,0 +1,53 @@
+package memstore
+
+import (
+	"slices"
+
+	"social-network/app/mention"
+	"social-network/app/models"
+)
+
+// mentionRow is a mentions row, one of postID, commentID and groupMessageID is set
+type mentionRow struct {
+	mention        models.Mention
+	postID         int
+	commentID      int
+	groupMessageID int
+}
+
+// saveMentions is sqlstore's saveMentions for the row's ids, its mention is left empty
+func (m *memory) saveMentions(owner mentionRow, content string) []models.Mention {
+	m.mentions = slices.DeleteFunc(m.mentions, func(r mentionRow) bool { return r.sameOwner(owner) })
+	var mentions []models.Mention
+	for _, found := range mention.Parse(content) {
+		i := slices.IndexFunc(m.users, func(u userRow) bool { return deref(u.user.Username) == found.Username })
+		if i < 0 {
+			continue
+		}
+		found.UserID = m.users[i].user.ID
+		row := owner
+		row.mention = found
+		m.mentions = append(m.mentions, row)
+		mentions = append(mentions, found)
+	}
+	return mentions
+}
+
+// mentionsOf returns the mentions of the owner with the users' current username
+func (m *memory) mentionsOf(owner mentionRow) []models.Mention {
+	var mentions []models.Mention
+	for _, r := range m.mentions {
+		if r.sameOwner(owner) {
+			found := r.mention
+			u, _ := m.user(found.UserID)
+			found.Username = deref(u.Username)
+			mentions = append(mentions, found)
+		}
+	}
+	slices.SortFunc(mentions, func(a, b models.Mention) int { return a.Offset - b.Offset })
+	return mentions
+}
+
+func (r mentionRow) sameOwner(o mentionRow) bool {
+	return r.postID == o.postID && r.commentID == o.commentID && r.groupMessageID == o.groupMessageID
+}
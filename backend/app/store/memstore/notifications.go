@@ -0,0 +1,105 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type notificationStore struct{ *memory }
+
+func (s *notificationStore) Create(ctx context.Context, n *models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.create(n)
+	return nil
+}
+
+func (s *notificationStore) create(n *models.Notification) {
+	n.ID = s.nextID()
+	n.IsRead = false
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if n.SenderCount == 0 {
+		n.SenderCount = 1
+	}
+	s.notifications = append(s.notifications, *n)
+}
+
+func (s *notificationStore) Aggregate(ctx context.Context, n *models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.notifications {
+		if existing.UserID != n.UserID || existing.Type != n.Type || existing.IsRead ||
+			!stringIs(existing.TargetType, deref(n.TargetType)) || !intIs(existing.TargetID, deref(n.TargetID)) {
+			continue
+		}
+		if n.CreatedAt.IsZero() {
+			n.CreatedAt = time.Now()
+		}
+		existing.SenderID, existing.SenderName, existing.SenderAvatar = n.SenderID, n.SenderName, n.SenderAvatar
+		existing.SenderCount, existing.CreatedAt = n.SenderCount, n.CreatedAt
+		s.notifications[i] = existing
+		n.ID, n.IsRead = existing.ID, false
+		return nil
+	}
+	s.create(n)
+	return nil
+}
+
+// List returns the notifications of the user newest first
+func (s *notificationStore) List(ctx context.Context, userID int, page store.Page) ([]models.Notification, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for i := len(s.notifications) - 1; i >= 0; i-- {
+		if s.notifications[i].UserID == userID {
+			notifications = append(notifications, s.notifications[i])
+		}
+	}
+	notifications, next := paginate(notifications, page, func(n models.Notification) store.Cursor {
+		return store.Cursor{Time: n.CreatedAt, ID: n.ID}
+	}, true)
+	return notifications, next, nil
+}
+
+func (s *notificationStore) MarkAllRead(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].UserID == userID {
+			s.notifications[i].IsRead = true
+		}
+	}
+	return nil
+}
+
+func (s *notificationStore) Delete(ctx context.Context, id, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := s.deleteNotifications(func(n models.Notification) bool { return n.ID == id && n.UserID == userID })
+	return removed > 0, nil
+}
+
+func (s *notificationStore) DeleteMatching(ctx context.Context, filter store.NotificationFilter) error {
+	if filter == (store.NotificationFilter{}) {
+		return errors.New("empty notification filter")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteNotifications(func(n models.Notification) bool {
+		return (filter.UserID == 0 || n.UserID == filter.UserID) &&
+			(filter.SenderID == 0 || intIs(n.SenderID, filter.SenderID)) &&
+			(filter.GroupID == 0 || intIs(n.GroupID, filter.GroupID)) &&
+			(filter.Type == "" || n.Type == filter.Type)
+	})
+	return nil
+}
@@ -0,0 +1,31 @@
+package memstore
+
+import (
+	"slices"
+
+	"social-network/app/store"
+)
+
+// paginate sorts rows by their cursor (newest first when desc), drops the ones up to the page's
+// cursor and cuts the page, the in-memory counterpart of sqlstore's keyset
+func paginate[T any](rows []T, p store.Page, key func(T) store.Cursor, desc bool) ([]T, *store.Cursor) {
+	compare := func(a, b store.Cursor) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	}
+	slices.SortStableFunc(rows, func(a, b T) int {
+		if desc {
+			return compare(key(b), key(a))
+		}
+		return compare(key(a), key(b))
+	})
+	if p.After != nil {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			c := compare(key(row), *p.After)
+			return c == 0 || (c < 0) != desc
+		})
+	}
+	return store.Cut(rows, p, key)
+}
@@ -0,0 +1,129 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type pollStore struct{ *memory }
+
+// pollVote is a poll_votes row
+type pollVote struct {
+	pollID, optionID, userID int
+}
+
+// savePoll is sqlstore's savePoll, one of postID and groupPostID is set
+func (m *memory) savePoll(postID, groupPostID int, poll *models.Poll) {
+	if poll == nil {
+		return
+	}
+	poll.ID = m.nextID()
+	poll.PostID, poll.GroupPostID = nil, nil
+	if postID != 0 {
+		poll.PostID = &postID
+	} else {
+		poll.GroupPostID = &groupPostID
+	}
+	for i := range poll.Options {
+		poll.Options[i].ID, poll.Options[i].Votes = m.nextID(), 0
+	}
+	poll.Voters, poll.MyVotes = 0, []int{}
+
+	stored := *poll
+	stored.Options = slices.Clone(poll.Options)
+	m.polls = append(m.polls, stored)
+}
+
+// counted is the stored poll with its counts, the viewer's votes, its author and group
+func (m *memory) counted(p models.Poll, viewerID int) models.Poll {
+	p.Options = slices.Clone(p.Options)
+	p.MyVotes = []int{}
+	voters := map[int]bool{}
+	for i, o := range p.Options {
+		for _, v := range m.pollVotes {
+			if v.optionID != o.ID {
+				continue
+			}
+			p.Options[i].Votes++
+			voters[v.userID] = true
+			if v.userID == viewerID {
+				p.MyVotes = append(p.MyVotes, o.ID)
+			}
+		}
+	}
+	p.Voters = len(voters)
+
+	if p.PostID != nil {
+		if i := slices.IndexFunc(m.posts, func(post models.Post) bool { return post.ID == *p.PostID }); i >= 0 {
+			p.AuthorID = m.posts[i].UserID
+		}
+	} else if i := slices.IndexFunc(m.groupPosts, func(post models.GroupPost) bool { return post.ID == *p.GroupPostID }); i >= 0 {
+		groupID := m.groupPosts[i].GroupID
+		p.AuthorID, p.GroupID = m.groupPosts[i].UserID, &groupID
+	}
+	return p
+}
+
+func (s *pollStore) Get(ctx context.Context, id, viewerID int) (models.Poll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.polls {
+		if p.ID == id {
+			return s.counted(p, viewerID), nil
+		}
+	}
+	return models.Poll{}, store.ErrNotFound
+}
+
+func (s *pollStore) Polls(ctx context.Context, targetType string, postIDs []int, viewerID int) (map[int]models.Poll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	polls := map[int]models.Poll{}
+	for _, p := range s.polls {
+		postID := p.PostID
+		if targetType == models.TargetGroupPost {
+			postID = p.GroupPostID
+		}
+		if postID != nil && slices.Contains(postIDs, *postID) {
+			polls[*postID] = s.counted(p, viewerID)
+		}
+	}
+	return polls, nil
+}
+
+func (s *pollStore) Vote(ctx context.Context, pollID, userID int, optionIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pollVotes = slices.DeleteFunc(s.pollVotes, func(v pollVote) bool { return v.pollID == pollID && v.userID == userID })
+	for _, optionID := range optionIDs {
+		s.pollVotes = append(s.pollVotes, pollVote{pollID: pollID, optionID: optionID, userID: userID})
+	}
+	return nil
+}
+
+func (s *pollStore) Unvote(ctx context.Context, pollID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.pollVotes)
+	s.pollVotes = slices.DeleteFunc(s.pollVotes, func(v pollVote) bool { return v.pollID == pollID && v.userID == userID })
+	return len(s.pollVotes) < before, nil
+}
+
+func (s *pollStore) Voters(ctx context.Context, pollID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	voters := []int{}
+	for _, v := range s.pollVotes {
+		if v.pollID == pollID && !slices.Contains(voters, v.userID) {
+			voters = append(voters, v.userID)
+		}
+	}
+	return voters, nil
+}
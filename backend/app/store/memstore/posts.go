@@ -0,0 +1,365 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type postStore struct{ *memory }
+
+// now mimics CURRENT_TIMESTAMP: UTC with second precision
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (s *postStore) Create(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post.ID = s.nextID()
+	post.CreatedAt = now()
+	if post.Status == "" {
+		post.Status = models.PostPublished
+	}
+	if post.Status != models.PostScheduled {
+		post.PublishAt = nil
+	}
+	s.saveAudience(post)
+	s.saveTags(post.ID, 0, post.Content)
+	post.Mentions = s.saveMentions(mentionRow{postID: post.ID}, post.Content)
+	s.saveAttachments(attachmentRow{postID: post.ID}, post.Attachments)
+	s.savePoll(post.ID, 0, post.Poll)
+
+	stored := *post
+	stored.AllowedFollowers, stored.AudienceLists, stored.Original, stored.Mentions, stored.Attachments, stored.Poll = nil, nil, nil, nil, nil, nil
+	s.posts = append(s.posts, stored)
+	return nil
+}
+
+func (s *postStore) saveAudience(post *models.Post) {
+	if post.Privacy == models.PrivacyAlmostPrivate {
+		for _, followerID := range post.AllowedFollowers {
+			s.visibility = append(s.visibility, pair{post.ID, followerID})
+		}
+		for _, listID := range post.AudienceLists {
+			if !slices.Contains(s.postLists, pair{post.ID, listID}) {
+				s.postLists = append(s.postLists, pair{post.ID, listID})
+			}
+		}
+	}
+}
+
+func (s *postStore) clearAudience(postID int) {
+	s.visibility = slices.DeleteFunc(s.visibility, func(v pair) bool { return v.a == postID })
+	s.postLists = slices.DeleteFunc(s.postLists, func(l pair) bool { return l.a == postID })
+}
+
+func (s *postStore) PruneAudience(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the author of each almost_private post
+	authors := map[int]int{}
+	for _, p := range s.posts {
+		if p.Privacy == models.PrivacyAlmostPrivate {
+			authors[p.ID] = p.UserID
+		}
+	}
+	n := len(s.visibility) + len(s.postLists)
+	s.visibility = slices.DeleteFunc(s.visibility, func(v pair) bool {
+		author, ok := authors[v.a]
+		return !ok || !s.isFollowing(v.b, author)
+	})
+	s.postLists = slices.DeleteFunc(s.postLists, func(l pair) bool {
+		_, ok := authors[l.a]
+		return !ok
+	})
+	return n - len(s.visibility) - len(s.postLists), nil
+}
+
+func (s *postStore) Update(ctx context.Context, post *models.Post) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.posts {
+		if p.ID != post.ID || p.GroupID != nil {
+			continue
+		}
+		published := p.CreatedAt
+		if p.EditedAt != nil {
+			published = *p.EditedAt
+		}
+		editedAt := now()
+		s.revisions = append(s.revisions, models.PostRevision{
+			ID: s.nextID(), PostID: p.ID, Content: p.Content, Format: p.Format, ContentHTML: p.ContentHTML,
+			Image: p.Image, Privacy: p.Privacy, CreatedAt: published, ReplacedAt: editedAt,
+		})
+
+		p.Content, p.Format, p.ContentHTML = post.Content, post.Format, post.ContentHTML
+		p.Image, p.Privacy, p.EditedAt = post.Image, post.Privacy, &editedAt
+		s.posts[i] = p
+		s.clearAudience(p.ID)
+		s.saveAudience(post)
+		s.saveTags(p.ID, 0, post.Content)
+		post.Mentions = s.saveMentions(mentionRow{postID: p.ID}, post.Content)
+		dropped := []string{}
+		for _, a := range s.attachmentsOf(attachmentRow{postID: p.ID}) {
+			if !slices.ContainsFunc(post.Attachments, func(kept models.Attachment) bool { return kept.Path == a.Path }) {
+				dropped = append(dropped, a.Path)
+			}
+		}
+		s.saveAttachments(attachmentRow{postID: p.ID}, post.Attachments)
+
+		post.UserID, post.CreatedAt, post.EditedAt = p.UserID, p.CreatedAt, p.EditedAt
+		return slices.DeleteFunc(dropped, s.imageInUse), nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *postStore) Delete(ctx context.Context, id int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.posts, func(p models.Post) bool { return p.ID == id && p.GroupID == nil })
+	if i < 0 {
+		return nil, store.ErrNotFound
+	}
+	images := []string{}
+	addImage := func(image *string) {
+		if image != nil && *image != "" && !slices.Contains(images, *image) {
+			images = append(images, *image)
+		}
+	}
+	addImage(s.posts[i].Image)
+	for _, r := range s.revisions {
+		if r.PostID == id {
+			addImage(r.Image)
+		}
+	}
+	for _, c := range s.comments {
+		if c.PostID == id {
+			addImage(c.Image)
+		}
+	}
+	for _, r := range s.attachments {
+		if r.postID == id || slices.ContainsFunc(s.comments, func(c models.Comment) bool { return c.ID == r.commentID && c.PostID == id }) {
+			addImage(&r.attachment.Path)
+		}
+	}
+
+	s.posts = slices.Delete(s.posts, i, i+1)
+	s.revisions = slices.DeleteFunc(s.revisions, func(r models.PostRevision) bool { return r.PostID == id })
+	s.reactions = slices.DeleteFunc(s.reactions, func(r models.Reaction) bool {
+		if r.TargetType == models.TargetComment {
+			return slices.ContainsFunc(s.comments, func(c models.Comment) bool { return c.ID == r.TargetID && c.PostID == id })
+		}
+		return r.TargetType == models.TargetPost && r.TargetID == id
+	})
+	s.mentions = slices.DeleteFunc(s.mentions, func(m mentionRow) bool {
+		if m.commentID != 0 {
+			return slices.ContainsFunc(s.comments, func(c models.Comment) bool { return c.ID == m.commentID && c.PostID == id })
+		}
+		return m.postID == id
+	})
+	s.attachments = slices.DeleteFunc(s.attachments, func(r attachmentRow) bool {
+		if r.commentID != 0 {
+			return slices.ContainsFunc(s.comments, func(c models.Comment) bool { return c.ID == r.commentID && c.PostID == id })
+		}
+		return r.postID == id
+	})
+	s.comments = slices.DeleteFunc(s.comments, func(c models.Comment) bool { return c.PostID == id })
+	s.clearAudience(id)
+	s.tags = slices.DeleteFunc(s.tags, func(t tagRow) bool { return t.postID == id })
+	s.items = slices.DeleteFunc(s.items, func(item models.CollectionItem) bool { return intIs(item.PostID, id) })
+	if i := slices.IndexFunc(s.polls, func(p models.Poll) bool { return intIs(p.PostID, id) }); i >= 0 {
+		pollID := s.polls[i].ID
+		s.polls = slices.Delete(s.polls, i, i+1)
+		s.pollVotes = slices.DeleteFunc(s.pollVotes, func(v pollVote) bool { return v.pollID == pollID })
+	}
+	s.notifications = slices.DeleteFunc(s.notifications, func(n models.Notification) bool {
+		return n.PostID != nil && *n.PostID == id
+	})
+	return slices.DeleteFunc(images, s.imageInUse), nil
+}
+
+func (s *postStore) Revisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions := []models.PostRevision{}
+	for _, r := range s.revisions {
+		if r.PostID == postID {
+			revisions = append(revisions, r)
+		}
+	}
+	return revisions, nil
+}
+
+func (s *postStore) Get(ctx context.Context, id int) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.posts {
+		if p.ID == id {
+			return s.withAuthor(p), nil
+		}
+	}
+	return models.Post{}, store.ErrNotFound
+}
+
+// canView is the in-memory twin of sqlstore's visibleTo
+func (s *postStore) canView(p models.Post, viewerID int) bool {
+	if p.GroupID != nil || p.Status != models.PostPublished {
+		return false
+	}
+	switch {
+	case p.UserID == viewerID:
+		return true
+	case p.Privacy == models.PrivacyPublic:
+		return true
+	case p.Privacy == models.PrivacyAlmostPrivate:
+		for _, v := range s.visibility {
+			if v.a == p.ID && v.b == viewerID {
+				return true
+			}
+		}
+		for _, l := range s.postLists {
+			if l.a == p.ID && slices.Contains(s.listMembers, pair{l.b, viewerID}) && s.isFollowing(viewerID, p.UserID) {
+				return true
+			}
+		}
+	case p.Privacy == models.PrivacyPrivate:
+		return s.isFollowing(viewerID, p.UserID)
+	}
+	return false
+}
+
+func (s *postStore) CanView(ctx context.Context, postID, viewerID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.posts {
+		if p.ID == postID {
+			return s.canView(p, viewerID), nil
+		}
+	}
+	return false, nil
+}
+
+func (s *postStore) Viewers(ctx context.Context, postID int, userIDs []int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	viewers := []int{}
+	i := slices.IndexFunc(s.posts, func(p models.Post) bool { return p.ID == postID })
+	if i < 0 {
+		return viewers, nil
+	}
+	for _, id := range userIDs {
+		if _, ok := s.user(id); ok && s.canView(s.posts[i], id) {
+			viewers = append(viewers, id)
+		}
+	}
+	return viewers, nil
+}
+
+func (s *postStore) Feed(ctx context.Context, viewerID int, page store.Page) ([]models.Post, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := s.filter(func(p models.Post) bool { return s.canView(p, viewerID) })
+	posts, next := paginate(posts, page, func(p models.Post) store.Cursor {
+		return store.Cursor{Time: p.CreatedAt, ID: p.ID}
+	}, true)
+	return posts, next, nil
+}
+
+func (s *postStore) ByAuthor(ctx context.Context, authorID, viewerID int) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter(func(p models.Post) bool { return p.UserID == authorID && s.canView(p, viewerID) }), nil
+}
+
+// filter returns the matching posts newest first
+func (s *postStore) filter(keep func(p models.Post) bool) []models.Post {
+	posts := []models.Post{}
+	for _, p := range s.posts {
+		if keep(p) {
+			posts = append(posts, s.withAuthor(p))
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return posts
+}
+
+func (s *postStore) withAuthor(p models.Post) models.Post {
+	u, _ := s.user(p.UserID)
+	p.Username = deref(u.Username)
+	p.Avatar = deref(u.Avatar)
+	p.Mentions = s.mentionsOf(mentionRow{postID: p.ID})
+	p.Attachments = s.attachmentsOf(attachmentRow{postID: p.ID})
+	return p
+}
+
+func (s *postStore) CountByAuthor(ctx context.Context, authorID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, p := range s.posts {
+		if p.UserID == authorID && p.GroupID == nil && p.Status == models.PostPublished {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *postStore) CreateComment(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.ID = s.nextID()
+	comment.CreatedAt = now()
+	comment.Mentions = nil
+	s.saveAttachments(attachmentRow{commentID: comment.ID}, comment.Attachments)
+	stored := *comment
+	stored.Attachments = nil
+	s.comments = append(s.comments, stored)
+	s.saveMentions(mentionRow{commentID: comment.ID}, comment.Content)
+	*comment = s.withCommenter(*comment)
+	return nil
+}
+
+func (s *postStore) Comments(ctx context.Context, postID int, page store.Page) ([]models.Comment, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := []models.Comment{}
+	for _, c := range s.comments {
+		if c.PostID == postID {
+			comments = append(comments, s.withCommenter(c))
+		}
+	}
+	comments, next := paginate(comments, page, func(c models.Comment) store.Cursor {
+		return store.Cursor{Time: c.CreatedAt, ID: c.ID}
+	}, false)
+	return comments, next, nil
+}
+
+func (s *postStore) withCommenter(c models.Comment) models.Comment {
+	u, _ := s.user(c.UserID)
+	c.Username = deref(u.Username)
+	c.FirstName = u.FirstName
+	c.LastName = u.LastName
+	c.Avatar = u.Avatar
+	c.Mentions = s.mentionsOf(mentionRow{commentID: c.ID})
+	c.Attachments = s.attachmentsOf(attachmentRow{commentID: c.ID})
+	return c
+}
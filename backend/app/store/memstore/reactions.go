@@ -0,0 +1,100 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type reactionStore struct{ *memory }
+
+func (s *reactionStore) Target(ctx context.Context, targetType string, targetID int) (models.ReactionTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch targetType {
+	case models.TargetPost:
+		for _, p := range s.posts {
+			if p.ID == targetID && p.GroupID == nil {
+				return models.ReactionTarget{OwnerID: p.UserID, PostID: p.ID}, nil
+			}
+		}
+	case models.TargetComment:
+		for _, c := range s.comments {
+			if c.ID == targetID {
+				return models.ReactionTarget{OwnerID: c.UserID, PostID: c.PostID}, nil
+			}
+		}
+	case models.TargetGroupPost:
+		for _, p := range s.groupPosts {
+			if p.ID == targetID {
+				return models.ReactionTarget{OwnerID: p.UserID, PostID: p.ID, GroupID: p.GroupID}, nil
+			}
+		}
+	case models.TargetGroupComment:
+		for _, c := range s.groupComments {
+			if c.ID != targetID {
+				continue
+			}
+			for _, p := range s.groupPosts {
+				if p.ID == c.PostID {
+					return models.ReactionTarget{OwnerID: c.UserID, PostID: p.ID, GroupID: p.GroupID}, nil
+				}
+			}
+		}
+	}
+	return models.ReactionTarget{}, store.ErrNotFound
+}
+
+func (s *reactionStore) React(ctx context.Context, r models.Reaction) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.reactions {
+		if sameReactor(existing, r.UserID, r.TargetType, r.TargetID) {
+			s.reactions[i].Type = r.Type
+			return false, nil
+		}
+	}
+	s.reactions = append(s.reactions, r)
+	return true, nil
+}
+
+func (s *reactionStore) Unreact(ctx context.Context, userID int, targetType string, targetID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.reactions)
+	s.reactions = slices.DeleteFunc(s.reactions, func(r models.Reaction) bool {
+		return sameReactor(r, userID, targetType, targetID)
+	})
+	return len(s.reactions) < before, nil
+}
+
+func sameReactor(r models.Reaction, userID int, targetType string, targetID int) bool {
+	return r.UserID == userID && r.TargetType == targetType && r.TargetID == targetID
+}
+
+func (s *reactionStore) Summaries(ctx context.Context, targetType string, targetIDs []int, viewerID int) (map[int]models.ReactionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summaries := map[int]models.ReactionSummary{}
+	for _, r := range s.reactions {
+		if r.TargetType != targetType || !slices.Contains(targetIDs, r.TargetID) {
+			continue
+		}
+		summary, ok := summaries[r.TargetID]
+		if !ok {
+			summary.Reactions = map[string]int{}
+		}
+		summary.Reactions[r.Type]++
+		if r.UserID == viewerID {
+			summary.MyReaction = r.Type
+		}
+		summaries[r.TargetID] = summary
+	}
+	return summaries, nil
+}
@@ -0,0 +1,135 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"social-network/app/hashtag"
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type tagStore struct{ *memory }
+
+// tagRow is a post_tags row, one of postID and groupPostID is set
+type tagRow struct {
+	tag         string
+	postID      int
+	groupPostID int
+	taggedAt    time.Time
+}
+
+// saveTags is sqlstore's saveTags: the kept tags keep their date, the new ones are tagged now
+func (m *memory) saveTags(postID, groupPostID int, content string) {
+	tags := hashtag.Parse(content)
+	current := []string{}
+	m.tags = slices.DeleteFunc(m.tags, func(t tagRow) bool {
+		if t.postID != postID || t.groupPostID != groupPostID {
+			return false
+		}
+		current = append(current, t.tag)
+		return !slices.Contains(tags, t.tag)
+	})
+	for _, tag := range tags {
+		if !slices.Contains(current, tag) {
+			m.tags = append(m.tags, tagRow{tag: tag, postID: postID, groupPostID: groupPostID, taggedAt: now()})
+		}
+	}
+}
+
+func (s *tagStore) Posts(ctx context.Context, tag string, viewerID int, page store.Page) ([]models.Post, *store.Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := &postStore{s.memory}
+	tagged := posts.filter(func(p models.Post) bool {
+		return posts.canView(p, viewerID) && slices.ContainsFunc(s.tags, func(t tagRow) bool {
+			return t.postID == p.ID && t.tag == tag
+		})
+	})
+	tagged, next := paginate(tagged, page, func(p models.Post) store.Cursor {
+		return store.Cursor{Time: p.CreatedAt, ID: p.ID}
+	}, true)
+	return tagged, next, nil
+}
+
+func (s *tagStore) Trending(ctx context.Context, viewerID int, now time.Time, weights []float64, limit int) ([]models.TrendingTag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := &postStore{s.memory}
+	byTag := map[string]*models.TrendingTag{}
+	for _, t := range s.tags {
+		// sqlstore's window and whole hours, truncated toward zero
+		elapsed := now.Unix() - t.taggedAt.Unix()
+		if elapsed >= int64(len(weights))*3600 {
+			continue
+		}
+		visible := false
+		if t.postID != 0 {
+			i := slices.IndexFunc(s.posts, func(p models.Post) bool { return p.ID == t.postID })
+			visible = i >= 0 && posts.canView(s.posts[i], viewerID)
+		} else {
+			i := slices.IndexFunc(s.groupPosts, func(p models.GroupPost) bool { return p.ID == t.groupPostID })
+			visible = i >= 0 && s.isMember(s.groupPosts[i].GroupID, viewerID)
+		}
+		if !visible {
+			continue
+		}
+		tag, ok := byTag[t.tag]
+		if !ok {
+			tag = &models.TrendingTag{Tag: t.tag}
+			byTag[t.tag] = tag
+		}
+		tag.Uses++
+		if age := int(elapsed / 3600); age >= 0 {
+			tag.Score += weights[age]
+		}
+	}
+
+	trending := []models.TrendingTag{}
+	for _, t := range byTag {
+		trending = append(trending, *t)
+	}
+	slices.SortFunc(trending, func(a, b models.TrendingTag) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	if len(trending) > limit {
+		trending = trending[:limit]
+	}
+	return trending, nil
+}
+
+func (s *tagStore) Backfill(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hasTags := func(postID, groupPostID int) bool {
+		return slices.ContainsFunc(s.tags, func(t tagRow) bool { return t.postID == postID && t.groupPostID == groupPostID })
+	}
+	add := func(postID, groupPostID int, content string, at time.Time) int {
+		tags := hashtag.Parse(content)
+		for _, tag := range tags {
+			s.tags = append(s.tags, tagRow{tag: tag, postID: postID, groupPostID: groupPostID, taggedAt: at})
+		}
+		return min(len(tags), 1)
+	}
+	tagged := 0
+	for _, p := range s.posts {
+		if !hasTags(p.ID, 0) {
+			tagged += add(p.ID, 0, p.Content, p.CreatedAt)
+		}
+	}
+	for _, p := range s.groupPosts {
+		if !hasTags(0, p.ID) {
+			tagged += add(0, p.ID, p.Content, time.Unix(p.CreatedAt, 0))
+		}
+	}
+	return tagged, nil
+}
@@ -0,0 +1,199 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+)
+
+type userStore struct{ *memory }
+
+func (s *userStore) Create(ctx context.Context, reg models.RegisterStruct, passwordHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, row := range s.users {
+		if row.user.Email == reg.Email {
+			return 0, store.ErrConflict
+		}
+	}
+
+	user := models.User{
+		ID:          s.nextID(),
+		Email:       reg.Email,
+		FirstName:   reg.FirstName,
+		LastName:    reg.LastName,
+		DateOfBirth: reg.DateOfBirth,
+		Avatar:      ptr(reg.Avatar),
+		Username:    ptr(reg.Username),
+		AboutMe:     ptr(reg.AboutMe),
+		IsPrivate:   reg.IsPrivate,
+		CreatedAt:   time.Now().UTC(),
+	}
+	s.users = append(s.users, userRow{user: user, passwordHash: passwordHash})
+	return user.ID, nil
+}
+
+func (s *userStore) EmailTaken(ctx context.Context, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if row.user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *userStore) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if deref(row.user.Username) == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *userStore) Credentials(ctx context.Context, email string) (int, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if row.user.Email == email {
+			return row.user.ID, row.passwordHash, nil
+		}
+	}
+	return 0, "", store.ErrNotFound
+}
+
+func (s *userStore) Get(ctx context.Context, id int) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.user(id)
+	if !ok {
+		return user, store.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *userStore) SetPrivacy(ctx context.Context, id int, isPrivate bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].user.ID == id {
+			s.users[i].user.IsPrivate = isPrivate
+		}
+	}
+	return nil
+}
+
+func (s *userStore) Search(ctx context.Context, query string, limit int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = strings.ToLower(query)
+	users := []models.User{}
+	for _, row := range s.users {
+		u := row.user
+		if strings.Contains(strings.ToLower(deref(u.Username)), query) ||
+			strings.Contains(strings.ToLower(u.FirstName), query) ||
+			strings.Contains(strings.ToLower(u.LastName), query) {
+			users = append(users, listed(u))
+		}
+		if len(users) == limit {
+			break
+		}
+	}
+	return users, nil
+}
+
+func (s *userStore) Autocomplete(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix = strings.ToLower(prefix)
+	users := []models.User{}
+	for _, row := range s.users {
+		if row.user.Username != nil && strings.HasPrefix(strings.ToLower(*row.user.Username), prefix) {
+			users = append(users, listed(row.user))
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.ToLower(*users[i].Username) < strings.ToLower(*users[j].Username)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Random isn't random here, tests want stable results
+func (s *userStore) Random(ctx context.Context, limit int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, row := range s.users {
+		if len(users) == limit {
+			break
+		}
+		users = append(users, listed(row.user))
+	}
+	return users, nil
+}
+
+// listed keeps the fields the user lists of sqlstore select
+func listed(u models.User) models.User {
+	return models.User{ID: u.ID, Username: u.Username, FirstName: u.FirstName, LastName: u.LastName, Avatar: u.Avatar}
+}
+
+func sortUsers(users []models.User) {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+}
+
+func (s *userStore) CreateSession(ctx context.Context, userID int, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, session := range s.sessions {
+		if session.userID == userID {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = sessionRow{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *userStore) SessionUser(ctx context.Context, token string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return 0, store.ErrNotFound
+	}
+	return session.userID, nil
+}
+
+func (s *userStore) DeleteSession(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
+
+func (s *userStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for t, session := range s.sessions {
+		if session.expiresAt.Before(now) {
+			delete(s.sessions, t)
+			removed++
+		}
+	}
+	return removed, nil
+}
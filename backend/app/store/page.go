@@ -0,0 +1,70 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadCursor is returned by ParseCursor for a cursor this API didn't hand out
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Page selects one page of a keyset-paginated list: the rows after the After position in the
+// list's order, at most Limit of them. Limit 0 is the whole list
+type Page struct {
+	Limit int
+	After *Cursor
+}
+
+// Cursor is the position of a row in a list sorted by (Time, ID), the sort key is the row's
+// created_at (event_date for events) and the id breaks the ties
+type Cursor struct {
+	Time time.Time
+	ID   int
+}
+
+// String encodes the cursor for the next_cursor field, clients treat it as opaque
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", c.Time.UnixMicro(), c.ID))
+}
+
+// ParseCursor decodes a Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrBadCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, ErrBadCursor
+	}
+	t, err1 := strconv.ParseInt(micros, 10, 64)
+	n, err2 := strconv.Atoi(id)
+	if err1 != nil || err2 != nil || n <= 0 {
+		return Cursor{}, ErrBadCursor
+	}
+	return Cursor{Time: time.UnixMicro(t).UTC(), ID: n}, nil
+}
+
+// Fetch is the LIMIT a store queries with: one row more than the page, so it knows if a next
+// page exists. 0 (no limit) stays 0
+func (p Page) Fetch() int {
+	if p.Limit <= 0 {
+		return 0
+	}
+	return p.Limit + 1
+}
+
+// Cut trims the rows a store fetched (Fetch of them at most) to the page and returns the cursor
+// of the next page, nil on the last one
+func Cut[T any](rows []T, p Page, key func(T) Cursor) ([]T, *Cursor) {
+	if p.Limit <= 0 || len(rows) <= p.Limit {
+		return rows, nil
+	}
+	rows = rows[:p.Limit]
+	next := key(rows[len(rows)-1])
+	return rows, &next
+}
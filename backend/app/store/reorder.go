@@ -0,0 +1,25 @@
+package store
+
+// Reordered returns current with the ids of order moved to the front, in that order. The ids
+// order leaves out keep their order behind them, so a client that only knows some of the items
+// can still move them. ErrNotFound when order has an id that isn't in current, or has one twice
+func Reordered(current, order []int) ([]int, error) {
+	rest := map[int]bool{}
+	for _, id := range current {
+		rest[id] = true
+	}
+	result := make([]int, 0, len(current))
+	for _, id := range order {
+		if !rest[id] {
+			return nil, ErrNotFound
+		}
+		delete(rest, id)
+		result = append(result, id)
+	}
+	for _, id := range current {
+		if rest[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
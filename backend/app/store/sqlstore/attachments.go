@@ -0,0 +1,110 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"social-network/app/models"
+	"social-network/db"
+)
+
+// saveAttachments replaces the attachments of the post, comment or group post (column post_id,
+// comment_id or group_post_id) with the list in its order, and fills in the ids
+func saveAttachments(ctx context.Context, tx *db.Tx, column string, id int, attachments []models.Attachment) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attachments WHERE "+column+" = ?", id); err != nil {
+		return err
+	}
+	for i := range attachments {
+		a := &attachments[i]
+		attachmentID, err := tx.InsertContext(ctx, `
+			INSERT INTO attachments (`+column+`, position, path, alt_text, width, height, content_type)
+			VALUES (?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?)`,
+			id, i+1, a.Path, a.AltText, a.Width, a.Height, a.ContentType)
+		if err != nil {
+			return err
+		}
+		a.ID = int(attachmentID)
+	}
+	return nil
+}
+
+// loadAttachments reads the attachments of the posts, comments or group posts with the ids, by id
+func loadAttachments(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}, column string, ids []int) (map[int][]models.Attachment, error) {
+	attachments := map[int][]models.Attachment{}
+	if len(ids) == 0 {
+		return attachments, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+column+`, id, path, alt_text, COALESCE(width, 0), COALESCE(height, 0), content_type
+		FROM attachments
+		WHERE `+column+` IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+		ORDER BY position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var a models.Attachment
+		if err := rows.Scan(&id, &a.ID, &a.Path, &a.AltText, &a.Width, &a.Height, &a.ContentType); err != nil {
+			return nil, err
+		}
+		attachments[id] = append(attachments[id], a)
+	}
+	return attachments, rows.Err()
+}
+
+// droppedAttachments returns the paths of the attachments of the post, comment or group post that
+// the new list leaves out
+func droppedAttachments(ctx context.Context, tx *db.Tx, column string, id int, attachments []models.Attachment) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT path FROM attachments WHERE "+column+" = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dropped := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(attachments, func(a models.Attachment) bool { return a.Path == path }) {
+			dropped = append(dropped, path)
+		}
+	}
+	return dropped, rows.Err()
+}
+
+// unusedImages keeps the paths no row references anymore. Attach only checks the prefix, a path
+// can be someone else's upload, or the avatar it was copied from
+func unusedImages(ctx context.Context, tx *db.Tx, paths []string) ([]string, error) {
+	unused := []string{}
+	for _, path := range paths {
+		var used bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (
+			SELECT 1 FROM users WHERE avatar = ?
+			UNION ALL SELECT 1 FROM posts WHERE image = ?
+			UNION ALL SELECT 1 FROM post_revisions WHERE image = ?
+			UNION ALL SELECT 1 FROM comments WHERE image = ?
+			UNION ALL SELECT 1 FROM group_posts WHERE image = ?
+			UNION ALL SELECT 1 FROM group_post_comments WHERE image = ?
+			UNION ALL SELECT 1 FROM attachments WHERE path = ?)`,
+			path, path, path, path, path, path, path).Scan(&used)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			unused = append(unused, path)
+		}
+	}
+	return unused, nil
+}
@@ -0,0 +1,149 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type audienceStore struct {
+	db *db.DB
+}
+
+func (s *audienceStore) List(ctx context.Context, userID int) ([]models.AudienceList, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.id, l.user_id, l.name, l.created_at, COUNT(m.user_id)
+		FROM audience_lists l
+		LEFT JOIN audience_list_members m ON m.list_id = l.id
+		WHERE l.user_id = ?
+		GROUP BY l.id, l.user_id, l.name, l.created_at
+		ORDER BY l.name, l.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.AudienceList{}
+	for rows.Next() {
+		var l models.AudienceList
+		var createdAt int64
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &createdAt, &l.MemberCount); err != nil {
+			return nil, err
+		}
+		l.CreatedAt = time.Unix(createdAt, 0)
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+func (s *audienceStore) Get(ctx context.Context, id int) (models.AudienceList, error) {
+	var l models.AudienceList
+	var createdAt int64
+	err := s.db.QueryRowContext(ctx, "SELECT id, user_id, name, created_at FROM audience_lists WHERE id = ?", id).
+		Scan(&l.ID, &l.UserID, &l.Name, &createdAt)
+	if err != nil {
+		return l, notFound(err)
+	}
+	l.CreatedAt = time.Unix(createdAt, 0)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, COALESCE(u.username, ''), COALESCE(u.avatar, '')
+		FROM audience_list_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.list_id = ?
+		ORDER BY u.username, u.id`, id)
+	if err != nil {
+		return l, err
+	}
+	defer rows.Close()
+	l.Members = []models.UserSummary{}
+	for rows.Next() {
+		var u models.UserSummary
+		if err := rows.Scan(&u.ID, &u.Username, &u.Avatar); err != nil {
+			return l, err
+		}
+		l.Members = append(l.Members, u)
+	}
+	l.MemberCount = len(l.Members)
+	return l, rows.Err()
+}
+
+func (s *audienceStore) Create(ctx context.Context, l *models.AudienceList) error {
+	l.CreatedAt = time.Unix(time.Now().Unix(), 0)
+	id, err := s.db.InsertContext(ctx,
+		"INSERT INTO audience_lists (user_id, name, created_at) VALUES (?, ?, ?)", l.UserID, l.Name, l.CreatedAt.Unix())
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	l.ID, l.MemberCount, l.Members = int(id), 0, []models.UserSummary{}
+	return nil
+}
+
+func (s *audienceStore) Rename(ctx context.Context, id int, name string) error {
+	renamed, err := affected(s.db.ExecContext(ctx, "UPDATE audience_lists SET name = ? WHERE id = ?", name, id))
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	if err == nil && !renamed {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *audienceStore) Delete(ctx context.Context, id int) error {
+	deleted, err := affected(s.db.ExecContext(ctx, "DELETE FROM audience_lists WHERE id = ?", id))
+	if err == nil && !deleted {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *audienceStore) AddMembers(ctx context.Context, listID int, userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the users must exist, a foreign key error wouldn't say which one is missing anyway
+	args := make([]any, len(userIDs))
+	distinct := map[int]bool{}
+	for i, id := range userIDs {
+		args[i], distinct[id] = id, true
+	}
+	var found int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM users WHERE id IN (?"+strings.Repeat(", ?", len(userIDs)-1)+")", args...).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != len(distinct) {
+		return store.ErrNotFound
+	}
+
+	now := time.Now().Unix()
+	for id := range distinct {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO audience_list_members (list_id, user_id, added_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+			listID, id, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *audienceStore) RemoveMember(ctx context.Context, listID, userID int) (bool, error) {
+	return affected(s.db.ExecContext(ctx,
+		"DELETE FROM audience_list_members WHERE list_id = ? AND user_id = ?", listID, userID))
+}
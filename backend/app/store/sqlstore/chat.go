@@ -0,0 +1,158 @@
+package sqlstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type chatStore struct {
+	db *db.DB
+}
+
+// SaveMessage stores a private message, msg.CreatedAt (unix seconds) is kept as the message date
+func (s *chatStore) SaveMessage(ctx context.Context, msg *models.Message) error {
+	msgID, err := s.db.InsertContext(ctx,
+		"INSERT INTO messages (sender_id, receiver_id, content, content_html, created_at, is_read) VALUES (?, ?, ?, ?, ?, FALSE)",
+		msg.SenderID, msg.ReceiverID, msg.Content, msg.ContentHTML, time.Unix(msg.CreatedAt, 0),
+	)
+	if err != nil {
+		return err
+	}
+	msg.ID = int(msgID)
+	msg.IsRead = false
+	return nil
+}
+
+// messageOrder walks back from the latest message, the pages are put back in chronological order
+var messageOrder = keyset{sortCol: "created_at", idCol: "id", desc: true}
+
+func (s *chatStore) Conversation(ctx context.Context, userID, otherID int, page store.Page) ([]models.Message, *store.Cursor, error) {
+	after, args := messageOrder.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, sender_id, receiver_id, content, content_html, created_at, is_read
+		FROM messages
+		WHERE ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND `+after+
+		messageOrder.orderBy(page),
+		append([]any{userID, otherID, otherID, userID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		var msg models.Message
+		var createdAt time.Time
+		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Content, &msg.ContentHTML, &createdAt, &msg.IsRead); err != nil {
+			return nil, nil, err
+		}
+		msg.Format = formatOf(msg.ContentHTML)
+		msg.CreatedAt = createdAt.Unix()
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	messages, next := store.Cut(messages, page, func(m models.Message) store.Cursor {
+		return store.Cursor{Time: time.Unix(m.CreatedAt, 0), ID: m.ID}
+	})
+	slices.Reverse(messages)
+	return messages, next, nil
+}
+
+func (s *chatStore) MarkRead(ctx context.Context, senderID, receiverID int) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE messages SET is_read = TRUE WHERE sender_id = ? AND receiver_id = ? AND is_read = FALSE",
+		senderID, receiverID,
+	)
+	return err
+}
+
+// UnreadCount is the number of unread messages senderID sent to receiverID
+func (s *chatStore) UnreadCount(ctx context.Context, receiverID, senderID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM messages WHERE receiver_id = ? AND sender_id = ? AND is_read = FALSE",
+		receiverID, senderID,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *chatStore) SaveGroupMessage(ctx context.Context, msg *models.GroupMessage) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	msgID, err := tx.InsertContext(ctx,
+		"INSERT INTO group_messages (group_id, sender_id, content, content_html, created_at) VALUES (?, ?, ?, ?, ?)",
+		msg.GroupID, msg.SenderID, msg.Content, msg.ContentHTML, time.Unix(msg.CreatedAt, 0),
+	)
+	if err != nil {
+		return err
+	}
+	msg.ID = int(msgID)
+	if msg.Mentions, err = saveMentions(ctx, tx, "group_message_id", msg.ID, msg.Content); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(username, first_name) FROM users WHERE id = ?", msg.SenderID).
+		Scan(&msg.SenderName)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *chatStore) GroupMessages(ctx context.Context, groupID int, page store.Page) ([]models.GroupMessage, *store.Cursor, error) {
+	order := keyset{sortCol: "gm.created_at", idCol: "gm.id", desc: true}
+	after, args := order.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT gm.id, gm.group_id, gm.sender_id, COALESCE(u.username, u.first_name), gm.content, gm.content_html, gm.created_at
+		FROM group_messages gm
+		JOIN users u ON gm.sender_id = u.id
+		WHERE gm.group_id = ? AND `+after+order.orderBy(page),
+		append([]any{groupID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.GroupMessage{}
+	for rows.Next() {
+		var msg models.GroupMessage
+		var createdAt time.Time
+		if err := rows.Scan(&msg.ID, &msg.GroupID, &msg.SenderID, &msg.SenderName, &msg.Content, &msg.ContentHTML, &createdAt); err != nil {
+			return nil, nil, err
+		}
+		msg.Format = formatOf(msg.ContentHTML)
+		msg.CreatedAt = createdAt.Unix()
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	messages, next := store.Cut(messages, page, func(m models.GroupMessage) store.Cursor {
+		return store.Cursor{Time: time.Unix(m.CreatedAt, 0), ID: m.ID}
+	})
+	slices.Reverse(messages)
+
+	ids := make([]int, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	mentions, err := loadMentions(ctx, s.db, "group_message_id", ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range messages {
+		messages[i].Mentions = mentions[messages[i].ID]
+	}
+	return messages, next, nil
+}
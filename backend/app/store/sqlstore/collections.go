@@ -0,0 +1,308 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type collectionStore struct {
+	db *db.DB
+}
+
+func (s *collectionStore) List(ctx context.Context, userID int) ([]models.Collection, error) {
+	if err := s.ensureDefault(ctx, userID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, name, is_default, created_at
+		FROM collections
+		WHERE user_id = ?
+		ORDER BY is_default DESC, name, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// ensureDefault creates the user's "Saved" collection when it isn't there yet. The unique index
+// on the default collection settles two requests doing it at once
+func (s *collectionStore) ensureDefault(ctx context.Context, userID int) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM collections WHERE user_id = ? AND is_default)", userID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO collections (user_id, name, is_default, created_at) VALUES (?, ?, TRUE, ?)
+		 ON CONFLICT DO NOTHING`,
+		userID, models.DefaultCollection, time.Now().Unix())
+	return err
+}
+
+func scanCollection(row scanner) (models.Collection, error) {
+	var c models.Collection
+	var createdAt int64
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.IsDefault, &createdAt)
+	c.CreatedAt = time.Unix(createdAt, 0)
+	return c, err
+}
+
+func (s *collectionStore) Get(ctx context.Context, id int) (models.Collection, error) {
+	c, err := scanCollection(s.db.QueryRowContext(ctx,
+		"SELECT id, user_id, name, is_default, created_at FROM collections WHERE id = ?", id))
+	return c, notFound(err)
+}
+
+func (s *collectionStore) Create(ctx context.Context, c *models.Collection) error {
+	// the default one first, so a collection can't take its name
+	if err := s.ensureDefault(ctx, c.UserID); err != nil {
+		return err
+	}
+	c.IsDefault = false
+	c.CreatedAt = time.Unix(time.Now().Unix(), 0)
+	id, err := s.db.InsertContext(ctx,
+		"INSERT INTO collections (user_id, name, is_default, created_at) VALUES (?, ?, FALSE, ?)",
+		c.UserID, c.Name, c.CreatedAt.Unix())
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *collectionStore) Delete(ctx context.Context, id int) error {
+	deleted, err := affected(s.db.ExecContext(ctx, "DELETE FROM collections WHERE id = ?", id))
+	if err == nil && !deleted {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *collectionStore) Add(ctx context.Context, item *models.CollectionItem, viewerID int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var column, visible string
+	var id int
+	var args []any
+	if item.GroupPostID != nil {
+		column, id = "group_post_id", *item.GroupPostID
+		visible = `SELECT EXISTS (SELECT 1 FROM group_posts gp
+			JOIN group_members gm ON gm.group_id = gp.group_id AND gm.user_id = ?
+			WHERE gp.id = ?)`
+		args = []any{viewerID, id}
+	} else {
+		column, id = "post_id", *item.PostID
+		visible = `SELECT EXISTS (SELECT 1 FROM posts p WHERE p.id = ? AND ` + visibleTo + `)`
+		args = []any{id, viewerID, viewerID, viewerID}
+	}
+	var ok bool
+	if err := tx.QueryRowContext(ctx, visible, args...).Scan(&ok); err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, store.ErrNotFound
+	}
+
+	var savedAt int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, position, saved_at FROM collection_items WHERE collection_id = ? AND "+column+" = ?",
+		item.CollectionID, id,
+	).Scan(&item.ID, &item.Position, &savedAt)
+	if err == nil {
+		item.SavedAt = time.Unix(savedAt, 0)
+		return false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	// appended behind the last item
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(position), 0) + 1 FROM collection_items WHERE collection_id = ?", item.CollectionID,
+	).Scan(&item.Position); err != nil {
+		return false, err
+	}
+	item.SavedAt = time.Unix(time.Now().Unix(), 0)
+	itemID, err := tx.InsertContext(ctx,
+		"INSERT INTO collection_items (collection_id, "+column+", position, saved_at) VALUES (?, ?, ?, ?)",
+		item.CollectionID, id, item.Position, item.SavedAt.Unix())
+	if err != nil {
+		return false, err
+	}
+	item.ID = int(itemID)
+	return true, tx.Commit()
+}
+
+func (s *collectionStore) Remove(ctx context.Context, collectionID, itemID int) error {
+	deleted, err := affected(s.db.ExecContext(ctx,
+		"DELETE FROM collection_items WHERE id = ? AND collection_id = ?", itemID, collectionID))
+	if err == nil && !deleted {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *collectionStore) Reorder(ctx context.Context, collectionID int, itemIDs []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM collection_items WHERE collection_id = ? ORDER BY position, id", collectionID)
+	if err != nil {
+		return err
+	}
+	current := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	order, err := store.Reordered(current, itemIDs)
+	if err != nil {
+		return err
+	}
+	for i, id := range order {
+		if _, err := tx.ExecContext(ctx, "UPDATE collection_items SET position = ? WHERE id = ?", i+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// itemRow reads ci.id, ci.position and ci.saved_at in front of the columns its caller scans
+type itemRow struct {
+	scanner
+	item    *models.CollectionItem
+	savedAt *int64
+}
+
+func (r itemRow) Scan(dest ...any) error {
+	return r.scanner.Scan(append([]any{&r.item.ID, &r.item.Position, r.savedAt}, dest...)...)
+}
+
+func (s *collectionStore) Items(ctx context.Context, collectionID, viewerID int) ([]models.CollectionItem, error) {
+	items := []models.CollectionItem{}
+
+	// regular posts with the feed's rules
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ci.id, ci.position, ci.saved_at, `+postColumns+`
+		FROM collection_items ci
+		JOIN posts p ON p.id = ci.post_id
+		JOIN users u ON p.user_id = u.id
+		WHERE ci.collection_id = ? AND `+visibleTo,
+		collectionID, viewerID, viewerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	postIDs := []int{}
+	for rows.Next() {
+		item := models.CollectionItem{CollectionID: collectionID}
+		var savedAt int64
+		post, err := scanPost(itemRow{rows, &item, &savedAt})
+		if err != nil {
+			return nil, err
+		}
+		item.SavedAt = time.Unix(savedAt, 0)
+		item.Post, item.PostID = &post, &post.ID
+		items = append(items, item)
+		postIDs = append(postIDs, post.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	mentions, err := loadMentions(ctx, s.db, "post_id", postIDs)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := loadAttachments(ctx, s.db, "post_id", postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		item.Post.Mentions = mentions[item.Post.ID]
+		item.Post.Attachments = attachments[item.Post.ID]
+	}
+
+	// group posts of the groups the viewer is (still) in
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT ci.id, ci.position, ci.saved_at, gp.id, gp.group_id, gp.user_id, gp.content, COALESCE(gp.image, ''),
+		       gp.created_at, COALESCE(u.username, ''), COALESCE(u.avatar, '')
+		FROM collection_items ci
+		JOIN group_posts gp ON gp.id = ci.group_post_id
+		JOIN group_members gm ON gm.group_id = gp.group_id AND gm.user_id = ?
+		JOIN users u ON gp.user_id = u.id
+		WHERE ci.collection_id = ?`,
+		viewerID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groupPostIDs := []int{}
+	for rows.Next() {
+		item := models.CollectionItem{CollectionID: collectionID, GroupPost: &models.GroupPost{}}
+		p, a := item.GroupPost, &models.UserSummary{}
+		var savedAt int64
+		if err := rows.Scan(&item.ID, &item.Position, &savedAt, &p.ID, &p.GroupID, &p.UserID, &p.Content, &p.Image,
+			&p.CreatedAt, &a.Username, &a.Avatar); err != nil {
+			return nil, err
+		}
+		a.ID = p.UserID
+		p.Author = a
+		item.SavedAt = time.Unix(savedAt, 0)
+		item.GroupPostID = &p.ID
+		items = append(items, item)
+		groupPostIDs = append(groupPostIDs, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if attachments, err = loadAttachments(ctx, s.db, "group_post_id", groupPostIDs); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.GroupPost != nil {
+			item.GroupPost.Attachments = attachments[item.GroupPost.ID]
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items, nil
+}
@@ -0,0 +1,143 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+func (s *postStore) Drafts(ctx context.Context, authorID int) ([]models.Post, error) {
+	return s.list(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ? AND p.group_id IS NULL AND p.status <> 'published'
+		ORDER BY p.created_at DESC, p.id DESC`, authorID)
+}
+
+func (s *postStore) UpdateDraft(ctx context.Context, post *models.Post) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updated, err := affected(tx.ExecContext(ctx, `
+		UPDATE posts SET content = ?, content_html = ?, image = ?, privacy = ?, status = ?, publish_at = ?
+		WHERE id = ? AND group_id IS NULL AND status <> 'published'`,
+		post.Content, post.ContentHTML, post.Image, post.Privacy, post.Status, publishAt(post), post.ID))
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return store.ErrNotFound
+	}
+	if err := clearAudience(ctx, tx, post.ID); err != nil {
+		return err
+	}
+	if err := saveAudience(ctx, tx, post); err != nil {
+		return err
+	}
+	if err := saveTags(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return err
+	}
+	if post.Mentions, err = saveMentions(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return err
+	}
+	if err := saveAttachments(ctx, tx, "post_id", post.ID, post.Attachments); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT user_id, created_at FROM posts WHERE id = ?", post.ID).
+		Scan(&post.UserID, &post.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *postStore) Publish(ctx context.Context, id int) (models.Post, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Post{}, err
+	}
+	defer tx.Rollback()
+
+	published, err := publish(ctx, tx, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if !published {
+		return models.Post{}, store.ErrNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Post{}, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *postStore) PublishDue(ctx context.Context, now time.Time) ([]models.Post, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM posts WHERE status = 'scheduled' AND publish_at <= ? ORDER BY publish_at, id", now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a worker that got here first took the post, it isn't returned twice
+	due := []int{}
+	for _, id := range ids {
+		published, err := publish(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if published {
+			due = append(due, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	posts := []models.Post{}
+	for _, id := range due {
+		post, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// publish flips an unpublished post to published, dated now. Its tags count for the trending
+// list from now on too, false when the post is missing or published already
+func publish(ctx context.Context, tx *db.Tx, id int) (bool, error) {
+	published, err := affected(tx.ExecContext(ctx, `
+		UPDATE posts SET status = 'published', publish_at = NULL, created_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND group_id IS NULL AND status <> 'published'`, id))
+	if err != nil || !published {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE post_tags SET tagged_at = ? WHERE post_id = ?", time.Now().Unix(), id)
+	return err == nil, err
+}
@@ -0,0 +1,206 @@
+package sqlstore
+
+import (
+	"context"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type followStore struct {
+	db *db.DB
+}
+
+func (s *followStore) IsFollowing(ctx context.Context, followerID, followedID int) (bool, error) {
+	var following bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM followers WHERE follower_id = ? AND followed_id = ?)",
+		followerID, followedID,
+	).Scan(&following)
+	return following, err
+}
+
+func (s *followStore) Follow(ctx context.Context, followerID, followedID int) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO followers (follower_id, followed_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		followerID, followedID,
+	)
+	return err
+}
+
+func (s *followStore) Unfollow(ctx context.Context, followerID, followedID int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	removed, err := affected(tx.ExecContext(ctx,
+		"DELETE FROM followers WHERE follower_id = ? AND followed_id = ?",
+		followerID, followedID,
+	))
+	if err != nil || !removed {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM post_visibility WHERE user_id = ? AND post_id IN (SELECT id FROM posts WHERE user_id = ?)",
+		followerID, followedID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *followStore) Followers(ctx context.Context, userID int) ([]models.User, error) {
+	return s.listUsers(ctx, `
+		SELECT u.id, u.username, u.first_name, u.last_name, u.avatar
+		FROM users u
+		JOIN followers f ON u.id = f.follower_id
+		WHERE f.followed_id = ?
+		ORDER BY u.id`, userID)
+}
+
+func (s *followStore) Following(ctx context.Context, userID int) ([]models.User, error) {
+	return s.listUsers(ctx, `
+		SELECT u.id, u.username, u.first_name, u.last_name, u.avatar
+		FROM users u
+		JOIN followers f ON u.id = f.followed_id
+		WHERE f.follower_id = ?
+		ORDER BY u.id`, userID)
+}
+
+func (s *followStore) listUsers(ctx context.Context, query string, args ...any) ([]models.User, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Avatar); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *followStore) Counts(ctx context.Context, userID int) (int, int, error) {
+	var followers, following int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM followers WHERE followed_id = ?),
+			(SELECT COUNT(*) FROM followers WHERE follower_id = ?)`,
+		userID, userID,
+	).Scan(&followers, &following)
+	return followers, following, err
+}
+
+func (s *followStore) CreateRequest(ctx context.Context, requesterID, targetID int) (int64, error) {
+	return s.db.InsertContext(ctx,
+		"INSERT INTO follow_user_requests (userToFollow_id, requester_id) VALUES (?, ?)",
+		targetID, requesterID,
+	)
+}
+
+func (s *followStore) HasPendingRequest(ctx context.Context, requesterID, targetID int) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM follow_user_requests WHERE requester_id = ? AND userToFollow_id = ? AND status = 'pending')`,
+		requesterID, targetID,
+	).Scan(&pending)
+	return pending, err
+}
+
+func (s *followStore) PendingRequests(ctx context.Context, targetID int) ([]models.FollowRequest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT fur.id, fur.requester_id, COALESCE(u.username, u.first_name, ''), u.avatar, fur.status, fur.created_at
+		FROM follow_user_requests fur
+		LEFT JOIN users u ON u.id = fur.requester_id
+		WHERE fur.userToFollow_id = ? AND fur.status = 'pending'
+		ORDER BY fur.created_at DESC, fur.id DESC`, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.FollowRequest{}
+	for rows.Next() {
+		var req models.FollowRequest
+		if err := rows.Scan(&req.ID, &req.RequesterID, &req.SenderName, &req.SenderAvatar, &req.Status, &req.CreatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+func (s *followStore) ResolveRequest(ctx context.Context, requestID, targetID int, approve bool) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var requesterID int
+	var status string
+	err = tx.QueryRowContext(ctx,
+		"SELECT requester_id, status FROM follow_user_requests WHERE id = ? AND userToFollow_id = ?",
+		requestID, targetID,
+	).Scan(&requesterID, &status)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	if status != "pending" {
+		return requesterID, store.ErrConflict
+	}
+
+	if approve {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO followers (follower_id, followed_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			requesterID, targetID,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// the request and its notification are removed so they don't get refetched
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notifications WHERE follow_request_id = ?", requestID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM follow_user_requests WHERE id = ?", requestID); err != nil {
+		return 0, err
+	}
+
+	return requesterID, tx.Commit()
+}
+
+func (s *followStore) CancelRequest(ctx context.Context, requesterID, targetID int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM notifications WHERE user_id = ? AND type = 'follow_request' AND sender_id = ?",
+		targetID, requesterID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := affected(tx.ExecContext(ctx,
+		"DELETE FROM follow_user_requests WHERE requester_id = ? AND userToFollow_id = ? AND status = 'pending'",
+		requesterID, targetID,
+	))
+	if err != nil || !removed {
+		return false, err
+	}
+	return true, tx.Commit()
+}
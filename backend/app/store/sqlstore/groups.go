@@ -0,0 +1,756 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type groupStore struct {
+	db *db.DB
+}
+
+func (s *groupStore) Create(ctx context.Context, g *models.Group) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	groupID, err := tx.InsertContext(ctx,
+		"INSERT INTO groups (group_name, title, description, creator_id) VALUES (?, ?, ?, ?)",
+		g.Groupname, g.Title, g.Description, g.CreatorID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// the creator is the first member
+	now := time.Now().Unix()
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'creator', ?)",
+		groupID, g.CreatorID, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	g.ID = int(groupID)
+	g.CreatedAt = now
+	g.MemberCount = 1
+	g.IsMember = true
+	g.IsCreator = true
+	return nil
+}
+
+// groupSelect needs the viewer id four times before any other argument
+const groupSelect = `
+	SELECT
+		g.id,
+		g.group_name,
+		g.title,
+		g.description,
+		g.creator_id,
+		g.created_at,
+		(SELECT COUNT(*) FROM group_members WHERE group_id = g.id) as member_count,
+		EXISTS(SELECT 1 FROM group_members WHERE group_id = g.id AND user_id = ?) as is_member,
+		(g.creator_id = ?) as is_creator,
+		EXISTS(SELECT 1 FROM group_invitations WHERE group_id = g.id AND user_id = ? AND status = 'pending') as is_invited,
+		EXISTS(SELECT 1 FROM group_join_requests WHERE group_id = g.id AND user_id = ? AND status = 'pending') as has_requested
+	FROM groups g`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGroup(row rowScanner) (models.Group, error) {
+	var g models.Group
+	var createdAt time.Time
+	err := row.Scan(
+		&g.ID, &g.Groupname, &g.Title, &g.Description, &g.CreatorID, &createdAt,
+		&g.MemberCount, &g.IsMember, &g.IsCreator, &g.IsInvited, &g.HasRequested,
+	)
+	g.CreatedAt = createdAt.Unix()
+	return g, err
+}
+
+func (s *groupStore) List(ctx context.Context, viewerID int) ([]models.Group, error) {
+	rows, err := s.db.QueryContext(ctx, groupSelect+`
+		ORDER BY g.created_at DESC, g.id DESC`,
+		viewerID, viewerID, viewerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID, viewerID int) (models.Group, error) {
+	g, err := scanGroup(s.db.QueryRowContext(ctx, groupSelect+`
+		WHERE g.id = ?`,
+		viewerID, viewerID, viewerID, viewerID, groupID))
+	if err != nil {
+		return g, notFound(err)
+	}
+
+	var creator models.User
+	err = s.db.QueryRowContext(ctx, "SELECT id, username, avatar FROM users WHERE id = ?", g.CreatorID).
+		Scan(&creator.ID, &creator.Username, &creator.Avatar)
+	if err != nil {
+		return g, notFound(err)
+	}
+	g.Creator = &creator
+	return g, nil
+}
+
+// MemberGroups only fills ID and Groupname, it feeds the chat sidebar
+func (s *groupStore) MemberGroups(ctx context.Context, userID int) ([]models.Group, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT groups.id, groups.group_name
+		FROM group_members
+		JOIN groups ON group_members.group_id = groups.id
+		WHERE group_members.user_id = ?
+		ORDER BY groups.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var g models.Group
+		if err := rows.Scan(&g.ID, &g.Groupname); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (s *groupStore) IsMember(ctx context.Context, groupID, userID int) (bool, error) {
+	var isMember bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
+		groupID, userID,
+	).Scan(&isMember)
+	return isMember, err
+}
+
+func (s *groupStore) Members(ctx context.Context, groupID int) ([]models.GroupMember, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, COALESCE(u.username, ''), COALESCE(u.avatar, ''), COALESCE(gm.role, 'member'), gm.joined_at
+		FROM group_members gm
+		JOIN users u ON gm.user_id = u.id
+		WHERE gm.group_id = ?
+		ORDER BY gm.joined_at ASC, gm.id ASC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.GroupMember{}
+	for rows.Next() {
+		var m models.GroupMember
+		if err := rows.Scan(&m.ID, &m.Username, &m.Avatar, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// MemberIDs lists the members except excludeUserID (usually whoever triggered a notification)
+func (s *groupStore) MemberIDs(ctx context.Context, groupID, excludeUserID int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT user_id FROM group_members WHERE group_id = ? AND user_id != ? ORDER BY user_id",
+		groupID, excludeUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		memberIDs = append(memberIDs, userID)
+	}
+	return memberIDs, rows.Err()
+}
+
+func (s *groupStore) AddMember(ctx context.Context, groupID, userID int, role string) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)",
+		groupID, userID, role, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *groupStore) RemoveMember(ctx context.Context, groupID, userID int) (bool, error) {
+	return affected(s.db.ExecContext(ctx,
+		"DELETE FROM group_members WHERE group_id = ? AND user_id = ?",
+		groupID, userID,
+	))
+}
+
+// ---------------------------------------------------------------------------------------------
+// invitations
+
+func (s *groupStore) HasPendingInvite(ctx context.Context, groupID, userID int) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_invitations WHERE group_id = ? AND user_id = ? AND status = 'pending')",
+		groupID, userID,
+	).Scan(&pending)
+	return pending, err
+}
+
+func (s *groupStore) CreateInvite(ctx context.Context, groupID, inviterID, userID int) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO group_invitations (group_id, inviter_id, user_id, status, created_at)
+		 VALUES (?, ?, ?, 'pending', ?)`,
+		groupID, inviterID, userID, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *groupStore) AcceptInvite(ctx context.Context, groupID, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inviteID int
+	err = tx.QueryRowContext(ctx,
+		"SELECT id FROM group_invitations WHERE group_id = ? AND user_id = ? AND status = 'pending'",
+		groupID, userID,
+	).Scan(&inviteID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'member', ?)",
+		groupID, userID, time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := clearInvitation(ctx, tx, groupID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *groupStore) DeclineInvite(ctx context.Context, groupID, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	declined, err := affected(tx.ExecContext(ctx,
+		"UPDATE group_invitations SET status = 'declined' WHERE group_id = ? AND user_id = ? AND status = 'pending'",
+		groupID, userID,
+	))
+	if err != nil {
+		return err
+	}
+	if !declined {
+		return store.ErrNotFound
+	}
+
+	if err := clearInvitation(ctx, tx, groupID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// clearInvitation deletes the invitation rows and their notification once the user answered
+func clearInvitation(ctx context.Context, tx *db.Tx, groupID, userID int) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM notifications WHERE user_id = ? AND type = 'group_invitation' AND group_id = ?",
+		userID, groupID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM group_invitations WHERE user_id = ? AND group_id = ?", userID, groupID)
+	return err
+}
+
+// ---------------------------------------------------------------------------------------------
+// join requests
+
+func (s *groupStore) HasPendingJoinRequest(ctx context.Context, groupID, userID int) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_join_requests WHERE group_id = ? AND user_id = ? AND status = 'pending')",
+		groupID, userID,
+	).Scan(&pending)
+	return pending, err
+}
+
+func (s *groupStore) CreateJoinRequest(ctx context.Context, groupID, userID int) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO group_join_requests (group_id, user_id, status, created_at) VALUES (?, ?, 'pending', ?)",
+		groupID, userID, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *groupStore) JoinRequests(ctx context.Context, groupID int) ([]models.GroupJoinRequest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT gjr.id, gjr.user_id, gjr.created_at, COALESCE(u.username, ''), COALESCE(u.avatar, '')
+		FROM group_join_requests gjr
+		JOIN users u ON gjr.user_id = u.id
+		WHERE gjr.group_id = ? AND gjr.status = 'pending'
+		ORDER BY gjr.created_at DESC, gjr.id DESC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.GroupJoinRequest{}
+	for rows.Next() {
+		var req models.GroupJoinRequest
+		if err := rows.Scan(&req.ID, &req.UserID, &req.CreatedAt, &req.Username, &req.Avatar); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// JoinRequestStatus returns the status of the newest request of the user, ErrNotFound if there is none
+func (s *groupStore) JoinRequestStatus(ctx context.Context, groupID, userID int) (string, error) {
+	var status sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		"SELECT status FROM group_join_requests WHERE group_id = ? AND user_id = ? ORDER BY id DESC LIMIT 1",
+		groupID, userID,
+	).Scan(&status)
+	if err != nil {
+		return "", notFound(err)
+	}
+	if !status.Valid {
+		return "pending", nil
+	}
+	return status.String, nil
+}
+
+func (s *groupStore) ApproveJoinRequest(ctx context.Context, groupID, userID int) error {
+	// transaction explanation: begin, rollback on error, commit at end
+	// if one statement fails we rollback to the initial state, else we commit the changes
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	approved, err := affected(tx.ExecContext(ctx,
+		"UPDATE group_join_requests SET status = 'approved' WHERE group_id = ? AND user_id = ? AND status = 'pending'",
+		groupID, userID,
+	))
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return store.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'member', ?)",
+		groupID, userID, time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := clearJoinRequest(ctx, tx, groupID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *groupStore) DeleteJoinRequest(ctx context.Context, groupID, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clearJoinRequest(ctx, tx, groupID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// clearJoinRequest always deletes the join request notification together with the request
+func clearJoinRequest(ctx context.Context, tx *db.Tx, groupID, userID int) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM notifications WHERE group_id = ? AND sender_id = ? AND type = 'group_join_request'",
+		groupID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM group_join_requests WHERE group_id = ? AND user_id = ?", groupID, userID)
+	return err
+}
+
+// ---------------------------------------------------------------------------------------------
+// posts and comments
+
+// author loads the short user info shown next to group posts and comments
+func author(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, userID int) (*models.UserSummary, error) {
+	var summary models.UserSummary
+	var username, avatar sql.NullString
+	err := q.QueryRowContext(ctx, "SELECT id, username, avatar FROM users WHERE id = ?", userID).
+		Scan(&summary.ID, &username, &avatar)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	summary.Username = stringOrEmpty(username)
+	summary.Avatar = stringOrEmpty(avatar)
+	return &summary, nil
+}
+
+func (s *groupStore) CreatePost(ctx context.Context, post *models.GroupPost) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	post.CreatedAt = time.Now().Unix()
+	postID, err := tx.InsertContext(ctx,
+		"INSERT INTO group_posts (group_id, user_id, content, image, created_at) VALUES (?, ?, ?, ?, ?)",
+		post.GroupID, post.UserID, post.Content, post.Image, post.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	post.ID = int(postID)
+	if err := saveTags(ctx, tx, "group_post_id", post.ID, post.Content); err != nil {
+		return err
+	}
+	if err := saveAttachments(ctx, tx, "group_post_id", post.ID, post.Attachments); err != nil {
+		return err
+	}
+	if err := savePoll(ctx, tx, "group_post_id", post.ID, post.Poll); err != nil {
+		return err
+	}
+
+	if post.Author, err = author(ctx, tx, post.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *groupStore) Posts(ctx context.Context, groupID int, page store.Page) ([]models.GroupPost, *store.Cursor, error) {
+	order := keyset{sortCol: "gp.created_at", idCol: "gp.id", unix: true, desc: true}
+	after, args := order.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT gp.id, gp.group_id, gp.user_id, gp.content, COALESCE(gp.image, ''), gp.created_at,
+		       COALESCE(u.username, ''), COALESCE(u.avatar, '')
+		FROM group_posts gp
+		JOIN users u ON gp.user_id = u.id
+		WHERE gp.group_id = ? AND `+after+order.orderBy(page),
+		append([]any{groupID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	posts := []models.GroupPost{}
+	for rows.Next() {
+		var p models.GroupPost
+		a := &models.UserSummary{}
+		if err := rows.Scan(&p.ID, &p.GroupID, &p.UserID, &p.Content, &p.Image, &p.CreatedAt, &a.Username, &a.Avatar); err != nil {
+			return nil, nil, err
+		}
+		a.ID = p.UserID
+		p.Author = a
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	attachments, err := loadAttachments(ctx, s.db, "group_post_id", ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range posts {
+		posts[i].Attachments = attachments[posts[i].ID]
+	}
+	posts, next := store.Cut(posts, page, func(p models.GroupPost) store.Cursor {
+		return store.Cursor{Time: time.Unix(p.CreatedAt, 0), ID: p.ID}
+	})
+	return posts, next, nil
+}
+
+func (s *groupStore) PostInGroup(ctx context.Context, postID, groupID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_posts WHERE id = ? AND group_id = ?)",
+		postID, groupID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (s *groupStore) CreateComment(ctx context.Context, comment *models.GroupComment) error {
+	comment.CreatedAt = time.Now().Unix()
+	commentID, err := s.db.InsertContext(ctx,
+		"INSERT INTO group_post_comments (post_id, user_id, content, image, created_at) VALUES (?, ?, ?, ?, ?)",
+		comment.PostID, comment.UserID, comment.Content, comment.Image, comment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	comment.ID = int(commentID)
+
+	comment.Author, err = author(ctx, s.db, comment.UserID)
+	return err
+}
+
+func (s *groupStore) Comments(ctx context.Context, postID int, page store.Page) ([]models.GroupComment, *store.Cursor, error) {
+	order := keyset{sortCol: "gc.created_at", idCol: "gc.id", unix: true}
+	after, args := order.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT gc.id, gc.post_id, gc.user_id, COALESCE(gc.content, ''), COALESCE(gc.image, ''), gc.created_at,
+		       COALESCE(u.username, ''), COALESCE(u.avatar, '')
+		FROM group_post_comments gc
+		JOIN users u ON gc.user_id = u.id
+		WHERE gc.post_id = ? AND `+after+order.orderBy(page),
+		append([]any{postID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.GroupComment{}
+	for rows.Next() {
+		var c models.GroupComment
+		a := &models.UserSummary{}
+		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.Image, &c.CreatedAt, &a.Username, &a.Avatar); err != nil {
+			return nil, nil, err
+		}
+		a.ID = c.UserID
+		c.Author = a
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	comments, next := store.Cut(comments, page, func(c models.GroupComment) store.Cursor {
+		return store.Cursor{Time: time.Unix(c.CreatedAt, 0), ID: c.ID}
+	})
+	return comments, next, nil
+}
+
+// ---------------------------------------------------------------------------------------------
+// events
+
+func (s *groupStore) CreateEvent(ctx context.Context, event *models.GroupEvent) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	event.CreatedAt = time.Now().Unix()
+	eventID, err := tx.InsertContext(ctx,
+		`INSERT INTO group_events (group_id, creator_id, title, description, event_date, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		event.GroupID, event.CreatorID, event.Title, event.Description, event.EventDate, event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	event.ID = int(eventID)
+
+	var groupName string
+	if err := tx.QueryRowContext(ctx, "SELECT group_name FROM groups WHERE id = ?", event.GroupID).Scan(&groupName); err != nil {
+		return nil, notFound(err)
+	}
+
+	creator := models.User{ID: event.CreatorID}
+	err = tx.QueryRowContext(ctx, "SELECT username, avatar FROM users WHERE id = ?", event.CreatorID).
+		Scan(&creator.Username, &creator.Avatar)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	event.Creator = &creator
+
+	memberIDs, err := memberIDsTx(ctx, tx, event.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// every member, the creator included, gets an event_created notification
+	for _, memberID := range memberIDs {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO notifications (
+				user_id, type, sender_id, sender_name, group_id, group_name,
+				event_id, event_title, event_date, created_at
+			) VALUES (?, 'event_created', ?, ?, ?, ?, ?, ?, ?, ?)`,
+			memberID, event.CreatorID, creator.Username, event.GroupID, groupName,
+			event.ID, event.Title, event.EventDate, time.Unix(event.CreatedAt, 0),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return memberIDs, tx.Commit()
+}
+
+func memberIDsTx(ctx context.Context, tx *db.Tx, groupID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT user_id FROM group_members WHERE group_id = ? ORDER BY user_id", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// eventOrder sorts on the event date, stored as text by SQLite
+var eventOrder = keyset{sortCol: "CAST(ge.event_date AS BIGINT)", idCol: "ge.id", unix: true}
+
+func (s *groupStore) Events(ctx context.Context, groupID, viewerID int, page store.Page) ([]models.GroupEvent, *store.Cursor, error) {
+	after, args := eventOrder.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ge.id, ge.group_id, ge.creator_id, ge.title, COALESCE(ge.description, ''), ge.event_date, ge.created_at,
+		       u.username, u.avatar,
+		       (SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND response = 'going') as going_count,
+		       (SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND response = 'not_going') as not_going_count,
+		       (SELECT response FROM group_event_responses WHERE event_id = ge.id AND user_id = ?) as user_response
+		FROM group_events ge
+		JOIN users u ON ge.creator_id = u.id
+		WHERE ge.group_id = ? AND `+after+eventOrder.orderBy(page),
+		append([]any{viewerID, groupID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := []models.GroupEvent{}
+	for rows.Next() {
+		var e models.GroupEvent
+		var creator models.User
+		var userResponse sql.NullString
+		if err := rows.Scan(
+			&e.ID, &e.GroupID, &e.CreatorID, &e.Title, &e.Description, &e.EventDate, &e.CreatedAt,
+			&creator.Username, &creator.Avatar,
+			&e.GoingCount, &e.NotGoingCount, &userResponse,
+		); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		creator.ID = e.CreatorID
+		e.Creator = &creator
+		e.UserResponse = stringOrEmpty(userResponse)
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	events, next := store.Cut(events, page, func(e models.GroupEvent) store.Cursor {
+		return store.Cursor{Time: time.Unix(e.EventDate, 0), ID: e.ID}
+	})
+
+	// responses are loaded once the event rows are closed, SQLite may only have one connection
+	for i := range events {
+		events[i].Responses, err = s.eventResponses(ctx, events[i].ID, groupID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return events, next, nil
+}
+
+func (s *groupStore) eventResponses(ctx context.Context, eventID, groupID int) ([]models.EventResponse, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ger.event_id, ger.user_id, ger.response, ger.created_at,
+		       u.username, u.avatar
+		FROM group_event_responses ger
+		JOIN users u ON ger.user_id = u.id
+		WHERE ger.event_id = ?
+		ORDER BY ger.created_at ASC, ger.id ASC`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	responses := []models.EventResponse{}
+	for rows.Next() {
+		var r models.EventResponse
+		var user models.User
+		if err := rows.Scan(&r.EventID, &r.UserID, &r.Response, &r.CreatedAt, &user.Username, &user.Avatar); err != nil {
+			return nil, err
+		}
+		r.GroupID = groupID
+		user.ID = r.UserID
+		r.User = &user
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+func (s *groupStore) EventInGroup(ctx context.Context, eventID, groupID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_events WHERE id = ? AND group_id = ?)",
+		eventID, groupID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (s *groupStore) RespondToEvent(ctx context.Context, eventID, userID int, response string) error {
+	now := time.Now().Unix()
+	// upsert: answering twice changes the previous answer
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO group_event_responses (event_id, user_id, response, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(event_id, user_id) DO UPDATE SET response = ?, created_at = ?`,
+		eventID, userID, response, now, response, now)
+	return err
+}
@@ -0,0 +1,65 @@
+package sqlstore
+
+import (
+	"context"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type idempotencyStore struct {
+	db *db.DB
+}
+
+func (s *idempotencyStore) Reserve(ctx context.Context, key *models.IdempotencyKey, now int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// an expired key is free again, the cleanup job may not have passed yet
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND key = ? AND expires_at <= ?",
+		key.UserID, key.Key, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO idempotency_keys (user_id, key, request_hash, status, created_at, expires_at)
+		VALUES (?, ?, ?, 0, ?, ?)`, key.UserID, key.Key, key.RequestHash, key.CreatedAt, key.ExpiresAt)
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	key.Status = 0
+	return tx.Commit()
+}
+
+func (s *idempotencyStore) Get(ctx context.Context, userID int, key string) (models.IdempotencyKey, error) {
+	k := models.IdempotencyKey{UserID: userID, Key: key}
+	var body string
+	err := s.db.QueryRowContext(ctx, `SELECT request_hash, status, content_type, body, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key).
+		Scan(&k.RequestHash, &k.Status, &k.ContentType, &body, &k.CreatedAt, &k.ExpiresAt)
+	k.Body = []byte(body)
+	return k, notFound(err)
+}
+
+func (s *idempotencyStore) Save(ctx context.Context, key models.IdempotencyKey) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status = ?, content_type = ?, body = ? WHERE user_id = ? AND key = ?",
+		key.Status, key.ContentType, string(key.Body), key.UserID, key.Key)
+	return err
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, userID int, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?", userID, key)
+	return err
+}
+
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", now)
+	return count(res, err)
+}
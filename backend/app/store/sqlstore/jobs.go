@@ -0,0 +1,178 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type jobStore struct {
+	db *db.DB
+}
+
+const jobColumns = `id, type, payload, unique_key, status, attempts, max_attempts, run_at, last_error, created_at, updated_at`
+
+func (s *jobStore) Enqueue(ctx context.Context, job *models.Job) error {
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+	job.Status = models.JobPending
+	job.Attempts = 0
+	job.UpdatedAt = job.CreatedAt
+
+	id, err := s.db.InsertContext(ctx, `INSERT INTO jobs
+	(type, payload, unique_key, status, attempts, max_attempts, run_at, created_at, updated_at)
+	VALUES (?, ?, ?, ?, 0, ?, ?, ?, ?)`,
+		job.Type, string(job.Payload), job.UniqueKey, job.Status, job.MaxAttempts, job.RunAt, job.CreatedAt, job.UpdatedAt)
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	job.ID = int(id)
+	return nil
+}
+
+func (s *jobStore) Claim(ctx context.Context, now int64) (models.Job, error) {
+	// the status check in the outer WHERE keeps two SQLite workers from taking the same row,
+	// PostgreSQL gets SKIP LOCKED so the second worker moves on to the next job instead of waiting
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_at = ?, updated_at = ?
+		WHERE status = 'pending' AND id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= ?
+			ORDER BY run_at, id
+			LIMIT 1 `+s.db.Dialect.SkipLocked()+`
+		)
+		RETURNING `+jobColumns, now, now, now)
+	job, err := scanJob(row)
+	return job, notFound(err)
+}
+
+func (s *jobStore) Complete(ctx context.Context, id int, now int64) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET status = 'done', locked_at = NULL, updated_at = ? WHERE id = ?", now, id)
+	return err
+}
+
+func (s *jobStore) Retry(ctx context.Context, id int, now, runAt int64, lastError string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET status = 'pending', locked_at = NULL, run_at = ?, last_error = ?, updated_at = ? WHERE id = ?",
+		runAt, lastError, now, id)
+	return err
+}
+
+func (s *jobStore) Fail(ctx context.Context, id int, now int64, lastError string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET status = 'failed', locked_at = NULL, last_error = ?, updated_at = ? WHERE id = ?",
+		lastError, now, id)
+	return err
+}
+
+func (s *jobStore) RequeueStale(ctx context.Context, lockedBefore int64) (int, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET status = 'pending', locked_at = NULL WHERE status = 'running' AND locked_at < ?", lockedBefore)
+	return count(res, err)
+}
+
+func (s *jobStore) DeleteFinished(ctx context.Context, before int64) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM jobs WHERE status = 'done' AND updated_at < ?", before)
+	return count(res, err)
+}
+
+func (s *jobStore) List(ctx context.Context, status string, limit int) ([]models.Job, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM jobs
+		WHERE (? = '' OR status = ?)
+		ORDER BY id DESC
+		LIMIT ?`, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *jobStore) Counts(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM jobs GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{models.JobPending: 0, models.JobRunning: 0, models.JobDone: 0, models.JobFailed: 0}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// scanner is what *sql.Row and *sql.Rows have in common
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (models.Job, error) {
+	var job models.Job
+	var payload string
+	var uniqueKey, lastError sql.NullString
+	err := row.Scan(&job.ID, &job.Type, &payload, &uniqueKey, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &lastError, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return job, err
+	}
+	job.Payload = json.RawMessage(payload)
+	if uniqueKey.Valid {
+		job.UniqueKey = &uniqueKey.String
+	}
+	if lastError.Valid {
+		job.LastError = &lastError.String
+	}
+	return job, nil
+}
+
+// count is affected for statements that touch many rows
+func count(res sql.Result, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// isUniqueViolation recognises a UNIQUE or PRIMARY KEY constraint error of either driver
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return false
+}
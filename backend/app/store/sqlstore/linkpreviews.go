@@ -0,0 +1,57 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/db"
+)
+
+type linkPreviewStore struct {
+	db *db.DB
+}
+
+func (s *linkPreviewStore) Get(ctx context.Context, urls []string) (map[string]models.LinkPreview, error) {
+	previews := map[string]models.LinkPreview{}
+	if len(urls) == 0 {
+		return previews, nil
+	}
+	args := make([]any, len(urls))
+	for i, u := range urls {
+		args[i] = u
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT url, title, description, image, site_name, fetched_at
+		FROM link_previews
+		WHERE url IN (?`+strings.Repeat(", ?", len(urls)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.LinkPreview
+		var fetchedAt int64
+		if err := rows.Scan(&p.URL, &p.Title, &p.Description, &p.Image, &p.SiteName, &fetchedAt); err != nil {
+			return nil, err
+		}
+		p.FetchedAt = time.Unix(fetchedAt, 0)
+		previews[p.URL] = p
+	}
+	return previews, rows.Err()
+}
+
+func (s *linkPreviewStore) Save(ctx context.Context, p models.LinkPreview) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO link_previews (url, title, description, image, site_name, fetched_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET title = ?, description = ?, image = ?, site_name = ?, fetched_at = ?`,
+		p.URL, p.Title, p.Description, p.Image, p.SiteName, p.FetchedAt.Unix(),
+		p.Title, p.Description, p.Image, p.SiteName, p.FetchedAt.Unix())
+	return err
+}
+
+func (s *linkPreviewStore) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	return count(s.db.ExecContext(ctx, "DELETE FROM link_previews WHERE fetched_at < ?", before.Unix()))
+}
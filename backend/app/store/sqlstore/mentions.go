@@ -0,0 +1,74 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"social-network/app/mention"
+	"social-network/app/models"
+	"social-network/db"
+)
+
+// saveMentions replaces the mentions rows of a post (column post_id), comment (comment_id) or
+// group message (group_message_id) with the @usernames of its content that match a user, and
+// returns them. Usernames are matched exactly, like they are unique
+func saveMentions(ctx context.Context, tx *db.Tx, column string, id int, content string) ([]models.Mention, error) {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mentions WHERE "+column+" = ?", id); err != nil {
+		return nil, err
+	}
+	var mentions []models.Mention
+	for _, m := range mention.Parse(content) {
+		err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE username = ?", m.Username).Scan(&m.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO mentions (user_id, "+column+", start_pos, length) VALUES (?, ?, ?, ?)",
+			m.UserID, id, m.Offset, m.Length)
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, nil
+}
+
+// loadMentions reads the mentions of the posts, comments or group messages with the ids, by id.
+// The username is the current one, the content keeps what was written
+func loadMentions(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}, column string, ids []int) (map[int][]models.Mention, error) {
+	mentions := map[int][]models.Mention{}
+	if len(ids) == 0 {
+		return mentions, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT m.`+column+`, m.user_id, COALESCE(u.username, ''), m.start_pos, m.length
+		FROM mentions m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.`+column+` IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+		ORDER BY m.start_pos`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var m models.Mention
+		if err := rows.Scan(&id, &m.UserID, &m.Username, &m.Offset, &m.Length); err != nil {
+			return nil, err
+		}
+		mentions[id] = append(mentions[id], m)
+	}
+	return mentions, rows.Err()
+}
@@ -0,0 +1,235 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type notificationStore struct {
+	db *db.DB
+}
+
+func (s *notificationStore) Create(ctx context.Context, n *models.Notification) error {
+	return createNotification(ctx, s.db, n)
+}
+
+// inserter is a *db.DB or a *db.Tx
+type inserter interface {
+	InsertContext(ctx context.Context, query string, args ...any) (int64, error)
+}
+
+func createNotification(ctx context.Context, q inserter, n *models.Notification) error {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if n.SenderCount == 0 {
+		n.SenderCount = 1
+	}
+
+	// horizontal expansion WORK BETTER
+	id, err := q.InsertContext(ctx, `INSERT INTO notifications
+	(user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, sender_count, post_id, group_id, group_name, event_id,
+	target_type, target_id, is_read, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.UserID,
+		n.FollowRequestID,
+		n.Type,
+		n.SenderID,
+		n.SenderName,
+		n.SenderAvatar,
+		n.SenderCount,
+		n.PostID,
+		n.GroupID,
+		n.GroupName,
+		n.EventID,
+		n.TargetType,
+		n.TargetID,
+		false,
+		n.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	n.ID = int(id)
+	n.IsRead = false
+	return nil
+}
+
+func (s *notificationStore) Aggregate(ctx context.Context, n *models.Notification) error {
+	err := s.aggregate(ctx, n)
+	if isUniqueViolation(err) {
+		// a concurrent reaction inserted the unread notification first (the unique index on the
+		// unread reaction notifications), this time the SELECT finds it
+		err = s.aggregate(ctx, n)
+	}
+	return err
+}
+
+func (s *notificationStore) aggregate(ctx context.Context, n *models.Notification) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM notifications
+		WHERE user_id = ? AND type = ? AND target_type = ? AND target_id = ? AND is_read = FALSE`,
+		n.UserID, n.Type, n.TargetType, n.TargetID).Scan(&n.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := createNotification(ctx, tx, n); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	// the notification moves back to the top of the list with the latest sender
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notifications SET sender_id = ?, sender_name = ?, sender_avatar = ?, sender_count = ?, created_at = ?
+		WHERE id = ?`,
+		n.SenderID, n.SenderName, n.SenderAvatar, n.SenderCount, n.CreatedAt, n.ID); err != nil {
+		return err
+	}
+	n.IsRead = false
+	return tx.Commit()
+}
+
+var notificationOrder = keyset{sortCol: "created_at", idCol: "id", desc: true}
+
+func (s *notificationStore) List(ctx context.Context, userID int, page store.Page) ([]models.Notification, *store.Cursor, error) {
+	after, args := notificationOrder.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+		  id,
+		  user_id,
+		  type,
+		  sender_id,
+		  follow_request_id,
+		  sender_name,
+		  sender_avatar,
+		  sender_count,
+		  post_id,
+		  group_id,
+		  group_name,
+		  event_id,
+		  target_type,
+		  target_id,
+		  is_read,
+		  created_at
+		FROM notifications
+		WHERE user_id = ? AND `+after+notificationOrder.orderBy(page),
+		append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var senderID, postID, groupID, eventID, targetID sql.NullInt64
+
+		if err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.Type,
+			&senderID,
+			&n.FollowRequestID,
+			&n.SenderName,
+			&n.SenderAvatar,
+			&n.SenderCount,
+			&postID,
+			&groupID,
+			&n.GroupName,
+			&eventID,
+			&n.TargetType,
+			&targetID,
+			&n.IsRead,
+			&n.CreatedAt,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		n.SenderID = nullInt(senderID)
+		n.PostID = nullInt(postID)
+		n.GroupID = nullInt(groupID)
+		n.EventID = nullInt(eventID)
+		n.TargetID = nullInt(targetID)
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	notifications, next := store.Cut(notifications, page, func(n models.Notification) store.Cursor {
+		return store.Cursor{Time: n.CreatedAt, ID: n.ID}
+	})
+	return notifications, next, nil
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+func (s *notificationStore) MarkAllRead(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE notifications SET is_read = TRUE WHERE user_id = ?", userID)
+	return err
+}
+
+func (s *notificationStore) Delete(ctx context.Context, id, userID int) (bool, error) {
+	return affected(s.db.ExecContext(ctx, "DELETE FROM notifications WHERE id = ? AND user_id = ?", id, userID))
+}
+
+func (s *notificationStore) DeleteMatching(ctx context.Context, filter store.NotificationFilter) error {
+	query, args, err := notificationWhere(filter)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM notifications WHERE "+query, args...)
+	return err
+}
+
+// notificationWhere builds the WHERE clause for a filter, an empty filter is refused
+// so a bug in a handler can't wipe the whole table
+func notificationWhere(filter store.NotificationFilter) (string, []any, error) {
+	var conditions []string
+	var args []any
+
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.SenderID != 0 {
+		conditions = append(conditions, "sender_id = ?")
+		args = append(args, filter.SenderID)
+	}
+	if filter.GroupID != 0 {
+		conditions = append(conditions, "group_id = ?")
+		args = append(args, filter.GroupID)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.Type)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, errors.New("empty notification filter")
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
@@ -0,0 +1,51 @@
+package sqlstore
+
+import (
+	"fmt"
+	"strconv"
+
+	"social-network/app/store"
+	"social-network/db"
+)
+
+// keyset is the sort order of a paginated list: sortCol then idCol, both descending for the
+// newest first lists
+type keyset struct {
+	sortCol string
+	idCol   string
+	unix    bool // sortCol holds unix seconds, a TIMESTAMP otherwise
+	desc    bool
+}
+
+// after returns the WHERE condition of the rows that come after the page's cursor, "1 = 1" on the
+// first page so the queries can always AND it
+func (k keyset) after(d db.Dialect, p store.Page) (string, []any) {
+	if p.After == nil {
+		return "1 = 1", nil
+	}
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	col, param := k.sortCol, "?"
+	var arg any = p.After.Time.Unix()
+	if !k.unix {
+		col, param = d.Timestamp(col), d.Timestamp("?")
+		arg = p.After.Time.UTC().Format("2006-01-02 15:04:05.999999")
+	}
+	cond := fmt.Sprintf("(%s %s %s OR (%s = %s AND %s %s ?))", col, op, param, col, param, k.idCol, op)
+	return cond, []any{arg, arg, p.After.ID}
+}
+
+// orderBy is the ORDER BY and LIMIT of the page, one row more than the page (see store.Page.Fetch)
+func (k keyset) orderBy(p store.Page) string {
+	dir := "ASC"
+	if k.desc {
+		dir = "DESC"
+	}
+	clause := fmt.Sprintf(" ORDER BY %s %s, %s %s", k.sortCol, dir, k.idCol, dir)
+	if n := p.Fetch(); n > 0 {
+		clause += " LIMIT " + strconv.Itoa(n)
+	}
+	return clause
+}
@@ -0,0 +1,234 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type pollStore struct {
+	db *db.DB
+}
+
+// savePoll inserts the poll of a new post (column post_id) or group post (group_post_id) with its
+// options in their order, and fills in the ids. Nothing to do without a poll
+func savePoll(ctx context.Context, tx *db.Tx, column string, id int, poll *models.Poll) error {
+	if poll == nil {
+		return nil
+	}
+	var closesAt *int64
+	if poll.ClosesAt != nil {
+		at := poll.ClosesAt.Unix()
+		closesAt = &at
+	}
+	pollID, err := tx.InsertContext(ctx,
+		"INSERT INTO polls ("+column+", multiple, hide_results, closes_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		id, poll.Multiple, poll.HideResults, closesAt, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	poll.ID = int(pollID)
+	if column == "post_id" {
+		poll.PostID = &id
+	} else {
+		poll.GroupPostID = &id
+	}
+	for i := range poll.Options {
+		optionID, err := tx.InsertContext(ctx,
+			"INSERT INTO poll_options (poll_id, position, text) VALUES (?, ?, ?)", poll.ID, i+1, poll.Options[i].Text)
+		if err != nil {
+			return err
+		}
+		poll.Options[i].ID, poll.Options[i].Votes = int(optionID), 0
+	}
+	poll.Voters, poll.MyVotes = 0, []int{}
+	return nil
+}
+
+func (s *pollStore) Get(ctx context.Context, id, viewerID int) (models.Poll, error) {
+	polls, err := s.load(ctx, "id", []int{id}, viewerID)
+	if err != nil {
+		return models.Poll{}, err
+	}
+	poll, ok := polls[id]
+	if !ok {
+		return models.Poll{}, store.ErrNotFound
+	}
+	return poll, nil
+}
+
+func (s *pollStore) Polls(ctx context.Context, targetType string, postIDs []int, viewerID int) (map[int]models.Poll, error) {
+	column := "post_id"
+	if targetType == models.TargetGroupPost {
+		column = "group_post_id"
+	}
+	return s.load(ctx, column, postIDs, viewerID)
+}
+
+// load reads the polls whose column is one of the ids, keyed by that column
+func (s *pollStore) load(ctx context.Context, column string, ids []int, viewerID int) (map[int]models.Poll, error) {
+	polls := map[int]models.Poll{}
+	if len(ids) == 0 {
+		return polls, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pl.id, pl.post_id, pl.group_post_id, gp.group_id, COALESCE(p.user_id, gp.user_id, 0),
+		       pl.multiple, pl.hide_results, pl.closes_at
+		FROM polls pl
+		LEFT JOIN posts p ON p.id = pl.post_id
+		LEFT JOIN group_posts gp ON gp.id = pl.group_post_id
+		WHERE pl.`+column+` IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int]*models.Poll{}
+	keys := map[int]int{} // poll id -> the id the map is keyed by
+	for rows.Next() {
+		poll := &models.Poll{Options: []models.PollOption{}, MyVotes: []int{}}
+		var closesAt *int64
+		if err := rows.Scan(&poll.ID, &poll.PostID, &poll.GroupPostID, &poll.GroupID, &poll.AuthorID,
+			&poll.Multiple, &poll.HideResults, &closesAt); err != nil {
+			return nil, err
+		}
+		if closesAt != nil {
+			at := time.Unix(*closesAt, 0)
+			poll.ClosesAt = &at
+		}
+		byID[poll.ID] = poll
+		switch column {
+		case "post_id":
+			keys[poll.ID] = *poll.PostID
+		case "group_post_id":
+			keys[poll.ID] = *poll.GroupPostID
+		default:
+			keys[poll.ID] = poll.ID
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(byID) == 0 {
+		return polls, nil
+	}
+
+	pollArgs := []any{}
+	for id := range byID {
+		pollArgs = append(pollArgs, id)
+	}
+	in := `(?` + strings.Repeat(", ?", len(pollArgs)-1) + `)`
+
+	// the options with their counts, in the author's order
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT o.poll_id, o.id, o.text, COUNT(v.user_id)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id IN `+in+`
+		GROUP BY o.poll_id, o.id, o.text, o.position
+		ORDER BY o.poll_id, o.position`, pollArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pollID int
+		var o models.PollOption
+		if err := rows.Scan(&pollID, &o.ID, &o.Text, &o.Votes); err != nil {
+			return nil, err
+		}
+		byID[pollID].Options = append(byID[pollID].Options, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// who voted, the viewer's options in the same order
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT v.poll_id, v.user_id, v.option_id
+		FROM poll_votes v
+		JOIN poll_options o ON o.id = v.option_id
+		WHERE v.poll_id IN `+in+`
+		ORDER BY v.poll_id, o.position`, pollArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	voters := map[int]map[int]bool{}
+	for rows.Next() {
+		var pollID, userID, optionID int
+		if err := rows.Scan(&pollID, &userID, &optionID); err != nil {
+			return nil, err
+		}
+		if voters[pollID] == nil {
+			voters[pollID] = map[int]bool{}
+		}
+		voters[pollID][userID] = true
+		if userID == viewerID {
+			byID[pollID].MyVotes = append(byID[pollID].MyVotes, optionID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, poll := range byID {
+		poll.Voters = len(voters[id])
+		polls[keys[id]] = *poll
+	}
+	return polls, nil
+}
+
+func (s *pollStore) Vote(ctx context.Context, pollID, userID int, optionIDs []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?", pollID, userID); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, optionID := range optionIDs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO poll_votes (poll_id, option_id, user_id, voted_at) VALUES (?, ?, ?, ?)",
+			pollID, optionID, userID, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *pollStore) Unvote(ctx context.Context, pollID, userID int) (bool, error) {
+	return affected(s.db.ExecContext(ctx, "DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?", pollID, userID))
+}
+
+func (s *pollStore) Voters(ctx context.Context, pollID int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT user_id FROM poll_votes WHERE poll_id = ?", pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	voters := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		voters = append(voters, id)
+	}
+	return voters, rows.Err()
+}
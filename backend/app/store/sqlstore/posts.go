@@ -0,0 +1,527 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type postStore struct {
+	db *db.DB
+}
+
+// visibleTo is the WHERE fragment behind every post read, it needs the viewer id three times.
+// It is the SQL form of the rules documented on store.PostStore
+const visibleTo = `p.group_id IS NULL AND p.status = 'published' AND (
+		p.user_id = ?
+		OR p.privacy = 'public'
+		OR (p.privacy = 'almost_private' AND ? IN (
+			SELECT pv.user_id FROM post_visibility pv WHERE pv.post_id = p.id
+			UNION ALL SELECT m.user_id FROM post_audience_lists pa
+			JOIN audience_list_members m ON m.list_id = pa.list_id
+			JOIN followers lf ON lf.follower_id = m.user_id AND lf.followed_id = p.user_id
+			WHERE pa.post_id = p.id))
+		OR (p.privacy = 'private' AND EXISTS (
+			SELECT 1 FROM followers f WHERE f.followed_id = p.user_id AND f.follower_id = ?))
+	)`
+
+// visibleToUser is visibleTo for the user of the row u instead of a parameter
+var visibleToUser = strings.ReplaceAll(visibleTo, "?", "u.id")
+
+const postColumns = `p.id, p.content, p.content_html, p.image, p.privacy, p.user_id, p.created_at, p.edited_at, p.repost_of,
+	p.status, p.publish_at, COALESCE(u.username, ''), COALESCE(u.avatar, '')`
+
+// scanPost reads the postColumns of a row
+func scanPost(row scanner) (models.Post, error) {
+	var post models.Post
+	var publishAt *int64
+	err := row.Scan(&post.ID, &post.Content, &post.ContentHTML, &post.Image, &post.Privacy, &post.UserID, &post.CreatedAt,
+		&post.EditedAt, &post.RepostOf, &post.Status, &publishAt, &post.Username, &post.Avatar)
+	post.Format = formatOf(post.ContentHTML)
+	if publishAt != nil {
+		at := time.Unix(*publishAt, 0)
+		post.PublishAt = &at
+	}
+	return post, err
+}
+
+// publishAt is the publish_at column of the post, only scheduled posts have one
+func publishAt(post *models.Post) *int64 {
+	if post.Status != models.PostScheduled || post.PublishAt == nil {
+		return nil
+	}
+	at := post.PublishAt.Unix()
+	return &at
+}
+
+func (s *postStore) Create(ctx context.Context, post *models.Post) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if post.Status == "" {
+		post.Status = models.PostPublished
+	}
+	postID, err := tx.InsertContext(ctx,
+		`INSERT INTO posts (content, content_html, image, privacy, user_id, group_id, repost_of, status, publish_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		post.Content, post.ContentHTML, post.Image, post.Privacy, post.UserID, post.GroupID, post.RepostOf, post.Status, publishAt(post),
+	)
+	if err != nil {
+		return err
+	}
+	post.ID = int(postID)
+
+	if err := saveAudience(ctx, tx, post); err != nil {
+		return err
+	}
+	if err := saveTags(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return err
+	}
+	if post.Mentions, err = saveMentions(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return err
+	}
+	if err := saveAttachments(ctx, tx, "post_id", post.ID, post.Attachments); err != nil {
+		return err
+	}
+	if err := savePoll(ctx, tx, "post_id", post.ID, post.Poll); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, "SELECT created_at FROM posts WHERE id = ?", post.ID).Scan(&post.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveAudience writes the post_visibility rows of an almost_private post, only the chosen followers
+// end up there, and the lists it is shared with. Lists are read at view time, so their members are
+// not copied
+func saveAudience(ctx context.Context, tx *db.Tx, post *models.Post) error {
+	if post.Privacy != models.PrivacyAlmostPrivate {
+		return nil
+	}
+	for _, followerID := range post.AllowedFollowers {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO post_visibility (post_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			post.ID, followerID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, listID := range post.AudienceLists {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO post_audience_lists (post_id, list_id) VALUES (?, ?) ON CONFLICT DO NOTHING", post.ID, listID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearAudience drops the audience saveAudience wrote, before it is written again
+func clearAudience(ctx context.Context, tx *db.Tx, postID int) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_visibility WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM post_audience_lists WHERE post_id = ?", postID)
+	return err
+}
+
+func (s *postStore) PruneAudience(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	visibility, err := count(tx.ExecContext(ctx, `
+		DELETE FROM post_visibility WHERE NOT EXISTS (
+			SELECT 1 FROM posts p
+			JOIN followers f ON f.followed_id = p.user_id AND f.follower_id = post_visibility.user_id
+			WHERE p.id = post_visibility.post_id AND p.privacy = 'almost_private')`))
+	if err != nil {
+		return 0, err
+	}
+	lists, err := count(tx.ExecContext(ctx,
+		"DELETE FROM post_audience_lists WHERE post_id IN (SELECT id FROM posts WHERE privacy <> 'almost_private')"))
+	if err != nil {
+		return 0, err
+	}
+	return visibility + lists, tx.Commit()
+}
+
+func (s *postStore) Update(ctx context.Context, post *models.Post) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the current version becomes a revision, published at its last edit (or creation)
+	var old models.PostRevision
+	var editedAt *time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT content, content_html, image, privacy, created_at, edited_at FROM posts WHERE id = ? AND group_id IS NULL", post.ID,
+	).Scan(&old.Content, &old.ContentHTML, &old.Image, &old.Privacy, &old.CreatedAt, &editedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if editedAt != nil {
+		old.CreatedAt = *editedAt
+	}
+	// the revision only keeps the first image, like it did before there were several
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO post_revisions (post_id, content, content_html, image, privacy, created_at, replaced_at)
+		 VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		post.ID, old.Content, old.ContentHTML, old.Image, old.Privacy, old.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE posts SET content = ?, content_html = ?, image = ?, privacy = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?",
+		post.Content, post.ContentHTML, post.Image, post.Privacy, post.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// the audience is replaced as a whole, an empty list leaves the post to its author
+	if err := clearAudience(ctx, tx, post.ID); err != nil {
+		return nil, err
+	}
+	if err := saveAudience(ctx, tx, post); err != nil {
+		return nil, err
+	}
+	if err := saveTags(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return nil, err
+	}
+	if post.Mentions, err = saveMentions(ctx, tx, "post_id", post.ID, post.Content); err != nil {
+		return nil, err
+	}
+	dropped, err := droppedAttachments(ctx, tx, "post_id", post.ID, post.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveAttachments(ctx, tx, "post_id", post.ID, post.Attachments); err != nil {
+		return nil, err
+	}
+	if dropped, err = unusedImages(ctx, tx, dropped); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT user_id, created_at, edited_at FROM posts WHERE id = ?", post.ID).
+		Scan(&post.UserID, &post.CreatedAt, &post.EditedAt)
+	if err != nil {
+		return nil, err
+	}
+	return dropped, tx.Commit()
+}
+
+func (s *postStore) Delete(ctx context.Context, id int) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT image FROM posts WHERE id = ? AND image IS NOT NULL AND image <> ''
+		UNION SELECT image FROM post_revisions WHERE post_id = ? AND image IS NOT NULL AND image <> ''
+		UNION SELECT image FROM comments WHERE post_id = ? AND image IS NOT NULL AND image <> ''
+		UNION SELECT path FROM attachments WHERE post_id = ? OR comment_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		id, id, id, id, id)
+	if err != nil {
+		return nil, err
+	}
+	images := []string{}
+	for rows.Next() {
+		var image string
+		if err := rows.Scan(&image); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// reactions can't cascade (target_id points into several tables), they go before the comments do
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reactions
+		WHERE (target_type = 'post' AND target_id = ?)
+		OR (target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id = ?))`,
+		id, id); err != nil {
+		return nil, err
+	}
+
+	// comments, revisions and the audience go with the post (ON DELETE CASCADE)
+	deleted, err := affected(tx.ExecContext(ctx, "DELETE FROM posts WHERE id = ? AND group_id IS NULL", id))
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, store.ErrNotFound
+	}
+	// notifications only keep the post id, no foreign key to cascade on
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notifications WHERE post_id = ?", id); err != nil {
+		return nil, err
+	}
+	if images, err = unusedImages(ctx, tx, images); err != nil {
+		return nil, err
+	}
+	return images, tx.Commit()
+}
+
+func (s *postStore) Revisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, post_id, content, content_html, image, privacy, created_at, replaced_at
+		FROM post_revisions
+		WHERE post_id = ?
+		ORDER BY id ASC`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.PostRevision{}
+	for rows.Next() {
+		var r models.PostRevision
+		if err := rows.Scan(&r.ID, &r.PostID, &r.Content, &r.ContentHTML, &r.Image, &r.Privacy, &r.CreatedAt, &r.ReplacedAt); err != nil {
+			return nil, err
+		}
+		r.Format = formatOf(r.ContentHTML)
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *postStore) Get(ctx context.Context, id int) (models.Post, error) {
+	post, err := scanPost(s.db.QueryRowContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.id = ?`, id))
+	if err != nil {
+		return post, notFound(err)
+	}
+	mentions, err := loadMentions(ctx, s.db, "post_id", []int{id})
+	if err != nil {
+		return post, err
+	}
+	post.Mentions = mentions[id]
+	attachments, err := loadAttachments(ctx, s.db, "post_id", []int{id})
+	post.Attachments = attachments[id]
+	return post, err
+}
+
+func (s *postStore) CanView(ctx context.Context, postID, viewerID int) (bool, error) {
+	var visible bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM posts p WHERE p.id = ? AND `+visibleTo+`)`,
+		postID, viewerID, viewerID, viewerID,
+	).Scan(&visible)
+	return visible, err
+}
+
+// feedOrder is the newest first order of the feed
+var feedOrder = keyset{sortCol: "p.created_at", idCol: "p.id", desc: true}
+
+func (s *postStore) Viewers(ctx context.Context, postID int, userIDs []int) ([]int, error) {
+	viewers := []int{}
+	if len(userIDs) == 0 {
+		return viewers, nil
+	}
+	args := []any{postID}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id
+		FROM users u
+		JOIN posts p ON p.id = ?
+		WHERE u.id IN (?`+strings.Repeat(", ?", len(userIDs)-1)+`) AND `+visibleToUser,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		viewers = append(viewers, id)
+	}
+	return viewers, rows.Err()
+}
+
+func (s *postStore) Feed(ctx context.Context, viewerID int, page store.Page) ([]models.Post, *store.Cursor, error) {
+	after, args := feedOrder.after(s.db.Dialect, page)
+	posts, err := s.list(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE `+visibleTo+` AND `+after+feedOrder.orderBy(page),
+		append([]any{viewerID, viewerID, viewerID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, next := store.Cut(posts, page, postCursor)
+	return posts, next, nil
+}
+
+func postCursor(p models.Post) store.Cursor { return store.Cursor{Time: p.CreatedAt, ID: p.ID} }
+
+func (s *postStore) ByAuthor(ctx context.Context, authorID, viewerID int) ([]models.Post, error) {
+	return s.list(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ? AND `+visibleTo+`
+		ORDER BY p.created_at DESC, p.id DESC`, authorID, viewerID, viewerID, viewerID)
+}
+
+func (s *postStore) list(ctx context.Context, query string, args ...any) ([]models.Post, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	mentions, err := loadMentions(ctx, s.db, "post_id", ids)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := loadAttachments(ctx, s.db, "post_id", ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Mentions = mentions[posts[i].ID]
+		posts[i].Attachments = attachments[posts[i].ID]
+	}
+	return posts, nil
+}
+
+func (s *postStore) CountByAuthor(ctx context.Context, authorID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE user_id = ? AND group_id IS NULL AND status = 'published'", authorID).Scan(&count)
+	return count, err
+}
+
+const commentColumns = `c.id, c.content, c.content_html, c.image, c.post_id, c.user_id, c.created_at,
+	COALESCE(u.username, ''), u.first_name, u.last_name, u.avatar`
+
+func (s *postStore) CreateComment(ctx context.Context, comment *models.Comment) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	commentID, err := tx.InsertContext(ctx,
+		`INSERT INTO comments (content, content_html, image, post_id, user_id, created_at)
+		 VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		comment.Content, comment.ContentHTML, comment.Image, comment.PostID, comment.UserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if comment.Mentions, err = saveMentions(ctx, tx, "comment_id", int(commentID), comment.Content); err != nil {
+		return err
+	}
+	if err := saveAttachments(ctx, tx, "comment_id", int(commentID), comment.Attachments); err != nil {
+		return err
+	}
+
+	// read the row back so the response has the author info and the stored date
+	err = tx.QueryRowContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = ?`, commentID,
+	).Scan(&comment.ID, &comment.Content, &comment.ContentHTML, &comment.Image, &comment.PostID, &comment.UserID, &comment.CreatedAt,
+		&comment.Username, &comment.FirstName, &comment.LastName, &comment.Avatar)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var commentOrder = keyset{sortCol: "c.created_at", idCol: "c.id"}
+
+func (s *postStore) Comments(ctx context.Context, postID int, page store.Page) ([]models.Comment, *store.Cursor, error) {
+	after, args := commentOrder.after(s.db.Dialect, page)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.post_id = ? AND `+after+commentOrder.orderBy(page),
+		append([]any{postID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		if err := rows.Scan(&c.ID, &c.Content, &c.ContentHTML, &c.Image, &c.PostID, &c.UserID, &c.CreatedAt,
+			&c.Username, &c.FirstName, &c.LastName, &c.Avatar); err != nil {
+			return nil, nil, err
+		}
+		c.Format = formatOf(c.ContentHTML)
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]int, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+	mentions, err := loadMentions(ctx, s.db, "comment_id", ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachments, err := loadAttachments(ctx, s.db, "comment_id", ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+		comments[i].Attachments = attachments[comments[i].ID]
+	}
+	comments, next := store.Cut(comments, page, func(c models.Comment) store.Cursor {
+		return store.Cursor{Time: c.CreatedAt, ID: c.ID}
+	})
+	return comments, next, nil
+}
@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type reactionStore struct {
+	db *db.DB
+}
+
+// targetQueries select owner, post and group of each target type by id
+var targetQueries = map[string]string{
+	models.TargetPost:      "SELECT user_id, id, 0 FROM posts WHERE id = ? AND group_id IS NULL",
+	models.TargetComment:   "SELECT user_id, post_id, 0 FROM comments WHERE id = ?",
+	models.TargetGroupPost: "SELECT user_id, id, group_id FROM group_posts WHERE id = ?",
+	models.TargetGroupComment: `SELECT c.user_id, c.post_id, p.group_id
+		FROM group_post_comments c JOIN group_posts p ON p.id = c.post_id
+		WHERE c.id = ?`,
+}
+
+func (s *reactionStore) Target(ctx context.Context, targetType string, targetID int) (models.ReactionTarget, error) {
+	query, ok := targetQueries[targetType]
+	if !ok {
+		return models.ReactionTarget{}, store.ErrNotFound
+	}
+	var t models.ReactionTarget
+	err := s.db.QueryRowContext(ctx, query, targetID).Scan(&t.OwnerID, &t.PostID, &t.GroupID)
+	return t, notFound(err)
+}
+
+func (s *reactionStore) React(ctx context.Context, r models.Reaction) (bool, error) {
+	changed, err := affected(s.db.ExecContext(ctx,
+		"UPDATE reactions SET type = ? WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		r.Type, r.UserID, r.TargetType, r.TargetID))
+	if err != nil || changed {
+		return false, err
+	}
+	// a double click can get here twice, the second insert turns into the update
+	_, err = s.db.ExecContext(ctx, `INSERT INTO reactions (user_id, target_type, target_id, type) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE SET type = excluded.type`,
+		r.UserID, r.TargetType, r.TargetID, r.Type)
+	return err == nil, err
+}
+
+func (s *reactionStore) Unreact(ctx context.Context, userID int, targetType string, targetID int) (bool, error) {
+	return affected(s.db.ExecContext(ctx,
+		"DELETE FROM reactions WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		userID, targetType, targetID))
+}
+
+func (s *reactionStore) Summaries(ctx context.Context, targetType string, targetIDs []int, viewerID int) (map[int]models.ReactionSummary, error) {
+	summaries := map[int]models.ReactionSummary{}
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	args := []any{viewerID, targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT target_id, type, COUNT(*), MAX(CASE WHEN user_id = ? THEN 1 ELSE 0 END)
+		FROM reactions
+		WHERE target_type = ? AND target_id IN (?`+strings.Repeat(", ?", len(targetIDs)-1)+`)
+		GROUP BY target_id, type`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID, count, mine int
+		var reaction string
+		if err := rows.Scan(&targetID, &reaction, &count, &mine); err != nil {
+			return nil, err
+		}
+		summary, ok := summaries[targetID]
+		if !ok {
+			summary.Reactions = map[string]int{}
+		}
+		summary.Reactions[reaction] = count
+		if mine == 1 {
+			summary.MyReaction = reaction
+		}
+		summaries[targetID] = summary
+	}
+	return summaries, rows.Err()
+}
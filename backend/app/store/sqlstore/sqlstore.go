@@ -0,0 +1,77 @@
+// Package sqlstore implements the store interfaces on top of db.DB.
+// Queries are written once with "?" placeholders, db.DB rebinds them for
+// PostgreSQL so the same code serves both backends.
+package sqlstore
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+// New returns every store backed by the given database
+func New(database *db.DB) *store.Stores {
+	return &store.Stores{
+		Users:         &userStore{db: database},
+		Posts:         &postStore{db: database},
+		Follows:       &followStore{db: database},
+		Groups:        &groupStore{db: database},
+		Chat:          &chatStore{db: database},
+		Notifications: &notificationStore{db: database},
+		Jobs:          &jobStore{db: database},
+		Idempotency:   &idempotencyStore{db: database},
+		Reactions:     &reactionStore{db: database},
+		Tags:          &tagStore{db: database},
+		Collections:   &collectionStore{db: database},
+		Polls:         &pollStore{db: database},
+		LinkPreviews:  &linkPreviewStore{db: database},
+		Audiences:     &audienceStore{db: database},
+	}
+}
+
+// notFound turns sql.ErrNoRows into store.ErrNotFound so handlers don't depend on database/sql
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// affected reports if an Exec touched at least one row
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// stringOrEmpty is used for the nullable text columns (users.avatar, users.username) that
+// the handlers expose as plain strings
+func stringOrEmpty(s sql.NullString) string {
+	if s.Valid {
+		return s.String
+	}
+	return ""
+}
+
+// formatOf is the Format of a content stored with its HTML: only Markdown has one
+func formatOf(contentHTML *string) string {
+	if contentHTML != nil {
+		return models.FormatMarkdown
+	}
+	return ""
+}
+
+/* notes:
+every store is a small struct holding the *db.DB, methods take the request context first so a
+client that goes away cancels its queries
+
+sql.ErrNoRows never leaves this package, callers compare against store.ErrNotFound instead
+*/
@@ -0,0 +1,211 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"social-network/app/hashtag"
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/db"
+)
+
+type tagStore struct {
+	db *db.DB
+}
+
+// saveTags makes the post_tags rows of a post (column post_id) or group post (group_post_id)
+// match its content. A tag the post keeps keeps its tagged_at, editing a typo doesn't push the
+// tag back up the trending list
+func saveTags(ctx context.Context, tx *db.Tx, column string, id int, content string) error {
+	rows, err := tx.QueryContext(ctx, "SELECT tag FROM post_tags WHERE "+column+" = ?", id)
+	if err != nil {
+		return err
+	}
+	current := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tags := hashtag.Parse(content)
+	for _, tag := range current {
+		if slices.Contains(tags, tag) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE "+column+" = ? AND tag = ?", id, tag); err != nil {
+			return err
+		}
+	}
+	now := time.Now().Unix()
+	for _, tag := range tags {
+		if slices.Contains(current, tag) {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO post_tags (tag, "+column+", tagged_at) VALUES (?, ?, ?)", tag, id, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tagStore) Posts(ctx context.Context, tag string, viewerID int, page store.Page) ([]models.Post, *store.Cursor, error) {
+	after, args := feedOrder.after(s.db.Dialect, page)
+	posts, err := (&postStore{db: s.db}).list(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE EXISTS (SELECT 1 FROM post_tags t WHERE t.post_id = p.id AND t.tag = ?)
+		AND `+visibleTo+` AND `+after+feedOrder.orderBy(page),
+		append([]any{tag, viewerID, viewerID, viewerID}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, next := store.Cut(posts, page, postCursor)
+	return posts, next, nil
+}
+
+func (s *tagStore) Trending(ctx context.Context, viewerID int, now time.Time, weights []float64, limit int) ([]models.TrendingTag, error) {
+	if len(weights) == 0 {
+		return []models.TrendingTag{}, nil
+	}
+	// the weights are numbers of the code, not input, they go in as literals
+	weight := "CASE (? - tagged_at) / 3600"
+	for h, w := range weights {
+		weight += " WHEN " + strconv.Itoa(h) + " THEN " + strconv.FormatFloat(w, 'f', -1, 64)
+	}
+	weight += " ELSE 0.0 END"
+	since := now.Unix() - int64(len(weights))*3600
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH uses AS (
+			SELECT t.tag, t.tagged_at
+			FROM post_tags t
+			JOIN posts p ON p.id = t.post_id
+			WHERE t.tagged_at > ? AND `+visibleTo+`
+			UNION ALL
+			SELECT t.tag, t.tagged_at
+			FROM post_tags t
+			JOIN group_posts gp ON gp.id = t.group_post_id
+			JOIN group_members gm ON gm.group_id = gp.group_id AND gm.user_id = ?
+			WHERE t.tagged_at > ?
+		)
+		SELECT tag, COUNT(*), SUM(`+weight+`) AS score
+		FROM uses
+		GROUP BY tag
+		ORDER BY score DESC, tag
+		LIMIT ?`,
+		since, viewerID, viewerID, viewerID, viewerID, since, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trending := []models.TrendingTag{}
+	for rows.Next() {
+		var t models.TrendingTag
+		if err := rows.Scan(&t.Tag, &t.Uses, &t.Score); err != nil {
+			return nil, err
+		}
+		trending = append(trending, t)
+	}
+	return trending, rows.Err()
+}
+
+// backfillBatch is how many untagged posts one transaction of Backfill looks at
+const backfillBatch = 500
+
+func (s *tagStore) Backfill(ctx context.Context) (int, error) {
+	posts, err := s.backfill(ctx, "posts", "post_id")
+	if err != nil {
+		return posts, err
+	}
+	groupPosts, err := s.backfill(ctx, "group_posts", "group_post_id")
+	return posts + groupPosts, err
+}
+
+// backfill tags the rows of table (posts or group_posts, column is their post_tags column) by
+// batches, only the content with a '#' can have tags
+func (s *tagStore) backfill(ctx context.Context, table, column string) (int, error) {
+	tagged, after := 0, 0
+	for {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return tagged, err
+		}
+		rows, err := tx.QueryContext(ctx, `
+			SELECT x.id, COALESCE(x.content, ''), x.created_at FROM `+table+` x
+			WHERE x.id > ? AND x.content LIKE '%#%'
+			AND NOT EXISTS (SELECT 1 FROM post_tags t WHERE t.`+column+` = x.id)
+			ORDER BY x.id
+			LIMIT ?`, after, backfillBatch)
+		if err != nil {
+			tx.Rollback()
+			return tagged, err
+		}
+		type untagged struct {
+			id      int
+			content string
+			at      int64
+		}
+		batch := []untagged{}
+		for rows.Next() {
+			var u untagged
+			var createdAt any // posts have a TIMESTAMP, group posts unix seconds
+			if err := rows.Scan(&u.id, &u.content, &createdAt); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return tagged, err
+			}
+			switch at := createdAt.(type) {
+			case time.Time:
+				u.at = at.Unix()
+			case int64:
+				u.at = at
+			default:
+				rows.Close()
+				tx.Rollback()
+				return tagged, fmt.Errorf("%s %d: unexpected created_at %T", table, u.id, createdAt)
+			}
+			batch = append(batch, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			tx.Rollback()
+			return tagged, err
+		}
+
+		for _, u := range batch {
+			tags := hashtag.Parse(u.content)
+			for _, tag := range tags {
+				_, err := tx.ExecContext(ctx, "INSERT INTO post_tags (tag, "+column+", tagged_at) VALUES (?, ?, ?)", tag, u.id, u.at)
+				if err != nil {
+					tx.Rollback()
+					return tagged, err
+				}
+			}
+			if len(tags) > 0 {
+				tagged++
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return tagged, err
+		}
+		if len(batch) < backfillBatch {
+			return tagged, nil
+		}
+		after = batch[len(batch)-1].id
+	}
+}
@@ -0,0 +1,159 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"social-network/app/models"
+	"social-network/db"
+)
+
+type userStore struct {
+	db *db.DB
+}
+
+func (s *userStore) Create(ctx context.Context, reg models.RegisterStruct, passwordHash string) (int, error) {
+	query := `
+		INSERT INTO users (
+			email, password, first_name, last_name,
+			date_of_birth, avatar, username, about_me,
+			is_private, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+
+	id, err := s.db.InsertContext(ctx, query,
+		reg.Email,
+		passwordHash,
+		reg.FirstName,
+		reg.LastName,
+		reg.DateOfBirth,
+		reg.Avatar,
+		reg.Username,
+		reg.AboutMe,
+		reg.IsPrivate,
+	)
+	return int(id), err
+}
+
+func (s *userStore) EmailTaken(ctx context.Context, email string) (bool, error) {
+	var taken bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&taken)
+	return taken, err
+}
+
+func (s *userStore) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	var taken bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", username).Scan(&taken)
+	return taken, err
+}
+
+func (s *userStore) Credentials(ctx context.Context, email string) (int, string, error) {
+	var userID int
+	var hashedPassword string
+	err := s.db.QueryRowContext(ctx, "SELECT id, password FROM users WHERE email = ? LIMIT 1", email).
+		Scan(&userID, &hashedPassword)
+	if err != nil {
+		return 0, "", notFound(err)
+	}
+	return userID, hashedPassword, nil
+}
+
+func (s *userStore) Get(ctx context.Context, id int) (models.User, error) {
+	var user models.User
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, email, first_name, last_name, date_of_birth, avatar, username, about_me, is_private, created_at
+		 FROM users WHERE id = ?`, id,
+	).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.DateOfBirth,
+		&user.Avatar, &user.Username, &user.AboutMe, &user.IsPrivate, &user.CreatedAt)
+	return user, notFound(err)
+}
+
+func (s *userStore) SetPrivacy(ctx context.Context, id int, isPrivate bool) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE users SET is_private = ? WHERE id = ?", isPrivate, id)
+	return err
+}
+
+// Search matches the query anywhere in the username, first or last name, case insensitive
+func (s *userStore) Search(ctx context.Context, query string, limit int) ([]models.User, error) {
+	pattern := "%" + query + "%"
+	like := s.db.Dialect.ILike()
+	return s.listUsers(ctx, `
+		SELECT id, username, first_name, last_name, avatar
+		FROM users
+		WHERE username `+like+` ? OR first_name `+like+` ? OR last_name `+like+` ?
+		ORDER BY id
+		LIMIT ?`, pattern, pattern, pattern, limit)
+}
+
+// likeEscaper makes a user's text literal inside a LIKE pattern, with '\' as the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *userStore) Autocomplete(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	return s.listUsers(ctx, `
+		SELECT id, username, first_name, last_name, avatar
+		FROM users
+		WHERE `+s.db.Dialect.PrefixLike("username")+`
+		ORDER BY LOWER(username), id
+		LIMIT ?`, likeEscaper.Replace(strings.ToLower(prefix))+"%", limit)
+}
+
+func (s *userStore) Random(ctx context.Context, limit int) ([]models.User, error) {
+	return s.listUsers(ctx, `
+		SELECT id, username, first_name, last_name, avatar
+		FROM users
+		ORDER BY `+s.db.Dialect.Random()+`
+		LIMIT ?`, limit)
+}
+
+func (s *userStore) listUsers(ctx context.Context, query string, args ...any) ([]models.User, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Avatar); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *userStore) CreateSession(ctx context.Context, userID int, token string, expiresAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// one session per user: logging in somewhere else logs out the old browser
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)",
+		token, userID, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *userStore) SessionUser(ctx context.Context, token string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM sessions WHERE id = ?", token).Scan(&userID)
+	return userID, notFound(err)
+}
+
+func (s *userStore) DeleteSession(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", token)
+	return err
+}
+
+func (s *userStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < ?", now)
+	return count(res, err)
+}
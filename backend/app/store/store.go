@@ -0,0 +1,360 @@
+// Package store defines the data layer the handlers talk to.
+//
+// Handlers never write SQL themselves: they receive a *Stores from server.SetupRoutes
+// and call the typed methods below. Two implementations exist:
+//   - sqlstore: backed by db.DB (SQLite or PostgreSQL, see package db)
+//   - memstore: plain maps, used by the handler tests
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"social-network/app/models"
+)
+
+var (
+	// ErrNotFound is returned when the requested row doesn't exist (or isn't visible to the caller)
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a row is not in the state the operation expects
+	ErrConflict = errors.New("conflict")
+)
+
+// Stores groups every store so it can be handed to the handlers in one go
+type Stores struct {
+	Users         UserStore
+	Posts         PostStore
+	Follows       FollowStore
+	Groups        GroupStore
+	Chat          ChatStore
+	Notifications NotificationStore
+	Jobs          JobStore
+	Idempotency   IdempotencyStore
+	Reactions     ReactionStore
+	Tags          TagStore
+	Collections   CollectionStore
+	Polls         PollStore
+	LinkPreviews  LinkPreviewStore
+	Audiences     AudienceStore
+}
+
+type UserStore interface {
+	// Create inserts a new user, reg.Password is ignored in favour of passwordHash
+	Create(ctx context.Context, reg models.RegisterStruct, passwordHash string) (int, error)
+	EmailTaken(ctx context.Context, email string) (bool, error)
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	// Credentials returns the id and bcrypt hash for an email, ErrNotFound if unknown
+	Credentials(ctx context.Context, email string) (int, string, error)
+	Get(ctx context.Context, id int) (models.User, error)
+	SetPrivacy(ctx context.Context, id int, isPrivate bool) error
+	Search(ctx context.Context, query string, limit int) ([]models.User, error)
+	// Autocomplete returns the users whose username starts with the prefix, case insensitive,
+	// in username order. It is backed by an index, it can run on every keystroke
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]models.User, error)
+	Random(ctx context.Context, limit int) ([]models.User, error)
+
+	// CreateSession replaces any existing session of the user
+	CreateSession(ctx context.Context, userID int, token string, expiresAt time.Time) error
+	SessionUser(ctx context.Context, token string) (int, error)
+	DeleteSession(ctx context.Context, token string) error
+	// DeleteExpiredSessions removes the sessions that expired before now and returns how many went
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int, error)
+}
+
+// PostStore covers regular (non group) posts and their comments.
+//
+// A published post is visible to a viewer when:
+//   - the viewer is the author
+//   - it is public
+//   - it is almost_private and the viewer is in its post_visibility list, or a member of one of
+//     its audience lists who follows the author, both at the time of the read
+//   - it is private and the viewer follows the author
+//
+// Drafts and scheduled posts are visible to nobody, the author only reaches them through Drafts.
+// Posts and comments come with their Attachments in order, Image is stored as given: the handlers
+// keep it the first attachment's path
+type PostStore interface {
+	// Create inserts the post and its almost_private audience (AllowedFollowers and AudienceLists),
+	// and sets post.ID and post.CreatedAt.
+	// The post is published unless its Status is draft or scheduled, its Attachments and Poll are
+	// saved with it
+	Create(ctx context.Context, post *models.Post) error
+	Get(ctx context.Context, id int) (models.Post, error)
+	CanView(ctx context.Context, postID, viewerID int) (bool, error)
+	// Viewers returns the users among userIDs who can see the post, with the rules of CanView
+	Viewers(ctx context.Context, postID int, userIDs []int) ([]int, error)
+	// Feed returns the posts visible to the viewer, newest first
+	Feed(ctx context.Context, viewerID int, page Page) ([]models.Post, *Cursor, error)
+	// ByAuthor returns the author's posts that the viewer is allowed to see
+	ByAuthor(ctx context.Context, authorID, viewerID int) ([]models.Post, error)
+	CountByAuthor(ctx context.Context, authorID int) (int, error)
+	// Update replaces content, images, privacy and audience of the post, the version it replaces
+	// goes to post_revisions. It sets post.EditedAt and post.CreatedAt, ErrNotFound if missing.
+	// It returns the paths of the attachments the edit dropped that no row references anymore
+	Update(ctx context.Context, post *models.Post) (images []string, err error)
+	// Delete removes the post with its comments, revisions and reactions and returns the image paths
+	// they used (attachments included) that no other row references, so the files can go too
+	Delete(ctx context.Context, id int) (images []string, err error)
+	// Revisions returns the earlier versions of a post, oldest first
+	Revisions(ctx context.Context, postID int) ([]models.PostRevision, error)
+
+	// Drafts returns the author's drafts and scheduled posts, newest first
+	Drafts(ctx context.Context, authorID int) ([]models.Post, error)
+	// UpdateDraft replaces content, images, privacy, audience, status and publish time of a post that
+	// isn't published yet, no revision is kept. ErrNotFound if missing or published
+	UpdateDraft(ctx context.Context, post *models.Post) error
+	// Publish makes a draft or scheduled post visible, dated now so it lands on top of the feeds.
+	// ErrNotFound if missing or already published
+	Publish(ctx context.Context, id int) (models.Post, error)
+	// PublishDue publishes the scheduled posts whose publish time is reached by now and returns them
+	PublishDue(ctx context.Context, now time.Time) ([]models.Post, error)
+	// PruneAudience removes the almost_private grants that don't hold anymore: post_visibility rows
+	// of users who don't follow the author, and the audience of posts that aren't almost_private.
+	// It returns how many rows went
+	PruneAudience(ctx context.Context) (int, error)
+
+	// CreateComment inserts the comment with its Attachments and fills in its id, date and author fields
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	// Comments are oldest first
+	Comments(ctx context.Context, postID int, page Page) ([]models.Comment, *Cursor, error)
+}
+
+type FollowStore interface {
+	IsFollowing(ctx context.Context, followerID, followedID int) (bool, error)
+	Follow(ctx context.Context, followerID, followedID int) error
+	// Unfollow ends the follow and takes the follower out of the post_visibility of the followed
+	// user's posts, false if they weren't following
+	Unfollow(ctx context.Context, followerID, followedID int) (bool, error)
+	Followers(ctx context.Context, userID int) ([]models.User, error)
+	Following(ctx context.Context, userID int) ([]models.User, error)
+	Counts(ctx context.Context, userID int) (followers int, following int, err error)
+
+	CreateRequest(ctx context.Context, requesterID, targetID int) (int64, error)
+	HasPendingRequest(ctx context.Context, requesterID, targetID int) (bool, error)
+	PendingRequests(ctx context.Context, targetID int) ([]models.FollowRequest, error)
+	// ResolveRequest approves or rejects a pending request addressed to targetID,
+	// removes it together with its notification and returns the requester.
+	// ErrNotFound if there is no such request, ErrConflict if it was already processed
+	ResolveRequest(ctx context.Context, requestID, targetID int, approve bool) (int, error)
+	// CancelRequest removes a pending request and its notification
+	CancelRequest(ctx context.Context, requesterID, targetID int) (bool, error)
+}
+
+type GroupStore interface {
+	// Create inserts the group, adds the creator as a member and sets g.ID
+	Create(ctx context.Context, g *models.Group) error
+	// List and Get fill the viewer dependent flags (IsMember, IsCreator, IsInvited, HasRequested)
+	List(ctx context.Context, viewerID int) ([]models.Group, error)
+	Get(ctx context.Context, groupID, viewerID int) (models.Group, error)
+	MemberGroups(ctx context.Context, userID int) ([]models.Group, error)
+
+	IsMember(ctx context.Context, groupID, userID int) (bool, error)
+	Members(ctx context.Context, groupID int) ([]models.GroupMember, error)
+	MemberIDs(ctx context.Context, groupID, excludeUserID int) ([]int, error)
+	AddMember(ctx context.Context, groupID, userID int, role string) error
+	RemoveMember(ctx context.Context, groupID, userID int) (bool, error)
+
+	HasPendingInvite(ctx context.Context, groupID, userID int) (bool, error)
+	CreateInvite(ctx context.Context, groupID, inviterID, userID int) error
+	// AcceptInvite and DeclineInvite remove the invitation and its notification, ErrNotFound if none is pending
+	AcceptInvite(ctx context.Context, groupID, userID int) error
+	DeclineInvite(ctx context.Context, groupID, userID int) error
+
+	HasPendingJoinRequest(ctx context.Context, groupID, userID int) (bool, error)
+	CreateJoinRequest(ctx context.Context, groupID, userID int) error
+	JoinRequests(ctx context.Context, groupID int) ([]models.GroupJoinRequest, error)
+	JoinRequestStatus(ctx context.Context, groupID, userID int) (string, error)
+	// ApproveJoinRequest adds the member and clears the request and its notification in one transaction
+	ApproveJoinRequest(ctx context.Context, groupID, userID int) error
+	// DeleteJoinRequest removes the request and its notification
+	DeleteJoinRequest(ctx context.Context, groupID, userID int) error
+
+	// CreatePost saves the post with its Attachments and Poll and fills in the id, date and author
+	CreatePost(ctx context.Context, post *models.GroupPost) error
+	// Posts are newest first, their comments oldest first
+	Posts(ctx context.Context, groupID int, page Page) ([]models.GroupPost, *Cursor, error)
+	PostInGroup(ctx context.Context, postID, groupID int) (bool, error)
+	CreateComment(ctx context.Context, comment *models.GroupComment) error
+	Comments(ctx context.Context, postID int, page Page) ([]models.GroupComment, *Cursor, error)
+
+	// CreateEvent inserts the event and an event_created notification for every member in one
+	// transaction, it fills event.ID, CreatedAt and Creator and returns the notified member ids
+	CreateEvent(ctx context.Context, event *models.GroupEvent) ([]int, error)
+	// Events are sorted by event_date, the cursor time is the event date
+	Events(ctx context.Context, groupID, viewerID int, page Page) ([]models.GroupEvent, *Cursor, error)
+	EventInGroup(ctx context.Context, eventID, groupID int) (bool, error)
+	RespondToEvent(ctx context.Context, eventID, userID int, response string) error
+}
+
+// ChatStore pages go back in time: the first page has the latest messages, the next one the
+// messages before them. Inside a page the messages are oldest first, the way a chat shows them
+type ChatStore interface {
+	SaveMessage(ctx context.Context, msg *models.Message) error
+	Conversation(ctx context.Context, userID, otherID int, page Page) ([]models.Message, *Cursor, error)
+	MarkRead(ctx context.Context, senderID, receiverID int) error
+	UnreadCount(ctx context.Context, receiverID, senderID int) (int, error)
+
+	SaveGroupMessage(ctx context.Context, msg *models.GroupMessage) error
+	GroupMessages(ctx context.Context, groupID int, page Page) ([]models.GroupMessage, *Cursor, error)
+}
+
+// NotificationFilter selects notifications to delete, zero fields are ignored
+type NotificationFilter struct {
+	UserID   int
+	SenderID int
+	GroupID  int
+	Type     string
+}
+
+type NotificationStore interface {
+	Create(ctx context.Context, n *models.Notification) error
+	// List is newest first
+	List(ctx context.Context, userID int, page Page) ([]models.Notification, *Cursor, error)
+	MarkAllRead(ctx context.Context, userID int) error
+	// Delete removes one notification of the user, false if it didn't exist
+	Delete(ctx context.Context, id, userID int) (bool, error)
+	DeleteMatching(ctx context.Context, filter NotificationFilter) error
+	// Aggregate updates the user's unread notification of the same type and target with the new
+	// sender, count and date, and creates it when there is none. n.TargetType and n.TargetID must be set
+	Aggregate(ctx context.Context, n *models.Notification) error
+}
+
+// ReactionStore keeps one reaction per user and target, targetType is one of the models.Target* constants.
+// Deleting a post removes the reactions of the post and its comments (see PostStore.Delete)
+type ReactionStore interface {
+	// Target returns who wrote the target and where it lives, ErrNotFound if it doesn't exist
+	Target(ctx context.Context, targetType string, targetID int) (models.ReactionTarget, error)
+	// React sets the user's reaction, replacing the one they had. added is false when it was a replacement
+	React(ctx context.Context, r models.Reaction) (added bool, err error)
+	// Unreact removes the user's reaction, false if there was none
+	Unreact(ctx context.Context, userID int, targetType string, targetID int) (bool, error)
+	// Summaries returns the counts and the viewer's reaction of the targets, the ones without
+	// reactions are left out of the map
+	Summaries(ctx context.Context, targetType string, targetIDs []int, viewerID int) (map[int]models.ReactionSummary, error)
+}
+
+// PollStore reads the polls that Create of the post stores saves with their post, and takes the
+// votes. Who may vote and see the results is up to the handlers (see app/handlers/poll)
+type PollStore interface {
+	// Get returns the poll with its counts and the viewer's votes, ErrNotFound if it doesn't exist
+	Get(ctx context.Context, id, viewerID int) (models.Poll, error)
+	// Polls returns the polls of the posts (target type post) or group posts (group_post) by post
+	// id, the posts without a poll are left out of the map
+	Polls(ctx context.Context, targetType string, postIDs []int, viewerID int) (map[int]models.Poll, error)
+	// Vote replaces the user's votes on the poll with the options, they must be options of the poll
+	Vote(ctx context.Context, pollID, userID int, optionIDs []int) error
+	// Unvote removes the user's votes, false if there were none
+	Unvote(ctx context.Context, pollID, userID int) (bool, error)
+	// Voters returns the users who voted
+	Voters(ctx context.Context, pollID int) ([]int, error)
+}
+
+// TagStore reads the #tags that Create and Update of the post stores save from the content
+// (see app/hashtag). Group posts are tagged when they are created, they can't be edited
+type TagStore interface {
+	// Posts returns the regular posts with the tag that the viewer can see, newest first, with the
+	// rules of PostStore.Feed
+	Posts(ctx context.Context, tag string, viewerID int, page Page) ([]models.Post, *Cursor, error)
+	// Trending ranks the tags given to the posts the viewer can see and the group posts of the
+	// viewer's groups in the len(weights) hours before now. A use h whole hours old scores
+	// weights[h], Uses is the plain count. The limit best scores come first, ties by tag
+	Trending(ctx context.Context, viewerID int, now time.Time, weights []float64, limit int) ([]models.TrendingTag, error)
+	// Backfill tags the posts and group posts that have no tags saved, dated when they were
+	// written, and returns how many it tagged. Running it again finds nothing new
+	Backfill(ctx context.Context) (int, error)
+}
+
+// CollectionStore keeps the users' collections of saved posts and group posts. A saved post
+// stays in the collection when the viewer can't see it anymore (the author restricted it, the
+// user left the group), Items just leaves it out until it is visible again
+type CollectionStore interface {
+	// List returns the user's collections, the default one first and the others by name. The
+	// default collection is created by the first call
+	List(ctx context.Context, userID int) ([]models.Collection, error)
+	Get(ctx context.Context, id int) (models.Collection, error)
+	// Create sets c.ID and c.CreatedAt, ErrConflict when the user has a collection with the name
+	Create(ctx context.Context, c *models.Collection) error
+	// Delete removes the collection and its items
+	Delete(ctx context.Context, id int) error
+
+	// Add appends item.PostID or item.GroupPostID to the collection and fills the item. A post
+	// the viewer can't see (PostStore rules, group membership) is ErrNotFound. added is false
+	// when it was saved there already, the item is then the existing one
+	Add(ctx context.Context, item *models.CollectionItem, viewerID int) (added bool, err error)
+	// Remove deletes an item of the collection, ErrNotFound if it isn't in there
+	Remove(ctx context.Context, collectionID, itemID int) error
+	// Reorder puts the items in the given order at the front of the collection, the others keep
+	// their order behind them. ErrNotFound when an id isn't an item of the collection
+	Reorder(ctx context.Context, collectionID int, itemIDs []int) error
+	// Items returns the items the viewer can see with their post, in position order
+	Items(ctx context.Context, collectionID, viewerID int) ([]models.CollectionItem, error)
+}
+
+// LinkPreviewStore caches the previews of links by URL. A link that couldn't be fetched is saved
+// too, as an empty preview, so it isn't fetched again on every read
+type LinkPreviewStore interface {
+	// Get returns the cached previews of the URLs, the ones never fetched are left out of the map
+	Get(ctx context.Context, urls []string) (map[string]models.LinkPreview, error)
+	// Save inserts or replaces the preview of preview.URL, fetched at preview.FetchedAt
+	Save(ctx context.Context, preview models.LinkPreview) error
+	// DeleteBefore removes the previews fetched before the cutoff
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// AudienceStore keeps the users' audience lists, the owner checks are the handlers'. Deleting a list
+// takes it off the posts shared with it, their other audience stays
+type AudienceStore interface {
+	// List returns the user's lists by name with their member counts, without the members
+	List(ctx context.Context, userID int) ([]models.AudienceList, error)
+	// Get returns the list with its members by username, ErrNotFound if it doesn't exist
+	Get(ctx context.Context, id int) (models.AudienceList, error)
+	// Create sets l.ID and l.CreatedAt, ErrConflict when the user has a list with the name
+	Create(ctx context.Context, l *models.AudienceList) error
+	// Rename is ErrNotFound for a missing list and ErrConflict for a name the user already has
+	Rename(ctx context.Context, id int, name string) error
+	Delete(ctx context.Context, id int) error
+	// AddMembers adds the users to the list, the ones already in are left alone. ErrNotFound when
+	// a user doesn't exist, nobody is added then
+	AddMembers(ctx context.Context, listID int, userIDs []int) error
+	// RemoveMember takes the user off the list, false if they weren't on it
+	RemoveMember(ctx context.Context, listID, userID int) (bool, error)
+}
+
+// JobStore persists the background jobs of app/jobs, times are unix seconds
+type JobStore interface {
+	// Enqueue inserts a pending job and sets job.ID, ErrConflict if a pending or running job
+	// already has the same unique key
+	Enqueue(ctx context.Context, job *models.Job) error
+	// Claim marks the next due pending job as running, counts the attempt and returns it,
+	// ErrNotFound when nothing is due. Two workers never get the same job
+	Claim(ctx context.Context, now int64) (models.Job, error)
+	Complete(ctx context.Context, id int, now int64) error
+	// Retry puts a running job back to pending, it runs again at runAt
+	Retry(ctx context.Context, id int, now, runAt int64, lastError string) error
+	// Fail gives up on a job
+	Fail(ctx context.Context, id int, now int64, lastError string) error
+	// RequeueStale puts running jobs claimed before lockedBefore back to pending,
+	// their worker died with the process
+	RequeueStale(ctx context.Context, lockedBefore int64) (int, error)
+	// DeleteFinished removes done jobs last updated before the cutoff
+	DeleteFinished(ctx context.Context, before int64) (int, error)
+
+	// List returns the jobs with the status, newest first, all statuses when status is ""
+	List(ctx context.Context, status string, limit int) ([]models.Job, error)
+	// Counts returns the number of jobs per status
+	Counts(ctx context.Context) (map[string]int, error)
+}
+
+// IdempotencyStore keeps the Idempotency-Key of a user with the response of the request, times are unix seconds
+type IdempotencyStore interface {
+	// Reserve inserts the key with Status 0 before the request runs. ErrConflict when the user
+	// already has the key and it hasn't expired, a key past its ExpiresAt is replaced
+	Reserve(ctx context.Context, key *models.IdempotencyKey, now int64) error
+	Get(ctx context.Context, userID int, key string) (models.IdempotencyKey, error)
+	// Save stores the response of a reserved key
+	Save(ctx context.Context, key models.IdempotencyKey) error
+	// Release forgets a reserved key so the request can be sent again with it
+	Release(ctx context.Context, userID int, key string) error
+	DeleteExpired(ctx context.Context, now int64) (int, error)
+}
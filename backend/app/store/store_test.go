@@ -0,0 +1,1348 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"social-network/app/models"
+	"social-network/app/store"
+	"social-network/app/store/memstore"
+	"social-network/app/store/sqlstore"
+	"social-network/db"
+	"social-network/db/dbtest"
+)
+
+// eachStore runs fn against the in-memory stores and the SQL stores of every backend,
+// so both implementations are held to the same behaviour
+func eachStore(t *testing.T, fn func(t *testing.T, s *store.Stores)) {
+	t.Run("memstore", func(t *testing.T) {
+		fn(t, memstore.New())
+	})
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		fn(t, sqlstore.New(database))
+	})
+}
+
+func createUser(t *testing.T, s *store.Stores, name string, isPrivate bool) int {
+	t.Helper()
+	id, err := s.Users.Create(context.Background(), models.RegisterStruct{
+		Email:       name + "@test.com",
+		FirstName:   name,
+		LastName:    "Test",
+		DateOfBirth: "2000-01-01",
+		Username:    name,
+		IsPrivate:   isPrivate,
+	}, "hash")
+	if err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return id
+}
+
+func createPost(t *testing.T, s *store.Stores, userID int, privacy string, allowed ...int) int {
+	t.Helper()
+	post := models.Post{UserID: userID, Content: privacy + " post", Privacy: privacy, AllowedFollowers: allowed}
+	if err := s.Posts.Create(context.Background(), &post); err != nil {
+		t.Fatalf("create %s post: %v", privacy, err)
+	}
+	return post.ID
+}
+
+func postIDs(posts []models.Post) string {
+	ids := []int{}
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestPostVisibility(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		follower := createUser(t, s, "follower", false)
+		chosen := createUser(t, s, "chosen", false)
+		stranger := createUser(t, s, "stranger", false)
+
+		for _, id := range []int{follower, chosen} {
+			if err := s.Follows.Follow(ctx, id, author); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		public := createPost(t, s, author, models.PrivacyPublic)
+		private := createPost(t, s, author, models.PrivacyPrivate)
+		almost := createPost(t, s, author, models.PrivacyAlmostPrivate, chosen)
+
+		// newest first
+		want := map[int]string{
+			author:   fmt.Sprint([]int{almost, private, public}),
+			follower: fmt.Sprint([]int{private, public}),
+			chosen:   fmt.Sprint([]int{almost, private, public}),
+			stranger: fmt.Sprint([]int{public}),
+		}
+		for viewer, ids := range want {
+			feed, _, err := s.Posts.Feed(ctx, viewer, store.Page{Limit: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := postIDs(feed); got != ids {
+				t.Errorf("feed of user %d = %s, want %s", viewer, got, ids)
+			}
+
+			byAuthor, err := s.Posts.ByAuthor(ctx, author, viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := postIDs(byAuthor); got != ids {
+				t.Errorf("profile posts seen by user %d = %s, want %s", viewer, got, ids)
+			}
+		}
+
+		if ok, _ := s.Posts.CanView(ctx, almost, follower); ok {
+			t.Error("follower outside the audience can view an almost_private post")
+		}
+		if ok, _ := s.Posts.CanView(ctx, private, stranger); ok {
+			t.Error("stranger can view a private post")
+		}
+		everyone := []int{author, follower, chosen, stranger}
+		for postID, ids := range map[int][]int{public: everyone, private: {author, follower, chosen}, almost: {author, chosen}} {
+			viewers, err := s.Posts.Viewers(ctx, postID, everyone)
+			slices.Sort(viewers)
+			if err != nil || fmt.Sprint(viewers) != fmt.Sprint(ids) {
+				t.Errorf("viewers of post %d = %v, %v, want %v", postID, viewers, err, ids)
+			}
+		}
+		if count, _ := s.Posts.CountByAuthor(ctx, author); count != 3 {
+			t.Errorf("CountByAuthor = %d, want 3", count)
+		}
+	})
+}
+
+func TestEditAndDeletePost(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		chosen := createUser(t, s, "chosen", false)
+		other := createUser(t, s, "other", false)
+
+		image := "/images/posts/first.png"
+		post := models.Post{UserID: author, Content: "first", Image: &image, Privacy: models.PrivacyAlmostPrivate, AllowedFollowers: []int{chosen}}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+
+		edit := models.Post{ID: post.ID, Content: "second", Privacy: models.PrivacyAlmostPrivate, AllowedFollowers: []int{other}}
+		if _, err := s.Posts.Update(ctx, &edit); err != nil {
+			t.Fatal(err)
+		}
+		if edit.EditedAt == nil || edit.UserID != author || !edit.CreatedAt.Equal(post.CreatedAt) {
+			t.Errorf("updated post = %+v", edit)
+		}
+		if ok, _ := s.Posts.CanView(ctx, post.ID, chosen); ok {
+			t.Error("the audience wasn't replaced, chosen still sees the post")
+		}
+		if ok, _ := s.Posts.CanView(ctx, post.ID, other); !ok {
+			t.Error("the new audience doesn't see the post")
+		}
+
+		got, err := s.Posts.Get(ctx, post.ID)
+		if err != nil || got.Content != "second" || got.Image != nil || got.EditedAt == nil {
+			t.Errorf("Get after edit = %+v, %v", got, err)
+		}
+		revisions, err := s.Posts.Revisions(ctx, post.ID)
+		if err != nil || len(revisions) != 1 {
+			t.Fatalf("revisions = %+v, %v", revisions, err)
+		}
+		if revisions[0].Content != "first" || revisions[0].Image == nil || *revisions[0].Image != image ||
+			!revisions[0].CreatedAt.Equal(post.CreatedAt) {
+			t.Errorf("revision = %+v", revisions[0])
+		}
+
+		if _, err := s.Posts.Update(ctx, &models.Post{ID: post.ID + 1000, Content: "x", Privacy: models.PrivacyPublic}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("update of a missing post: %v, want ErrNotFound", err)
+		}
+
+		commentImage := "/images/comments/c.png"
+		if err := s.Posts.CreateComment(ctx, &models.Comment{PostID: post.ID, UserID: other, Content: "hi", Image: &commentImage}); err != nil {
+			t.Fatal(err)
+		}
+		images, err := s.Posts.Delete(ctx, post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(images) != fmt.Sprint([]string{image, commentImage}) && fmt.Sprint(images) != fmt.Sprint([]string{commentImage, image}) {
+			t.Errorf("images of the deleted post = %v", images)
+		}
+		if _, err := s.Posts.Get(ctx, post.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get after delete: %v", err)
+		}
+		if comments, _, _ := s.Posts.Comments(ctx, post.ID, store.Page{}); len(comments) != 0 {
+			t.Errorf("comments survived the post: %v", comments)
+		}
+		if revisions, _ := s.Posts.Revisions(ctx, post.ID); len(revisions) != 0 {
+			t.Errorf("revisions survived the post: %v", revisions)
+		}
+		if _, err := s.Posts.Delete(ctx, post.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("second delete: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestAttachments(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+
+		first := "/images/posts/a.png"
+		post := models.Post{UserID: author, Content: "album", Image: &first, Privacy: models.PrivacyPublic, Attachments: []models.Attachment{
+			{Path: first, AltText: "a cat", Width: 800, Height: 600, ContentType: "image/png"},
+			{Path: "/images/posts/b.gif", ContentType: "image/gif"},
+		}}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		if post.Attachments[0].ID == 0 || post.Attachments[1].ID == 0 {
+			t.Errorf("Create didn't fill in the attachment ids: %+v", post.Attachments)
+		}
+		got, err := s.Posts.Get(ctx, post.ID)
+		if err != nil || fmt.Sprint(got.Attachments) != fmt.Sprint(post.Attachments) {
+			t.Errorf("attachments = %+v, %v, want %+v", got.Attachments, err, post.Attachments)
+		}
+		if feed, _, _ := s.Posts.Feed(ctx, author, store.Page{}); len(feed) != 1 || len(feed[0].Attachments) != 2 {
+			t.Errorf("feed = %+v", feed)
+		}
+
+		// an edit replaces the list, in its new order
+		edit := models.Post{ID: post.ID, Content: "album", Privacy: models.PrivacyPublic, Attachments: []models.Attachment{
+			{Path: "/images/posts/b.gif", ContentType: "image/gif"}, {Path: "/images/posts/c.jpg", ContentType: "image/jpeg"},
+		}}
+		// a.png was the post's image, the revision keeps it
+		if dropped, err := s.Posts.Update(ctx, &edit); err != nil || len(dropped) != 0 {
+			t.Fatalf("Update = %v, %v, want nothing to remove", dropped, err)
+		}
+		got, _ = s.Posts.Get(ctx, post.ID)
+		if len(got.Attachments) != 2 || got.Attachments[0].Path != "/images/posts/b.gif" || got.Attachments[1].Path != "/images/posts/c.jpg" {
+			t.Errorf("attachments after the edit = %+v", got.Attachments)
+		}
+
+		comment := models.Comment{PostID: post.ID, UserID: author, Content: "more",
+			Attachments: []models.Attachment{{Path: "/images/comments/d.webp", ContentType: "image/webp"}}}
+		if err := s.Posts.CreateComment(ctx, &comment); err != nil {
+			t.Fatal(err)
+		}
+		if comments, _, _ := s.Posts.Comments(ctx, post.ID, store.Page{}); len(comments) != 1 || len(comments[0].Attachments) != 1 {
+			t.Errorf("comments = %+v", comments)
+		}
+
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		groupPost := models.GroupPost{GroupID: group.ID, UserID: author, Content: "photos",
+			Attachments: []models.Attachment{{Path: "/images/posts/e.png", AltText: "the meetup", ContentType: "image/png"}}}
+		if err := s.Groups.CreatePost(ctx, &groupPost); err != nil {
+			t.Fatal(err)
+		}
+		if posts, _, _ := s.Groups.Posts(ctx, group.ID, store.Page{}); len(posts) != 1 || len(posts[0].Attachments) != 1 ||
+			posts[0].Attachments[0].AltText != "the meetup" {
+			t.Errorf("group posts = %+v", posts)
+		}
+
+		// the files of every attachment go with the post
+		images, err := s.Posts.Delete(ctx, post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{first, "/images/posts/b.gif", "/images/posts/c.jpg", "/images/comments/d.webp"} {
+			if !slices.Contains(images, path) {
+				t.Errorf("images of the deleted post = %v, missing %s", images, path)
+			}
+		}
+
+		// a post reusing someone else's upload leaves the file to its owner
+		other := createUser(t, s, "other", false)
+		reuse := models.Post{UserID: other, Content: "mine now", Privacy: models.PrivacyPublic,
+			Attachments: []models.Attachment{{Path: "/images/posts/e.png", ContentType: "image/png"}}}
+		if err := s.Posts.Create(ctx, &reuse); err != nil {
+			t.Fatal(err)
+		}
+		if images, err := s.Posts.Delete(ctx, reuse.ID); err != nil || len(images) != 0 {
+			t.Errorf("images of a post reusing a group post's upload = %v, %v, want none", images, err)
+		}
+
+		// an edit gives up the attachments it drops, unless another row uses them
+		album := models.Post{UserID: other, Content: "album", Privacy: models.PrivacyPublic, Attachments: []models.Attachment{
+			{Path: "/images/posts/f.png", ContentType: "image/png"}, {Path: "/images/posts/g.png", ContentType: "image/png"},
+			{Path: "/images/posts/e.png", ContentType: "image/png"},
+		}}
+		if err := s.Posts.Create(ctx, &album); err != nil {
+			t.Fatal(err)
+		}
+		album.Attachments = album.Attachments[:1]
+		dropped, err := s.Posts.Update(ctx, &album)
+		if err != nil || fmt.Sprint(dropped) != "[/images/posts/g.png]" {
+			t.Errorf("images dropped by the edit = %v, %v, want g.png", dropped, err)
+		}
+	})
+}
+
+func TestMarkdownContent(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		other := createUser(t, s, "other", false)
+		html := "<p><strong>hi</strong></p>"
+
+		post := models.Post{UserID: author, Content: "**hi**", Format: models.FormatMarkdown, ContentHTML: &html,
+			Privacy: models.PrivacyPublic}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Posts.Get(ctx, post.ID)
+		if err != nil || got.Format != models.FormatMarkdown || got.ContentHTML == nil || *got.ContentHTML != html {
+			t.Fatalf("Get = %+v, %v, want the Markdown post", got, err)
+		}
+		// back to plain text, the revision keeps the HTML
+		post.Content, post.Format, post.ContentHTML = "plain", "", nil
+		if _, err := s.Posts.Update(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		got, _ = s.Posts.Get(ctx, post.ID)
+		revisions, err := s.Posts.Revisions(ctx, post.ID)
+		if err != nil || got.Format != "" || got.ContentHTML != nil || len(revisions) != 1 ||
+			revisions[0].Format != models.FormatMarkdown || revisions[0].ContentHTML == nil || *revisions[0].ContentHTML != html {
+			t.Errorf("after the edit: post %+v, revisions %+v, %v", got, revisions, err)
+		}
+
+		comment := models.Comment{PostID: post.ID, UserID: other, Content: "**hi**", Format: models.FormatMarkdown, ContentHTML: &html}
+		if err := s.Posts.CreateComment(ctx, &comment); err != nil {
+			t.Fatal(err)
+		}
+		comments, _, err := s.Posts.Comments(ctx, post.ID, store.Page{})
+		if err != nil || len(comments) != 1 || comments[0].Format != models.FormatMarkdown ||
+			comments[0].ContentHTML == nil || *comments[0].ContentHTML != html {
+			t.Errorf("Comments = %+v, %v", comments, err)
+		}
+
+		msg := models.Message{SenderID: author, ReceiverID: other, Content: "**hi**", Format: models.FormatMarkdown,
+			ContentHTML: &html, CreatedAt: time.Now().Unix()}
+		if err := s.Chat.SaveMessage(ctx, &msg); err != nil {
+			t.Fatal(err)
+		}
+		messages, _, err := s.Chat.Conversation(ctx, other, author, store.Page{})
+		if err != nil || len(messages) != 1 || messages[0].Format != models.FormatMarkdown ||
+			messages[0].ContentHTML == nil || *messages[0].ContentHTML != html {
+			t.Errorf("Conversation = %+v, %v", messages, err)
+		}
+	})
+}
+
+func TestRepostKeepsOriginalID(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		sharer := createUser(t, s, "sharer", false)
+		original := createPost(t, s, author, models.PrivacyPublic)
+
+		repost := models.Post{UserID: sharer, Privacy: models.PrivacyPublic, RepostOf: &original}
+		if err := s.Posts.Create(ctx, &repost); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Posts.Delete(ctx, original); err != nil {
+			t.Fatal(err)
+		}
+		// the repost outlives its original and still knows what it shared
+		got, err := s.Posts.Get(ctx, repost.ID)
+		if err != nil || got.RepostOf == nil || *got.RepostOf != original {
+			t.Errorf("repost after deleting the original = %+v, %v", got, err)
+		}
+		feed, _, _ := s.Posts.Feed(ctx, sharer, store.Page{})
+		if len(feed) != 1 || feed[0].RepostOf == nil {
+			t.Errorf("feed = %+v", feed)
+		}
+	})
+}
+
+func TestTags(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		reader := createUser(t, s, "reader", false)
+		now := time.Now()
+
+		public := models.Post{UserID: author, Content: "#Go and #go, #golang", Privacy: models.PrivacyPublic}
+		private := models.Post{UserID: author, Content: "#go secret", Privacy: models.PrivacyPrivate}
+		for _, p := range []*models.Post{&public, &private} {
+			if err := s.Posts.Create(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		posts, _, err := s.Tags.Posts(ctx, "go", reader, store.Page{})
+		if err != nil || postIDs(posts) != fmt.Sprint([]int{public.ID}) {
+			t.Errorf("#go seen by reader = %s, %v", postIDs(posts), err)
+		}
+		if posts, _, _ := s.Tags.Posts(ctx, "go", author, store.Page{}); len(posts) != 2 {
+			t.Errorf("#go seen by author = %s", postIDs(posts))
+		}
+
+		// the edit drops #go, #golang stays
+		public.Content = "#golang only"
+		if _, err := s.Posts.Update(ctx, &public); err != nil {
+			t.Fatal(err)
+		}
+		if posts, _, _ := s.Tags.Posts(ctx, "go", reader, store.Page{}); len(posts) != 0 {
+			t.Errorf("#go after the edit = %s", postIDs(posts))
+		}
+
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Groups.CreatePost(ctx, &models.GroupPost{GroupID: group.ID, UserID: author, Content: "#meetup"}); err != nil {
+			t.Fatal(err)
+		}
+		tags := func(viewerID int) map[string]int {
+			trending, err := s.Tags.Trending(ctx, viewerID, now, []float64{1, 1}, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts := map[string]int{}
+			for _, tag := range trending {
+				counts[tag.Tag] = tag.Uses
+			}
+			return counts
+		}
+		if got := fmt.Sprint(tags(reader)); got != "map[golang:1]" {
+			t.Errorf("uses seen by reader = %s", got)
+		}
+		if got := fmt.Sprint(tags(author)); got != "map[go:1 golang:1 meetup:1]" {
+			t.Errorf("uses seen by author = %s", got)
+		}
+
+		if _, err := s.Posts.Delete(ctx, public.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := tags(reader); len(got) != 0 {
+			t.Errorf("uses after deleting the post = %v", got)
+		}
+
+		// two hours later a use scores weights[2], the limit keeps the best scores
+		for _, content := range []string{"#golang", "#golang again", "#rust"} {
+			if err := s.Posts.Create(ctx, &models.Post{UserID: author, Content: content, Privacy: models.PrivacyPublic}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		weights := []float64{1, 0.5, 0.25}
+		trending, err := s.Tags.Trending(ctx, reader, now.Add(2*time.Hour+time.Minute), weights, 1)
+		if err != nil || fmt.Sprint(trending) != fmt.Sprint([]models.TrendingTag{{Tag: "golang", Score: 0.5, Uses: 2}}) {
+			t.Errorf("trending two hours later = %+v, %v", trending, err)
+		}
+		if trending, _ := s.Tags.Trending(ctx, reader, now.Add(3*time.Hour+time.Minute), weights, 10); len(trending) != 0 {
+			t.Errorf("uses out of the window still trend: %+v", trending)
+		}
+	})
+}
+
+// TestTagsBackfill drops the saved tags, as before post_tags, and has Backfill find them again
+func TestTagsBackfill(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		s := sqlstore.New(database)
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		now := time.Now()
+
+		for _, content := range []string{"#go and #golang", "no tags"} {
+			if err := s.Posts.Create(ctx, &models.Post{UserID: author, Content: content, Privacy: models.PrivacyPublic}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Groups.CreatePost(ctx, &models.GroupPost{GroupID: group.ID, UserID: author, Content: "#meetup"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.Exec("DELETE FROM post_tags"); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := s.Tags.Backfill(ctx); n != 2 || err != nil {
+			t.Fatalf("Backfill = %d, %v, want the post and the group post", n, err)
+		}
+		if n, err := s.Tags.Backfill(ctx); n != 0 || err != nil {
+			t.Errorf("second Backfill = %d, %v", n, err)
+		}
+		trending, err := s.Tags.Trending(ctx, author, now.Add(time.Minute), []float64{1}, 10)
+		if err != nil || fmt.Sprint(trending) != fmt.Sprint([]models.TrendingTag{{Tag: "go", Score: 1, Uses: 1}, {Tag: "golang", Score: 1, Uses: 1}, {Tag: "meetup", Score: 1, Uses: 1}}) {
+			t.Errorf("trending after Backfill = %+v, %v", trending, err)
+		}
+	})
+}
+
+func TestMentions(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		bob := createUser(t, s, "bob", false)
+		createUser(t, s, "Bobby", false)
+
+		post := models.Post{UserID: author, Content: "hi @bob and @nobody", Privacy: models.PrivacyPublic}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprint([]models.Mention{{UserID: bob, Username: "bob", Offset: 3, Length: 4}})
+		if got := fmt.Sprint(post.Mentions); got != want {
+			t.Errorf("created post mentions = %s, want %s", got, want)
+		}
+		feed, _, _ := s.Posts.Feed(ctx, bob, store.Page{})
+		if len(feed) != 1 || fmt.Sprint(feed[0].Mentions) != want {
+			t.Errorf("feed mentions = %+v", feed)
+		}
+
+		// an edit replaces them, the offsets move with the text
+		post.Content = "@bob: see above"
+		if _, err := s.Posts.Update(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := s.Posts.Get(ctx, post.ID)
+		if len(got.Mentions) != 1 || got.Mentions[0].Offset != 0 {
+			t.Errorf("mentions after the edit = %+v", got.Mentions)
+		}
+
+		comment := models.Comment{PostID: post.ID, UserID: bob, Content: "thanks @author"}
+		if err := s.Posts.CreateComment(ctx, &comment); err != nil {
+			t.Fatal(err)
+		}
+		comments, _, _ := s.Posts.Comments(ctx, post.ID, store.Page{})
+		if len(comments) != 1 || len(comments[0].Mentions) != 1 || comments[0].Mentions[0].UserID != author {
+			t.Errorf("comment mentions = %+v", comments)
+		}
+
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		msg := models.GroupMessage{GroupID: group.ID, SenderID: author, Content: "@bob @bob", CreatedAt: time.Now().Unix()}
+		if err := s.Chat.SaveGroupMessage(ctx, &msg); err != nil {
+			t.Fatal(err)
+		}
+		messages, _, _ := s.Chat.GroupMessages(ctx, group.ID, store.Page{})
+		if len(messages) != 1 || len(messages[0].Mentions) != 2 || messages[0].Mentions[1].Offset != 5 {
+			t.Errorf("group message mentions = %+v", messages)
+		}
+
+		users, err := s.Users.Autocomplete(ctx, "BOB", 10)
+		if err != nil || len(users) != 2 || *users[0].Username != "bob" || *users[1].Username != "Bobby" {
+			t.Errorf("Autocomplete(BOB) = %+v, %v", users, err)
+		}
+		if users, _ := s.Users.Autocomplete(ctx, "b_", 10); len(users) != 0 {
+			t.Errorf("'_' matched as a wildcard: %+v", users)
+		}
+	})
+}
+
+func TestDrafts(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		reader := createUser(t, s, "reader", false)
+
+		soon := time.Now().Add(time.Hour).Truncate(time.Second)
+		draft := models.Post{UserID: author, Content: "#later", Privacy: models.PrivacyPublic, Status: models.PostDraft}
+		scheduled := models.Post{UserID: author, Content: "#later", Privacy: models.PrivacyPublic,
+			Status: models.PostScheduled, PublishAt: &soon}
+		for _, p := range []*models.Post{&draft, &scheduled} {
+			if err := s.Posts.Create(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// nobody sees them, the author neither
+		for _, viewer := range []int{author, reader} {
+			if posts, _, _ := s.Posts.Feed(ctx, viewer, store.Page{}); len(posts) != 0 {
+				t.Errorf("feed of %d has the unpublished posts %s", viewer, postIDs(posts))
+			}
+			if posts, _ := s.Posts.ByAuthor(ctx, author, viewer); len(posts) != 0 {
+				t.Errorf("profile seen by %d has the unpublished posts %s", viewer, postIDs(posts))
+			}
+			if visible, _ := s.Posts.CanView(ctx, draft.ID, viewer); visible {
+				t.Errorf("%d can view the draft", viewer)
+			}
+		}
+		if posts, _, _ := s.Tags.Posts(ctx, "later", author, store.Page{}); len(posts) != 0 {
+			t.Errorf("tag page has the unpublished posts %s", postIDs(posts))
+		}
+		if n, _ := s.Posts.CountByAuthor(ctx, author); n != 0 {
+			t.Errorf("post count = %d, want 0", n)
+		}
+		drafts, err := s.Posts.Drafts(ctx, author)
+		if err != nil || postIDs(drafts) != fmt.Sprint([]int{scheduled.ID, draft.ID}) {
+			t.Errorf("drafts = %s, %v", postIDs(drafts), err)
+		}
+		got, err := s.Posts.Get(ctx, scheduled.ID)
+		if err != nil || got.Status != models.PostScheduled || got.PublishAt == nil || !got.PublishAt.Equal(soon) {
+			t.Errorf("scheduled post = %+v, %v", got, err)
+		}
+
+		// the draft becomes scheduled later than the other one
+		later := soon.Add(time.Hour)
+		draft.Status, draft.PublishAt = models.PostScheduled, &later
+		if err := s.Posts.UpdateDraft(ctx, &draft); err != nil {
+			t.Fatal(err)
+		}
+
+		published, err := s.Posts.PublishDue(ctx, soon)
+		if err != nil || postIDs(published) != fmt.Sprint([]int{scheduled.ID}) {
+			t.Fatalf("published at the first time = %s, %v", postIDs(published), err)
+		}
+		if published, _ := s.Posts.PublishDue(ctx, soon); len(published) != 0 {
+			t.Errorf("published twice: %s", postIDs(published))
+		}
+		if posts, _, _ := s.Posts.Feed(ctx, reader, store.Page{}); postIDs(posts) != fmt.Sprint([]int{scheduled.ID}) {
+			t.Errorf("feed after publishing = %s", postIDs(posts))
+		}
+		if posts, _, _ := s.Tags.Posts(ctx, "later", reader, store.Page{}); len(posts) != 1 {
+			t.Errorf("tag page after publishing = %s", postIDs(posts))
+		}
+		if err := s.Posts.UpdateDraft(ctx, &scheduled); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("UpdateDraft of a published post: %v, want ErrNotFound", err)
+		}
+
+		// publishing by hand before the time
+		post, err := s.Posts.Publish(ctx, draft.ID)
+		if err != nil || post.Status != models.PostPublished || post.PublishAt != nil {
+			t.Errorf("Publish = %+v, %v", post, err)
+		}
+		if _, err := s.Posts.Publish(ctx, draft.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("publishing again: %v, want ErrNotFound", err)
+		}
+		if published, _ := s.Posts.PublishDue(ctx, later); len(published) != 0 {
+			t.Errorf("the job published %s again", postIDs(published))
+		}
+	})
+}
+
+func TestCollections(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		reader := createUser(t, s, "reader", false)
+
+		collections, err := s.Collections.List(ctx, reader)
+		if err != nil || len(collections) != 1 || !collections[0].IsDefault || collections[0].Name != models.DefaultCollection {
+			t.Fatalf("collections of a new user = %+v, %v", collections, err)
+		}
+		saved := collections[0]
+		if err := s.Collections.Create(ctx, &models.Collection{UserID: reader, Name: models.DefaultCollection}); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("collection named like the default: %v, want ErrConflict", err)
+		}
+
+		post := models.Post{UserID: author, Content: "public", Privacy: models.PrivacyPublic}
+		hidden := models.Post{UserID: author, Content: "private", Privacy: models.PrivacyPrivate}
+		for _, p := range []*models.Post{&post, &hidden} {
+			if err := s.Posts.Create(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Groups.AddMember(ctx, group.ID, reader, "member"); err != nil {
+			t.Fatal(err)
+		}
+		groupPost := models.GroupPost{GroupID: group.ID, UserID: author, Content: "meetup"}
+		if err := s.Groups.CreatePost(ctx, &groupPost); err != nil {
+			t.Fatal(err)
+		}
+
+		add := func(item models.CollectionItem) (models.CollectionItem, bool, error) {
+			item.CollectionID = saved.ID
+			added, err := s.Collections.Add(ctx, &item, reader)
+			return item, added, err
+		}
+		first, added, err := add(models.CollectionItem{PostID: &post.ID})
+		if err != nil || !added {
+			t.Fatalf("add post: %v, %v", added, err)
+		}
+		if again, added, err := add(models.CollectionItem{PostID: &post.ID}); err != nil || added || again.ID != first.ID {
+			t.Errorf("adding the post again = %+v, %v, %v, want the first item", again, added, err)
+		}
+		second, _, err := add(models.CollectionItem{GroupPostID: &groupPost.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := add(models.CollectionItem{PostID: &hidden.ID}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("saving a post the reader can't see: %v, want ErrNotFound", err)
+		}
+
+		items := func() string {
+			items, err := s.Collections.Items(ctx, saved.ID, reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, item := range items {
+				if (item.Post == nil) == (item.GroupPost == nil) {
+					t.Errorf("item %d without its post: %+v", item.ID, item)
+				}
+				ids = append(ids, item.ID)
+			}
+			return fmt.Sprint(ids)
+		}
+		if got, want := items(), fmt.Sprint([]int{first.ID, second.ID}); got != want {
+			t.Errorf("items = %s, want %s", got, want)
+		}
+		if err := s.Collections.Reorder(ctx, saved.ID, []int{second.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := items(), fmt.Sprint([]int{second.ID, first.ID}); got != want {
+			t.Errorf("items after the reorder = %s, want %s", got, want)
+		}
+		if err := s.Collections.Reorder(ctx, saved.ID, []int{first.ID, first.ID}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("reorder with a duplicate: %v, want ErrNotFound", err)
+		}
+
+		// hidden while the post is private, back when it's public again
+		post.Privacy = models.PrivacyPrivate
+		if _, err := s.Posts.Update(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := items(), fmt.Sprint([]int{second.ID}); got != want {
+			t.Errorf("items with the post private = %s, want %s", got, want)
+		}
+		post.Privacy = models.PrivacyPublic
+		if _, err := s.Posts.Update(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Groups.RemoveMember(ctx, group.ID, reader); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := items(), fmt.Sprint([]int{first.ID}); got != want {
+			t.Errorf("items after leaving the group = %s, want %s", got, want)
+		}
+
+		if _, err := s.Posts.Delete(ctx, post.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Groups.AddMember(ctx, group.ID, reader, "member"); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := items(), fmt.Sprint([]int{second.ID}); got != want {
+			t.Errorf("items after deleting the post = %s, want %s", got, want)
+		}
+		if err := s.Collections.Remove(ctx, saved.ID, first.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("removing the item of the deleted post: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPolls(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		voter := createUser(t, s, "voter", false)
+
+		closes := time.Now().Add(time.Hour).Truncate(time.Second)
+		post := models.Post{UserID: author, Content: "lunch?", Privacy: models.PrivacyPublic, Poll: &models.Poll{
+			Multiple: true, ClosesAt: &closes,
+			Options: []models.PollOption{{Text: "pizza"}, {Text: "sushi"}, {Text: "salad"}},
+		}}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		options := post.Poll.Options
+		if post.Poll.ID == 0 || options[0].ID == 0 || options[2].ID == 0 {
+			t.Fatalf("Create didn't fill in the poll ids: %+v", post.Poll)
+		}
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: author}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		groupPost := models.GroupPost{GroupID: group.ID, UserID: author, Content: "when?", Poll: &models.Poll{
+			Options: []models.PollOption{{Text: "monday"}, {Text: "friday"}},
+		}}
+		if err := s.Groups.CreatePost(ctx, &groupPost); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Polls.Vote(ctx, post.Poll.ID, voter, []int{options[0].ID, options[2].ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Polls.Vote(ctx, post.Poll.ID, author, []int{options[0].ID}); err != nil {
+			t.Fatal(err)
+		}
+		// voting again replaces the vote
+		if err := s.Polls.Vote(ctx, post.Poll.ID, voter, []int{options[1].ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		poll, err := s.Polls.Get(ctx, post.Poll.ID, voter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes := fmt.Sprint(poll.Options[0].Votes, poll.Options[1].Votes, poll.Options[2].Votes)
+		if votes != "1 1 0" || poll.Voters != 2 || fmt.Sprint(poll.MyVotes) != fmt.Sprint([]int{options[1].ID}) {
+			t.Errorf("poll = %+v, want votes 1 1 0 from 2 voters", poll)
+		}
+		if poll.AuthorID != author || poll.PostID == nil || *poll.PostID != post.ID || !poll.Multiple ||
+			poll.ClosesAt == nil || !poll.ClosesAt.Equal(closes) || poll.Options[1].Text != "sushi" {
+			t.Errorf("poll = %+v", poll)
+		}
+
+		polls, err := s.Polls.Polls(ctx, models.TargetGroupPost, []int{groupPost.ID, groupPost.ID + 100}, voter)
+		if err != nil || len(polls) != 1 || polls[groupPost.ID].GroupID == nil || *polls[groupPost.ID].GroupID != group.ID {
+			t.Errorf("group post polls = %+v, %v", polls, err)
+		}
+		if polls, _ := s.Polls.Polls(ctx, models.TargetPost, []int{post.ID}, voter); polls[post.ID].ID != post.Poll.ID {
+			t.Errorf("post polls = %+v, want the poll of post %d", polls, post.ID)
+		}
+
+		if removed, err := s.Polls.Unvote(ctx, post.Poll.ID, voter); err != nil || !removed {
+			t.Errorf("Unvote = %v, %v", removed, err)
+		}
+		if voters, _ := s.Polls.Voters(ctx, post.Poll.ID); fmt.Sprint(voters) != fmt.Sprint([]int{author}) {
+			t.Errorf("voters after unvoting = %v", voters)
+		}
+
+		if _, err := s.Posts.Delete(ctx, post.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Polls.Get(ctx, post.Poll.ID, author); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("poll of a deleted post: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestLinkPreviews(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+		if err := s.LinkPreviews.Save(ctx, models.LinkPreview{URL: "https://a.example", Title: "A", FetchedAt: old}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.LinkPreviews.Save(ctx, models.LinkPreview{URL: "https://b.example", FetchedAt: old}); err != nil {
+			t.Fatal(err)
+		}
+		// a second fetch replaces the first
+		now := time.Now().Truncate(time.Second)
+		fresh := models.LinkPreview{URL: "https://a.example", Title: "A2", Description: "d", Image: "https://a.example/i.png", SiteName: "a", FetchedAt: now}
+		if err := s.LinkPreviews.Save(ctx, fresh); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.LinkPreviews.Get(ctx, []string{"https://a.example", "https://b.example", "https://c.example"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || !got["https://a.example"].FetchedAt.Equal(now) || got["https://a.example"].Title != "A2" ||
+			got["https://a.example"].Image != fresh.Image || !got["https://b.example"].Empty() {
+			t.Errorf("Get = %+v", got)
+		}
+
+		if n, err := s.LinkPreviews.DeleteBefore(ctx, now.Add(-time.Hour)); err != nil || n != 1 {
+			t.Errorf("DeleteBefore = %d, %v, want the old one", n, err)
+		}
+		if got, _ := s.LinkPreviews.Get(ctx, []string{"https://b.example"}); len(got) != 0 {
+			t.Errorf("b is still cached: %+v", got)
+		}
+	})
+}
+
+func TestAudienceLists(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		friend := createUser(t, s, "friend", false)
+		other := createUser(t, s, "other", false)
+
+		stranger := createUser(t, s, "stranger", false)
+		for _, id := range []int{friend, other} {
+			if err := s.Follows.Follow(ctx, id, author); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		friends := models.AudienceList{UserID: author, Name: "Friends"}
+		if err := s.Audiences.Create(ctx, &friends); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Audiences.Create(ctx, &models.AudienceList{UserID: author, Name: "Friends"}); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("duplicate name: err = %v, want ErrConflict", err)
+		}
+		if err := s.Audiences.AddMembers(ctx, friends.ID, []int{friend, 9999}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("unknown member: err = %v, want ErrNotFound", err)
+		}
+		if err := s.Audiences.AddMembers(ctx, friends.ID, []int{friend, friend, stranger}); err != nil {
+			t.Fatal(err)
+		}
+
+		post := models.Post{UserID: author, Content: "for friends", Privacy: models.PrivacyAlmostPrivate,
+			AudienceLists: []int{friends.ID}}
+		if err := s.Posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		visible := func(viewer int) bool {
+			t.Helper()
+			ok, err := s.Posts.CanView(ctx, post.ID, viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed, _, err := s.Posts.Feed(ctx, viewer, store.Page{Limit: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inFeed := postIDs(feed) == fmt.Sprint([]int{post.ID}); inFeed != ok {
+				t.Errorf("user %d: CanView %v but the feed is %s", viewer, ok, postIDs(feed))
+			}
+			return ok
+		}
+		if !visible(friend) || visible(other) {
+			t.Error("only the member should see the post")
+		}
+		// a list only counts for the members who follow the author
+		if visible(stranger) {
+			t.Error("a member who doesn't follow the author sees the post")
+		}
+		if removed, err := s.Audiences.RemoveMember(ctx, friends.ID, stranger); err != nil || !removed {
+			t.Fatalf("RemoveMember = %v, %v", removed, err)
+		}
+
+		// membership is read with the post, joining or leaving the list changes who sees it
+		if err := s.Audiences.AddMembers(ctx, friends.ID, []int{other}); err != nil {
+			t.Fatal(err)
+		}
+		if removed, err := s.Audiences.RemoveMember(ctx, friends.ID, friend); err != nil || !removed {
+			t.Fatalf("RemoveMember = %v, %v", removed, err)
+		}
+		if visible(friend) || !visible(other) {
+			t.Error("the post should follow the list's current members")
+		}
+		got, err := s.Audiences.Get(ctx, friends.ID)
+		if err != nil || got.MemberCount != 1 || len(got.Members) != 1 || got.Members[0].ID != other {
+			t.Errorf("Get = %+v, %v, want other as the only member", got, err)
+		}
+
+		if err := s.Audiences.Rename(ctx, friends.ID, "Close friends"); err != nil {
+			t.Fatal(err)
+		}
+		lists, err := s.Audiences.List(ctx, author)
+		if err != nil || len(lists) != 1 || lists[0].Name != "Close friends" || lists[0].MemberCount != 1 {
+			t.Errorf("List = %+v, %v", lists, err)
+		}
+
+		if err := s.Audiences.Delete(ctx, friends.ID); err != nil {
+			t.Fatal(err)
+		}
+		if visible(other) {
+			t.Error("the post is still shared with a deleted list")
+		}
+		if err := s.Audiences.Delete(ctx, friends.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("second delete: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestAudienceFollowsTheFollowerGraph(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		leaving := createUser(t, s, "leaving", false)
+		staying := createUser(t, s, "staying", false)
+		for _, id := range []int{leaving, staying} {
+			if err := s.Follows.Follow(ctx, id, author); err != nil {
+				t.Fatal(err)
+			}
+		}
+		almost := createPost(t, s, author, models.PrivacyAlmostPrivate, leaving, staying)
+		// a row written before the post-time check, for a user who never followed
+		never := createUser(t, s, "never", false)
+		legacy := createPost(t, s, author, models.PrivacyAlmostPrivate, never)
+
+		if removed, err := s.Follows.Unfollow(ctx, leaving, author); err != nil || !removed {
+			t.Fatalf("Unfollow = %v, %v", removed, err)
+		}
+		// following again doesn't bring the grant back, the author picks them again if they want
+		if err := s.Follows.Follow(ctx, leaving, author); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := s.Posts.CanView(ctx, almost, leaving); ok {
+			t.Error("the grant survived the unfollow")
+		}
+		if ok, _ := s.Posts.CanView(ctx, almost, staying); !ok {
+			t.Error("the unfollow of another user revoked this one's grant")
+		}
+
+		n, err := s.Posts.PruneAudience(ctx)
+		if err != nil || n != 1 {
+			t.Errorf("PruneAudience = %d, %v, want the never-following user's row", n, err)
+		}
+		if ok, _ := s.Posts.CanView(ctx, legacy, never); ok {
+			t.Error("a user who doesn't follow the author still sees the post after the prune")
+		}
+		if n, err := s.Posts.PruneAudience(ctx); err != nil || n != 0 {
+			t.Errorf("second PruneAudience = %d, %v, want nothing left", n, err)
+		}
+	})
+}
+
+func TestPagination(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		other := createUser(t, s, "other", false)
+
+		// created within the same second, the id breaks the tie
+		var want []int
+		for range 5 {
+			want = append([]int{createPost(t, s, author, models.PrivacyPublic)}, want...)
+		}
+		var got []int
+		page := store.Page{Limit: 2}
+		for pages := 0; ; pages++ {
+			posts, next, err := s.Posts.Feed(ctx, other, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range posts {
+				got = append(got, p.ID)
+			}
+			if next == nil {
+				break
+			}
+			if pages > 5 {
+				t.Fatal("the feed never ends")
+			}
+			cursor, err := store.ParseCursor(next.String())
+			if err != nil || cursor != *next {
+				t.Fatalf("cursor round trip: %v, %v", cursor, err)
+			}
+			page.After = &cursor
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("paged feed = %v, want %v", got, want)
+		}
+
+		// chat pages start at the latest messages and are oldest first inside
+		base := time.Now().Add(-time.Hour).Unix()
+		for i := range 5 {
+			msg := models.Message{SenderID: author, ReceiverID: other, Content: fmt.Sprint(i), CreatedAt: base + int64(i)}
+			if err := s.Chat.SaveMessage(ctx, &msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		contents := func(msgs []models.Message) string {
+			var c []string
+			for _, m := range msgs {
+				c = append(c, m.Content)
+			}
+			return fmt.Sprint(c)
+		}
+		latest, next, err := s.Chat.Conversation(ctx, other, author, store.Page{Limit: 3})
+		if err != nil || contents(latest) != "[2 3 4]" || next == nil {
+			t.Fatalf("first chat page = %s, next %v, %v", contents(latest), next, err)
+		}
+		older, next, err := s.Chat.Conversation(ctx, other, author, store.Page{Limit: 3, After: next})
+		if err != nil || contents(older) != "[0 1]" || next != nil {
+			t.Errorf("second chat page = %s, next %v, %v", contents(older), next, err)
+		}
+	})
+}
+
+func TestFollowRequestFlow(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		target := createUser(t, s, "target", true)
+		requester := createUser(t, s, "requester", false)
+
+		requestID, err := s.Follows.CreateRequest(ctx, requester, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Notifications.Create(ctx, &models.Notification{
+			UserID: target, Type: "follow_request", SenderID: &requester, FollowRequestID: &requestID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pending, _ := s.Follows.HasPendingRequest(ctx, requester, target); !pending {
+			t.Fatal("request is not pending")
+		}
+		requests, err := s.Follows.PendingRequests(ctx, target)
+		if err != nil || len(requests) != 1 || requests[0].RequesterID != requester {
+			t.Fatalf("PendingRequests = %+v, %v", requests, err)
+		}
+
+		// somebody else can't answer it
+		if _, err := s.Follows.ResolveRequest(ctx, int(requestID), requester, true); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("resolve by the requester: %v, want ErrNotFound", err)
+		}
+
+		got, err := s.Follows.ResolveRequest(ctx, int(requestID), target, true)
+		if err != nil || got != requester {
+			t.Fatalf("ResolveRequest = %d, %v", got, err)
+		}
+		if following, _ := s.Follows.IsFollowing(ctx, requester, target); !following {
+			t.Error("approved requester is not following")
+		}
+		if notifications, _, _ := s.Notifications.List(ctx, target, store.Page{}); len(notifications) != 0 {
+			t.Errorf("follow request notification was not removed: %+v", notifications)
+		}
+		if _, err := s.Follows.ResolveRequest(ctx, int(requestID), target, true); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("resolving twice: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestGroupJoinRequest(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		creator := createUser(t, s, "creator", false)
+		joiner := createUser(t, s, "joiner", false)
+
+		group := models.Group{Groupname: "gophers", Title: "Gophers", Description: "go", CreatorID: creator}
+		if err := s.Groups.Create(ctx, &group); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Groups.CreateJoinRequest(ctx, group.ID, joiner); err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := s.Groups.Get(ctx, group.ID, joiner)
+		if err != nil || g.IsMember || !g.HasRequested {
+			t.Fatalf("group seen by joiner = %+v, %v", g, err)
+		}
+		if status, _ := s.Groups.JoinRequestStatus(ctx, group.ID, joiner); status != "pending" {
+			t.Fatalf("JoinRequestStatus = %q", status)
+		}
+
+		if err := s.Groups.ApproveJoinRequest(ctx, group.ID, joiner); err != nil {
+			t.Fatal(err)
+		}
+		if member, _ := s.Groups.IsMember(ctx, group.ID, joiner); !member {
+			t.Error("approved user is not a member")
+		}
+		if _, err := s.Groups.JoinRequestStatus(ctx, group.ID, joiner); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("request still there after approval: %v", err)
+		}
+		ids, _ := s.Groups.MemberIDs(ctx, group.ID, creator)
+		if fmt.Sprint(ids) != fmt.Sprint([]int{joiner}) {
+			t.Errorf("MemberIDs without creator = %v", ids)
+		}
+	})
+}
+
+func TestReactions(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		author := createUser(t, s, "author", false)
+		fan := createUser(t, s, "fan", false)
+		other := createUser(t, s, "other", false)
+		postID := createPost(t, s, author, models.PrivacyPublic)
+		comment := models.Comment{PostID: postID, UserID: fan, Content: "hi"}
+		if err := s.Posts.CreateComment(ctx, &comment); err != nil {
+			t.Fatal(err)
+		}
+
+		target, err := s.Reactions.Target(ctx, models.TargetComment, comment.ID)
+		if err != nil || target != (models.ReactionTarget{OwnerID: fan, PostID: postID}) {
+			t.Errorf("Target(comment) = %+v, %v", target, err)
+		}
+		if _, err := s.Reactions.Target(ctx, models.TargetGroupPost, postID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("a post isn't a group post: %v", err)
+		}
+
+		react := func(userID int, targetType string, targetID int, reaction string) bool {
+			t.Helper()
+			added, err := s.Reactions.React(ctx, models.Reaction{UserID: userID, TargetType: targetType, TargetID: targetID, Type: reaction})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return added
+		}
+		if !react(fan, models.TargetPost, postID, "like") || react(fan, models.TargetPost, postID, "love") {
+			t.Error("React should add the first reaction and replace the second")
+		}
+		react(other, models.TargetPost, postID, "love")
+		react(author, models.TargetComment, comment.ID, "haha")
+
+		summaries, err := s.Reactions.Summaries(ctx, models.TargetPost, []int{postID, postID + 1000}, fan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(summaries) != fmt.Sprint(map[int]models.ReactionSummary{postID: {Reactions: map[string]int{"love": 2}, MyReaction: "love"}}) {
+			t.Errorf("Summaries = %v", summaries)
+		}
+		if removed, _ := s.Reactions.Unreact(ctx, other, models.TargetPost, postID); !removed {
+			t.Error("Unreact didn't find the reaction")
+		}
+		if removed, _ := s.Reactions.Unreact(ctx, other, models.TargetPost, postID); removed {
+			t.Error("second Unreact removed something")
+		}
+
+		// a reaction notification is updated while unread, a new one starts once it was read
+		notify := func(senderID, count int) {
+			targetType, targetID := models.TargetPost, postID
+			n := models.Notification{UserID: author, Type: "reaction", SenderID: &senderID, SenderCount: count,
+				PostID: &postID, TargetType: &targetType, TargetID: &targetID}
+			if err := s.Notifications.Aggregate(ctx, &n); err != nil {
+				t.Fatal(err)
+			}
+		}
+		notify(fan, 1)
+		notify(other, 2)
+		list, _, _ := s.Notifications.List(ctx, author, store.Page{})
+		if len(list) != 1 || *list[0].SenderID != other || list[0].SenderCount != 2 || *list[0].TargetType != models.TargetPost {
+			t.Fatalf("aggregated notifications = %+v", list)
+		}
+		s.Notifications.MarkAllRead(ctx, author)
+		notify(fan, 3)
+		if list, _, _ := s.Notifications.List(ctx, author, store.Page{}); len(list) != 2 {
+			t.Errorf("%d notifications after reading the first, want 2", len(list))
+		}
+
+		// the post takes its reactions, its comments' reactions and the notifications along
+		if _, err := s.Posts.Delete(ctx, postID); err != nil {
+			t.Fatal(err)
+		}
+		for targetType, id := range map[string]int{models.TargetPost: postID, models.TargetComment: comment.ID} {
+			if summaries, _ := s.Reactions.Summaries(ctx, targetType, []int{id}, author); len(summaries) != 0 {
+				t.Errorf("%s reactions survived the post: %v", targetType, summaries)
+			}
+		}
+		if list, _, _ := s.Notifications.List(ctx, author, store.Page{}); len(list) != 0 {
+			t.Errorf("reaction notifications survived the post: %+v", list)
+		}
+	})
+}
+
+func TestJobQueue(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		// the migrations may have queued jobs of their own
+		queued, err := s.Jobs.Counts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := "every:test"
+		later := models.Job{Type: "later", MaxAttempts: 3, RunAt: 200, CreatedAt: 100, UniqueKey: &key}
+		first := models.Job{Type: "first", MaxAttempts: 3, RunAt: 100, CreatedAt: 100}
+		for _, job := range []*models.Job{&later, &first} {
+			if err := s.Jobs.Enqueue(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Jobs.Enqueue(ctx, &models.Job{Type: "later", MaxAttempts: 3, RunAt: 100, UniqueKey: &key}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("second job with the same unique key: %v", err)
+		}
+
+		job, err := s.Jobs.Claim(ctx, 150)
+		if err != nil || job.ID != first.ID || job.Status != models.JobRunning || job.Attempts != 1 {
+			t.Fatalf("Claim = %+v, %v", job, err)
+		}
+		if _, err := s.Jobs.Claim(ctx, 150); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("claimed a job that isn't due: %v", err)
+		}
+
+		if err := s.Jobs.Retry(ctx, first.ID, 150, 300, "boom"); err != nil {
+			t.Fatal(err)
+		}
+		job, _ = s.Jobs.Claim(ctx, 300)
+		if job.ID != later.ID {
+			t.Fatalf("expected the earlier run_at first, got %+v", job)
+		}
+		if err := s.Jobs.Complete(ctx, later.ID, 300); err != nil {
+			t.Fatal(err)
+		}
+		// the key is free again once the job is done
+		if err := s.Jobs.Enqueue(ctx, &models.Job{Type: "later", MaxAttempts: 3, RunAt: 900, CreatedAt: 300, UniqueKey: &key}); err != nil {
+			t.Fatalf("unique key of a finished job: %v", err)
+		}
+
+		job, _ = s.Jobs.Claim(ctx, 300)
+		if job.ID != first.ID || job.Attempts != 2 || job.LastError == nil || *job.LastError != "boom" {
+			t.Fatalf("retried job = %+v", job)
+		}
+		if err := s.Jobs.Fail(ctx, first.ID, 310, "boom again"); err != nil {
+			t.Fatal(err)
+		}
+
+		counts, err := s.Jobs.Counts(ctx)
+		if err != nil || counts[models.JobPending] != queued[models.JobPending]+1 || counts[models.JobDone] != 1 || counts[models.JobFailed] != 1 {
+			t.Fatalf("Counts = %v, %v", counts, err)
+		}
+		failed, _ := s.Jobs.List(ctx, models.JobFailed, 10)
+		if len(failed) != 1 || failed[0].ID != first.ID {
+			t.Errorf("failed jobs = %+v", failed)
+		}
+		if n, _ := s.Jobs.DeleteFinished(ctx, 301); n != 1 {
+			t.Errorf("DeleteFinished = %d, want only the done job", n)
+		}
+	})
+}
+
+func TestDeleteExpiredSessions(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		old := createUser(t, s, "old", false)
+		fresh := createUser(t, s, "fresh", false)
+		now := time.Now()
+		s.Users.CreateSession(ctx, old, "old-token", now.Add(-time.Hour))
+		s.Users.CreateSession(ctx, fresh, "fresh-token", now.Add(time.Hour))
+
+		if n, err := s.Users.DeleteExpiredSessions(ctx, now); err != nil || n != 1 {
+			t.Fatalf("DeleteExpiredSessions = %d, %v", n, err)
+		}
+		if id, err := s.Users.SessionUser(ctx, "fresh-token"); err != nil || id != fresh {
+			t.Errorf("fresh session gone: %d, %v", id, err)
+		}
+	})
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Stores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user", false)
+
+		key := models.IdempotencyKey{UserID: user, Key: "k1", RequestHash: "h", CreatedAt: 100, ExpiresAt: 200}
+		if err := s.Idempotency.Reserve(ctx, &key, 100); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Idempotency.Reserve(ctx, &key, 150); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("second Reserve = %v", err)
+		}
+
+		key.Status, key.ContentType, key.Body = 201, "application/json", []byte(`{"id":1}`)
+		if err := s.Idempotency.Save(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Idempotency.Get(ctx, user, "k1")
+		if err != nil || got.Status != 201 || string(got.Body) != `{"id":1}` || got.RequestHash != "h" {
+			t.Fatalf("Get = %+v, %v", got, err)
+		}
+
+		// expired keys can be reserved again
+		again := models.IdempotencyKey{UserID: user, Key: "k1", RequestHash: "h2", CreatedAt: 200, ExpiresAt: 300}
+		if err := s.Idempotency.Reserve(ctx, &again, 200); err != nil {
+			t.Fatalf("Reserve after expiry = %v", err)
+		}
+		if err := s.Idempotency.Release(ctx, user, "k1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Idempotency.Get(ctx, user, "k1"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("released key still there: %v", err)
+		}
+
+		s.Idempotency.Reserve(ctx, &models.IdempotencyKey{UserID: user, Key: "k2", CreatedAt: 100, ExpiresAt: 200}, 100)
+		if n, err := s.Idempotency.DeleteExpired(ctx, 250); err != nil || n != 1 {
+			t.Errorf("DeleteExpired = %d, %v", n, err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// Package telemetry sets up OpenTelemetry tracing.
+//
+// Every HTTP request gets a span (HTTP below), the SQL queries made while serving it are child
+// spans (the database driver is wrapped in package db), and so are the websocket sends and the
+// background jobs. The spans go to the exporter picked with OTEL_TRACES_EXPORTER:
+//
+//	none    the default, nothing is recorded
+//	stdout  pretty printed JSON on stdout, handy with `go run`
+//	file    one JSON object per span appended to OTEL_TRACES_FILE (traces.json by default)
+//
+// OTEL_SERVICE_NAME overrides the service name ("social-network").
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "social-network"
+
+// Setup installs the tracer provider chosen by the environment, the returned function flushes
+// the spans still buffered and must be called on shutdown
+func Setup() (shutdown func(context.Context) error, err error) {
+	// traceparent headers are read and written even with tracing off, so a proxy's trace id goes through
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			path = "traces.json"
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open traces file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		// OTLP would plug in here once the collector side exists
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q (none, stdout or file)", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "social-network"
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
+
+// Tracer is the tracer of the app packages
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Start starts a span under the one in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span (if any) and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Go runs fn in a new goroutine with a span that belongs to the trace of ctx. The goroutine usually
+// outlives the request, so fn gets the trace but not the request's cancellation
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, span := Start(ctx, name)
+		defer span.End()
+		fn(ctx)
+	}()
+}
+
+// HTTP starts the server span of every request, a traceparent header sent by the client becomes
+// its parent. The span is named after the method until Route gives it the matched pattern
+func HTTP(next http.Handler) http.Handler {
+	propagator := otel.GetTextMapPropagator()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, "HTTP "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(r.RemoteAddr),
+			))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+		if sw.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// Route names the request span after the route pattern ("GET /groups/{id}/events"), so every
+// request of a route lands under the same name whatever the ids are
+func Route(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusWriter remembers the status. It keeps Hijack working, the websocket upgrade needs it
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("telemetry: response writer can't be hijacked")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
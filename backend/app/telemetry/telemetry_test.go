@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func useTestProvider(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestHTTPSpanUsesRouteAndTraceparent(t *testing.T) {
+	exporter := useTestProvider(t)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /groups/{id}", Route("GET /groups/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+
+	r := httptest.NewRequest(http.MethodGet, "/groups/7", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	HTTP(mux).ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /groups/{id}" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span = %s (%s)", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("traceparent ignored, trace id %s", span.SpanContext.TraceID())
+	}
+	for _, attr := range span.Attributes {
+		if attr.Key == "http.response.status_code" && attr.Value.AsInt64() != http.StatusTeapot {
+			t.Errorf("status attribute = %d", attr.Value.AsInt64())
+		}
+	}
+}
+
+func TestGoKeepsTheTrace(t *testing.T) {
+	exporter := useTestProvider(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, parent := Start(ctx, "request")
+	done := make(chan error)
+	Go(ctx, "background", func(ctx context.Context) {
+		cancel() // the request is over, the goroutine keeps going
+		done <- ctx.Err()
+	})
+	if err := <-done; err != nil {
+		t.Errorf("goroutine got the request's cancellation: %v", err)
+	}
+	parent.End()
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "background" && span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("background span isn't a child of the request")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"fmt"
+	"os"
+)
+
+// Config selects the storage backend
+//
+//	DB_DRIVER       sqlite3 (default) or postgres
+//	DB_PATH         SQLite database file (default ./db/social-network.db)
+//	DATABASE_URL    PostgreSQL connection string, required when DB_DRIVER=postgres
+//	MIGRATIONS_DIR  root folder holding the sqlite/ and postgres/ migration sets (default ./db/migrations)
+type Config struct {
+	Driver        Dialect
+	DSN           string
+	MigrationsDir string
+}
+
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Driver:        Dialect(getEnv("DB_DRIVER", string(SQLite))),
+		MigrationsDir: getEnv("MIGRATIONS_DIR", "./db/migrations"),
+	}
+
+	switch cfg.Driver {
+	case SQLite:
+		cfg.DSN = getEnv("DB_PATH", "./db/social-network.db")
+	case Postgres:
+		cfg.DSN = os.Getenv("DATABASE_URL")
+		if cfg.DSN == "" {
+			return cfg, fmt.Errorf("DATABASE_URL is required when DB_DRIVER=postgres")
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported DB_DRIVER %q (use sqlite3 or postgres)", cfg.Driver)
+	}
+
+	return cfg, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
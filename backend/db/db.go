@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DB wraps *sql.DB so every query is rebound for the configured dialect
+// handlers keep writing "?" placeholders and never need to know which backend is running
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Tx is the transaction counterpart of DB
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.Query(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRow(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	return db.DB.Prepare(db.Dialect.Rebind(query))
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.DB.PrepareContext(ctx, db.Dialect.Rebind(query))
+}
+
+// Insert runs an INSERT statement and returns the id of the new row
+func (db *DB) Insert(query string, args ...any) (int64, error) {
+	return db.InsertContext(context.Background(), query, args...)
+}
+
+func (db *DB) InsertContext(ctx context.Context, query string, args ...any) (int64, error) {
+	return insertID(ctx, db.Dialect, db.DB, query, args...)
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, Dialect: db.Dialect}, nil
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRow(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Insert(query string, args ...any) (int64, error) {
+	return tx.InsertContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) InsertContext(ctx context.Context, query string, args ...any) (int64, error) {
+	return insertID(ctx, tx.Dialect, tx.Tx, query, args...)
+}
+
+// the part of *sql.DB and *sql.Tx that insertID needs
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertID(ctx context.Context, d Dialect, q execQueryer, query string, args ...any) (int64, error) {
+	var id int64
+	if d.SupportsReturning() {
+		err := q.QueryRowContext(ctx, d.Rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := q.ExecContext(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+/* notes:
+the wrapper methods shadow the ones of the embedded *sql.DB / *sql.Tx, so code like
+db.Database.QueryRow("... WHERE id = ?", id) keeps compiling and works on both backends
+
+Insert replaces the old Exec + result.LastInsertId() combo, because lib/pq (PostgreSQL) doesn't
+support LastInsertId, instead we append "RETURNING id" and read it back
+queries passed to Insert must not end with a ";"
+*/
@@ -0,0 +1,143 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"social-network/db"
+	"social-network/db/dbtest"
+)
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM users WHERE id = ? AND about_me = 'why?' AND email = ?"
+
+	if got := db.SQLite.Rebind(query); got != query {
+		t.Errorf("sqlite rebind changed the query: %s", got)
+	}
+
+	want := "SELECT * FROM users WHERE id = $1 AND about_me = 'why?' AND email = $2"
+	if got := db.Postgres.Rebind(query); got != want {
+		t.Errorf("postgres rebind\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func insertUser(t *testing.T, database *db.DB, email, username string) int64 {
+	t.Helper()
+	id, err := database.Insert(`INSERT INTO users (email, password, first_name, last_name, date_of_birth, username, is_private)
+		VALUES (?, 'x', 'First', 'Last', '2000-01-01', ?, ?)`, email, username, false)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return id
+}
+
+func TestInsertReturnsID(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		first := insertUser(t, database, "a@test.com", "alice")
+		second := insertUser(t, database, "b@test.com", "bob")
+		if first <= 0 || second != first+1 {
+			t.Fatalf("unexpected ids %d, %d", first, second)
+		}
+
+		tx, err := database.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		groupID, err := tx.Insert("INSERT INTO groups (group_name, title, description, creator_id) VALUES (?, ?, ?, ?)",
+			"g", "title", "desc", first)
+		if err != nil || groupID <= 0 {
+			t.Fatalf("insert in tx: id=%d err=%v", groupID, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestPortableQueries(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		alice := insertUser(t, database, "a@test.com", "Alice")
+		bob := insertUser(t, database, "b@test.com", "bob")
+
+		// booleans written as TRUE/FALSE work on both backends
+		_, err := database.Exec("INSERT INTO messages (sender_id, receiver_id, content, created_at, is_read) VALUES (?, ?, ?, ?, FALSE)",
+			alice, bob, "hi", time.Now())
+		if err != nil {
+			t.Fatalf("insert message: %v", err)
+		}
+		var unread int
+		if err := database.QueryRow("SELECT COUNT(*) FROM messages WHERE receiver_id = ? AND is_read = FALSE", bob).Scan(&unread); err != nil || unread != 1 {
+			t.Fatalf("unread count = %d, err = %v", unread, err)
+		}
+
+		// ON CONFLICT DO NOTHING replaces SQLite's INSERT OR IGNORE
+		for i := 0; i < 2; i++ {
+			if _, err := database.Exec("INSERT INTO followers (follower_id, followed_id) VALUES (?, ?) ON CONFLICT DO NOTHING", alice, bob); err != nil {
+				t.Fatalf("follow #%d: %v", i, err)
+			}
+		}
+
+		// EXISTS scans into bool on both backends
+		var following bool
+		if err := database.QueryRow("SELECT EXISTS(SELECT 1 FROM followers WHERE follower_id = ? AND followed_id = ?)", alice, bob).Scan(&following); err != nil || !following {
+			t.Fatalf("following = %v, err = %v", following, err)
+		}
+
+		// case-insensitive search
+		var found int
+		like := database.Dialect.ILike()
+		if err := database.QueryRow("SELECT COUNT(*) FROM users WHERE username "+like+" ?", "%ali%").Scan(&found); err != nil || found != 1 {
+			t.Fatalf("search found %d users, err = %v", found, err)
+		}
+
+		rows, err := database.Query("SELECT id FROM users ORDER BY " + database.Dialect.Random() + " LIMIT 10")
+		if err != nil {
+			t.Fatalf("random order: %v", err)
+		}
+		rows.Close()
+	})
+}
+
+func TestPendingInvitationIsUnique(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		alice := insertUser(t, database, "a@test.com", "alice")
+		bob := insertUser(t, database, "b@test.com", "bob")
+		groupID, err := database.Insert("INSERT INTO groups (group_name, title, description, creator_id) VALUES (?, ?, ?, ?)",
+			"g", "title", "desc", alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invite := "INSERT INTO group_invitations (group_id, inviter_id, user_id, status, created_at) VALUES (?, ?, ?, ?, ?)"
+		if _, err := database.Exec(invite, groupID, alice, bob, "pending", time.Now().Unix()); err != nil {
+			t.Fatalf("first invite: %v", err)
+		}
+		if _, err := database.Exec(invite, groupID, alice, bob, "pending", time.Now().Unix()); err == nil {
+			t.Fatal("second pending invite should violate the partial unique index")
+		}
+		if _, err := database.Exec(invite, groupID, alice, bob, "declined", time.Now().Unix()); err != nil {
+			t.Fatalf("declined invite next to a pending one: %v", err)
+		}
+	})
+}
+
+func TestUnreadReactionNotificationIsUnique(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		alice := insertUser(t, database, "a@test.com", "alice")
+
+		notify := "INSERT INTO notifications (user_id, type, target_type, target_id, is_read, created_at) VALUES (?, ?, 'post', 1, ?, ?)"
+		if _, err := database.Exec(notify, alice, "reaction", false, time.Now()); err != nil {
+			t.Fatalf("first notification: %v", err)
+		}
+		if _, err := database.Exec(notify, alice, "reaction", false, time.Now()); err == nil {
+			t.Fatal("second unread reaction notification should violate the partial unique index")
+		}
+		if _, err := database.Exec(notify, alice, "reaction", true, time.Now()); err != nil {
+			t.Fatalf("read notification next to an unread one: %v", err)
+		}
+		if _, err := database.Exec(notify, alice, "mention", false, time.Now()); err != nil {
+			t.Fatalf("mention of the same target: %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Package dbtest opens throwaway databases for tests.
+//
+// SQLite tests always run, on a fresh file in a temp folder.
+// PostgreSQL tests run only when TEST_DATABASE_URL points at a server,
+// every test then gets its own schema that is dropped afterwards.
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"social-network/db"
+)
+
+// Backends lists the dialects ForEachBackend runs against
+var Backends = []db.Dialect{db.SQLite, db.Postgres}
+
+// ForEachBackend runs fn once per available backend as a subtest
+func ForEachBackend(t *testing.T, fn func(t *testing.T, database *db.DB)) {
+	t.Helper()
+	for _, dialect := range Backends {
+		t.Run(string(dialect), func(t *testing.T) {
+			fn(t, Open(t, dialect))
+		})
+	}
+}
+
+// Open returns a migrated database for the given dialect, skipping the test if
+// the backend isn't available
+func Open(t *testing.T, dialect db.Dialect) *db.DB {
+	t.Helper()
+
+	cfg := db.Config{Driver: dialect, MigrationsDir: migrationsDir(t)}
+	switch dialect {
+	case db.SQLite:
+		cfg.DSN = filepath.Join(t.TempDir(), "test.db")
+	case db.Postgres:
+		cfg.DSN = postgresSchema(t)
+	default:
+		t.Fatalf("unknown dialect %q", dialect)
+	}
+
+	database, err := db.Open(cfg)
+	if err != nil {
+		t.Fatalf("open %s: %v", dialect, err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func migrationsDir(t *testing.T) string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot locate migrations folder")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "migrations")
+}
+
+// postgresSchema creates an empty schema and returns a DSN that uses it
+func postgresSchema(t *testing.T) string {
+	base := os.Getenv("TEST_DATABASE_URL")
+	if base == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping PostgreSQL")
+	}
+
+	admin, err := sql.Open("postgres", base)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer admin.Close()
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		cleanup, err := sql.Open("postgres", base)
+		if err != nil {
+			return
+		}
+		defer cleanup.Close()
+		cleanup.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	// lib/pq accepts both URL and key=value connection strings
+	if strings.HasPrefix(base, "postgres://") || strings.HasPrefix(base, "postgresql://") {
+		sep := "?"
+		if strings.Contains(base, "?") {
+			sep = "&"
+		}
+		return base + sep + "search_path=" + schema
+	}
+	return base + " search_path=" + schema
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect describes the SQL differences between the backends we support.
+// Queries in the handlers are written once with "?" placeholders and
+// the dialect takes care of turning them into something the driver understands
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite3"
+	Postgres Dialect = "postgres"
+)
+
+// Rebind converts "?" placeholders into the style the backend expects
+// SQLite keeps "?", PostgreSQL wants numbered "$1, $2, ..." placeholders
+// question marks inside quoted strings are left alone
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	inQuote := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+			b.WriteByte(c)
+		case c == '?' && !inQuote:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Random returns the expression used for "ORDER BY <random>"
+func (d Dialect) Random() string {
+	if d == Postgres {
+		return "random()"
+	}
+	return "RANDOM()"
+}
+
+// ILike returns the case-insensitive LIKE operator
+// SQLite's LIKE is already case-insensitive for ASCII, PostgreSQL needs ILIKE
+func (d Dialect) ILike() string {
+	if d == Postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// PrefixLike returns "column starts with ?" for a lowercased, LIKE-escaped pattern ending in '%'.
+// Each backend gets the form its index can serve (see the 000025 migration): SQLite's LIKE is
+// case-insensitive and uses a NOCASE index on the column, PostgreSQL has an index on LOWER(column)
+func (d Dialect) PrefixLike(column string) string {
+	if d == Postgres {
+		return "LOWER(" + column + ") LIKE ? ESCAPE '\\'"
+	}
+	return column + " LIKE ? ESCAPE '\\'"
+}
+
+// SupportsReturning reports if "INSERT ... RETURNING id" has to be used to read
+// the new row id (PostgreSQL drivers don't implement LastInsertId)
+func (d Dialect) SupportsReturning() bool {
+	return d == Postgres
+}
+
+// SkipLocked returns the row locking clause for "pick the next free row" queries.
+// PostgreSQL locks the row and lets other transactions skip it, SQLite has a single writer
+// and doesn't know the syntax
+func (d Dialect) SkipLocked() string {
+	if d == Postgres {
+		return "FOR UPDATE SKIP LOCKED"
+	}
+	return ""
+}
+
+// Timestamp wraps a TIMESTAMP column (or a ? holding a timestamp) so two of them compare as times.
+// SQLite keeps timestamps as text, CURRENT_TIMESTAMP and the driver write them in different
+// formats, datetime() brings both to the same one. PostgreSQL compares them natively
+func (d Dialect) Timestamp(expr string) string {
+	if d == Postgres {
+		return expr
+	}
+	return "datetime(" + expr + ")"
+}
@@ -0,0 +1,12 @@
+-- the foreign key on group_id is added in 000007, once the groups table exists
+-- (SQLite doesn't check the referenced table at creation time, PostgreSQL does)
+CREATE TABLE posts (
+    id SERIAL PRIMARY KEY,
+    content TEXT,
+    image TEXT,
+    privacy TEXT NOT NULL CHECK(privacy IN ('public', 'almost_private', 'private')) DEFAULT 'public',
+    user_id INTEGER NOT NULL,
+    group_id INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,9 @@
+-- the foreign key on group_id is added in 000007, once the groups table exists
+CREATE TABLE group_messages (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (sender_id) REFERENCES users(id)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS group_invitations (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    inviter_id INTEGER NOT NULL,
+    status TEXT DEFAULT 'pending',
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (inviter_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_invitations_pending ON group_invitations(group_id, user_id) WHERE status='pending';
+
+CREATE INDEX IF NOT EXISTS idx_group_invitations_group ON group_invitations(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_invitations_user ON group_invitations(user_id);
+CREATE INDEX IF NOT EXISTS idx_group_invitations_status ON group_invitations(status);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS group_events (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    creator_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group ON group_events(group_id);
@@ -0,0 +1,23 @@
+-- the foreign key on follow_request_id is added in 000014, once follow_user_requests exists
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    follow_request_id INTEGER,
+    type TEXT NOT NULL,
+    sender_id INTEGER,
+    sender_name TEXT,
+    sender_avatar TEXT,
+    post_id INTEGER,
+    group_id INTEGER,
+    group_name TEXT,
+    event_id INTEGER,
+    event_date BIGINT,
+    event_title TEXT,
+    is_read BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation')),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE
+);
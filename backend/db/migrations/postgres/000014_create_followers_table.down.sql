@@ -0,0 +1,3 @@
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_follow_request_id_fkey;
+DROP TABLE IF EXISTS followers;
+DROP TABLE IF EXISTS follow_user_requests;
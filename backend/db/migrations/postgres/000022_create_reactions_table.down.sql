@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS reactions;
+
+DELETE FROM notifications WHERE type = 'reaction';
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation'));
+ALTER TABLE notifications DROP COLUMN sender_count;
+ALTER TABLE notifications DROP COLUMN target_type;
+ALTER TABLE notifications DROP COLUMN target_id;
@@ -0,0 +1,22 @@
+-- one reaction per user and target, target_type says which table target_id points into
+-- (posts, comments, group_posts or group_post_comments) so there is no foreign key on it
+CREATE TABLE IF NOT EXISTS reactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK(target_type IN ('post', 'comment', 'group_post', 'group_comment')),
+    target_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK(type IN ('like', 'love', 'haha', 'wow', 'sad', 'angry')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, target_type, target_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions(target_type, target_id);
+
+-- reaction notifications are aggregated per target: one unread notification per target,
+-- sender_* is the latest reactor and sender_count how many people have reacted so far
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction'));
+ALTER TABLE notifications ADD COLUMN sender_count INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notifications ADD COLUMN target_type TEXT;
+ALTER TABLE notifications ADD COLUMN target_id INTEGER;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_repost_of;
+ALTER TABLE posts DROP COLUMN repost_of;
+
+DELETE FROM notifications WHERE type = 'repost';
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction'));
@@ -0,0 +1,9 @@
+-- a repost (no content) or a quote post (with content) points at the post it shares.
+-- No foreign key: when the original is deleted the repost stays and shows it as unavailable
+ALTER TABLE posts ADD COLUMN repost_of INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_posts_repost_of ON posts(repost_of);
+
+-- the original's author gets a 'repost' notification
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction','repost'));
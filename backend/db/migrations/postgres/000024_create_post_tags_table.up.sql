@@ -0,0 +1,19 @@
+-- the #tags of posts and group posts (see app/hashtag), one row per tag and post.
+-- Exactly one of post_id and group_post_id is set. tagged_at (unix seconds) is when the content got
+-- the tag, the trending tags are computed from it. Posts written before this table get their tags
+-- from the tags.backfill job (migration 000034)
+CREATE TABLE IF NOT EXISTS post_tags (
+    id SERIAL PRIMARY KEY,
+    tag TEXT NOT NULL,
+    post_id INTEGER,
+    group_post_id INTEGER,
+    tagged_at INTEGER NOT NULL,
+    CHECK ((post_id IS NULL) <> (group_post_id IS NULL)),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag, tagged_at);
+CREATE INDEX IF NOT EXISTS idx_post_tags_tagged_at ON post_tags(tagged_at);
+CREATE INDEX IF NOT EXISTS idx_post_tags_post ON post_tags(post_id);
+CREATE INDEX IF NOT EXISTS idx_post_tags_group_post ON post_tags(group_post_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_username_lower;
+DROP TABLE IF EXISTS mentions;
+
+DELETE FROM notifications WHERE type = 'mention';
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction','repost'));
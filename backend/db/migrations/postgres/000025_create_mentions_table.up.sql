@@ -0,0 +1,29 @@
+-- @mentions of posts, comments and group messages (see app/mention), one row per mention.
+-- Exactly one of post_id, comment_id and group_message_id is set, the mention goes with its content.
+-- start_pos and length are in UTF-16 code units, the way the frontend indexes strings
+CREATE TABLE IF NOT EXISTS mentions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    group_message_id INTEGER,
+    start_pos INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    CHECK (num_nonnulls(post_id, comment_id, group_message_id) = 1),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_message_id) REFERENCES group_messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_post ON mentions(post_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_comment ON mentions(comment_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_group_message ON mentions(group_message_id);
+
+-- the mention autocomplete is LOWER(username) LIKE 'prefix%', text_pattern_ops lets it use the
+-- index whatever the database collation is
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username) text_pattern_ops);
+
+-- the mentioned users get a 'mention' notification
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction','repost','mention'));
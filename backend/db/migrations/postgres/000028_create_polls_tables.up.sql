@@ -0,0 +1,39 @@
+-- a poll belongs to exactly one post or group post and goes with it. It is saved with its post and
+-- never edited, closes_at (unix seconds) is when voting ends, NULL keeps it open
+CREATE TABLE IF NOT EXISTS polls (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER UNIQUE,
+    group_post_id INTEGER UNIQUE,
+    multiple BOOLEAN NOT NULL DEFAULT FALSE,
+    hide_results BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at INTEGER,
+    created_at INTEGER NOT NULL,
+    CHECK ((post_id IS NULL) <> (group_post_id IS NULL)),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE
+);
+
+-- the choices in the order the author gave them (position 1 first)
+CREATE TABLE IF NOT EXISTS poll_options (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_options_poll ON poll_options(poll_id, position);
+
+-- one row per chosen option, a single choice poll has one per voter
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    voted_at INTEGER NOT NULL,
+    PRIMARY KEY (option_id, user_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_poll_user ON poll_votes(poll_id, user_id);
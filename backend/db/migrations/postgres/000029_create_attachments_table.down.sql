@@ -0,0 +1,2 @@
+-- the image columns still have the first image of each post, the others are lost
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,48 @@
+-- the images of a post, comment or group post in display order (position 1 first). The image
+-- column of posts, comments and group_posts keeps the first one for the clients that show one
+-- image, width and height are NULL when unknown
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER,
+    comment_id INTEGER,
+    group_post_id INTEGER,
+    position INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT '',
+    width INTEGER,
+    height INTEGER,
+    content_type TEXT NOT NULL,
+    CHECK (num_nonnulls(post_id, comment_id, group_post_id) = 1),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_post ON attachments(post_id, position);
+CREATE INDEX IF NOT EXISTS idx_attachments_comment ON attachments(comment_id, position);
+CREATE INDEX IF NOT EXISTS idx_attachments_group_post ON attachments(group_post_id, position);
+
+-- the single images so far become the first attachment, the type is guessed from the extension
+INSERT INTO attachments (post_id, position, path, content_type)
+SELECT id, 1, image, CASE
+        WHEN LOWER(image) LIKE '%.png' THEN 'image/png'
+        WHEN LOWER(image) LIKE '%.gif' THEN 'image/gif'
+        WHEN LOWER(image) LIKE '%.webp' THEN 'image/webp'
+        ELSE 'image/jpeg' END
+FROM posts WHERE image IS NOT NULL AND image <> '';
+
+INSERT INTO attachments (comment_id, position, path, content_type)
+SELECT id, 1, image, CASE
+        WHEN LOWER(image) LIKE '%.png' THEN 'image/png'
+        WHEN LOWER(image) LIKE '%.gif' THEN 'image/gif'
+        WHEN LOWER(image) LIKE '%.webp' THEN 'image/webp'
+        ELSE 'image/jpeg' END
+FROM comments WHERE image IS NOT NULL AND image <> '';
+
+INSERT INTO attachments (group_post_id, position, path, content_type)
+SELECT id, 1, image, CASE
+        WHEN LOWER(image) LIKE '%.png' THEN 'image/png'
+        WHEN LOWER(image) LIKE '%.gif' THEN 'image/gif'
+        WHEN LOWER(image) LIKE '%.webp' THEN 'image/webp'
+        ELSE 'image/jpeg' END
+FROM group_posts WHERE image IS NOT NULL AND image <> '';
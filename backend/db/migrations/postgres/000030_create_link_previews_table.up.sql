@@ -0,0 +1,11 @@
+-- the previews of the links of posts and messages, an empty row is a link that couldn't be fetched
+CREATE TABLE IF NOT EXISTS link_previews (
+    url TEXT PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_previews_fetched_at ON link_previews(fetched_at);
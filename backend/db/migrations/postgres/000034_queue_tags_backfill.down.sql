@@ -0,0 +1 @@
+DELETE FROM jobs WHERE type = 'tags.backfill' AND status = 'pending';
@@ -0,0 +1,16 @@
+-- edited_at stays NULL until the first edit, the frontend shows "edited" when it's set
+ALTER TABLE posts ADD COLUMN edited_at TIMESTAMP;
+
+-- every edit keeps the version it replaced, newest post content stays in posts
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    content TEXT,
+    image TEXT,
+    privacy TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL, -- when this version was published
+    replaced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions(post_id);
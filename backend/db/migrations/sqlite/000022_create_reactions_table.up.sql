@@ -0,0 +1,52 @@
+-- one reaction per user and target, target_type says which table target_id points into
+-- (posts, comments, group_posts or group_post_comments) so there is no foreign key on it
+CREATE TABLE IF NOT EXISTS reactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK(target_type IN ('post', 'comment', 'group_post', 'group_comment')),
+    target_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK(type IN ('like', 'love', 'haha', 'wow', 'sad', 'angry')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, target_type, target_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions(target_type, target_id);
+
+-- reaction notifications are aggregated per target: one unread notification per target,
+-- sender_* is the latest reactor and sender_count how many people have reacted so far.
+-- SQLite can't change a CHECK constraint, so the table is rebuilt with the new type
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    follow_request_id INTEGER,
+    type TEXT NOT NULL CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction')),
+    sender_id INTEGER,
+    sender_name TEXT,
+    sender_avatar TEXT,
+    sender_count INTEGER NOT NULL DEFAULT 1,
+    post_id INTEGER,
+    group_id INTEGER,
+    group_name TEXT,
+    event_id INTEGER,
+    event_date TIMESTAMP,
+    event_title TEXT,
+    target_type TEXT,
+    target_id INTEGER,
+    is_read BOOLEAN DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_request_id) REFERENCES follow_user_requests(id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_new (id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar,
+    post_id, group_id, group_name, event_id, event_date, event_title, is_read, created_at)
+SELECT id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar,
+    post_id, group_id, group_name, event_id, event_date, event_title, is_read, created_at
+FROM notifications;
+
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
@@ -0,0 +1,37 @@
+DROP INDEX IF EXISTS idx_users_username_nocase;
+DROP TABLE IF EXISTS mentions;
+
+CREATE TABLE notifications_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    follow_request_id INTEGER,
+    type TEXT NOT NULL CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction','repost')),
+    sender_id INTEGER,
+    sender_name TEXT,
+    sender_avatar TEXT,
+    sender_count INTEGER NOT NULL DEFAULT 1,
+    post_id INTEGER,
+    group_id INTEGER,
+    group_name TEXT,
+    event_id INTEGER,
+    event_date TIMESTAMP,
+    event_title TEXT,
+    target_type TEXT,
+    target_id INTEGER,
+    is_read BOOLEAN DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_request_id) REFERENCES follow_user_requests(id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_old (id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, sender_count,
+    post_id, group_id, group_name, event_id, event_date, event_title, target_type, target_id, is_read, created_at)
+SELECT id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, sender_count,
+    post_id, group_id, group_name, event_id, event_date, event_title, target_type, target_id, is_read, created_at
+FROM notifications WHERE type <> 'mention';
+
+DROP TABLE notifications;
+ALTER TABLE notifications_old RENAME TO notifications;
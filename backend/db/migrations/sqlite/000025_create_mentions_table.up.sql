@@ -0,0 +1,61 @@
+-- @mentions of posts, comments and group messages (see app/mention), one row per mention.
+-- Exactly one of post_id, comment_id and group_message_id is set, the mention goes with its content.
+-- start_pos and length are in UTF-16 code units, the way the frontend indexes strings
+CREATE TABLE IF NOT EXISTS mentions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    group_message_id INTEGER,
+    start_pos INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    CHECK ((post_id IS NOT NULL) + (comment_id IS NOT NULL) + (group_message_id IS NOT NULL) = 1),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_message_id) REFERENCES group_messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_post ON mentions(post_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_comment ON mentions(comment_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_group_message ON mentions(group_message_id);
+
+-- the mention autocomplete is a case insensitive prefix LIKE, SQLite only uses an index for it
+-- when the index is NOCASE
+CREATE INDEX IF NOT EXISTS idx_users_username_nocase ON users(username COLLATE NOCASE);
+
+-- the mentioned users get a 'mention' notification, the table is rebuilt for the new type like in 000022
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    follow_request_id INTEGER,
+    type TEXT NOT NULL CHECK(type IN ('follow_request','new_follower','user_accepted_follow','new_comment', 'group_invitation', 'group_join_request_approved', 'event_created','group_join_request','event_invitation','reaction','repost','mention')),
+    sender_id INTEGER,
+    sender_name TEXT,
+    sender_avatar TEXT,
+    sender_count INTEGER NOT NULL DEFAULT 1,
+    post_id INTEGER,
+    group_id INTEGER,
+    group_name TEXT,
+    event_id INTEGER,
+    event_date TIMESTAMP,
+    event_title TEXT,
+    target_type TEXT,
+    target_id INTEGER,
+    is_read BOOLEAN DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_request_id) REFERENCES follow_user_requests(id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_new (id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, sender_count,
+    post_id, group_id, group_name, event_id, event_date, event_title, target_type, target_id, is_read, created_at)
+SELECT id, user_id, follow_request_id, type, sender_id, sender_name, sender_avatar, sender_count,
+    post_id, group_id, group_name, event_id, event_date, event_title, target_type, target_id, is_read, created_at
+FROM notifications;
+
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
@@ -0,0 +1,35 @@
+-- private collections of saved posts and group posts. Every user has one default "Saved"
+-- collection, created the first time their collections are read (see CollectionStore)
+CREATE TABLE IF NOT EXISTS collections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+-- one default collection per user, two requests creating it at once can't both win
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collections_default ON collections(user_id) WHERE is_default;
+
+-- exactly one of post_id and group_post_id is set, the item goes with its post. position orders
+-- the items of a collection (1 is the first), saved_at is unix seconds
+CREATE TABLE IF NOT EXISTS collection_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    collection_id INTEGER NOT NULL,
+    post_id INTEGER,
+    group_post_id INTEGER,
+    position INTEGER NOT NULL,
+    saved_at INTEGER NOT NULL,
+    CHECK ((post_id IS NULL) <> (group_post_id IS NULL)),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE,
+    UNIQUE(collection_id, post_id),
+    UNIQUE(collection_id, group_post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_items_collection ON collection_items(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_collection_items_post ON collection_items(post_id);
+CREATE INDEX IF NOT EXISTS idx_collection_items_group_post ON collection_items(group_post_id);
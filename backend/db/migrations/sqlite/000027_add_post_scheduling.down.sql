@@ -0,0 +1,7 @@
+-- the unpublished posts would show up in the feeds without the column
+DELETE FROM posts WHERE status <> 'published';
+
+DROP INDEX IF EXISTS idx_posts_scheduled;
+DROP INDEX IF EXISTS idx_posts_unpublished;
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
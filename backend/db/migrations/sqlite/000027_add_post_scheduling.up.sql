@@ -0,0 +1,7 @@
+-- drafts and scheduled posts live in posts but only published ones are visible (see visibleTo).
+-- publish_at is unix seconds, the posts.publish job flips scheduled posts once it's reached
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published'));
+ALTER TABLE posts ADD COLUMN publish_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_posts_unpublished ON posts(user_id) WHERE status <> 'published';
@@ -0,0 +1,6 @@
+-- the HTML of the content written in Markdown, NULL for plain text. The source stays in content
+ALTER TABLE posts ADD COLUMN content_html TEXT;
+ALTER TABLE post_revisions ADD COLUMN content_html TEXT;
+ALTER TABLE comments ADD COLUMN content_html TEXT;
+ALTER TABLE messages ADD COLUMN content_html TEXT;
+ALTER TABLE group_messages ADD COLUMN content_html TEXT;
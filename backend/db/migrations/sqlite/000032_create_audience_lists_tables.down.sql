@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_audience_lists;
+DROP TABLE IF EXISTS audience_list_members;
+DROP TABLE IF EXISTS audience_lists;
@@ -0,0 +1,33 @@
+-- named lists of people a user shares almost_private posts with ("Close friends", "Family")
+CREATE TABLE IF NOT EXISTS audience_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+-- added_at is unix seconds
+CREATE TABLE IF NOT EXISTS audience_list_members (
+    list_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    added_at INTEGER NOT NULL,
+    PRIMARY KEY (list_id, user_id),
+    FOREIGN KEY (list_id) REFERENCES audience_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_list_members_user ON audience_list_members(user_id);
+
+-- the lists an almost_private post is shared with, next to the people picked one by one in
+-- post_visibility. Who is in a list is read when the post is, not copied at post time
+CREATE TABLE IF NOT EXISTS post_audience_lists (
+    post_id INTEGER NOT NULL,
+    list_id INTEGER NOT NULL,
+    PRIMARY KEY (post_id, list_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (list_id) REFERENCES audience_lists(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_audience_lists_list ON post_audience_lists(list_id);
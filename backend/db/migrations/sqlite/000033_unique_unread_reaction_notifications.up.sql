@@ -0,0 +1,11 @@
+-- one unread reaction notification per user and target, Aggregate updates it instead of adding
+-- another. The duplicates concurrent reactions could leave before are marked read first
+UPDATE notifications SET is_read = TRUE
+WHERE type = 'reaction' AND is_read = FALSE AND id NOT IN (
+    SELECT MAX(id) FROM notifications
+    WHERE type = 'reaction' AND is_read = FALSE
+    GROUP BY user_id, target_type, target_id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unread_reaction
+ON notifications(user_id, type, target_type, target_id) WHERE type = 'reaction' AND is_read = FALSE;
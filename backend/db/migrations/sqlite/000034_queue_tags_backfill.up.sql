@@ -0,0 +1,4 @@
+-- the posts and group posts written before post_tags existed get their tags from the
+-- tags.backfill job (see app/handlers/post), queued once here. On a new database it finds nothing
+INSERT INTO jobs (type, payload, status, attempts, max_attempts, run_at, created_at, updated_at)
+VALUES ('tags.backfill', '{}', 'pending', 0, 5, CAST(strftime('%s', 'now') AS INTEGER), CAST(strftime('%s', 'now') AS INTEGER), CAST(strftime('%s', 'now') AS INTEGER));
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/lib/pq"
+)
+
+func openPostgres(dsn string) (*sql.DB, error) {
+	sqlDB, openErr := openTraced("postgres", dsn, Postgres)
+	if openErr != nil {
+		return nil, fmt.Errorf("failed to open database: %v", openErr)
+	}
+
+	pingErr := sqlDB.Ping()
+	if pingErr != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", pingErr)
+	}
+
+	// foreign keys are always enforced on PostgreSQL, nothing to switch on here
+	return sqlDB, nil
+}
+
+func postgresMigrationDriver(sqlDB *sql.DB) (database.Driver, error) {
+	return postgres.WithInstance(sqlDB, &postgres.Config{})
+}
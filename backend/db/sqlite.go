@@ -5,20 +5,62 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var Database *sql.DB
+var Database *DB
 
-func InitDB() error {
+// InitDB opens the configured backend, applies its migrations and stores the connection in Database
+func InitDB(cfg Config) error {
+	database, err := Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	Database = database
+	return nil
+}
+
+// Open connects to the backend described by cfg and applies pending migrations
+func Open(cfg Config) (*DB, error) {
+	var sqlDB *sql.DB
+	var err error
+
+	switch cfg.Driver {
+	case SQLite:
+		sqlDB, err = openSQLite(cfg.DSN)
+	case Postgres:
+		sqlDB, err = openPostgres(cfg.DSN)
+	default:
+		err = fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// ------------------------------------------------------------------------------------------------------
+	// APPLYING MIGRATIONS
+	migrationErr := applyMigrations(sqlDB, cfg)
+	if migrationErr != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %v", migrationErr)
+	}
+
+	return &DB{DB: sqlDB, Dialect: cfg.Driver}, nil
+}
+
+func openSQLite(path string) (*sql.DB, error) {
 	// ------------------------------------------------------------------------------------------------------
-	// CHECKING if the "./db" directory exists, if not we create it
+	// CHECKING if the database directory exists, if not we create it
 
-	_, statErr := os.Stat("./db")
+	dir := filepath.Dir(path)
+	_, statErr := os.Stat(dir)
 	// os.Stat returns info about a file/folder or an error if it doesnt exist
 	// we only need the error here, so we can use it in next check
 
@@ -27,42 +69,33 @@ func InitDB() error {
 		// it returns True if the error from os.Stat indicates that the file/directory doesn't exist or
 		// False for any other error (the file/folder exists or permission issues etc)
 
-		makeDirErr := os.MkdirAll("./db", 0o755)
+		makeDirErr := os.MkdirAll(dir, 0o755)
 		if makeDirErr != nil {
-			return fmt.Errorf("failed to create database directory: %v", makeDirErr)
+			return nil, fmt.Errorf("failed to create database directory: %v", makeDirErr)
 		}
 	}
 
 	// ------------------------------------------------------------------------------------------------------
-	// OPENING DATABASE CONNECTION and storing it in the variable "database" we created in start
-
-	var openErr error
-
-	Database, openErr = sql.Open("sqlite3", "./db/social-network.db")
-	// sql.Open returns: *sql.DB, error
+	// OPENING DATABASE CONNECTION
+	// _foreign_keys=on is applied to every connection of the pool, a single PRAGMA would only hit one of them
+	// _busy_timeout makes a writer wait for the lock instead of failing with "database is locked",
+	// the job workers write while requests are being served
+
+	// openTraced is sql.Open plus a span for every query (see traced.go)
+	sqlDB, openErr := openTraced("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000", SQLite)
+	// it returns: *sql.DB, error
 	if openErr != nil {
-		return fmt.Errorf("failed to open database: %v", openErr)
+		return nil, fmt.Errorf("failed to open database: %v", openErr)
 	}
 
-	pingErr := Database.Ping()
+	pingErr := sqlDB.Ping()
 	// Ping() is a method of the sql.DB struct (func (db *sql.DB) Ping() error)
 	if pingErr != nil {
-		return fmt.Errorf("failed to connect to database: %v", pingErr)
-	}
-
-	_, foreignKeyErr := Database.Exec("PRAGMA foreign_keys = ON;")
-	if foreignKeyErr != nil {
-		return fmt.Errorf("failed to enable foreign keys: %v", foreignKeyErr)
-	}
-
-	// ------------------------------------------------------------------------------------------------------
-	// APPLYING MIGRATIONS
-	migrationErr := applyMigrations()
-	if migrationErr != nil {
-		return fmt.Errorf("failed to apply migrations: %v", migrationErr)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", pingErr)
 	}
 
-	return nil
+	return sqlDB, nil
 }
 
 func CloseDB() error {
@@ -72,17 +105,26 @@ func CloseDB() error {
 	return nil
 }
 
-func applyMigrations() error {
+func applyMigrations(sqlDB *sql.DB, cfg Config) error {
 	// 1. DRIVER: create driver instance (of database.Driver type), driver: its how the DB talks to the migrator) -----
-	driver, err := sqlite3.WithInstance(Database, &sqlite3.Config{})
+	var driver database.Driver
+	var err error
+	switch cfg.Driver {
+	case Postgres:
+		driver, err = postgresMigrationDriver(sqlDB)
+	default:
+		driver, err = sqlite3.WithInstance(sqlDB, &sqlite3.Config{})
+	}
 	if err != nil {
 		return fmt.Errorf("could not create migration driver: %v", err)
 	}
 
 	// 2. MIGRATE OBJECT: create a migrate instance (of *migrate.Migrate type) ----------------------------------------
+	// every backend has its own folder of migrations: db/migrations/sqlite, db/migrations/postgres
+	sourceURL := "file://" + filepath.ToSlash(filepath.Join(cfg.MigrationsDir, migrationsFolder(cfg.Driver)))
 	migration, err := migrate.NewWithDatabaseInstance(
-		"file://db/migrations/sqlite",
-		"sqlite3",
+		sourceURL,
+		string(cfg.Driver),
 		driver,
 	)
 	if err != nil {
@@ -95,10 +137,17 @@ func applyMigrations() error {
 		return fmt.Errorf("could not apply migrations: %v", err)
 	}
 
-	log.Println("Migrations applied successfully")
+	log.Printf("Migrations applied successfully (%s)", cfg.Driver)
 	return nil
 }
 
+func migrationsFolder(d Dialect) string {
+	if d == Postgres {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
 /* ==================================================================================================================
 notes:
 	with the _ in start, we only run the package's init function
@@ -113,4 +162,7 @@ notes:
 
 
 	Down migrations are executed manually (not auto-applied) - use CLI tool or separate script for rollback if needed
+
+	the sqlite and postgres migration folders must stay in step: every new migration gets a file with the
+	same version number in both of them
 */
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openTraced is sql.Open with a driver wrapper that records a span per query. Queries only get a span
+// when their context is already part of a trace (a request, a job...), so the job polling and the
+// migrations don't fill the exporter with lonely one-span traces
+func openTraced(driverName, dsn string, d Dialect) (*sql.DB, error) {
+	// sql.Open doesn't connect, it's only the way to get at the registered driver
+	base, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := base.Driver()
+	base.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(&tracedConnector{Connector: connector, system: systemName(d)}), nil
+}
+
+func systemName(d Dialect) string {
+	if d == Postgres {
+		return "postgresql"
+	}
+	return "sqlite"
+}
+
+// dsnConnector is the connector of drivers that don't have one (go-sqlite3)
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+type tracedConnector struct {
+	driver.Connector
+	system string
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, system: c.system}, nil
+}
+
+// startSpan starts the span of one query, the returned span is nil outside of a trace
+func startSpan(ctx context.Context, system, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	return otel.Tracer("social-network/db").Start(ctx, "db "+operation(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", system),
+			attribute.String("db.query.text", query),
+		))
+}
+
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	// ErrSkip only means database/sql tries another way, the query itself didn't fail
+	if err != nil && err != driver.ErrSkip {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// operation is the first keyword of the query: SELECT, INSERT...
+func operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// tracedConn forwards everything to the driver's connection. The optional interfaces are always
+// implemented here, when the real connection lacks one the method answers driver.ErrSkip (or does
+// what database/sql would do without it)
+type tracedConn struct {
+	driver.Conn
+	system string
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, c.system, query)
+	res, err := execer.ExecContext(ctx, query, args)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, c.system, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, system: c.system}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt covers the queries database/sql runs through a prepared statement
+type tracedStmt struct {
+	driver.Stmt
+	query  string
+	system string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startSpan(ctx, s.system, s.query)
+	var res driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args))
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startSpan(ctx, s.system, s.query)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	endSpan(span, err)
+	return rows, err
+}
+
+func (s *tracedStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, arg := range args {
+		out[i] = arg.Value
+	}
+	return out
+}
+
+var (
+	_ driver.ExecerContext      = (*tracedConn)(nil)
+	_ driver.QueryerContext     = (*tracedConn)(nil)
+	_ driver.ConnPrepareContext = (*tracedConn)(nil)
+	_ driver.ConnBeginTx        = (*tracedConn)(nil)
+	_ driver.StmtExecContext    = (*tracedStmt)(nil)
+	_ driver.StmtQueryContext   = (*tracedStmt)(nil)
+)
@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"social-network/db"
+	"social-network/db/dbtest"
+)
+
+func TestQueriesAreTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	dbtest.ForEachBackend(t, func(t *testing.T, database *db.DB) {
+		exporter.Reset()
+		ctx := context.Background()
+
+		// outside of a trace: no span
+		var n int
+		database.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&n)
+		if spans := exporter.GetSpans(); len(spans) != 0 {
+			t.Fatalf("query without a parent span was traced: %v", spans)
+		}
+
+		ctx, parent := provider.Tracer("test").Start(ctx, "request")
+		database.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&n)
+		if _, err := database.ExecContext(ctx, "UPDATE missing_table SET x = 1"); err == nil {
+			t.Fatal("expected an error from a missing table")
+		}
+		parent.End()
+
+		spans := exporter.GetSpans()
+		if len(spans) != 3 {
+			t.Fatalf("got %d spans, want 2 queries + the parent", len(spans))
+		}
+		if spans[0].Name != "db SELECT" || spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("select span = %s, parent %s", spans[0].Name, spans[0].Parent.SpanID())
+		}
+		if spans[1].Name != "db UPDATE" || spans[1].Status.Code.String() != "Error" {
+			t.Errorf("failed query span = %s %v", spans[1].Name, spans[1].Status)
+		}
+	})
+}
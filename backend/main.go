@@ -1,17 +1,39 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
 	"social-network/app/handlers/websocket"
+	"social-network/app/jobs"
+	"social-network/app/store/sqlstore"
+	"social-network/app/telemetry"
 	"social-network/server"
+	"strconv"
+	"syscall"
+	"time"
 
 	"social-network/db"
 )
 
 func main() {
-	// initializing the database:
-	err := db.InitDB()
+	// tracing first, so the database and the jobs pick up the tracer provider (OTEL_TRACES_EXPORTER):
+	shutdownTracing, err := telemetry.Setup()
+	if err != nil {
+		log.Fatal("Invalid tracing configuration:", err)
+	}
+
+	// initializing the database (SQLite by default, PostgreSQL with DB_DRIVER=postgres):
+	dbConfig, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatal("Invalid database configuration:", err)
+	}
+	err = db.InitDB(dbConfig)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
@@ -22,13 +44,85 @@ func main() {
 	//start hub for websocket:
 	go websocket.StartHub()
 
+	// the handlers reach the database through the stores only
+	stores := sqlstore.New(db.Database)
+
+	// background jobs (session cleanup and friends), JOB_WORKERS workers, 2 by default:
+	runner := jobs.NewRunner(stores.Jobs)
+	jobs.RegisterMaintenance(runner, stores)
+	post.RegisterJobs(runner)
+	preview.RegisterJobs(runner)
+
 	// making the outside-wrapper handler with CORS enabled:
-	router := server.SetupRoutes()
+	router := server.SetupRoutes(stores)
+
+	// after SetupRoutes, the jobs of the handler packages use the stores it wires
+	runner.Start(jobWorkers())
+
+	// HTTPS (and HTTP/2) when TLS_CERT_FILE and TLS_KEY_FILE are set, plain HTTP otherwise
+	tlsConfig, err := server.TLSFromEnv()
+	if err != nil {
+		log.Fatal("Invalid TLS configuration:", err)
+	}
 
 	// starting the server:
 	port := ":8080"
-	log.Printf("Server starting on http://localhost%s", port)
-	if err := http.ListenAndServe(port, router); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv, err := server.NewServer(port, router, tlsConfig)
+	if err != nil {
+		log.Fatal("Failed to set up server:", err)
+	}
+	go func() {
+		var err error
+		if tlsConfig == nil {
+			log.Printf("Server starting on http://localhost%s", port)
+			err = srv.ListenAndServe()
+		} else {
+			log.Printf("Server starting on https://localhost%s", port)
+			err = srv.ListenAndServeTLS("", "") // the certificate comes from srv.TLSConfig
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// optional plain HTTP listener that only sends people to the HTTPS one
+	var redirect *http.Server
+	if tlsConfig != nil && tlsConfig.RedirectAddr != "" {
+		redirect = &http.Server{Addr: tlsConfig.RedirectAddr, Handler: server.RedirectToHTTPS(port)}
+		go func() {
+			log.Printf("Redirecting http://localhost%s to HTTPS", tlsConfig.RedirectAddr)
+			if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal("Failed to start redirect listener:", err)
+			}
+		}()
+	}
+
+	// waiting for ctrl+c / docker stop, then finishing the requests and jobs in flight
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	log.Println("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("Server shutdown:", err)
+	}
+	if redirect != nil {
+		redirect.Shutdown(ctx)
+	}
+	if err := runner.Stop(ctx); err != nil {
+		log.Println("Job workers shutdown:", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Println("Tracing shutdown:", err)
+	}
+}
+
+func jobWorkers() int {
+	n, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || n < 1 {
+		return 2
 	}
+	return n
 }
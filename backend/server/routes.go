@@ -4,180 +4,362 @@ import (
 	"net/http"
 	"os"
 
+	"social-network/app/generalfuncs"
+	"social-network/app/handlers/admin"
+	"social-network/app/handlers/audience"
 	"social-network/app/handlers/authorization"
 	"social-network/app/handlers/chat"
+	"social-network/app/handlers/collection"
 	"social-network/app/handlers/comment"
 	"social-network/app/handlers/groups"
 	"social-network/app/handlers/images"
+	"social-network/app/handlers/mention"
 	"social-network/app/handlers/notifications"
+	"social-network/app/handlers/poll"
 	"social-network/app/handlers/post"
+	"social-network/app/handlers/preview"
 	"social-network/app/handlers/profile"
+	"social-network/app/handlers/reaction"
 	"social-network/app/handlers/searchbar"
 	"social-network/app/handlers/websocket"
 	"social-network/app/middleware"
+	"social-network/app/models"
+	"social-network/app/openapi"
+	"social-network/app/params"
+	"social-network/app/ratelimit"
+	"social-network/app/response"
+	"social-network/app/store"
+	"social-network/app/telemetry"
 )
 
-func SetupRoutes() http.Handler {
-	mux := http.NewServeMux() // HTTP Request Multiplexer (router)
+func SetupRoutes(stores *store.Stores) http.Handler {
+	// every package that touches the database gets the same stores
+	middleware.SetStores(stores)
+	generalfuncs.SetStores(stores)
+	audience.SetStores(stores)
+	authorization.SetStores(stores)
+	chat.SetStores(stores)
+	collection.SetStores(stores)
+	comment.SetStores(stores)
+	groups.SetStores(stores)
+	mention.SetStores(stores)
+	notifications.SetStores(stores)
+	poll.SetStores(stores)
+	preview.SetStores(stores)
+	post.SetStores(stores)
+	profile.SetStores(stores)
+	reaction.SetStores(stores)
+	searchbar.SetStores(stores)
+	admin.SetStores(stores)
+
+	mux := newAPI()
+
+	// every API route lives under /api/v1, the old unversioned paths keep working as deprecated aliases
+	// openapi.Validate checks each request against openapi.json before the mux sees it
+	api := openapi.Validate(jsonErrors(mux.ServeMux))
+	root := http.NewServeMux()
+	root.Handle(apiPrefix+"/", http.StripPrefix(apiPrefix, api))
+	if os.Getenv("DISABLE_LEGACY_ROUTES") != "true" {
+		root.Handle("/", deprecated(api))
+	}
+
+	// the spec itself
+	root.HandleFunc("/openapi.json", openapi.ServeSpec)
+
+	// uploaded images are static files and their /images/... paths are stored in the database,
+	// so they stay where they are
+	fs := http.FileServer(http.Dir("./public/images"))
+	root.Handle("/images/", http.StripPrefix("/images/", immutable(fs)))
+
+	// JSON responses are compressed on the way out (the images are already compressed)
+	// telemetry.HTTP is outermost so the span covers the whole request
+	handler := telemetry.HTTP(middleware.HSTS(enableCORS(middleware.Compress(root))))
+	return handler
+}
+
+// newAPI registers every API route, the paths are relative to /api/v1
+func newAPI() *router {
+	mux := &router{ServeMux: http.NewServeMux()} // HTTP Request Multiplexer (router)
 
 	// ROUTING ====================================================================================
+	// patterns are "METHOD /path", the mux answers other methods with 405 and an Allow header
+	// ratelimit.Limit goes inside RequireAuth so the routes that create things are throttled per user
+	// middleware.Idempotent goes before it, a replayed Idempotency-Key doesn't cost a token
+	// middleware.ETag on the big reads answers 304 when the client already has the same JSON
+	// ids go in the path ({id}), the older ?id= / body routes are kept for the current frontend
+
 	// Public authentication endpoints
-	mux.HandleFunc("/register", authorization.RegisterHandler)
-	mux.HandleFunc("/login", authorization.LoginHandler)
+	mux.HandleFunc("POST /register", ratelimit.Limit(ratelimit.Auth, authorization.RegisterHandler))
+	mux.HandleFunc("POST /login", ratelimit.Limit(ratelimit.Auth, authorization.LoginHandler))
 	//de-auth user
-	mux.HandleFunc("/logout", middleware.RequireAuth(authorization.LogoutHandler))
+	mux.HandleFunc("POST /logout", middleware.RequireAuth(authorization.LogoutHandler))
 
 	// Auth-required endpoints
-	mux.HandleFunc("/me", middleware.MeHandler)
+	mux.HandleFunc("GET /me", middleware.MeHandler)
 
 	// Profile
-	mux.HandleFunc("/profile", middleware.RequireAuth(profile.ProfileHandler))
-	mux.HandleFunc("/profile/privacy", middleware.RequireAuth(profile.UpdateProfilePrivacyHandler))
+	mux.HandleFunc("GET /profile", middleware.RequireAuth(middleware.ETag(profile.ProfileHandler)))
+	mux.HandleFunc("GET /users/{id}", middleware.RequireAuth(middleware.ETag(profile.ProfileHandler)))
+	mux.HandleFunc("POST /profile/privacy", middleware.RequireAuth(profile.UpdateProfilePrivacyHandler))
 
 	// Notifications
-	mux.HandleFunc("/notifications", middleware.RequireAuth(notifications.GetNotificationsHandler))
-	mux.HandleFunc("/notifications/read-all", middleware.RequireAuth(notifications.MarkAllNotificationsRead))
-	mux.HandleFunc("/notifications/remove", middleware.RequireAuth(notifications.RemoveNotificationHandler))
+	mux.HandleFunc("GET /notifications", middleware.RequireAuth(notifications.GetNotificationsHandler))
+	mux.HandleFunc("POST /notifications/read-all", middleware.RequireAuth(notifications.MarkAllNotificationsRead))
+	mux.HandleFunc("DELETE /notifications/{id}", middleware.RequireAuth(notifications.RemoveNotificationHandler))
+	mux.HandleFunc("DELETE /notifications/remove", middleware.RequireAuth(notifications.RemoveNotificationHandler))
 
 	// follow requests
-	mux.HandleFunc("/follow/request", middleware.RequireAuth(profile.FollowRequestHandler))
-	mux.HandleFunc("/follow/requests/pending", middleware.RequireAuth(profile.ViewFollowRequestsHandler))
-	mux.HandleFunc("/follow/request/status", middleware.RequireAuth(profile.UpdateFollowRequestStatusHandler))
-	mux.HandleFunc("/follow/unfollow", middleware.RequireAuth(profile.UnfollowHandler))
-	mux.HandleFunc("/follow/request/cancel", middleware.RequireAuth(profile.CancelFollowRequestHandler))
-
-	// Upload images
-	fs := http.FileServer(http.Dir("./public/images"))
-	mux.Handle("/images/", http.StripPrefix("/images/", fs))
-	mux.HandleFunc("/image/upload", images.ImageUploadHandler)
+	mux.HandleFunc("POST /users/{id}/follow", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.FollowRequestHandler)))
+	mux.HandleFunc("DELETE /users/{id}/follow", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.UnfollowHandler)))
+	mux.HandleFunc("DELETE /users/{id}/follow-request", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.CancelFollowRequestHandler)))
+	mux.HandleFunc("GET /follow/requests/pending", middleware.RequireAuth(profile.ViewFollowRequestsHandler))
+	mux.HandleFunc("POST /follow/requests/{id}/{action}", middleware.RequireAuth(profile.UpdateFollowRequestStatusHandler))
+	mux.HandleFunc("POST /follow/request", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.FollowRequestHandler)))
+	mux.HandleFunc("POST /follow/request/status", middleware.RequireAuth(profile.UpdateFollowRequestStatusHandler))
+	mux.HandleFunc("POST /follow/unfollow", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.UnfollowHandler)))
+	mux.HandleFunc("POST /follow/request/cancel", middleware.RequireAuth(ratelimit.Limit(ratelimit.Follows, profile.CancelFollowRequestHandler)))
+
+	// Upload images (the uploaded files themselves are served outside the API, see below)
+	mux.HandleFunc("POST /image/upload", ratelimit.Limit(ratelimit.Uploads, images.ImageUploadHandler))
 
 	// Posts
-	mux.HandleFunc("/posts", middleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			post.GetFeedPosts(w, r)
-		case http.MethodPost:
-			post.CreatePost(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
+	mux.HandleFunc("GET /posts", middleware.RequireAuth(middleware.ETag(post.GetFeedPosts)))
+	mux.HandleFunc("POST /posts", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Posts, post.CreatePost))))
 	// single post retrieval
-	mux.HandleFunc("/post", middleware.RequireAuth(post.GetPostHandler))
+	mux.HandleFunc("GET /posts/{id}", middleware.RequireAuth(middleware.ETag(post.GetPostHandler)))
+	mux.HandleFunc("GET /post", middleware.RequireAuth(middleware.ETag(post.GetPostHandler)))
+	// author only: editing keeps the old version in post_revisions, deleting takes the images along
+	mux.HandleFunc("PUT /posts/{id}", middleware.RequireAuth(ratelimit.Limit(ratelimit.Posts, post.UpdatePost)))
+	mux.HandleFunc("DELETE /posts/{id}", middleware.RequireAuth(post.DeletePost))
+	mux.HandleFunc("GET /posts/{id}/revisions", middleware.RequireAuth(middleware.ETag(post.GetPostRevisions)))
+	// share a visible post, with content it's a quote post. Non public posts stay inside their audience
+	mux.HandleFunc("POST /posts/{id}/reposts", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Posts, post.Repost))))
+	// drafts and scheduled posts ("publish_at"), only their author sees them until they're published
+	mux.HandleFunc("GET /drafts", middleware.RequireAuth(post.ListDrafts))
+	mux.HandleFunc("POST /drafts", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Posts, post.CreateDraft))))
+	mux.HandleFunc("PUT /drafts/{id}", middleware.RequireAuth(ratelimit.Limit(ratelimit.Posts, post.UpdateDraft)))
+	mux.HandleFunc("DELETE /drafts/{id}", middleware.RequireAuth(post.DeleteDraft))
+	mux.HandleFunc("POST /drafts/{id}/publish", middleware.RequireAuth(post.PublishDraft))
+	// Hashtags, the tag page uses the feed's visibility rules
+	mux.HandleFunc("GET /tags/trending", middleware.RequireAuth(post.GetTrendingTags))
+	mux.HandleFunc("GET /tags/{tag}/posts", middleware.RequireAuth(middleware.ETag(post.GetTagPosts)))
 	// getFollowers endpoint for almost private posts
-	mux.HandleFunc("/followers", middleware.RequireAuth(post.GetFollowersHandler))
+	mux.HandleFunc("GET /followers", middleware.RequireAuth(post.GetFollowersHandler))
+
+	// Comments
+	mux.HandleFunc("GET /posts/{id}/comments", middleware.RequireAuth(comment.GetComments))
+	mux.HandleFunc("POST /posts/{id}/comments", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Comments, comment.CreateComment))))
+	mux.HandleFunc("GET /comments", middleware.RequireAuth(comment.GetComments))
+	mux.HandleFunc("POST /comments", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Comments, comment.CreateComment))))
+
+	// Reactions, one per user and target: PUT sets or changes it ({"type": "like"}), DELETE removes it
+	mux.HandleFunc("PUT /posts/{id}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.React(models.TargetPost))))
+	mux.HandleFunc("DELETE /posts/{id}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.Unreact(models.TargetPost))))
+	mux.HandleFunc("PUT /comments/{id}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.React(models.TargetComment))))
+	mux.HandleFunc("DELETE /comments/{id}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.Unreact(models.TargetComment))))
+
+	// Polls of posts and group posts, anyone who sees the post can vote. PUT sets or changes the
+	// vote ({"option_ids": [3]}) until the poll closes, DELETE takes it back
+	mux.HandleFunc("GET /polls/{id}", middleware.RequireAuth(poll.GetPoll))
+	mux.HandleFunc("PUT /polls/{id}/vote", middleware.RequireAuth(ratelimit.Limit(ratelimit.Votes, poll.Vote)))
+	mux.HandleFunc("DELETE /polls/{id}/vote", middleware.RequireAuth(ratelimit.Limit(ratelimit.Votes, poll.Unvote)))
+
+	// Bookmarks, private collections of saved posts and group posts. "Saved" is created on first use
+	// and can't be deleted, the items only show what the user can still see
+	mux.HandleFunc("GET /collections", middleware.RequireAuth(collection.ListCollections))
+	mux.HandleFunc("POST /collections", middleware.RequireAuth(collection.CreateCollection))
+	mux.HandleFunc("DELETE /collections/{id}", middleware.RequireAuth(collection.DeleteCollection))
+	mux.HandleFunc("GET /collections/{id}/items", middleware.RequireAuth(middleware.ETag(collection.ListItems)))
+	mux.HandleFunc("POST /collections/{id}/items", middleware.RequireAuth(collection.AddItem))
+	mux.HandleFunc("PUT /collections/{id}/items/order", middleware.RequireAuth(collection.ReorderItems))
+	mux.HandleFunc("DELETE /collections/{id}/items/{itemID}", middleware.RequireAuth(collection.RemoveItem))
+
+	// Audience lists, named groups of people an almost_private post can be shared with
+	// ("audience_lists": [3]). Only their owner sees them, a post follows their current members
+	mux.HandleFunc("GET /audience-lists", middleware.RequireAuth(audience.ListAudiences))
+	mux.HandleFunc("POST /audience-lists", middleware.RequireAuth(audience.CreateAudience))
+	mux.HandleFunc("GET /audience-lists/{id}", middleware.RequireAuth(audience.GetAudience))
+	mux.HandleFunc("PATCH /audience-lists/{id}", middleware.RequireAuth(audience.RenameAudience))
+	mux.HandleFunc("DELETE /audience-lists/{id}", middleware.RequireAuth(audience.DeleteAudience))
+	mux.HandleFunc("PUT /audience-lists/{id}/members", middleware.RequireAuth(audience.AddMembers))
+	mux.HandleFunc("DELETE /audience-lists/{id}/members/{userID}", middleware.RequireAuth(audience.RemoveMember))
+
 	// WebSocket endpoint
-	mux.HandleFunc("/ws", middleware.RequireAuth(websocket.WebSocketHandler))
-
-	// //private chat and group chat messages
-	mux.HandleFunc("/chat/messages", middleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			chat.GetMessages(w, r)
-		case http.MethodPost:
-			chat.SendMessage(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
-	// Group chat messages
-	mux.HandleFunc("/chat/group-messages", middleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			chat.GetGroupMessages(w, r)
-		case http.MethodPost:
-			chat.SendGroupMessage(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
+	mux.HandleFunc("GET /ws", middleware.RequireAuth(websocket.WebSocketHandler))
 
-	// // Chat conversations
-	mux.HandleFunc("/chat/conversations", middleware.RequireAuth(chat.GetConversations))
-	// mux.HandleFunc("/chat/messages", middleware.RequireAuth(chat.GetMessages))
+	// private chat and group chat messages
+	mux.HandleFunc("GET /users/{id}/messages", middleware.RequireAuth(chat.GetMessages))
+	mux.HandleFunc("POST /users/{id}/messages", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Messages, chat.SendMessage))))
+	mux.HandleFunc("GET /groups/{id}/messages", middleware.RequireAuth(chat.GetGroupMessages))
+	mux.HandleFunc("POST /groups/{id}/messages", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Messages, chat.SendGroupMessage))))
+	mux.HandleFunc("GET /chat/messages", middleware.RequireAuth(chat.GetMessages))
+	mux.HandleFunc("POST /chat/messages", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Messages, chat.SendMessage))))
+	mux.HandleFunc("GET /chat/group-messages", middleware.RequireAuth(chat.GetGroupMessages))
+	mux.HandleFunc("POST /chat/group-messages", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Messages, chat.SendGroupMessage))))
 
-	// Comments
-	mux.HandleFunc("/comments", middleware.RequireAuth(comment.CommentsHandler))
+	// Chat conversations
+	mux.HandleFunc("GET /chat/conversations", middleware.RequireAuth(chat.GetConversations))
 
 	// search bar
 	// Search (users / groups)
-	mux.HandleFunc("/search", middleware.RequireAuth(searchbar.SearchBarHandler))
+	mux.HandleFunc("GET /search", middleware.RequireAuth(searchbar.SearchBarHandler))
+	// the @mention popup, a prefix match on usernames
+	mux.HandleFunc("GET /users/autocomplete", middleware.RequireAuth(mention.Autocomplete))
 
 	// Groups
-	mux.Handle("/groups", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			groups.CreateGroup(w, r)
-		case http.MethodGet:
-			groups.ListGroups(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})))
-
-	mux.Handle("/groups/details", middleware.RequireAuth(http.HandlerFunc(groups.GetGroup)))
-	mux.Handle("/groups/members", middleware.RequireAuth(http.HandlerFunc(groups.GetGroupMembers)))
+	mux.HandleFunc("GET /groups", middleware.RequireAuth(groups.ListGroups))
+	mux.HandleFunc("POST /groups", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.CreateGroup)))
+	mux.HandleFunc("GET /groups/{id}", middleware.RequireAuth(middleware.ETag(groups.GetGroup)))
+	mux.HandleFunc("GET /groups/details", middleware.RequireAuth(middleware.ETag(groups.GetGroup)))
+
+	// Group members, DELETE is leaving (own id) or being removed by the creator
+	mux.HandleFunc("GET /groups/{id}/members", middleware.RequireAuth(groups.GetGroupMembers))
+	mux.HandleFunc("DELETE /groups/{id}/members/{userID}", middleware.RequireAuth(groups.RemoveGroupMember))
+	mux.HandleFunc("GET /groups/members", middleware.RequireAuth(groups.GetGroupMembers))
+	//added leave group handler
+	mux.HandleFunc("POST /groups/leave", middleware.RequireAuth(groups.LeaveGroup))
 
 	// get users for inviting to group
-	mux.Handle("/users", middleware.RequireAuth(http.HandlerFunc(groups.GetAllUsers)))
+	mux.HandleFunc("GET /users", middleware.RequireAuth(groups.GetAllUsers))
 
 	// Group Invitations
-	mux.Handle("/groups/invite", middleware.RequireAuth(http.HandlerFunc(groups.InviteUserToGroup)))
-	mux.Handle("/groups/invite/accept", middleware.RequireAuth(http.HandlerFunc(groups.AcceptGroupInvite)))
-	mux.Handle("/groups/invite/decline", middleware.RequireAuth(http.HandlerFunc(groups.DeclineGroupInvite)))
+	mux.HandleFunc("POST /groups/{id}/invites", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.InviteUserToGroup)))
+	mux.HandleFunc("POST /groups/{id}/invite/accept", middleware.RequireAuth(groups.AcceptGroupInvite))
+	mux.HandleFunc("POST /groups/{id}/invite/decline", middleware.RequireAuth(groups.DeclineGroupInvite))
+	mux.HandleFunc("POST /groups/invite", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.InviteUserToGroup)))
+	mux.HandleFunc("POST /groups/invite/accept", middleware.RequireAuth(groups.AcceptGroupInvite))
+	mux.HandleFunc("POST /groups/invite/decline", middleware.RequireAuth(groups.DeclineGroupInvite))
 
 	// Group Join Requests
-	mux.Handle("/groups/request", middleware.RequireAuth(http.HandlerFunc(groups.RequestJoinGroup)))
-	mux.Handle("/groups/requests", middleware.RequireAuth(http.HandlerFunc(groups.GetJoinRequests)))
-	mux.Handle("/groups/request/approve", middleware.RequireAuth(http.HandlerFunc(groups.ApproveJoinRequest)))
-	mux.Handle("/groups/request/reject", middleware.RequireAuth(http.HandlerFunc(groups.RejectJoinRequest)))
+	mux.HandleFunc("GET /groups/{id}/requests", middleware.RequireAuth(groups.GetJoinRequests))
+	mux.HandleFunc("POST /groups/{id}/requests", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.RequestJoinGroup)))
+	mux.HandleFunc("POST /groups/{id}/requests/{userID}/approve", middleware.RequireAuth(groups.ApproveJoinRequest))
+	mux.HandleFunc("POST /groups/{id}/requests/{userID}/reject", middleware.RequireAuth(groups.RejectJoinRequest))
+	mux.HandleFunc("POST /groups/request", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.RequestJoinGroup)))
+	mux.HandleFunc("GET /groups/requests", middleware.RequireAuth(groups.GetJoinRequests))
+	mux.HandleFunc("POST /groups/request/approve", middleware.RequireAuth(groups.ApproveJoinRequest))
+	mux.HandleFunc("POST /groups/request/reject", middleware.RequireAuth(groups.RejectJoinRequest))
 
 	// Group Posts
-	mux.Handle("/groups/posts", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			groups.CreateGroupPost(w, r)
-		case http.MethodGet:
-			groups.ListGroupPosts(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})))
+	mux.HandleFunc("GET /groups/{id}/posts", middleware.RequireAuth(middleware.ETag(groups.ListGroupPosts)))
+	mux.HandleFunc("POST /groups/{id}/posts", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Posts, groups.CreateGroupPost))))
+	mux.HandleFunc("GET /groups/posts", middleware.RequireAuth(middleware.ETag(groups.ListGroupPosts)))
+	mux.HandleFunc("POST /groups/posts", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Posts, groups.CreateGroupPost))))
 
 	// Group Comments
-	mux.Handle("/groups/comments", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			groups.CreateGroupComment(w, r)
-		case http.MethodGet:
-			groups.ListGroupComments(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})))
+	mux.HandleFunc("GET /groups/{id}/posts/{postID}/comments", middleware.RequireAuth(groups.ListGroupComments))
+	mux.HandleFunc("POST /groups/{id}/posts/{postID}/comments", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Comments, groups.CreateGroupComment))))
+	mux.HandleFunc("GET /groups/comments", middleware.RequireAuth(groups.ListGroupComments))
+	mux.HandleFunc("POST /groups/comments", middleware.RequireAuth(middleware.Idempotent(ratelimit.Limit(ratelimit.Comments, groups.CreateGroupComment))))
+
+	// Group reactions, same as the ones above
+	mux.HandleFunc("PUT /groups/{id}/posts/{postID}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.React(models.TargetGroupPost))))
+	mux.HandleFunc("DELETE /groups/{id}/posts/{postID}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.Unreact(models.TargetGroupPost))))
+	mux.HandleFunc("PUT /groups/{id}/comments/{commentID}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.React(models.TargetGroupComment))))
+	mux.HandleFunc("DELETE /groups/{id}/comments/{commentID}/reactions", middleware.RequireAuth(ratelimit.Limit(ratelimit.Reactions, reaction.Unreact(models.TargetGroupComment))))
 
 	// Group Events
-	mux.Handle("/groups/events", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			groups.CreateGroupEvent(w, r)
-		case http.MethodGet:
-			groups.ListGroupEvents(w, r)
+	mux.HandleFunc("GET /groups/{id}/events", middleware.RequireAuth(groups.ListGroupEvents))
+	mux.HandleFunc("POST /groups/{id}/events", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.CreateGroupEvent)))
+	mux.HandleFunc("POST /groups/{id}/events/{eventID}/response", middleware.RequireAuth(groups.RespondToEvent))
+	mux.HandleFunc("GET /groups/events", middleware.RequireAuth(groups.ListGroupEvents))
+	mux.HandleFunc("POST /groups/events", middleware.RequireAuth(ratelimit.Limit(ratelimit.Groups, groups.CreateGroupEvent)))
+	mux.HandleFunc("POST /groups/events/respond", middleware.RequireAuth(groups.RespondToEvent))
+
+	// Admin (ADMIN_EMAILS)
+	mux.HandleFunc("GET /admin/jobs", middleware.RequireAdmin(admin.ListJobs))
+
+	// ============================================================================================
+
+	return mux
+}
+
+// router is a ServeMux that remembers what was registered on it,
+// routes_test.go checks every pattern against openapi.json
+type router struct {
+	*http.ServeMux
+	patterns []string
+}
+
+// every route also names the request span after its pattern
+func (rt *router) Handle(pattern string, handler http.Handler) {
+	rt.patterns = append(rt.patterns, pattern)
+	rt.ServeMux.Handle(pattern, telemetry.Route(pattern, handler))
+}
+
+func (rt *router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// apiPrefix is the mount point of the current API version
+const apiPrefix = "/api/v1"
+
+// jsonErrors answers the mux's own 404 and 405 with the error envelope instead of plain text,
+// the Allow header the mux sets on a 405 is kept
+func jsonErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// no route matched, let the mux decide what that means and only keep the status and Allow
+		rec := &statusRecorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		switch rec.status {
+		case http.StatusNotFound:
+			response.Error(w, http.StatusNotFound, "Route not found")
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			response.Error(w, http.StatusMethodNotAllowed, "Method not allowed")
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			for key, values := range rec.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(rec.status)
 		}
-	})))
+	})
+}
 
-	mux.Handle("/groups/events/respond", middleware.RequireAuth(http.HandlerFunc(groups.RespondToEvent)))
-	//added leave group handler
-	mux.Handle("/groups/leave", middleware.RequireAuth(http.HandlerFunc(groups.LeaveGroup)))
+// statusRecorder is a ResponseWriter that throws the body away
+type statusRecorder struct {
+	header http.Header
+	status int
+}
 
-	// ============================================================================================
+func (rec *statusRecorder) Header() http.Header         { return rec.header }
+func (rec *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (rec *statusRecorder) WriteHeader(status int)      { rec.status = status }
 
-	handler := enableCORS(mux)
-	return handler
+// immutable marks the uploaded images as cacheable forever, every upload gets a new uuid file name
+// so a file never changes once it's there
+func immutable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(immutableWriter{w}, r)
+	})
+}
+
+// immutableWriter only adds the header to found files, a 404 must not be cached for a year
+type immutableWriter struct {
+	http.ResponseWriter
+}
+
+func (w immutableWriter) WriteHeader(status int) {
+	if status == http.StatusOK || status == http.StatusPartialContent || status == http.StatusNotModified {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// deprecated serves a route on its pre /api/v1 path and tells the client where it moved.
+// The lists keep their old unpaged bodies there (see params.Unpaged)
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiPrefix+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, params.MarkLegacy(r))
+	})
 }
 
 func enableCORS(next http.Handler) http.Handler {
@@ -189,8 +371,9 @@ func enableCORS(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, If-None-Match, traceparent, tracestate")
+		w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Link, ETag, Idempotent-Replayed, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == "OPTIONS" {
@@ -207,6 +390,25 @@ func enableCORS(next http.Handler) http.Handler {
 - setupRoutes() returns a handler and registers all the route handlers
 	this handler is actually a wrapper for the "inside" mux router-handler
 
+- setupRoutes() also hands the stores (the data layer, see app/store) to every handler package,
+	main.go passes the SQL ones, tests can pass memstore.New() instead
+
+- routes are registered once on the inner mux and mounted twice:
+	/api/v1/...   the versioned API, StripPrefix removes /api/v1 before the inner mux matches the path
+	/...          the old paths, same handlers plus "Deprecation: true" and a Link header to the /api/v1 path
+	the old paths are a transition period only, DISABLE_LEGACY_ROUTES=true turns them off
+
+- routes use the Go 1.22 patterns: "GET /groups/{id}/events" only matches GET, the handler reads the id
+	with params.ID / params.PathID (app/params). A path that exists with another method gets
+	405 + Allow from the mux, jsonErrors() turns that and the 404 into the JSON error envelope,
+	so handlers don't check r.Method anymore
+
+- the ?id= and body id routes (/groups/details, /chat/messages...) are the ones the frontend uses,
+	they point at the same handlers as the /{id} routes and are marked deprecated in openapi.json
+
+- app/openapi/openapi.json describes every route of newAPI(), it is served at /openapi.json
+	a new route needs its operation there too, TestEveryRouteIsInTheSpec fails otherwise
+
 - EnableCORS() is the function that actually creates the outside handler-wrapper (which is actually
 	what setupRoutes() returns)
 	It runs only once in main.go when setupRoutes() is called, not on every request
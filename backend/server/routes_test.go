@@ -0,0 +1,246 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"social-network/app/openapi"
+	"social-network/app/response"
+	"social-network/app/store/memstore"
+)
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) response.ErrorBody {
+	t.Helper()
+	var env response.Envelope
+	if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+		t.Fatalf("body is not the error envelope: %v", err)
+	}
+	return env.Error
+}
+
+func TestVersionedRoutesAndAliases(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+
+	tests := []struct {
+		path       string
+		status     int
+		code       string
+		deprecated bool
+	}{
+		{"/api/v1/posts", http.StatusUnauthorized, "unauthorized", false},
+		{"/posts", http.StatusUnauthorized, "unauthorized", true},
+		{"/api/v1/does-not-exist", http.StatusNotFound, "not_found", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if got := decodeError(t, w).Code; got != tt.code {
+				t.Errorf("code %q, want %q", got, tt.code)
+			}
+			if got := w.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+				t.Errorf("Deprecation header = %v, want %v", got, tt.deprecated)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notifications", nil))
+	if link := w.Header().Get("Link"); link != `</api/v1/notifications>; rel="successor-version"` {
+		t.Errorf("Link header = %q", link)
+	}
+}
+
+func TestValidationErrorListsFields(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(`{"email":"a@test.com"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d", w.Code)
+	}
+	body := decodeError(t, w)
+	if body.Code != response.CodeValidation || len(body.Fields) != 4 {
+		t.Errorf("error = %+v, want validation_failed with 4 fields", body)
+	}
+}
+
+func TestEveryRouteIsInTheSpec(t *testing.T) {
+	spec := openapi.Spec()
+	registered := map[string]bool{}
+	for _, pattern := range newAPI().patterns {
+		registered[pattern] = true
+		method, path, _ := strings.Cut(pattern, " ")
+		if spec.Paths[path][strings.ToLower(method)] == nil {
+			t.Errorf("route %s is registered but missing from app/openapi/openapi.json", pattern)
+		}
+	}
+	for _, op := range spec.Operations() {
+		if !registered[op] {
+			t.Errorf("openapi.json describes %s but no such route is registered", op)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	SetupRoutes(memstore.New()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi": "3.0.3"`) {
+		t.Errorf("GET /openapi.json = %d", w.Code)
+	}
+}
+
+func TestPreflightAllowsEveryMethod(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetupRoutes(memstore.New()).ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/api/v1/audience-lists/1", nil))
+	allowed := strings.Split(w.Header().Get("Access-Control-Allow-Methods"), ", ")
+	for _, pattern := range newAPI().patterns {
+		method, _, _ := strings.Cut(pattern, " ")
+		if !slices.Contains(allowed, method) {
+			t.Errorf("route %s: the preflight only allows %v", pattern, allowed)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/groups/3/events", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d, want 405", w.Code)
+	}
+	if got := decodeError(t, w).Code; got != "method_not_allowed" {
+		t.Errorf("code %q", got)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, POST" {
+		t.Errorf("Allow = %q", allow)
+	}
+}
+
+func TestPathParameterRoutes(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+	var cookies []*http.Cookie
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1"+target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	do(http.MethodPost, "/register", `{"email":"a@test.com","password":"secret","first_name":"A","last_name":"B","date_of_birth":"2000-01-01"}`)
+	login := do(http.MethodPost, "/login", `{"email":"a@test.com","password":"secret"}`)
+	if login.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", login.Code, login.Body)
+	}
+	cookies = login.Result().Cookies()
+	var me struct {
+		UserID int `json:"user_id"`
+	}
+	json.NewDecoder(login.Body).Decode(&me)
+
+	var group struct {
+		ID int `json:"id"`
+	}
+	json.NewDecoder(do(http.MethodPost, "/groups", `{"group_name":"gophers","title":"Gophers"}`).Body).Decode(&group)
+	groupPath := "/groups/" + strconv.Itoa(group.ID)
+
+	if w := do(http.MethodPost, groupPath+"/events", `{"title":"Meetup","event_date":4102444800}`); w.Code != http.StatusCreated {
+		t.Fatalf("create event: %d %s", w.Code, w.Body)
+	}
+	var events struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	json.NewDecoder(do(http.MethodGet, groupPath+"/events", "").Body).Decode(&events)
+	if len(events.Items) != 1 || events.Items[0].Title != "Meetup" {
+		t.Errorf("events = %+v", events)
+	}
+
+	if w := do(http.MethodGet, "/groups/abc/events", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("non numeric id: %d", w.Code)
+	}
+
+	if w := do(http.MethodDelete, groupPath+"/members/"+strconv.Itoa(me.UserID), ""); w.Code != http.StatusOK {
+		t.Fatalf("leave: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, groupPath+"/events", ""); w.Code != http.StatusForbidden {
+		t.Errorf("events after leaving: %d", w.Code)
+	}
+}
+
+func TestCompressionAndImageCaching(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+
+	r := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("spec not compressed: %v", w.Header())
+	}
+
+	// a missing image must not be cached as immutable
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images/missing.png", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("missing image: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestListEnvelope(t *testing.T) {
+	router := SetupRoutes(memstore.New())
+	var cookies []*http.Cookie
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/api/v1/register", `{"email":"a@test.com","password":"secret","first_name":"A","last_name":"B","date_of_birth":"2000-01-01"}`)
+	cookies = do(http.MethodPost, "/api/v1/login", `{"email":"a@test.com","password":"secret"}`).Result().Cookies()
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/api/v1/notifications", http.StatusOK, `{"items":[],"next_cursor":null}`},
+		// the deprecated route keeps the bare array the frontend reads, until it asks for a page
+		{"/notifications", http.StatusOK, `[]`},
+		{"/notifications?limit=5", http.StatusOK, `{"items":[],"next_cursor":null}`},
+		{"/api/v1/posts", http.StatusOK, `{"items":[],"next_cursor":null}`},
+		{"/posts", http.StatusOK, `{"posts":[]}`},
+		{"/api/v1/notifications?limit=0", http.StatusBadRequest, ""},
+		{"/api/v1/notifications?limit=500", http.StatusBadRequest, ""},
+		{"/api/v1/notifications?cursor=nonsense", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := do(http.MethodGet, tt.target, "")
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.target, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("%s: body %s, want %s", tt.target, w.Body, tt.body)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig is the HTTPS setup read from the environment, main.go serves plain HTTP when it's nil
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// RedirectAddr is where the HTTP -> HTTPS redirect listens (":80"), empty for none
+	RedirectAddr string
+}
+
+// TLSFromEnv reads TLS_CERT_FILE, TLS_KEY_FILE and HTTP_REDIRECT_ADDR, both files or none must be set
+func TLSFromEnv() (*TLSConfig, error) {
+	cfg := &TLSConfig{
+		CertFile:     os.Getenv("TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("TLS_KEY_FILE"),
+		RedirectAddr: os.Getenv("HTTP_REDIRECT_ADDR"),
+	}
+	switch {
+	case cfg.CertFile == "" && cfg.KeyFile == "":
+		if cfg.RedirectAddr != "" {
+			return nil, fmt.Errorf("HTTP_REDIRECT_ADDR needs TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	case cfg.CertFile == "" || cfg.KeyFile == "":
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	return cfg, nil
+}
+
+// NewServer returns the http.Server for addr, with HTTPS and HTTP/2 when cfg is set.
+// The certificate is loaded here once, so a wrong path fails at startup and not on the first request
+func NewServer(addr string, handler http.Handler, cfg *TLSConfig) (*http.Server, error) {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	if cfg == nil {
+		return srv, nil
+	}
+
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+	// HTTP/2 is what browsers pick over TLS, HTTP/1.1 stays for the older clients and the websocket
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(true)
+	return srv, nil
+}
+
+// certReloader hands out the certificate and reads the files again when they change on disk,
+// renewing a certificate (certbot...) then doesn't need a restart
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// reloadCheckEvery keeps the handshakes from stat-ing the files every time
+const reloadCheckEvery = 10 * time.Second
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	c.cert = &cert
+	c.modTime = c.lastModified()
+	return nil
+}
+
+// lastModified is the newest modification time of the two files
+func (c *certReloader) lastModified() time.Time {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		if info, err := os.Stat(name); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := time.Now(); now.Sub(c.checkedAt) >= reloadCheckEvery {
+		c.checkedAt = now
+		if c.lastModified().After(c.modTime) {
+			// a half written pair fails to load, the old certificate is kept until the next check
+			if err := c.load(); err != nil {
+				log.Printf("[TLS] keeping the current certificate: %v", err)
+			} else {
+				log.Println("[TLS] certificate reloaded")
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+// RedirectToHTTPS answers every plain HTTP request with a permanent redirect to the same URL on
+// httpsAddr. 308 keeps the method and body, a POST stays a POST
+func RedirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for localhost with the given common name
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, c *certReloader) string {
+	t.Helper()
+	cert, _ := c.GetCertificate(nil)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "first")
+	c, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := commonName(t, c); name != "first" {
+		t.Fatalf("loaded %q", name)
+	}
+
+	writeCert(t, dir, "renewed")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	c.checkedAt = time.Time{} // skip the wait between checks
+	if name := commonName(t, c); name != "renewed" {
+		t.Errorf("after renewal got %q", name)
+	}
+}
+
+func TestServerSpeaksHTTP2(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir(), "test")
+	srv, err := NewServer("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			t.Error("request without TLS")
+		}
+	}), &TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	res, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.ProtoMajor != 2 {
+		t.Errorf("served over %s", res.Proto)
+	}
+}
+
+func TestTLSFromEnv(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "")
+	if _, err := TLSFromEnv(); err == nil {
+		t.Error("cert without key accepted")
+	}
+	t.Setenv("TLS_CERT_FILE", "")
+	if cfg, err := TLSFromEnv(); cfg != nil || err != nil {
+		t.Errorf("no TLS = %+v, %v", cfg, err)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := map[string]string{
+		":443":  "https://example.com/api/v1/posts?id=2",
+		":8443": "https://example.com:8443/api/v1/posts?id=2",
+	}
+	for addr, want := range tests {
+		w := httptest.NewRecorder()
+		RedirectToHTTPS(addr).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://example.com:80/api/v1/posts?id=2", nil))
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != want {
+			t.Errorf("%s: %d %s", addr, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
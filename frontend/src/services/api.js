@@ -3,6 +3,16 @@ import { useAuthStore } from '../stores/auth'
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080'
 
+// errors come as {"error": {"code", "message", "fields"}}, fall back to the raw text
+async function errorMessage(res, fallback) {
+  const text = await res.text()
+  try {
+    return JSON.parse(text).error.message || fallback
+  } catch {
+    return text || fallback
+  }
+}
+
 // centralized fetch helper
 async function fetchWithAuth(url, options = {}) {
   const authStore = useAuthStore()
@@ -17,8 +27,7 @@ async function fetchWithAuth(url, options = {}) {
   }
 
   if (!res.ok) {
-    const text = await res.text()
-    throw new Error(text || 'API error')
+    throw new Error(await errorMessage(res, 'API error'))
   }
 
   const contentType = res.headers.get('content-type')
@@ -249,8 +258,7 @@ const api = {
     })
 
     if (!res.ok) {
-      const text = await res.text()
-      throw new Error(text || 'Upload failed')
+      throw new Error(await errorMessage(res, 'Upload failed'))
     }
 
     return res.json()